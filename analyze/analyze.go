@@ -0,0 +1,122 @@
+// Package analyze reports properties of object graphs that affect the cost
+// and the result of deep cloning them.
+package analyze
+
+import (
+    "fmt"
+    "reflect"
+    "strings"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Object is memory referenced by pointers, slices or maps in a graph.
+type Object struct {
+    // Type is the type of the references.
+    Type reflect.Type
+    // Paths lists every path referencing the object, in the order of
+    // cloner.Walk. The object is walked, and cloned, through the first one.
+    Paths []cloner.Path
+}
+
+// SharingReport lists the objects of a graph that are referenced more than
+// once. A clone preserves the sharing, copying each of them once.
+type SharingReport struct {
+    // Shared lists the objects referenced from more than one place.
+    Shared []*Object
+    // Cycles lists the objects that are part of a cycle, i.e. that can be
+    // reached from themselves. They are also in Shared.
+    Cycles []*Object
+}
+
+// String formats the report with one line per object.
+func (r *SharingReport) String() string {
+    var b strings.Builder
+    for _, o := range r.Shared {
+        fmt.Fprintf(&b, "shared %s referenced %d times: %s\n", o.Type, len(o.Paths), formatPaths(o.Paths))
+    }
+    for _, o := range r.Cycles {
+        fmt.Fprintf(&b, "cycle through %s at %s\n", o.Type, formatPaths(o.Paths[:1]))
+    }
+    return b.String()
+}
+
+func formatPaths(paths []cloner.Path) string {
+    formatted := make([]string, len(paths))
+    for i, path := range paths {
+        formatted[i] = string(path)
+        if path == "" {
+            formatted[i] = "(root)"
+        }
+    }
+    return strings.Join(formatted, ", ")
+}
+
+// Sharing reports which objects in v are referenced from multiple places
+// and which participate in cycles, following the traversal of cloner.Walk.
+// Objects are listed in the order they are first reached.
+func Sharing(v interface{}) *SharingReport {
+    objects := make(map[memref.Ref]*Object)
+    var order []*Object
+    inCycle := make(map[*Object]bool)
+    var active []*Object // references being walked, outermost first
+
+    cloner.Walk(v, func(node cloner.Node) error {
+        // Leave the references whose contents have all been visited
+        for len(active) > 0 && !isWithin(node.Path, active[len(active)-1].Paths[0]) {
+            active = active[:len(active)-1]
+        }
+
+        switch node.Value.Kind() {
+        case reflect.Ptr, reflect.Slice, reflect.Map:
+            if node.Value.IsNil() {
+                return nil
+            }
+        default:
+            return nil
+        }
+        ref := memref.Of(node.Value)
+        o, found := objects[ref]
+        if !found {
+            o = &Object{Type: ref.Type()}
+            objects[ref] = o
+            order = append(order, o)
+        }
+        o.Paths = append(o.Paths, node.Path)
+
+        if node.Cycle {
+            // Every reference from o down to this one is part of the cycle
+            for i := len(active) - 1; i >= 0; i-- {
+                inCycle[active[i]] = true
+                if active[i] == o {
+                    break
+                }
+            }
+        }
+        if !node.Shared {
+            active = append(active, o)
+        }
+        return nil
+    })
+
+    report := &SharingReport{}
+    for _, o := range order {
+        if len(o.Paths) > 1 {
+            report.Shared = append(report.Shared, o)
+        }
+        if inCycle[o] {
+            report.Cycles = append(report.Cycles, o)
+        }
+    }
+    return report
+}
+
+// isWithin reports whether path is base or a path below it.
+func isWithin(path, base cloner.Path) bool {
+    if !strings.HasPrefix(string(path), string(base)) {
+        return false
+    }
+    rest := path[len(base):]
+    return rest == "" || rest[0] == '.' || rest[0] == '['
+}
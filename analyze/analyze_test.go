@@ -0,0 +1,76 @@
+package analyze_test
+
+import (
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/analyze"
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Employee struct {
+    Name    string
+    Manager *Employee
+    Reports []*Employee
+    Tags    []string
+}
+
+// Test for reporting shared objects and cycles
+func TestSharing(t *testing.T) {
+    tags := []string{"eng"}
+    boss := &Employee{Name: "boss", Tags: tags}
+    alice := &Employee{Name: "alice", Manager: boss, Tags: tags}
+    bob := &Employee{Name: "bob", Tags: []string{"ops"}}
+    boss.Reports = []*Employee{alice}
+    team := []*Employee{boss, alice, bob}
+
+    report := analyze.Sharing(team)
+
+    paths := func(objects []*analyze.Object) [][]cloner.Path {
+        var all [][]cloner.Path
+        for _, o := range objects {
+            all = append(all, o.Paths)
+        }
+        return all
+    }
+    wantShared := [][]cloner.Path{
+        {"[0]", "[0].Reports[0].Manager"},
+        {"[0].Reports[0]", "[1]"},
+        {"[0].Reports[0].Tags", "[0].Tags"},
+    }
+    if got := paths(report.Shared); !reflect.DeepEqual(got, wantShared) {
+        t.Errorf("got shared %q, want %q", got, wantShared)
+    }
+    if report.Shared[2].Type != reflect.TypeOf(tags) {
+        t.Errorf("got type %v, want []string", report.Shared[1].Type)
+    }
+
+    // boss -> Reports -> alice -> Manager -> boss
+    wantCycles := [][]cloner.Path{
+        {"[0]", "[0].Reports[0].Manager"},
+        {"[0].Reports"},
+        {"[0].Reports[0]", "[1]"},
+    }
+    if got := paths(report.Cycles); !reflect.DeepEqual(got, wantCycles) {
+        t.Errorf("got cycles %q, want %q", got, wantCycles)
+    }
+
+    want := `shared *analyze_test.Employee referenced 2 times: [0], [0].Reports[0].Manager
+shared *analyze_test.Employee referenced 2 times: [0].Reports[0], [1]
+shared []string referenced 2 times: [0].Reports[0].Tags, [0].Tags
+cycle through *analyze_test.Employee at [0]
+cycle through []*analyze_test.Employee at [0].Reports
+cycle through *analyze_test.Employee at [0].Reports[0]
+`
+    if got := report.String(); got != want {
+        t.Errorf("got:\n%s\nwant:\n%s", got, want)
+    }
+}
+
+// Test for graphs without sharing
+func TestSharingTree(t *testing.T) {
+    report := analyze.Sharing(&Employee{Name: "a", Reports: []*Employee{{Name: "b"}}})
+    if len(report.Shared) != 0 || len(report.Cycles) != 0 || report.String() != "" {
+        t.Errorf("got %+v, want an empty report", report)
+    }
+}
@@ -0,0 +1,144 @@
+// Package bench measures the cost of deep copying values of user types with
+// the cloner, compared to other ways of copying them such as an encoding/gob
+// round trip or a hand-written copy.
+//
+// A typical use from a benchmark or a one-off program:
+//
+//	results, err := bench.Run(order, 1000,
+//	    bench.Clone(cloner.NewCloneManager()),
+//	    bench.Gob(),
+//	    bench.Manual("manual", copyOrder),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Print(bench.Format(results))
+package bench
+
+import (
+    "bytes"
+    "encoding/gob"
+    "fmt"
+    "reflect"
+    "runtime"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Copier is a way of deep copying values.
+type Copier struct {
+    Name string
+    Copy func(v interface{}) (interface{}, error)
+}
+
+// Clone returns a Copier cloning values with cm.
+func Clone(cm *cloner.CloneManager) Copier {
+    return Copier{Name: "cloner", Copy: func(v interface{}) (interface{}, error) {
+        return cm.Clone(v)
+    }}
+}
+
+// Gob returns a Copier encoding values with encoding/gob and decoding them
+// into a new value of the same type. Types stored in interfaces must be
+// registered with gob.Register.
+func Gob() Copier {
+    return Copier{Name: "gob", Copy: func(v interface{}) (interface{}, error) {
+        var buf bytes.Buffer
+        if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+            return nil, err
+        }
+        clone := reflect.New(reflect.TypeOf(v))
+        if err := gob.NewDecoder(&buf).DecodeValue(clone); err != nil {
+            return nil, err
+        }
+        return clone.Elem().Interface(), nil
+    }}
+}
+
+// Manual returns a Copier calling the hand-written copy function fn, which
+// must be called with values of type T.
+func Manual[T any](name string, fn func(T) T) Copier {
+    return Copier{Name: name, Copy: func(v interface{}) (interface{}, error) {
+        t, ok := v.(T)
+        if !ok {
+            return nil, fmt.Errorf("bench: %s copies %s, not %T", name, reflect.TypeOf((*T)(nil)).Elem(), v)
+        }
+        return fn(t), nil
+    }}
+}
+
+// Result is the cost of copying a value with a Copier.
+type Result struct {
+    Name string
+    // N is the number of copies made.
+    N int
+    // Nodes is the number of values in the copied graph, as walked by
+    // cloner.Walk.
+    Nodes       int
+    NsPerOp     int64
+    AllocsPerOp int64
+    BytesPerOp  int64
+}
+
+// NsPerNode returns the time spent per value of the graph.
+func (r Result) NsPerNode() float64 {
+    if r.Nodes == 0 {
+        return 0
+    }
+    return float64(r.NsPerOp) / float64(r.Nodes)
+}
+
+// Run copies v n times with each of the copiers and returns their results
+// in the same order. Each copier is run once before it is measured, and an
+// error from that run is returned.
+func Run(v interface{}, n int, copiers ...Copier) ([]Result, error) {
+    nodes := 0
+    cloner.Walk(v, func(cloner.Node) error {
+        nodes++
+        return nil
+    })
+
+    results := make([]Result, 0, len(copiers))
+    for _, c := range copiers {
+        if _, err := c.Copy(v); err != nil {
+            return nil, fmt.Errorf("bench: %s: %w", c.Name, err)
+        }
+        results = append(results, measure(c, v, n, nodes))
+    }
+    return results, nil
+}
+
+func measure(c Copier, v interface{}, n, nodes int) Result {
+    var before, after runtime.MemStats
+    runtime.GC()
+    runtime.ReadMemStats(&before)
+    start := time.Now()
+    for i := 0; i < n; i++ {
+        c.Copy(v)
+    }
+    elapsed := time.Since(start)
+    runtime.ReadMemStats(&after)
+
+    result := Result{Name: c.Name, N: n, Nodes: nodes}
+    if n > 0 {
+        result.NsPerOp = elapsed.Nanoseconds() / int64(n)
+        result.AllocsPerOp = int64(after.Mallocs-before.Mallocs) / int64(n)
+        result.BytesPerOp = int64(after.TotalAlloc-before.TotalAlloc) / int64(n)
+    }
+    return result
+}
+
+// Format formats results as a table.
+func Format(results []Result) string {
+    var b strings.Builder
+    w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', tabwriter.AlignRight)
+    fmt.Fprintln(w, "copier\tnodes\tns/op\tns/node\tallocs/op\tB/op\t")
+    for _, r := range results {
+        fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%d\t%d\t\n", r.Name, r.Nodes, r.NsPerOp, r.NsPerNode(), r.AllocsPerOp, r.BytesPerOp)
+    }
+    w.Flush()
+    return b.String()
+}
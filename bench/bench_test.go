@@ -0,0 +1,78 @@
+package bench_test
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/bench"
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Line struct {
+    SKU      string
+    Quantity int
+}
+
+type Order struct {
+    ID    int
+    Lines []Line
+    Notes map[string]string
+}
+
+func copyOrder(o Order) Order {
+    clone := o
+    clone.Lines = append([]Line(nil), o.Lines...)
+    clone.Notes = make(map[string]string, len(o.Notes))
+    for k, v := range o.Notes {
+        clone.Notes[k] = v
+    }
+    return clone
+}
+
+// Test for measuring copiers on a value
+func TestRun(t *testing.T) {
+    order := Order{ID: 1, Lines: []Line{{"a", 1}, {"b", 2}}, Notes: map[string]string{"gift": "yes"}}
+
+    results, err := bench.Run(order, 10,
+        bench.Clone(cloner.NewCloneManager()),
+        bench.Gob(),
+        bench.Manual("manual", copyOrder),
+    )
+    if err != nil {
+        t.Fatalf("Run failed: %v", err)
+    }
+    if len(results) != 3 {
+        t.Fatalf("got %d results, want 3", len(results))
+    }
+    for i, name := range []string{"cloner", "gob", "manual"} {
+        r := results[i]
+        // The order, its ID, Lines with 2 lines of 2 fields, and Notes with a key
+        // and a value
+        if r.Name != name || r.N != 10 || r.Nodes != 12 {
+            t.Errorf("Result %d is incorrect: %+v", i, r)
+        }
+        if r.NsPerOp <= 0 || r.AllocsPerOp <= 0 || r.BytesPerOp <= 0 {
+            t.Errorf("Result %s has no measurements: %+v", name, r)
+        }
+    }
+
+    table := bench.Format(results)
+    for _, want := range []string{"copier", "ns/node", "cloner", "gob", "manual"} {
+        if !strings.Contains(table, want) {
+            t.Errorf("Table does not contain %q:\n%s", want, table)
+        }
+    }
+}
+
+// Test that copiers failing on the value are reported
+func TestRunError(t *testing.T) {
+    _, err := bench.Run(struct{ Done chan bool }{make(chan bool)}, 1, bench.Clone(cloner.NewCloneManager()))
+    if err == nil || !strings.Contains(err.Error(), "bench: cloner:") {
+        t.Errorf("got error %v, want an error from the cloner", err)
+    }
+
+    _, err = bench.Run(1, 1, bench.Manual("manual", copyOrder))
+    if err == nil {
+        t.Errorf("Manual copier accepted a value of another type")
+    }
+}
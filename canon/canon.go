@@ -0,0 +1,306 @@
+// Package canon normalizes object graphs into a canonical form, so that
+// snapshots can be hashed or diffed without tripping over differences that
+// do not matter, such as the iteration order of maps or negative zeros.
+//
+// Normalize converts a value into a tree of a few generic types:
+//
+//   - nil for nil values, channels and functions
+//   - bool, int64, uint64, float64 and string for scalars
+//   - time.Time in UTC without its monotonic clock reading
+//   - []interface{} for slices and arrays
+//   - []Entry for maps, sorted by key
+//   - []Field for structs, holding their exported fields in order
+//   - Cycle for references leading back to one of their ancestors
+//
+// Pointers and interfaces are replaced by the value they hold, so memory
+// shared between paths is repeated at every path.
+package canon
+
+import (
+    "cmp"
+    "fmt"
+    "math"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Entry is an entry of a normalized map.
+type Entry struct {
+    Key   interface{}
+    Value interface{}
+}
+
+// Field is an exported field of a normalized struct.
+type Field struct {
+    Name  string
+    Value interface{}
+}
+
+// Cycle replaces a pointer, slice or map leading back to the value at Path,
+// one of its ancestors, e.g. ".Parent" or "" for the root.
+type Cycle struct {
+    Path string
+}
+
+// Option configures Normalize.
+type Option func(*options)
+
+type options struct {
+    emptyAsNil bool
+    digits     int
+}
+
+// EmptyAsNil normalizes empty slices, arrays, maps and strings to nil, so
+// that they compare equal to nil ones.
+func EmptyAsNil() Option {
+    return func(o *options) {
+        o.emptyAsNil = true
+    }
+}
+
+// WithFloatDigits rounds floats to n significant decimal digits, so that
+// values differing only by rounding errors compare equal.
+func WithFloatDigits(n int) Option {
+    return func(o *options) {
+        o.digits = n
+    }
+}
+
+// Normalize returns the canonical form of v. Floats are normalized in any
+// case: negative zero becomes zero and every NaN the same NaN.
+func Normalize(v interface{}, opts ...Option) interface{} {
+    n := &normalizer{active: make(map[memref.Ref]string)}
+    for _, opt := range opts {
+        opt(&n.options)
+    }
+    return n.normalize(reflect.ValueOf(v), "")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type normalizer struct {
+    options
+    // active holds the paths of the references being normalized, whose
+    // reappearance below themselves is a cycle
+    active map[memref.Ref]string
+}
+
+func (n *normalizer) normalize(v reflect.Value, path string) interface{} {
+    if !v.IsValid() {
+        return nil
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+        if v.IsNil() {
+            return nil
+        }
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        ref := memref.Of(v)
+        if ancestor, ok := n.active[ref]; ok {
+            return Cycle{Path: ancestor}
+        }
+        n.active[ref] = path
+        defer delete(n.active, ref)
+    }
+
+    if v.Type() == timeType && v.CanInterface() {
+        return v.Interface().(time.Time).UTC().Round(0)
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Interface:
+        return n.normalize(v.Elem(), path)
+    case reflect.Bool:
+        return v.Bool()
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return v.Int()
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return v.Uint()
+    case reflect.Float32, reflect.Float64:
+        return n.float(v.Float())
+    case reflect.String:
+        if v.Len() == 0 && n.emptyAsNil {
+            return nil
+        }
+        return v.String()
+    case reflect.Slice, reflect.Array:
+        if v.Len() == 0 && n.emptyAsNil {
+            return nil
+        }
+        elems := make([]interface{}, v.Len())
+        for i := range elems {
+            elems[i] = n.normalize(v.Index(i), path+"["+strconv.Itoa(i)+"]")
+        }
+        return elems
+    case reflect.Map:
+        if v.Len() == 0 && n.emptyAsNil {
+            return nil
+        }
+        entries := make([]Entry, 0, v.Len())
+        iter := v.MapRange()
+        for iter.Next() {
+            key := n.normalize(iter.Key(), path)
+            entries = append(entries, Entry{Key: key, Value: n.normalize(iter.Value(), path+"["+format(key)+"]")})
+        }
+        sort.SliceStable(entries, func(i, j int) bool {
+            return Compare(entries[i].Key, entries[j].Key) < 0
+        })
+        return entries
+    case reflect.Struct:
+        fields := []Field{}
+        for i := 0; i < v.NumField(); i++ {
+            if field := v.Type().Field(i); field.IsExported() {
+                fields = append(fields, Field{Name: field.Name, Value: n.normalize(v.Field(i), path+"."+field.Name)})
+            }
+        }
+        return fields
+    case reflect.Complex64, reflect.Complex128:
+        c := v.Complex()
+        return []interface{}{n.float(real(c)), n.float(imag(c))}
+    }
+    // Channels, functions and unsafe pointers have no canonical form
+    return nil
+}
+
+// float normalizes f.
+func (n *normalizer) float(f float64) float64 {
+    switch {
+    case math.IsNaN(f):
+        return math.NaN()
+    case f == 0:
+        return 0
+    case n.digits > 0 && !math.IsInf(f, 0):
+        f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', n.digits, 64), 64)
+    }
+    return f
+}
+
+// format returns the path step of a normalized map key.
+func format(key interface{}) string {
+    if s, ok := key.(string); ok {
+        return strconv.Quote(s)
+    }
+    return fmt.Sprint(key)
+}
+
+// Compare orders two normalized values: nil, booleans, numbers, strings,
+// times, lists, maps, structs and cycles, in that order, each ordered by
+// value. It returns -1, 0 or 1 like strings.Compare.
+func Compare(a, b interface{}) int {
+    if ra, rb := rank(a), rank(b); ra != rb {
+        return cmp.Compare(ra, rb)
+    }
+    switch a := a.(type) {
+    case bool:
+        return cmp.Compare(boolInt(a), boolInt(b.(bool)))
+    case int64, uint64, float64:
+        return compareNumbers(a, b)
+    case string:
+        return strings.Compare(a, b.(string))
+    case time.Time:
+        return a.Compare(b.(time.Time))
+    case []interface{}:
+        b := b.([]interface{})
+        for i := 0; i < len(a) && i < len(b); i++ {
+            if c := Compare(a[i], b[i]); c != 0 {
+                return c
+            }
+        }
+        return cmp.Compare(len(a), len(b))
+    case []Entry:
+        b := b.([]Entry)
+        for i := 0; i < len(a) && i < len(b); i++ {
+            if c := Compare(a[i].Key, b[i].Key); c != 0 {
+                return c
+            }
+            if c := Compare(a[i].Value, b[i].Value); c != 0 {
+                return c
+            }
+        }
+        return cmp.Compare(len(a), len(b))
+    case []Field:
+        b := b.([]Field)
+        for i := 0; i < len(a) && i < len(b); i++ {
+            if c := strings.Compare(a[i].Name, b[i].Name); c != 0 {
+                return c
+            }
+            if c := Compare(a[i].Value, b[i].Value); c != 0 {
+                return c
+            }
+        }
+        return cmp.Compare(len(a), len(b))
+    case Cycle:
+        return strings.Compare(a.Path, b.(Cycle).Path)
+    }
+    return 0
+}
+
+func rank(v interface{}) int {
+    switch v.(type) {
+    case nil:
+        return 0
+    case bool:
+        return 1
+    case int64, uint64, float64:
+        return 2
+    case string:
+        return 3
+    case time.Time:
+        return 4
+    case []interface{}:
+        return 5
+    case []Entry:
+        return 6
+    case []Field:
+        return 7
+    }
+    return 8
+}
+
+// compareNumbers orders int64, uint64 and float64 values by value, NaN
+// first.
+func compareNumbers(a, b interface{}) int {
+    switch a := a.(type) {
+    case int64:
+        switch b := b.(type) {
+        case int64:
+            return cmp.Compare(a, b)
+        case uint64:
+            if a < 0 {
+                return -1
+            }
+            return cmp.Compare(uint64(a), b)
+        }
+    case uint64:
+        switch b := b.(type) {
+        case uint64:
+            return cmp.Compare(a, b)
+        case int64:
+            return -compareNumbers(b, a)
+        }
+    }
+    return cmp.Compare(toFloat(a), toFloat(b))
+}
+
+func toFloat(v interface{}) float64 {
+    switch v := v.(type) {
+    case int64:
+        return float64(v)
+    case uint64:
+        return float64(v)
+    }
+    return v.(float64)
+}
+
+func boolInt(b bool) int {
+    if b {
+        return 1
+    }
+    return 0
+}
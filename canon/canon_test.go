@@ -0,0 +1,81 @@
+package canon_test
+
+import (
+    "math"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/canon"
+)
+
+type Reading struct {
+    Sensor string
+    Values map[string]float64
+    Tags   []string
+    Next   *Reading
+    note   string
+}
+
+// Test for normalizing values into their canonical form
+func TestNormalize(t *testing.T) {
+    reading := &Reading{
+        Sensor: "t1",
+        Values: map[string]float64{"max": math.Copysign(0, -1), "avg": 0.1 + 0.2, "min": math.NaN()},
+        note:   "ignored",
+    }
+    reading.Next = reading
+
+    got := canon.Normalize(reading)
+    want := []canon.Field{
+        {Name: "Sensor", Value: "t1"},
+        {Name: "Values", Value: []canon.Entry{
+            {Key: "avg", Value: 0.1 + 0.2},
+            {Key: "max", Value: 0.0},
+            {Key: "min", Value: math.NaN()},
+        }},
+        {Name: "Tags", Value: nil},
+        {Name: "Next", Value: canon.Cycle{Path: ""}},
+    }
+    if canon.Compare(got, want) != 0 {
+        t.Errorf("got = %+v, want = %+v", got, want)
+    }
+    if max := got.([]canon.Field)[1].Value.([]canon.Entry)[1].Value.(float64); math.Signbit(max) {
+        t.Errorf("Negative zero was not normalized")
+    }
+
+    // Maps in different orders normalize to the same value
+    a := canon.Normalize(map[interface{}]int{2: 0, "b": 0, 1.5: 0, true: 0})
+    b := canon.Normalize(map[interface{}]int{true: 0, 1.5: 0, "b": 0, 2: 0})
+    if !reflect.DeepEqual(a, b) {
+        t.Errorf("got = %+v, want = %+v", a, b)
+    }
+    keys := []interface{}{true, 1.5, int64(2), "b"}
+    for i, entry := range a.([]canon.Entry) {
+        if entry.Key != keys[i] {
+            t.Errorf("got key %v at %d, want %v", entry.Key, i, keys[i])
+        }
+    }
+
+    local := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("X", 3600))
+    if got := canon.Normalize(local); got != local.UTC() {
+        t.Errorf("got %v, want %v", got, local.UTC())
+    }
+}
+
+// Test for the options of Normalize
+func TestNormalizeOptions(t *testing.T) {
+    empty := Reading{Values: map[string]float64{}, Tags: []string{}}
+    unset := Reading{}
+    if reflect.DeepEqual(canon.Normalize(empty), canon.Normalize(unset)) {
+        t.Errorf("Empty and nil values normalized to the same value by default")
+    }
+    if !reflect.DeepEqual(canon.Normalize(empty, canon.EmptyAsNil()), canon.Normalize(unset, canon.EmptyAsNil())) {
+        t.Errorf("Empty and nil values normalized to different values with EmptyAsNil")
+    }
+
+    got := canon.Normalize([]float64{0.1 + 0.2, 1e-20}, canon.WithFloatDigits(6))
+    if want := []interface{}{0.3, 1e-20}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got = %v, want = %v", got, want)
+    }
+}
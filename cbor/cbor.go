@@ -0,0 +1,293 @@
+// Package cbor encodes object graphs as deterministic CBOR (RFC 8949), so
+// that snapshots can be read outside of Go and compared byte for byte.
+//
+// Values are written with the core deterministic encoding of RFC 8949: the
+// shortest form of every integer, length and float, definite lengths and
+// map keys sorted by their encoding. Structs are written as maps keyed by
+// the names of their exported fields, and time.Time as an RFC 3339 string
+// (tag 0). Pointers, slices and maps referenced more than once are written
+// once with the value-sharing tags registered with IANA: the first
+// occurrence is tagged shareable (28) and the others refer to it (29), so
+// that shared references and cycles survive the encoding.
+package cbor
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "reflect"
+    "sort"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// ErrUnsupportedType reports a channel, function, complex number or unsafe
+// pointer, which have no CBOR representation.
+var ErrUnsupportedType = errors.New("cbor: unsupported type")
+
+// Tags of the value-sharing extension.
+const (
+    TagShareable = 28
+    TagSharedRef = 29
+)
+
+// Marshal returns the deterministic CBOR encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+    var buf bytes.Buffer
+    if err := NewEncoder(&buf).Encode(v); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// Encoder writes CBOR values to an output stream.
+type Encoder struct {
+    w io.Writer
+}
+
+// NewEncoder returns an Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+    return &Encoder{w: w}
+}
+
+// Encode writes the CBOR encoding of v. Sharing is tracked within v only.
+func (e *Encoder) Encode(v interface{}) error {
+    enc := &encoder{shared: make(map[memref.Ref]bool), index: make(map[memref.Ref]int)}
+    err := cloner.Walk(v, func(node cloner.Node) error {
+        if node.Shared {
+            enc.shared[memref.Of(node.Value)] = true
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+    if err := enc.encode(reflect.ValueOf(v)); err != nil {
+        return err
+    }
+    _, err = e.w.Write(enc.buf.Bytes())
+    return err
+}
+
+// Major types of CBOR.
+const (
+    majorUint   = 0
+    majorNegInt = 1
+    majorBytes  = 2
+    majorText   = 3
+    majorArray  = 4
+    majorMap    = 5
+    majorTag    = 6
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type encoder struct {
+    buf    bytes.Buffer
+    shared map[memref.Ref]bool // references occurring more than once
+    index  map[memref.Ref]int  // index of the shareable values written
+}
+
+// head writes the initial bytes of a data item of the major type with the
+// argument n in its shortest form.
+func (e *encoder) head(major byte, n uint64) {
+    major <<= 5
+    switch {
+    case n < 24:
+        e.buf.WriteByte(major | byte(n))
+    case n <= math.MaxUint8:
+        e.buf.Write([]byte{major | 24, byte(n)})
+    case n <= math.MaxUint16:
+        e.buf.Write([]byte{major | 25, byte(n >> 8), byte(n)})
+    case n <= math.MaxUint32:
+        e.buf.Write([]byte{major | 26, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+    default:
+        e.buf.Write([]byte{major | 27, byte(n >> 56), byte(n >> 48), byte(n >> 40), byte(n >> 32),
+            byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+    }
+}
+
+func (e *encoder) encode(v reflect.Value) error {
+    if !v.IsValid() {
+        e.buf.WriteByte(0xf6)
+        return nil
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+        if v.IsNil() {
+            e.buf.WriteByte(0xf6)
+            return nil
+        }
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        ref := memref.Of(v)
+        if i, ok := e.index[ref]; ok {
+            e.head(majorTag, TagSharedRef)
+            e.head(majorUint, uint64(i))
+            return nil
+        }
+        if e.shared[ref] {
+            e.index[ref] = len(e.index)
+            e.head(majorTag, TagShareable)
+        }
+    }
+
+    if v.Type() == timeType {
+        e.head(majorTag, 0)
+        e.text(v.Interface().(time.Time).Format(time.RFC3339Nano))
+        return nil
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Interface:
+        return e.encode(v.Elem())
+    case reflect.Bool:
+        if v.Bool() {
+            e.buf.WriteByte(0xf5)
+        } else {
+            e.buf.WriteByte(0xf4)
+        }
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        if n := v.Int(); n < 0 {
+            e.head(majorNegInt, uint64(-1-n))
+        } else {
+            e.head(majorUint, uint64(n))
+        }
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        e.head(majorUint, v.Uint())
+    case reflect.Float32, reflect.Float64:
+        e.float(v.Float())
+    case reflect.String:
+        e.text(v.String())
+    case reflect.Slice, reflect.Array:
+        if v.Type().Elem().Kind() == reflect.Uint8 {
+            e.head(majorBytes, uint64(v.Len()))
+            for i := 0; i < v.Len(); i++ {
+                e.buf.WriteByte(byte(v.Index(i).Uint()))
+            }
+            return nil
+        }
+        e.head(majorArray, uint64(v.Len()))
+        for i := 0; i < v.Len(); i++ {
+            if err := e.encode(v.Index(i)); err != nil {
+                return err
+            }
+        }
+    case reflect.Map:
+        iter := v.MapRange()
+        var keys, values []reflect.Value
+        for iter.Next() {
+            keys = append(keys, iter.Key())
+            values = append(values, iter.Value())
+        }
+        return e.encodeMap(keys, values)
+    case reflect.Struct:
+        var keys, values []reflect.Value
+        for i := 0; i < v.NumField(); i++ {
+            if field := v.Type().Field(i); field.IsExported() {
+                keys = append(keys, reflect.ValueOf(field.Name))
+                values = append(values, v.Field(i))
+            }
+        }
+        return e.encodeMap(keys, values)
+    default:
+        return fmt.Errorf("%w: %v", ErrUnsupportedType, v.Type())
+    }
+    return nil
+}
+
+// encodeMap writes a map of keys to values, sorted by the encoding of the
+// keys.
+func (e *encoder) encodeMap(keys, values []reflect.Value) error {
+    encoded := make([][]byte, len(keys))
+    for i, key := range keys {
+        // Keys are ordered by their encoding without sharing tags, which
+        // depend on the order
+        sortKey := &encoder{index: make(map[memref.Ref]int)}
+        if err := sortKey.encode(key); err != nil {
+            return err
+        }
+        encoded[i] = sortKey.buf.Bytes()
+    }
+    order := make([]int, len(keys))
+    for i := range order {
+        order[i] = i
+    }
+    sort.SliceStable(order, func(i, j int) bool {
+        return bytes.Compare(encoded[order[i]], encoded[order[j]]) < 0
+    })
+
+    e.head(majorMap, uint64(len(keys)))
+    for _, i := range order {
+        if err := e.encode(keys[i]); err != nil {
+            return err
+        }
+        if err := e.encode(values[i]); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (e *encoder) text(s string) {
+    e.head(majorText, uint64(len(s)))
+    e.buf.WriteString(s)
+}
+
+// float writes f in the shortest of the half, single and double precision
+// forms that represents it exactly. NaNs are written as the quiet NaN
+// 0xf97e00.
+func (e *encoder) float(f float64) {
+    if math.IsNaN(f) {
+        e.buf.Write([]byte{0xf9, 0x7e, 0x00})
+        return
+    }
+    if float64(float32(f)) != f {
+        bits := math.Float64bits(f)
+        e.buf.WriteByte(0xfb)
+        for shift := 56; shift >= 0; shift -= 8 {
+            e.buf.WriteByte(byte(bits >> shift))
+        }
+        return
+    }
+    bits := math.Float32bits(float32(f))
+    if half, ok := float16(bits); ok {
+        e.buf.Write([]byte{0xf9, byte(half >> 8), byte(half)})
+        return
+    }
+    e.buf.Write([]byte{0xfa, byte(bits >> 24), byte(bits >> 16), byte(bits >> 8), byte(bits)})
+}
+
+// float16 returns the half precision bits of the single precision float
+// bits, if it can be represented exactly.
+func float16(bits uint32) (uint16, bool) {
+    sign := uint16(bits>>16) & 0x8000
+    exp := int(bits>>23) & 0xff
+    mant := bits & 0x7fffff
+    switch {
+    case exp == 0 && mant == 0:
+        return sign, true
+    case exp == 0xff:
+        return sign | 0x7c00, mant == 0
+    case exp == 0:
+        // Single precision subnormals are too small for half precision
+        return 0, false
+    }
+    e := exp - 127
+    switch {
+    case e >= -14 && e <= 15 && mant&0x1fff == 0:
+        return sign | uint16(e+15)<<10 | uint16(mant>>13), true
+    case e >= -24 && e < -14:
+        shift := uint(-1 - e)
+        m := mant | 1<<23
+        if m&(1<<shift-1) != 0 {
+            return 0, false
+        }
+        return sign | uint16(m>>shift), true
+    }
+    return 0, false
+}
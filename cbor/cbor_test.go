@@ -0,0 +1,71 @@
+package cbor_test
+
+import (
+    "encoding/hex"
+    "errors"
+    "math"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cbor"
+)
+
+type Link struct {
+    Name string
+    Next *Link
+}
+
+// Test for the deterministic encoding of values
+func TestMarshal(t *testing.T) {
+    one := 1
+    shared := &one
+    cycle := &Link{Name: "a"}
+    cycle.Next = cycle
+
+    tests := []struct {
+        name  string
+        value interface{}
+        want  string
+    }{
+        {"zero", 0, "00"},
+        {"negative", -1000, "3903e7"},
+        {"large", uint64(1) << 40, "1b0000010000000000"},
+        {"half", 1.0, "f93c00"},
+        {"half subnormal", 5.960464477539063e-8, "f90001"},
+        {"half max", 65504.0, "f97bff"},
+        {"single", 100000.0, "fa47c35000"},
+        {"double", 1.1, "fb3ff199999999999a"},
+        {"infinity", math.Inf(-1), "f9fc00"},
+        {"nan", math.NaN(), "f97e00"},
+        {"nil", []int(nil), "f6"},
+        {"bool", true, "f5"},
+        {"text", "IETF", "6449455446"},
+        {"bytes", []byte{1, 2, 3, 4}, "4401020304"},
+        {"array", [2]int{1, 2}, "820102"},
+        {"map", map[string]interface{}{"b": []int{2, 3}, "a": 1}, "a26161016162820203"},
+        {"struct", Link{Name: "x"}, "a2644e616d656178644e657874f6"},
+        {"time", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), "c074323031332d30332d32315432303a30343a30305a"},
+        {"shared", []*int{shared, shared}, "82d81c01d81d00"},
+        {"cycle", cycle, "d81ca2644e616d656161644e657874d81d00"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            data, err := cbor.Marshal(tt.value)
+            if err != nil {
+                t.Fatalf("Marshal failed: %v", err)
+            }
+            if got := hex.EncodeToString(data); got != tt.want {
+                t.Errorf("got %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+// Test for values without a CBOR representation
+func TestMarshalUnsupported(t *testing.T) {
+    for _, value := range []interface{}{make(chan int), func() {}, complex(1, 2)} {
+        if _, err := cbor.Marshal(value); !errors.Is(err, cbor.ErrUnsupportedType) {
+            t.Errorf("got error %v for %T, want ErrUnsupportedType", err, value)
+        }
+    }
+}
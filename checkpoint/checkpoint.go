@@ -0,0 +1,249 @@
+// Package checkpoint saves periodic checkpoints of a value to a
+// store.Backend, writing full snapshots only every so often and, in
+// between, the differences from the last full snapshot, as found by
+// cloner.Diff. A checkpoint is recovered by loading its full snapshot and
+// applying its differences.
+//
+// Checkpoints of key are numbered from 1 and stored as key/full/NNNNNNNN or
+// key/delta/NNNNNNNN. Each delta holds the changes from its full snapshot,
+// not from the previous delta, so recovering a checkpoint reads two blobs
+// at most and a lost delta only loses its own checkpoint.
+package checkpoint
+
+import (
+    "context"
+    "encoding/gob"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/delta"
+    "github.com/jayaprabhakar/go-deeper/store"
+)
+
+// Option configures a Checkpointer.
+type Option func(*options)
+
+type options struct {
+    fullEvery int
+    equal     []cloner.EqualOption
+}
+
+// Checkpointer saves and recovers the checkpoints of a value of type T
+// under a key. It is safe for concurrent use, but checkpointers saving the
+// same key to a shared backend must be coordinated by the caller.
+type Checkpointer[T any] struct {
+    backend store.Backend
+    key     string
+    manager *cloner.CloneManager
+    options options
+
+    mutex   sync.Mutex // serializes Save and Load
+    base    T          // clone of the last full snapshot
+    baseNum int        // its checkpoint number, 0 if none is known
+}
+
+// FullEvery makes every nth checkpoint a full snapshot; the default is 10.
+// A value of 1 makes every checkpoint a full snapshot.
+func FullEvery(n int) Option {
+    return func(o *options) {
+        o.fullEvery = n
+    }
+}
+
+// WithEqualOptions compares values with opts when computing deltas. Changes
+// that opts ignore, as well as those of fields tagged `deeper:"ignoreeq"`,
+// are only saved by full snapshots.
+func WithEqualOptions(opts ...cloner.EqualOption) Option {
+    return func(o *options) {
+        o.equal = append(o.equal, opts...)
+    }
+}
+
+// New returns a Checkpointer saving the checkpoints of key to backend with
+// the configuration of manager, or of cloner.Default if manager is nil. As
+// with store.Store, the dynamic types of interface values must be
+// registered with cloner.RegisterType, and unexported fields are not saved.
+func New[T any](backend store.Backend, key string, manager *cloner.CloneManager, opts ...Option) (*Checkpointer[T], error) {
+    if key == "" || strings.HasSuffix(key, "/") {
+        return nil, fmt.Errorf("checkpoint: invalid key %q", key)
+    }
+    if manager == nil {
+        manager = cloner.Default()
+    }
+    c := &Checkpointer[T]{backend: backend, key: key, manager: manager, options: options{fullEvery: 10}}
+    for _, opt := range opts {
+        opt(&c.options)
+    }
+    return c, nil
+}
+
+// blob kinds
+const (
+    fullKind  = "full"
+    deltaKind = "delta"
+)
+
+// name returns the name of the blob of checkpoint n of the given kind.
+func (c *Checkpointer[T]) name(kind string, n int) string {
+    return fmt.Sprintf("%s/%s/%08d", c.key, kind, n)
+}
+
+// Checkpoints returns the numbers of the checkpoints in the backend, in
+// increasing order.
+func (c *Checkpointer[T]) Checkpoints(ctx context.Context) ([]int, error) {
+    var numbers []int
+    for _, kind := range []string{fullKind, deltaKind} {
+        prefix := c.key + "/" + kind + "/"
+        names, err := c.backend.List(ctx, prefix)
+        if err != nil {
+            return nil, err
+        }
+        for _, name := range names {
+            suffix := strings.TrimPrefix(name, prefix)
+            if n, err := strconv.Atoi(suffix); err == nil && n > 0 && len(suffix) >= 8 {
+                numbers = append(numbers, n)
+            }
+        }
+    }
+    sort.Ints(numbers)
+    return numbers, nil
+}
+
+// Save saves a checkpoint of v and returns its number. The checkpoint is a
+// full snapshot if it is the first saved or loaded by c, or every
+// FullEvery checkpoints; it holds the changes from the last full snapshot
+// otherwise. v must not be changed during Save.
+func (c *Checkpointer[T]) Save(ctx context.Context, v T) (int, error) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    numbers, err := c.Checkpoints(ctx)
+    if err != nil {
+        return 0, err
+    }
+    n := 1
+    if len(numbers) > 0 {
+        n = numbers[len(numbers)-1] + 1
+    }
+
+    if c.baseNum == 0 || c.options.fullEvery <= 1 || n-c.baseNum >= c.options.fullEvery {
+        base, err := cloner.Clone(c.manager, v)
+        if err != nil {
+            return 0, err
+        }
+        if err := c.put(ctx, c.name(fullKind, n), func(enc *gob.Encoder) error {
+            return c.manager.CloneTo(enc, v)
+        }); err != nil {
+            return 0, err
+        }
+        c.base, c.baseNum = base, n
+        return n, nil
+    }
+
+    changes, err := delta.Changes(c.base, v, c.options.equal)
+    if err != nil {
+        return 0, err
+    }
+    if err := c.put(ctx, c.name(deltaKind, n), func(enc *gob.Encoder) error {
+        // A delta starts with the number of its full snapshot
+        if err := enc.Encode(c.baseNum); err != nil {
+            return err
+        }
+        return delta.Write(enc, c.manager, changes)
+    }); err != nil {
+        return 0, err
+    }
+    return n, nil
+}
+
+// put streams the blob written by write to the backend as name.
+func (c *Checkpointer[T]) put(ctx context.Context, name string, write func(*gob.Encoder) error) error {
+    r, w := io.Pipe()
+    done := make(chan error, 1)
+    go func() {
+        err := write(gob.NewEncoder(w))
+        w.CloseWithError(err)
+        done <- err
+    }()
+    err := c.backend.Put(ctx, name, r)
+    r.CloseWithError(err)
+    if writeErr := <-done; err == nil {
+        err = writeErr
+    }
+    return err
+}
+
+// Load recovers the latest checkpoint. It returns store.ErrNotFound if
+// there is none. Later checkpoints saved by c are deltas from the full
+// snapshot of the recovered one.
+func (c *Checkpointer[T]) Load(ctx context.Context) (T, error) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    numbers, err := c.Checkpoints(ctx)
+    if err != nil {
+        var zero T
+        return zero, err
+    }
+    if len(numbers) == 0 {
+        var zero T
+        return zero, store.ErrNotFound
+    }
+    v, base, baseNum, err := c.load(ctx, numbers[len(numbers)-1])
+    if err == nil {
+        c.base, c.baseNum = base, baseNum
+    }
+    return v, err
+}
+
+// LoadCheckpoint recovers checkpoint n. It returns store.ErrNotFound if
+// there is none.
+func (c *Checkpointer[T]) LoadCheckpoint(ctx context.Context, n int) (T, error) {
+    v, _, _, err := c.load(ctx, n)
+    return v, err
+}
+
+// load recovers checkpoint n and returns it together with its full
+// snapshot and the number of that snapshot.
+func (c *Checkpointer[T]) load(ctx context.Context, n int) (v, base T, baseNum int, err error) {
+    var dec *gob.Decoder
+    baseNum = n
+    r, err := c.backend.Get(ctx, c.name(fullKind, n))
+    if err == store.ErrNotFound {
+        var d io.ReadCloser
+        if d, err = c.backend.Get(ctx, c.name(deltaKind, n)); err != nil {
+            return v, base, 0, err
+        }
+        defer d.Close()
+        dec = gob.NewDecoder(d)
+        if err = dec.Decode(&baseNum); err != nil {
+            return v, base, 0, fmt.Errorf("checkpoint: reading delta %d: %w", n, err)
+        }
+        if r, err = c.backend.Get(ctx, c.name(fullKind, baseNum)); err != nil {
+            return v, base, 0, fmt.Errorf("checkpoint: reading full snapshot %d of delta %d: %w", baseNum, n, err)
+        }
+    } else if err != nil {
+        return v, base, 0, err
+    }
+    err = c.manager.CloneFrom(gob.NewDecoder(r), &base)
+    r.Close()
+    if err == io.EOF {
+        err = io.ErrUnexpectedEOF
+    }
+    if err != nil {
+        return v, base, 0, err
+    }
+
+    if v, err = cloner.Clone(c.manager, base); err != nil {
+        return v, base, 0, err
+    }
+    if dec != nil {
+        if err := delta.Apply(dec, c.manager, &v); err != nil {
+            return v, base, 0, fmt.Errorf("checkpoint: applying delta %d: %w", n, err)
+        }
+    }
+    return v, base, baseNum, nil
+}
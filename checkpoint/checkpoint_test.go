@@ -0,0 +1,175 @@
+package checkpoint_test
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/checkpoint"
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/store"
+)
+
+// Test for saving deltas between full snapshots and recovering them
+func TestCheckpointer(t *testing.T) {
+    type node struct {
+        Name   string
+        Weight float64
+        Edges  []string
+    }
+    type cluster struct {
+        Nodes   map[string]*node
+        Leader  *node
+        Config  interface{}
+        Epochs  [2]int
+        counter int
+    }
+    type limits struct {
+        MaxNodes int
+        Labels   map[string]string
+    }
+    cloner.RegisterType[limits]()
+
+    ctx := context.Background()
+    backend := store.Dir(t.TempDir())
+    c, err := checkpoint.New[cluster](backend, "cluster", nil, checkpoint.FullEvery(3))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    a := &node{Name: "a", Weight: 1, Edges: []string{"b"}}
+    value := cluster{
+        Nodes:  map[string]*node{"a": a, "b": {Name: "b", Weight: 2}},
+        Leader: a,
+        Config: limits{MaxNodes: 3, Labels: map[string]string{"zone": "eu"}},
+    }
+    var saved []cluster
+    mutations := []func(){
+        func() {},
+        func() { value.Nodes["a"].Weight = 1.5 },
+        func() {
+            value.Nodes["c"] = &node{Name: "c"}
+            delete(value.Nodes, "b")
+            value.Epochs[1] = 7
+        },
+        func() { value.Leader = nil },
+        func() {
+            config := value.Config.(limits)
+            config.Labels = map[string]string{"zone": "us"}
+            value.Config = config
+            value.Nodes["a"].Edges = append(value.Nodes["a"].Edges, "c")
+            value.counter++
+        },
+    }
+    for i, mutate := range mutations {
+        mutate()
+        n, err := c.Save(ctx, value)
+        if err != nil || n != i+1 {
+            t.Fatalf("Save returned %d, %v, want checkpoint %d", n, err, i+1)
+        }
+        snapshot := cloner.MustClone(cloner.NewCloneManager(), value)
+        snapshot.counter = 0
+        saved = append(saved, snapshot)
+    }
+
+    names, _ := backend.List(ctx, "cluster/")
+    want := []string{
+        "cluster/delta/00000002", "cluster/delta/00000003", "cluster/delta/00000005",
+        "cluster/full/00000001", "cluster/full/00000004",
+    }
+    if !reflect.DeepEqual(names, want) {
+        t.Errorf("got blobs %v, want %v", names, want)
+    }
+    for i, want := range saved {
+        got, err := c.LoadCheckpoint(ctx, i+1)
+        if err != nil {
+            t.Fatalf("LoadCheckpoint(%d) failed: %v", i+1, err)
+        }
+        if diffs := cloner.Diff(got, want); len(diffs) > 0 {
+            t.Errorf("checkpoint %d differs: %v", i+1, diffs)
+        }
+    }
+
+    // A new checkpointer recovers the latest checkpoint and saves deltas
+    // from its full snapshot
+    c, _ = checkpoint.New[cluster](backend, "cluster", nil, checkpoint.FullEvery(3))
+    recovered, err := c.Load(ctx)
+    if err != nil || !cloner.Equal(recovered, saved[4]) {
+        t.Fatalf("Load returned %+v, %v, want %+v", recovered, err, saved[4])
+    }
+    recovered.Nodes["a"].Name = "A"
+    if n, err := c.Save(ctx, recovered); err != nil || n != 6 {
+        t.Fatalf("Save returned %d, %v, want checkpoint 6", n, err)
+    }
+    if got, _ := c.LoadCheckpoint(ctx, 6); got.Nodes["a"].Name != "A" {
+        t.Errorf("got node %+v, want the renamed node", got.Nodes["a"])
+    }
+    if names, _ := backend.List(ctx, "cluster/full/"); len(names) != 2 {
+        t.Errorf("got full snapshots %v, want 2", names)
+    }
+}
+
+// Test for leaving the changes ignored by the equal options to full snapshots
+func TestWithEqualOptions(t *testing.T) {
+    type gauge struct {
+        Name    string
+        Samples []float64
+        Updated int `deeper:"ignoreeq"`
+    }
+    ctx := context.Background()
+    c, _ := checkpoint.New[gauge](store.NewMemory(), "gauge", nil,
+        checkpoint.FullEvery(3), checkpoint.WithEqualOptions(cloner.IgnorePaths("Samples")))
+
+    for i, g := range []gauge{
+        {Name: "cpu", Samples: []float64{1}, Updated: 1},
+        {Name: "load", Samples: []float64{1, 2}, Updated: 2},
+        {Name: "load", Samples: []float64{3}, Updated: 3},
+        {Name: "mem", Samples: []float64{4}, Updated: 4},
+    } {
+        if _, err := c.Save(ctx, g); err != nil {
+            t.Fatalf("Save failed: %v", err)
+        }
+        got, err := c.LoadCheckpoint(ctx, i+1)
+        if err != nil {
+            t.Fatalf("LoadCheckpoint(%d) failed: %v", i+1, err)
+        }
+        // Checkpoints 2 and 3 are deltas from checkpoint 1
+        want := g
+        if i == 1 || i == 2 {
+            want.Samples, want.Updated = []float64{1}, 1
+        }
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("got checkpoint %d = %+v, want %+v", i+1, got, want)
+        }
+    }
+}
+
+// Test for checkpoints that cannot be loaded
+func TestLoadErrors(t *testing.T) {
+    type counter struct {
+        Epochs [2]int
+    }
+    ctx := context.Background()
+    backend := store.NewMemory()
+    c, _ := checkpoint.New[counter](backend, "cluster", nil)
+    if _, err := c.Load(ctx); !errors.Is(err, store.ErrNotFound) {
+        t.Errorf("got error %v, want ErrNotFound", err)
+    }
+    if _, err := checkpoint.New[counter](backend, "cluster/", nil); err == nil {
+        t.Errorf("New accepted an invalid key")
+    }
+
+    value := counter{}
+    c.Save(ctx, value)
+    value.Epochs[0] = 1
+    c.Save(ctx, value)
+    backend.Put(ctx, "cluster/full/00000001", strings.NewReader(""))
+    if _, err := c.LoadCheckpoint(ctx, 2); err == nil {
+        t.Errorf("LoadCheckpoint succeeded with a truncated full snapshot")
+    }
+    if _, err := c.LoadCheckpoint(ctx, 3); !errors.Is(err, store.ErrNotFound) {
+        t.Errorf("got error %v, want ErrNotFound", err)
+    }
+}
@@ -0,0 +1,103 @@
+package cloner
+
+import (
+    "context"
+    "log"
+    "log/slog"
+    "reflect"
+    "sync"
+)
+
+var (
+    // ambientTypes are the types shared by clones, see ShareType. Interface
+    // types stand for every type implementing them.
+    ambientTypes = []reflect.Type{
+        reflect.TypeOf((*context.Context)(nil)).Elem(),
+        reflect.TypeOf((*slog.Logger)(nil)),
+        reflect.TypeOf((*log.Logger)(nil)),
+    }
+    ambientCache = make(map[reflect.Type]bool)
+    ambientMutex sync.RWMutex
+)
+
+// ShareType makes clones share the values of type T with the source instead
+// of cloning them, for ambient dependencies such as loggers, metrics
+// registries or database handles that a graph refers to but does not own.
+// If T is an interface type, every type implementing it is shared. By
+// default, context.Context values, *slog.Logger and *log.Logger are shared,
+// since cloning them is almost always wrong; third-party loggers such as
+// *zap.Logger can be added from an init function:
+//
+//	cloner.ShareType[*zap.Logger]()
+//
+// Cloners registered for a type, Cloneable implementations and type
+// replacements take precedence; see also UnshareType and
+// WithoutAmbientSharing.
+func ShareType[T any]() {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    ambientMutex.Lock()
+    defer ambientMutex.Unlock()
+    for _, a := range ambientTypes {
+        if a == t {
+            return
+        }
+    }
+    ambientTypes = append(ambientTypes[:len(ambientTypes):len(ambientTypes)], t)
+    ambientCache = make(map[reflect.Type]bool)
+}
+
+// UnshareType removes T from the types shared by ShareType, so that its
+// values are cloned like other values again.
+func UnshareType[T any]() {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    ambientMutex.Lock()
+    defer ambientMutex.Unlock()
+    types := make([]reflect.Type, 0, len(ambientTypes))
+    for _, a := range ambientTypes {
+        if a != t {
+            types = append(types, a)
+        }
+    }
+    ambientTypes = types
+    ambientCache = make(map[reflect.Type]bool)
+}
+
+// WithoutAmbientSharing makes the manager clone the values of the types
+// shared by ShareType like other values.
+func WithoutAmbientSharing() Option {
+    return func(o *options) {
+        o.cloneAmbient = true
+    }
+}
+
+// isAmbient reports whether the values of t are shared, as told by
+// ShareType.
+func isAmbient(t reflect.Type) bool {
+    ambientMutex.RLock()
+    ambient, ok := ambientCache[t]
+    ambientMutex.RUnlock()
+    if ok {
+        return ambient
+    }
+
+    ambientMutex.Lock()
+    defer ambientMutex.Unlock()
+    for _, a := range ambientTypes {
+        if t == a || a.Kind() == reflect.Interface && t.Implements(a) {
+            ambient = true
+            break
+        }
+    }
+    ambientCache[t] = ambient
+    return ambient
+}
+
+// shares reports whether src is an ambient dependency that the clone shares
+// with the source.
+func (cm *CloneManager) shares(src reflect.Value) bool {
+    switch src.Kind() {
+    case reflect.Ptr, reflect.Struct:
+        return !cm.options.cloneAmbient && src.CanInterface() && isAmbient(src.Type())
+    }
+    return false
+}
@@ -0,0 +1,61 @@
+package cloner_test
+
+import (
+    "context"
+    "log/slog"
+    "os"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Metrics struct {
+    Counts map[string]int
+}
+
+type Pipeline struct {
+    Ctx     context.Context
+    Log     *slog.Logger
+    Metrics *Metrics
+    Args    []string
+}
+
+// Test for sharing contexts, loggers and other ambient dependencies
+func TestShareType(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    job := &Pipeline{
+        Ctx:     ctx,
+        Log:     slog.New(slog.NewTextHandler(os.Stderr, nil)),
+        Metrics: &Metrics{Counts: map[string]int{}},
+        Args:    []string{"-v"},
+    }
+
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), job)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Ctx != ctx || cloned.Log != job.Log {
+        t.Errorf("Clone did not share the context and the logger")
+    }
+    if cloned.Metrics == job.Metrics || &cloned.Args[0] == &job.Args[0] {
+        t.Errorf("Clone shared values that are not ambient")
+    }
+
+    // Other types can be shared
+    cloner.ShareType[*Metrics]()
+    defer cloner.UnshareType[*Metrics]()
+    cloned, err = cloner.Clone(cloner.NewCloneManager(), job)
+    if err != nil || cloned.Metrics != job.Metrics {
+        t.Errorf("got metrics %p and error %v, want %p", cloned.Metrics, err, job.Metrics)
+    }
+
+    // Sharing can be turned off
+    cloned, err = cloner.Clone(cloner.NewCloneManager(), job, cloner.WithoutAmbientSharing())
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Log == job.Log || cloned.Metrics == job.Metrics {
+        t.Errorf("Clone shared the logger or the metrics without ambient sharing")
+    }
+}
@@ -0,0 +1,295 @@
+package cloner
+
+import (
+    "reflect"
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// WithApplyBack makes the manager keep a snapshot of every clone made by
+// Clone, CloneValue, CloneBatch and the functions using them, a second
+// clone taken with it, so that the edits later made to the clone can be
+// written back to its source with ApplyBack. Snapshots are kept in a side
+// table keyed by the root of the clone, which must be a pointer, a slice or
+// a map; they do not keep clones alive and are dropped once their clone is
+// garbage collected, with Go 1.24 or later.
+func WithApplyBack() Option {
+    return func(o *options) {
+        o.applyBack = true
+    }
+}
+
+// ApplyOption configures ApplyBack.
+type ApplyOption func(*applyOptions)
+
+type applyOptions struct {
+    locker     sync.Locker
+    onConflict ConflictHandler
+}
+
+// LockSource makes ApplyBack hold l while it writes to the source,
+// typically the mutex guarding it.
+func LockSource(l sync.Locker) ApplyOption {
+    return func(o *applyOptions) {
+        o.locker = l
+    }
+}
+
+// Conflict describes a source changed since the snapshot of its clone was
+// taken, or last written back.
+type Conflict struct {
+    Source, Edited interface{} // the arguments of ApplyBack
+    // Changes lists the values changed in the source, A being the value in
+    // the snapshot and B the value in the source.
+    Changes []Difference
+}
+
+// ConflictHandler decides what ApplyBack does with a Conflict: it writes
+// the edits anyway if the handler returns nil, and returns the error of the
+// handler otherwise.
+type ConflictHandler func(Conflict) error
+
+// RejectConflict is a ConflictHandler refusing to write onto a changed
+// source, with ErrConflict.
+func RejectConflict(Conflict) error {
+    return ErrConflict
+}
+
+// OverwriteConflict is a ConflictHandler writing the edits onto a changed
+// source anyway, the last writer winning. The changes made to the source
+// at other paths are kept.
+func OverwriteConflict(Conflict) error {
+    return nil
+}
+
+// OnConflict makes ApplyBack compare the source with the snapshot of the
+// clone before writing, holding the lock given with LockSource, and call fn
+// if they differ, for compare-and-swap semantics: the snapshot serves as
+// the token of the state the edits were made from, compared as by Equal
+// rather than by hash so that no change goes unnoticed. fn is
+// RejectConflict if nil.
+func OnConflict(fn ConflictHandler) ApplyOption {
+    if fn == nil {
+        fn = RejectConflict
+    }
+    return func(o *applyOptions) {
+        o.onConflict = fn
+    }
+}
+
+// snapshots holds the snapshots of the clones made with WithApplyBack by
+// reference to their root.
+var snapshots sync.Map
+
+// snapshot is the snapshot of a clone.
+type snapshot struct {
+    mutex sync.Mutex // serializes the ApplyBack of the clone
+    value reflect.Value
+}
+
+// keepSnapshot keeps a snapshot of clone, the root of a clone made by the
+// session cm, with WithApplyBack.
+func (cm *CloneManager) keepSnapshot(clone reflect.Value) {
+    if !cm.options.applyBack || !tracksProvenance(clone) {
+        return
+    }
+    value, err := cm.cloneUnrecorded(clone)
+    if err != nil {
+        return
+    }
+    s := &snapshot{value: value}
+    key := memref.Of(clone)
+    snapshots.Store(key, s)
+    dropWhenUnreachable(&snapshots, clone, key, s)
+}
+
+// cloneUnrecorded clones v with the configuration of cm, without recording
+// or reporting the clone.
+func (cm *CloneManager) cloneUnrecorded(v reflect.Value) (reflect.Value, error) {
+    session := cm.session(func(o *options) {
+        o.applyBack, o.provenance, o.mapping = false, false, false
+        o.record, o.onClone = nil, nil
+    })
+    cloned, err := session.deepClone(v)
+    if err != nil {
+        return reflect.Value{}, err
+    }
+    session.setParents()
+    return reflect.ValueOf(cloned), nil
+}
+
+// ApplyBack writes the edits made to edited, the root of a clone of src
+// made with WithApplyBack, back onto src, for edit-on-copy workflows:
+// edited is compared with the snapshot taken when it was cloned, as by
+// Diff but field by field, ignoring ignoreeq tags, comparers and Equal
+// methods, and only the values that differ are written to src, cloned so
+// that src and edited share nothing. Values of types with a comparer or an
+// Equal method, such as time.Time, are written as a whole. The values of
+// src that were not edited, including those changed in src since the
+// clone, are left as they are. Once written, the edits become part of the
+// snapshot, so that a later ApplyBack writes the next edits only.
+//
+// ApplyBack returns ErrNoSnapshot if edited is not the root of a clone made
+// with WithApplyBack. An edit that cannot be written to src, because src
+// has no value at its path or the value is an unexported field, is
+// reported as a *CloneError for its path, wrapping ErrSourceMismatch or
+// ErrUnexportedField; nothing is written then. Map entries are matched by
+// key, so maps keyed by pointers cannot be written back.
+//
+// By default, the changes made to src since the clone are not checked;
+// with OnConflict, they are handled before anything is written.
+func (cm *CloneManager) ApplyBack(src, edited interface{}, opts ...ApplyOption) error {
+    var o applyOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+    ev := reflect.ValueOf(edited)
+    var entry interface{}
+    if tracksProvenance(ev) {
+        entry, _ = snapshots.Load(memref.Of(ev))
+    }
+    if entry == nil {
+        return &CloneError{Type: reflect.TypeOf(edited), Err: ErrNoSnapshot}
+    }
+    s := entry.(*snapshot)
+    sv := reflect.ValueOf(src)
+    if !sv.IsValid() || sv.Type() != ev.Type() {
+        return &CloneError{Type: ev.Type(), Err: ErrTypeMismatch}
+    }
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    // The edited values are cloned before anything is written
+    e := newEqualer(nil)
+    e.diffs, e.keepEdits, e.exact = []Difference{}, true, true
+    e.equal(s.value, ev)
+    values := make([]reflect.Value, len(e.edits))
+    for i, ed := range e.edits {
+        if !ed.value.IsValid() || !ed.value.CanInterface() {
+            // Removed map entries have no value, and unexported fields
+            // cannot be written
+            values[i] = ed.value
+            continue
+        }
+        cloned, err := cm.cloneUnrecorded(ed.value)
+        if err != nil {
+            return err
+        }
+        values[i] = cloned
+    }
+
+    if o.locker != nil {
+        o.locker.Lock()
+        defer o.locker.Unlock()
+    }
+    if o.onConflict != nil {
+        c := newEqualer(nil)
+        c.diffs, c.exact = []Difference{}, true
+        c.equal(s.value, sv)
+        if len(c.diffs) > 0 {
+            conflict := Conflict{Source: src, Edited: edited, Changes: c.diffs}
+            if err := o.onConflict(conflict); err != nil {
+                return err
+            }
+        }
+    }
+    for _, write := range []bool{false, true} {
+        for i, ed := range e.edits {
+            if err := writeBack(sv, ed.steps, values[i], write); err != nil {
+                if err == ErrNoValue {
+                    err = ErrSourceMismatch
+                }
+                cloneErr := &CloneError{Path: formatPath(ed.steps), Steps: exportedSteps(ed.steps), Err: err}
+                if ed.value.IsValid() {
+                    cloneErr.Type = ed.value.Type()
+                }
+                return cloneErr
+            }
+        }
+    }
+    value, err := cm.cloneUnrecorded(ev)
+    if err != nil {
+        return err
+    }
+    s.value = value
+    return nil
+}
+
+// writeBack sets the value at the end of steps from v to value, or to the
+// zero value if value is invalid, or removes it if it is a map entry and
+// value is invalid. Unless write is true, it only checks that it can.
+func writeBack(v reflect.Value, steps []step, value reflect.Value, write bool) error {
+    if len(steps) == 0 {
+        if !v.CanSet() {
+            return ErrNoValue
+        }
+        if !value.IsValid() {
+            value = reflect.Zero(v.Type())
+        } else if !value.Type().AssignableTo(v.Type()) {
+            return ErrTypeMismatch
+        }
+        if write {
+            v.Set(value)
+        }
+        return nil
+    }
+    switch v.Kind() {
+    case reflect.Ptr:
+        if v.IsNil() {
+            return ErrNoValue
+        }
+        return writeBack(v.Elem(), steps, value, write)
+    case reflect.Interface:
+        if v.IsNil() || !v.CanSet() {
+            return ErrNoValue
+        }
+        // The values held by interfaces are changed in a copy
+        elem := reflect.New(v.Elem().Type()).Elem()
+        elem.Set(v.Elem())
+        if err := writeBack(elem, steps, value, write); err != nil {
+            return err
+        }
+        if write {
+            v.Set(elem)
+        }
+        return nil
+    case reflect.Map:
+        key, ok := mapKey(v, steps[0])
+        if !ok || v.IsNil() {
+            break
+        }
+        if len(steps) == 1 {
+            if value.IsValid() && !value.Type().AssignableTo(v.Type().Elem()) {
+                return ErrTypeMismatch
+            }
+            if write {
+                v.SetMapIndex(key, value)
+            }
+            return nil
+        }
+        // The values of maps are changed in a copy
+        current := v.MapIndex(key)
+        if !current.IsValid() {
+            return ErrNoValue
+        }
+        elem := reflect.New(current.Type()).Elem()
+        elem.Set(current)
+        if err := writeBack(elem, steps[1:], value, write); err != nil {
+            return err
+        }
+        if write {
+            v.SetMapIndex(key, elem)
+        }
+        return nil
+    }
+
+    next := elemOf(v, steps[0])
+    if !next.IsValid() {
+        return ErrNoValue
+    }
+    if !next.CanSet() {
+        return ErrUnexportedField
+    }
+    return writeBack(next, steps[1:], value, write)
+}
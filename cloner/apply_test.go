@@ -0,0 +1,154 @@
+package cloner_test
+
+import (
+    "errors"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Draft struct {
+    Title  string
+    Tags   map[string]int
+    Lines  []string
+    Author *Person
+    hidden int
+}
+
+// Test for writing the edits made to a clone back onto its source
+func TestApplyBack(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithApplyBack())
+    src := &Draft{
+        Title:  "draft",
+        Tags:   map[string]int{"a": 1, "b": 2},
+        Lines:  []string{"one", "two"},
+        Author: &Person{Name: "Ann"},
+    }
+    edited, err := cloner.Clone(cm, src)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    edited.Title = "final"
+    edited.Tags["c"] = 3
+    delete(edited.Tags, "a")
+    edited.Lines[1] = "TWO"
+    // Changes made to the source since the clone are kept
+    src.Author.Name = "Bob"
+    src.Tags["b"] = 20
+
+    var mutex sync.Mutex
+    if err := cm.ApplyBack(src, edited, cloner.LockSource(&mutex)); err != nil {
+        t.Fatalf("ApplyBack failed: %v", err)
+    }
+    want := &Draft{
+        Title:  "final",
+        Tags:   map[string]int{"b": 20, "c": 3},
+        Lines:  []string{"one", "TWO"},
+        Author: &Person{Name: "Bob"},
+    }
+    if diffs := cloner.Diff(src, want); len(diffs) > 0 {
+        t.Errorf("got source %+v, differing: %v", src, diffs)
+    }
+    if !mutex.TryLock() {
+        t.Errorf("ApplyBack kept the lock")
+    }
+
+    // Only the edits made since the previous ApplyBack are written
+    src.Title = "renamed"
+    edited.Lines = append(edited.Lines, "three")
+    if err := cm.ApplyBack(src, edited); err != nil {
+        t.Fatalf("ApplyBack failed: %v", err)
+    }
+    if src.Title != "renamed" || len(src.Lines) != 3 {
+        t.Errorf("got source %+v, want the renamed draft with three lines", src)
+    }
+    edited.Lines[0] = "ONE"
+    if src.Lines[0] != "one" {
+        t.Errorf("the source shares its lines with the clone")
+    }
+
+    // Edits that cannot be written leave the source as it is
+    src.Author = nil
+    edited.Author.Name = "Cy"
+    edited.Title = "again"
+    err = cm.ApplyBack(src, edited)
+    var cloneErr *cloner.CloneError
+    if !errors.Is(err, cloner.ErrSourceMismatch) || !errors.As(err, &cloneErr) || cloneErr.Path != ".Author.Name" {
+        t.Errorf("got error %v, want ErrSourceMismatch at .Author.Name", err)
+    }
+    if src.Title != "renamed" {
+        t.Errorf("got title %q, want the source unchanged", src.Title)
+    }
+
+    hidden, _ := cloner.Clone(cm, &Draft{})
+    hidden.hidden = 1
+    if err := cm.ApplyBack(&Draft{}, hidden); !errors.Is(err, cloner.ErrUnexportedField) {
+        t.Errorf("got error %v, want ErrUnexportedField", err)
+    }
+    if err := cm.ApplyBack(src, src); !errors.Is(err, cloner.ErrNoSnapshot) {
+        t.Errorf("got error %v, want ErrNoSnapshot", err)
+    }
+    plain, _ := cloner.Clone(cloner.NewCloneManager(), src)
+    if err := cm.ApplyBack(src, plain); !errors.Is(err, cloner.ErrNoSnapshot) {
+        t.Errorf("got error %v, want ErrNoSnapshot without WithApplyBack", err)
+    }
+}
+
+// Test for handling the changes made to the source since its clone
+func TestApplyBackConflict(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithApplyBack())
+    src := &Draft{Title: "draft", Tags: map[string]int{"a": 1}}
+    edited := cloner.MustClone(cm, src)
+    edited.Title = "final"
+    if err := cm.ApplyBack(src, edited, cloner.OnConflict(nil)); err != nil {
+        t.Fatalf("ApplyBack failed on an unchanged source: %v", err)
+    }
+
+    // Another writer changes the source
+    src.Tags["a"] = 2
+    edited.Title = "again"
+    if err := cm.ApplyBack(src, edited, cloner.OnConflict(nil)); !errors.Is(err, cloner.ErrConflict) {
+        t.Errorf("got error %v, want ErrConflict", err)
+    }
+    if src.Title != "final" {
+        t.Errorf("got title %q, want the source unchanged", src.Title)
+    }
+
+    var conflicts []cloner.Conflict
+    err := cm.ApplyBack(src, edited, cloner.OnConflict(func(c cloner.Conflict) error {
+        conflicts = append(conflicts, c)
+        return cloner.OverwriteConflict(c)
+    }))
+    if err != nil {
+        t.Fatalf("ApplyBack failed: %v", err)
+    }
+    if len(conflicts) != 1 || len(conflicts[0].Changes) != 1 || conflicts[0].Changes[0].Path != `.Tags["a"]` {
+        t.Errorf("got conflicts %+v, want the change of .Tags[\"a\"]", conflicts)
+    }
+    if src.Title != "again" || src.Tags["a"] != 2 {
+        t.Errorf("got source %+v, want the edit written and the other change kept", src)
+    }
+}
+
+// Test for writing back fields that Equal does not compare
+func TestApplyBackIgnoredFields(t *testing.T) {
+    type Doc struct {
+        Title   string
+        Cache   string `deeper:"ignoreeq"`
+        Updated time.Time
+    }
+    cm := cloner.NewCloneManager(cloner.WithApplyBack())
+    src := &Doc{Title: "a", Cache: "old", Updated: time.Unix(1, 0)}
+    edited := cloner.MustClone(cm, src)
+    edited.Title, edited.Cache = "b", "new"
+    // Equal instants in another location are equal to Time.Equal
+    edited.Updated = edited.Updated.In(time.FixedZone("X", 3600))
+    if err := cm.ApplyBack(src, edited); err != nil {
+        t.Fatalf("ApplyBack failed: %v", err)
+    }
+    if src.Title != "b" || src.Cache != "new" || src.Updated.Location().String() != "X" {
+        t.Errorf("got source %+v, want every edit written", src)
+    }
+}
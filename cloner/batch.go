@@ -0,0 +1,59 @@
+package cloner
+
+import (
+    "errors"
+    "reflect"
+)
+
+// CloneBatch clones srcs as a single graph: values referenced by several
+// elements of srcs are cloned once and the clones of the elements share
+// them, as if srcs were cloned as one slice, and the setup of a clone is paid
+// once for the batch. Errors are reported with the index of the element, e.g.
+// [2].Name. Options apply to the whole batch as in Clone.
+func (cm *CloneManager) CloneBatch(srcs []interface{}, opts ...Option) ([]interface{}, error) {
+    if srcs == nil {
+        return nil, nil
+    }
+    clones := make([]interface{}, len(srcs))
+    err := cm.batch(reflect.ValueOf(srcs), reflect.ValueOf(clones), opts)
+    return clones, err
+}
+
+// CloneAll is like CloneBatch for a slice of values of type T.
+func CloneAll[T any](cm *CloneManager, srcs []T, opts ...Option) ([]T, error) {
+    if srcs == nil {
+        return nil, nil
+    }
+    clones := make([]T, len(srcs))
+    err := cm.batch(reflect.ValueOf(srcs), reflect.ValueOf(clones), opts)
+    return clones, err
+}
+
+// batch clones the elements of the slice srcs into clones with a single
+// session.
+func (cm *CloneManager) batch(srcs, clones reflect.Value, opts []Option) error {
+    session := cm
+    if cm.visited == nil {
+        session = cm.session(opts...)
+    } else {
+        defer cm.withOptions(opts)()
+    }
+    for i := 0; i < srcs.Len(); i++ {
+        if err := session.cloneElem(clones.Index(i), srcs.Index(i), i); err != nil {
+            if session != cm {
+                session.reportClone(srcs, err)
+            }
+            return err
+        }
+    }
+    if session == cm {
+        return nil
+    }
+    session.setParents()
+    err := errors.Join(session.errs...)
+    session.reportClone(srcs, err)
+    if err == nil {
+        session.recordClone(srcs.Interface(), clones.Interface())
+    }
+    return err
+}
@@ -0,0 +1,58 @@
+package cloner_test
+
+import (
+    "errors"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Ledger struct {
+    ID    int
+    Owner *Holder
+}
+
+type Holder struct {
+    Name string
+}
+
+// Test for cloning records sharing values as one batch
+func TestCloneAll(t *testing.T) {
+    ann := &Holder{Name: "ann"}
+    records := []Ledger{{ID: 1, Owner: ann}, {ID: 2, Owner: ann}}
+
+    cloned, err := cloner.CloneAll(cloner.NewCloneManager(), records)
+    if err != nil {
+        t.Fatalf("CloneAll failed: %v", err)
+    }
+    deepEqual(t, cloned, records)
+    if cloned[0].Owner == ann || cloned[0].Owner != cloned[1].Owner {
+        t.Errorf("Cloned records do not share the cloned owner")
+    }
+
+    if got, err := cloner.CloneAll[Ledger](cloner.NewCloneManager(), nil); got != nil || err != nil {
+        t.Errorf("got %v, %v for nil records, want nil", got, err)
+    }
+}
+
+// Test for cloning values of different types as one batch
+func TestCloneBatch(t *testing.T) {
+    ann := &Holder{Name: "ann"}
+    cm := cloner.NewCloneManager()
+
+    cloned, err := cm.CloneBatch([]interface{}{ann, Ledger{ID: 1, Owner: ann}, "text"})
+    if err != nil {
+        t.Fatalf("CloneBatch failed: %v", err)
+    }
+    owner := cloned[0].(*Holder)
+    if owner == ann || cloned[1].(Ledger).Owner != owner || cloned[2] != "text" {
+        t.Errorf("got %+v, want clones sharing the cloned owner", cloned)
+    }
+
+    // Errors carry the index of the failing element
+    _, err = cm.CloneBatch([]interface{}{1, func() {}})
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || cloneErr.Path != "[1]" || !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUncloneableKind at [1]", err)
+    }
+}
@@ -3,110 +3,571 @@ package cloner
 import (
     "errors"
     "fmt"
+    "io"
+    "math"
     "reflect"
-    "strings"
-    "sync"
-)
+    "sync/atomic"
+    "time"
 
-var (
-    stats      = make(map[string]int)
-    statsMutex sync.Mutex // Mutex for concurrent access
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
 )
 
-// UpdateStats increments the count for the given type in the stats map.
-func UpdateStats(typeName string) {
-    statsMutex.Lock()
-    defer statsMutex.Unlock()
-    stats[typeName]++
-}
-
-func FormatStats() string {
-    statsMutex.Lock()
-    defer statsMutex.Unlock()
-    b := strings.Builder{}
-    for k, v := range stats {
-        b.WriteString(fmt.Sprintf("%s: %d\n", k, v))
-    }
-    return b.String()
-}
-
 // Cloneable interface defines objects that can clone themselves.
 type Cloneable interface {
     Clone(manager *CloneManager) (interface{}, error)
 }
 
+// CloneableV2 is implemented by objects that clone themselves and report
+// what their clone did, so that the manager accounts for it like for the
+// values it clones itself: the nodes and bytes of the report count towards
+// WithMaxNodes and WithMaxBytes, and the values skipped towards the Report
+// of WithOnClone. The manager prefers CloneWithReport to the Clone method
+// of Cloneable for types implementing both. The report counts the values
+// cloned by the method itself, not those it cloned through the manager,
+// which are counted already; its Type, Duration and Err are ignored.
+type CloneableV2 interface {
+    CloneWithReport(manager *CloneManager) (interface{}, Report, error)
+}
+
 // Cloner defines custom cloners for external types.
 type Cloner interface {
     Clone(value interface{}, manager *CloneManager) (interface{}, error)
 }
 
+// AfterCloner is implemented by types that rebuild state of their clones
+// that should not be copied as it is, such as derived indexes, back-pointers
+// or lazily computed caches. AfterDeepClone is called on each cloned value
+// once its contents are cloned; the clones of values reachable from it are
+// complete too, except for those leading back to it through a cycle.
+// Structs referenced by pointers are called through the cloned pointer, so
+// that back-pointers set by AfterDeepClone point to the clone in the graph.
+type AfterCloner interface {
+    AfterDeepClone()
+}
+
+// Transferable is implemented by resources that WithTransfer moves to the
+// clone, in addition to io.Closer values. TransferOwnership is called on the
+// resource once it has moved, so that it can update bookkeeping such as the
+// owner it reports to.
+type Transferable interface {
+    TransferOwnership()
+}
+
+// ClonerFunc adapts an ordinary function to the Cloner interface.
+type ClonerFunc func(value interface{}, manager *CloneManager) (interface{}, error)
+
+// Clone calls f(value, manager).
+func (f ClonerFunc) Clone(value interface{}, manager *CloneManager) (interface{}, error) {
+    return f(value, manager)
+}
+
 // CloneManager manages the cloning process and tracks visited references.
+//
+// Visited references are tracked per call to Clone, so a CloneManager can be
+// used for any number of clones, including concurrent ones, once its cloners
+// are registered. The manager passed to Cloneable and Cloner implementations
+// belongs to the clone in progress: calling its Clone method from there shares
+// the visited references of that clone.
 type CloneManager struct {
-    visited map[uintptr]interface{}
+    visited map[memref.Ref]interface{} // nil unless a clone is in progress
     cloners map[reflect.Type]Cloner
+    parent  *CloneManager // manager whose cloners apply too, see WithOverrides
+    options options
+    types   *typeStats     // statistics of the clones, see WithTypeStats
+    clones  *atomic.Uint64 // number of clones reported, see WithSampling
+    // mapping is the mapping of the last clone, see WithMapping
+    mapping *atomic.Pointer[mapping]
+
+    middleware []Middleware // see Use
+    chain      CloneFunc    // middleware of the clone in progress, if any
+
+    path     []step    // path from the root to the value being cloned
+    depth    int       // depth of the value being cloned
+    nodes    int       // number of values cloned so far
+    errs     []error   // errors collected by the clone in progress
+    deadline time.Time // end of the time budget of the clone; zero if none
+    started  time.Time // start of the clone, see WithOnClone
+    bytes    int64     // memory allocated by the clone so far, see WithMaxBytes
+    skipped  int       // number of values left out of the clone so far
+
+    // pointee is the pointer whose struct is being cloned at depth
+    // pointeeDepth; AfterDeepClone is called through it
+    pointee      reflect.Value
+    pointeeDepth int
+    // interned are the clones of pointers to basic values, see ValueDedup
+    interned map[internKey]interface{}
+    // basicCopies caches copiesBasic for the options of the clone
+    basicCopies map[reflect.Type]bool
+    // parents are the fields tagged `deeper:"parent"` set once the clone
+    // is complete
+    parents []parentField
+    // children is the time spent cloning the values referenced by the value
+    // being timed, see WithTypeStats
+    children time.Duration
+    // sources are the pointers, slices and maps met by the clone, see
+    // WithMapping
+    sources map[memref.Ref]interface{}
+}
+
+// parentField is a field tagged `deeper:"parent"` of a cloned struct, dst,
+// and the pointer it holds in the source.
+type parentField struct {
+    dst reflect.Value
+    src memref.Ref
 }
 
-// NewCloneManager creates a new CloneManager instance.
-func NewCloneManager() *CloneManager {
-    return &CloneManager{
-        visited: make(map[uintptr]interface{}),
+// NewCloneManager creates a new CloneManager instance configured by opts.
+func NewCloneManager(opts ...Option) *CloneManager {
+    cm := &CloneManager{
         cloners: make(map[reflect.Type]Cloner),
+        options: options{unexported: Zero, timers: Zero},
+        types:   &typeStats{},
+        clones:  &atomic.Uint64{},
+        mapping: &atomic.Pointer[mapping]{},
+    }
+    for _, opt := range opts {
+        opt(&cm.options)
     }
+    return cm
 }
 
 // RegisterCloner registers a custom Cloner for a specific type.
+// It must not be called concurrently with Clone.
 func (cm *CloneManager) RegisterCloner(t reflect.Type, cloner Cloner) {
     cm.cloners[t] = cloner
 }
 
 // Clone performs a deep clone of the given object.
-func (cm *CloneManager) Clone(src interface{}) (interface{}, error) {
-    return cm.deepClone(reflect.ValueOf(src))
+//
+// Values that cannot be cloned are reported as a *CloneError wrapping one of
+// the Err* sentinel errors, or the error returned by a Cloner or Cloneable.
+// With CollectErrors, the partial clone is returned together with the
+// collected errors.
+//
+// The clone has the topology of src: a pointer, map or slice reachable
+// through several references, be they shared values, cycles or the back
+// links of doubly-linked lists and other dense graphs, is cloned once and
+// every reference to it refers to that clone. Lists of container/list are
+// cloned with their elements, and references to their elements refer to
+// the elements of the cloned list.
+//
+// Struct fields tagged `deeper:"parent"`, like the Parent pointer of a tree
+// node, are not cloned themselves: they are set to the clone of the pointer
+// they hold once the rest of the graph is cloned, and left nil if that
+// pointer is not otherwise reachable, so that cloning a subtree detaches it
+// from its parent instead of cloning the whole tree.
+//
+// Options given to Clone apply on top of the configuration of the manager
+// for this clone only, e.g. cm.Clone(src, WithMaxDepth(3)). When a Cloner or
+// Cloneable calls Clone during a clone, its options apply to the part of the
+// graph it clones.
+func (cm *CloneManager) Clone(src interface{}, opts ...Option) (interface{}, error) {
+    if cm.visited != nil {
+        defer cm.withOptions(opts)()
+        return cm.deepClone(reflect.ValueOf(src))
+    }
+    session := cm.session(opts...)
+    cloned, err := session.deepClone(reflect.ValueOf(src))
+    session.setParents()
+    if err == nil && len(session.errs) > 0 {
+        err = errors.Join(session.errs...)
+    }
+    session.reportClone(reflect.ValueOf(src), err)
+    if err == nil {
+        session.recordClone(src, cloned)
+    }
+    return cloned, err
+}
+
+// session returns a manager sharing the configuration of cm, with opts
+// applied on top of it, that tracks the visited references of a single
+// clone.
+func (cm *CloneManager) session(opts ...Option) *CloneManager {
+    session := &CloneManager{
+        visited: make(map[memref.Ref]interface{}),
+        cloners: cm.cloners,
+        parent:  cm.parent,
+        options: cm.options,
+        types:   cm.types,
+        clones:  cm.clones,
+        mapping: cm.mapping,
+        chain:   chainOf(cm),
+    }
+    for _, opt := range opts {
+        opt(&session.options)
+    }
+    if session.options.onClone != nil {
+        session.started = time.Now()
+    }
+    if session.options.timeout > 0 {
+        session.deadline = time.Now().Add(session.options.timeout)
+    }
+    session.plant()
+    return session
+}
+
+// withOptions applies opts to the session cm and returns a function
+// restoring its previous options.
+func (cm *CloneManager) withOptions(opts []Option) func() {
+    if len(opts) == 0 {
+        return func() {}
+    }
+    saved, savedDeadline, savedCopies := cm.options, cm.deadline, cm.basicCopies
+    for _, opt := range opts {
+        opt(&cm.options)
+    }
+    cm.basicCopies = nil
+    cm.plant()
+    if cm.options.timeout != saved.timeout {
+        cm.deadline = time.Time{}
+        if cm.options.timeout > 0 {
+            cm.deadline = time.Now().Add(cm.options.timeout)
+        }
+    }
+    return func() {
+        cm.options, cm.deadline, cm.basicCopies = saved, savedDeadline, savedCopies
+    }
+}
+
+// lookupCloner returns the Cloner registered for t with the manager or the
+// managers it derives from, falling back to the default registry.
+func (cm *CloneManager) lookupCloner(t reflect.Type) (Cloner, bool) {
+    for m := cm; m != nil; m = m.parent {
+        if cloner, found := m.cloners[t]; found {
+            return cloner, true
+        }
+    }
+    return registered(t)
+}
+
+// WithOverrides returns a manager configured like cm with opts applied on
+// top of its configuration, so that a standard base policy can be tweaked
+// where it is used. The cloners of cm are not copied: the new manager uses
+// those registered with cm, including those registered later, in addition to
+// its own, which take precedence and do not affect cm. Its statistics are
+// its own.
+func (cm *CloneManager) WithOverrides(opts ...Option) *CloneManager {
+    child := &CloneManager{
+        cloners: make(map[reflect.Type]Cloner),
+        parent:  cm,
+        options: cm.options,
+        types:   &typeStats{},
+        clones:  &atomic.Uint64{},
+        mapping: &atomic.Pointer[mapping]{},
+    }
+    for _, opt := range opts {
+        opt(&child.options)
+    }
+    return child
 }
 
 // Clone performs a deep clone of the given object and returns it as the same type.
-func Clone[T any](cm *CloneManager, src T) (T, error) {
+//
+// Values of small types made of booleans, numbers and strings only, such as
+// ints, small structs and arrays, are returned as they are without
+// allocating, unless a cloner, a type replacement or an option visiting
+// values, like WithReplace or WithMaxNodes, applies to them, or clones are
+// reported with WithOnClone or WithRecorder.
+func Clone[T any](cm *CloneManager, src T, opts ...Option) (T, error) {
+    // Small values without references are their own clones
+    if len(opts) == 0 && cm.visited == nil && cm.options.onClone == nil && cm.options.record == nil {
+        if p := planOf(reflect.TypeOf((*T)(nil)).Elem()); p.plain && cm.copiesPlain(p) {
+            return src, nil
+        }
+    }
+
     // Initialize the result as a zero value of type T
     var result T
 
     // Handle nil case for pointer types
-    if reflect.ValueOf(src).IsNil() {
+    if isNil(reflect.ValueOf(src)) {
         return result, nil // Return zero value for nil pointers
     }
 
     // Deep clone the value
-    clonedValue, err := cm.Clone(src)
+    clonedValue, err := cm.Clone(src, opts...)
     if err != nil {
-        return result, err
+        // Keep the partial clone made with CollectErrors
+        clonedValueTyped, _ := clonedValue.(T)
+        return clonedValueTyped, err
     }
 
     // Assert the cloned value back to type T
     clonedValueTyped, ok := clonedValue.(T)
     if !ok {
-        return result, errors.New("failed to cast cloned value to the original type")
+        return result, fmt.Errorf("%w: failed to cast cloned value to the original type", ErrTypeMismatch)
     }
 
     return clonedValueTyped, nil
 }
 
-// deepClone handles recursive cloning and checks for registered Cloner or Cloneable interfaces.
+// MustClone is like Clone but panics if the value cannot be cloned.
+func MustClone[T any](cm *CloneManager, src T, opts ...Option) T {
+    cloned, err := Clone(cm, src, opts...)
+    if err != nil {
+        panic("cloner: MustClone: " + err.Error())
+    }
+    return cloned
+}
+
+// DeepCopy returns a deep clone of src made by the Default manager. It panics
+// if the value cannot be cloned; use Clone to handle the error instead.
+func DeepCopy[T any](src T) T {
+    return MustClone(Default(), src)
+}
+
+// ClonePtr clones src into a newly allocated value and returns a pointer to
+// it, so that the fields of a cloned struct can be addressed and modified in
+// place. A nil src yields nil.
+func (cm *CloneManager) ClonePtr(src interface{}, opts ...Option) (interface{}, error) {
+    clone, err := cm.CloneValue(reflect.ValueOf(src), opts...)
+    if !clone.IsValid() {
+        return nil, err
+    }
+    return clone.Addr().Interface(), err
+}
+
+// CloneNew is like ClonePtr but returns a typed pointer to the clone.
+func CloneNew[T any](cm *CloneManager, src T, opts ...Option) (*T, error) {
+    clone, err := cm.CloneValue(reflect.ValueOf(&src).Elem(), opts...)
+    if !clone.IsValid() {
+        return nil, err
+    }
+    return clone.Addr().Interface().(*T), err
+}
+
+// isNil reports whether v is invalid or a nil value of a nillable kind.
+func isNil(v reflect.Value) bool {
+    if !v.IsValid() {
+        return true
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+        return v.IsNil()
+    }
+    return false
+}
+
+// deepClone clones src, reporting failures as a *CloneError. With
+// CollectErrors, the error is recorded instead so that the rest of the clone
+// can proceed.
 func (cm *CloneManager) deepClone(src reflect.Value) (interface{}, error) {
+    if cm.options.profileContext != nil && cm.depth == 0 && src.IsValid() {
+        return cm.cloneLabeled(src)
+    }
+    if cm.options.trace != nil {
+        defer cm.traceSubtree(src, time.Now(), cm.nodes, cm.depth)
+    }
+    if cm.options.typeStats {
+        defer cm.stopTiming(src, cm.startTiming())
+    }
+    if err := cm.enter(); err != nil {
+        return nil, cm.report(src, err)
+    }
+    cm.log(LogValues, "value entered", src)
+    cloned, err := cm.tryCloneValue(src)
+    cm.depth--
+    if err != nil {
+        return nil, cm.report(src, err)
+    }
+    return cloned, nil
+}
+
+// enter accounts for one more value in the limits of the clone in progress,
+// descending one level unless a limit is exceeded.
+func (cm *CloneManager) enter() error {
+    cm.nodes++
+    if cm.options.progress != nil {
+        interval := cm.options.progressInterval
+        if interval <= 0 {
+            interval = defaultProgressInterval
+        }
+        if cm.nodes%interval == 0 {
+            cm.options.progress(cm.nodes, Path(formatPath(cm.path)))
+        }
+    }
+    if max := cm.options.maxNodes; max > 0 && cm.nodes > max {
+        return fmt.Errorf("%w: more than %d values", ErrBudgetExceeded, max)
+    }
+    if max := cm.options.maxDepth; max > 0 && cm.depth > max {
+        return fmt.Errorf("%w: deeper than %d levels", ErrDepthExceeded, max)
+    }
+    // Reading the clock for every value would slow small values down
+    if !cm.deadline.IsZero() && cm.nodes%timeoutInterval == 0 && time.Now().After(cm.deadline) {
+        return fmt.Errorf("%w: longer than %v", ErrTimeout, cm.options.timeout)
+    }
+    cm.depth++
+    return nil
+}
+
+// charge accounts for the memory allocated by the clone of the pointer,
+// slice or map src. If it exceeds WithMaxBytes, charge reports true with
+// the result of the WithOverBudget policy for src.
+func (cm *CloneManager) charge(src reflect.Value) (interface{}, bool, error) {
+    max := cm.options.maxBytes
+    if max <= 0 {
+        return nil, false, nil
+    }
+    size := sizeOf(src)
+    if cm.bytes+size <= max {
+        cm.bytes += size
+        return nil, false, nil
+    }
+    switch cm.options.overBudget {
+    case Share:
+        cm.log(LogSkips, "value over budget shared", src)
+        return src.Interface(), true, nil
+    case Zero:
+        cm.skip("value over budget excluded", src)
+        return nil, true, nil
+    }
+    return nil, true, fmt.Errorf("%w: more than %d bytes", ErrBudgetExceeded, max)
+}
+
+// sizeOf estimates the memory allocated by the clone of the pointer, slice
+// or map src, not counting the values it references.
+func sizeOf(src reflect.Value) int64 {
+    t := src.Type()
+    switch src.Kind() {
+    case reflect.Ptr:
+        return int64(t.Elem().Size())
+    case reflect.Slice:
+        return int64(src.Cap()) * int64(t.Elem().Size())
+    case reflect.Map:
+        return int64(src.Len()) * int64(t.Key().Size()+t.Elem().Size())
+    }
+    return 0
+}
+
+// defaultProgressInterval is the number of values cloned between calls to
+// the WithProgress callback unless set by WithProgressInterval.
+const defaultProgressInterval = 10000
+
+// timeoutInterval is the number of values cloned between checks of the
+// deadline set by WithTimeout.
+const timeoutInterval = 32
+
+// report wraps err in a *CloneError for src at the current path. With
+// CollectErrors, the error is recorded and nil is returned so that the clone
+// can proceed, leaving the value at its zero value.
+func (cm *CloneManager) report(src reflect.Value, err error) error {
+    err = cm.cloneError(src, err)
+    if cm.options.collectErrors && !errors.Is(err, ErrBudgetExceeded) && !errors.Is(err, ErrTimeout) {
+        cm.errs = append(cm.errs, err)
+        return nil
+    }
+    return err
+}
+
+// checkGuard returns the error of the WithGuard callback for src, if any.
+func (cm *CloneManager) checkGuard(src reflect.Value) error {
+    if cm.options.guard == nil {
+        return nil
+    }
+    return cm.options.guard(src.Type(), Path(formatPath(cm.path)))
+}
+
+// tryCloneValue calls cloneValue, through the middleware of the clone if
+// any, converting a panic, typically raised by reflection or by a custom
+// cloner, into a *CloneError for the value that was being cloned when it
+// occurred.
+func (cm *CloneManager) tryCloneValue(src reflect.Value) (cloned interface{}, err error) {
+    depth := len(cm.path)
+    defer func() {
+        if r := recover(); r != nil {
+            cloned, err = nil, cm.cloneError(src, fmt.Errorf("panic: %v", r))
+            cm.path = cm.path[:depth]
+        }
+    }()
+    if cm.chain != nil {
+        return cm.chain(cm, src)
+    }
+    return cm.cloneValue(src)
+}
+
+// cloneValue handles recursive cloning and checks for registered Cloner or Cloneable interfaces.
+func (cm *CloneManager) cloneValue(src reflect.Value) (interface{}, error) {
     if !src.IsValid() {
         return nil, nil
     }
 
+    if policy, ok := cm.pathPolicy(); ok {
+        if policy == Share && src.CanInterface() {
+            cm.log(LogSkips, "value shared by path", src)
+            return src.Interface(), nil
+        }
+        cm.skip("value excluded by path", src)
+        return nil, nil
+    }
+    if err := cm.checkGuard(src); err != nil {
+        return nil, err
+    }
+    if replaced, ok := cm.replaceValue(src); ok {
+        return replaced, nil
+    }
+    if cm.options.mapping {
+        cm.noteSource(src)
+    }
+
+    // Pointers cloned by a Cloneable are tracked too, so that every reference
+    // to the same object resolves to a single clone
+    isPtr := src.Kind() == reflect.Ptr && !src.IsNil()
+    if isPtr {
+        if cloned, ok := cm.lookup(memref.Of(src)); ok {
+            return cloned, nil
+        }
+    }
+
+    if r, ok := cm.options.replacements[src.Type()]; ok {
+        cm.log(LogCloners, "type replaced", src)
+        cloned, err := cm.replace(src, r)
+        if err == nil && isPtr {
+            cm.record(memref.Of(src), cloned)
+        }
+        return cloned, err
+    }
+
     // Check if the value implements Cloneable
-    if src.CanInterface() {
-        if cloneable, ok := src.Interface().(Cloneable); ok {
-            // Delegate to the Cloneable method
-            return cloneable.Clone(cm)
+    if cloned, ok, err := cm.cloneCloneable(src); ok {
+        if err == nil && isPtr {
+            cm.record(memref.Of(src), cloned)
         }
+        return cloned, err
     }
 
     // Check for registered Cloner
-    if cloner, found := cm.cloners[src.Type()]; found {
+    if cloner, found := cm.lookupCloner(src.Type()); found {
+        cm.log(LogCloners, "Cloner called", src)
         return cloner.Clone(src.Interface(), cm)
     }
+    if cm.shares(src) {
+        return src.Interface(), nil
+    }
+    if cloned, ok, err := cm.cloneTimer(src); ok {
+        return cloned, err
+    }
+    if cm.options.sharedBytes[src.Type()] {
+        cm.log(LogSkips, "bytes shared by policy", src)
+        return src.Interface(), nil
+    }
+    if (valueTypes[src.Type()] || planOf(src.Type()).scalar) && src.CanInterface() {
+        return src.Interface(), nil
+    }
+    if cloned, ok, err := cm.cloneValuer(src); ok {
+        return cloned, err
+    }
+    if cloned, ok, err := cm.cloneMarshaler(src); ok {
+        return cloned, err
+    }
+    if cloned, ok, err := cm.cloneReflect(src); ok {
+        return cloned, err
+    }
+    if cloned, ok, err := cm.cloneContainer(src); ok {
+        return cloned, err
+    }
+    if cloned, ok, err := cm.cloneList(src); ok {
+        return cloned, err
+    }
 
     // Perform default deep clone logic (same as in the previous example)
     // Clone for Ptr, Slice, Array, Map, Struct, etc.
@@ -123,39 +584,184 @@ func (cm *CloneManager) deepClone(src reflect.Value) (interface{}, error) {
         return cm.cloneStruct(src)
     case reflect.Interface:
         return cm.cloneInterface(src)
-    case reflect.Chan:
-        return nil, errors.New("channels cannot be cloned")
-    case reflect.Func:
-        return nil, errors.New(fmt.Sprintf("functions cannot be cloned: %v", src))
-        //return src.Interface(), nil // Functions are reference types but immutable
+    case reflect.Chan, reflect.Func:
+        if src.IsNil() {
+            return nil, nil // Nil channels and functions have nothing to clone
+        }
+        return cm.cloneUncloneable(src)
     default:
         return src.Interface(), nil // Primitive types can be copied directly
     }
 }
 
+// cloneCloneable clones src with its CloneWithReport or Clone method,
+// reporting false if it implements neither CloneableV2 nor Cloneable.
+func (cm *CloneManager) cloneCloneable(src reflect.Value) (interface{}, bool, error) {
+    if !src.CanInterface() {
+        return nil, false, nil
+    }
+    switch c := src.Interface().(type) {
+    case CloneableV2:
+        cm.log(LogCloners, "CloneableV2 called", src)
+        cloned, report, err := c.CloneWithReport(cm)
+        if err == nil {
+            err = cm.account(report)
+        }
+        return cloned, true, err
+    case Cloneable:
+        cm.log(LogCloners, "Cloneable called", src)
+        cloned, err := c.Clone(cm)
+        return cloned, true, err
+    }
+    return nil, false, nil
+}
+
+// account adds the values and memory of the report of a CloneableV2 to
+// those of the clone, reporting ErrBudgetExceeded if they exceed its limits.
+func (cm *CloneManager) account(report Report) error {
+    cm.nodes += report.Nodes
+    cm.skipped += report.Skipped
+    if max := cm.options.maxNodes; max > 0 && cm.nodes > max {
+        return fmt.Errorf("%w: more than %d values", ErrBudgetExceeded, max)
+    }
+    if max := cm.options.maxBytes; max > 0 {
+        cm.bytes += report.Bytes
+        if cm.bytes > max && cm.options.overBudget == Error {
+            return fmt.Errorf("%w: more than %d bytes", ErrBudgetExceeded, max)
+        }
+    }
+    return nil
+}
+
+// skip accounts for a value left out of the clone, logging msg.
+func (cm *CloneManager) skip(msg string, src reflect.Value) {
+    cm.skipped++
+    cm.log(LogSkips, msg, src)
+}
+
 // clonePtr clones a pointer value.
 func (cm *CloneManager) clonePtr(src reflect.Value) (interface{}, error) {
     if src.IsNil() {
         return nil, nil
     }
-    ptr := src.Pointer()
-    if cloned, ok := cm.visited[ptr]; ok {
+    ptr := memref.Of(src)
+    if cloned, ok := cm.lookup(ptr); ok {
+        return cloned, nil
+    }
+    key, intern := cm.internKeyOf(src.Elem())
+    if cloned, ok := cm.interned[key]; ok && intern {
+        cm.record(ptr, cloned)
         return cloned, nil
     }
+    if cloned, over, err := cm.charge(src); over {
+        return cloned, err
+    }
+
+    // Record the new pointer before cloning the pointed value, so that cycles
+    // leading back to src resolve to it
+    clonePtr, err := cm.allocate(src.Elem().Type())
+    if err != nil {
+        return nil, err
+    }
+    cm.record(ptr, clonePtr.Interface())
 
     // Recursively clone the pointed value
+    isStruct := src.Elem().Kind() == reflect.Struct
+    if isStruct {
+        cm.pointee, cm.pointeeDepth = clonePtr, cm.depth+1
+    }
     cloned, err := cm.deepClone(src.Elem())
+    cm.pointee = reflect.Value{}
     if err != nil {
         return nil, err
     }
     UpdateStats(src.Kind().String())
 
-    clonePtr := reflect.New(src.Elem().Type())
-    clonePtr.Elem().Set(reflect.ValueOf(cloned))
-    cm.visited[ptr] = clonePtr.Interface()
+    if err := cm.assign(clonePtr.Elem(), cloned, src.Elem()); err != nil {
+        return nil, err
+    }
+    if isStruct && planOf(src.Elem().Type()).afterCloner {
+        afterClone(clonePtr.Elem())
+    }
+    if intern {
+        if cm.interned == nil {
+            cm.interned = make(map[internKey]interface{})
+        }
+        cm.interned[key] = clonePtr.Interface()
+    }
     return clonePtr.Interface(), nil
 }
 
+// internKey identifies a boolean, number or string by its type and value.
+type internKey struct {
+    typ        reflect.Type
+    bits, imag uint64
+    str        string
+}
+
+// internKeyOf returns the key of v if pointers to it are collapsed with
+// ValueDedup.
+func (cm *CloneManager) internKeyOf(v reflect.Value) (internKey, bool) {
+    if cm.options.identity != ValueDedup {
+        return internKey{}, false
+    }
+    key := internKey{typ: v.Type()}
+    switch v.Kind() {
+    case reflect.Bool:
+        if v.Bool() {
+            key.bits = 1
+        }
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        key.bits = uint64(v.Int())
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        key.bits = v.Uint()
+    case reflect.Float32, reflect.Float64:
+        key.bits = math.Float64bits(v.Float())
+    case reflect.Complex64, reflect.Complex128:
+        key.bits, key.imag = math.Float64bits(real(v.Complex())), math.Float64bits(imag(v.Complex()))
+    case reflect.String:
+        key.str = v.String()
+    default:
+        return internKey{}, false
+    }
+    return key, true
+}
+
+// replaceValue returns the substitute for src given by the WithReplace
+// option, if any.
+func (cm *CloneManager) replaceValue(src reflect.Value) (interface{}, bool) {
+    if cm.options.replace == nil || !src.CanInterface() {
+        return nil, false
+    }
+    return cm.options.replace(Path(formatPath(cm.path)), src.Interface())
+}
+
+// replace converts src with the type replacement r.
+func (cm *CloneManager) replace(src reflect.Value, r replacement) (interface{}, error) {
+    replaced, err := r.converter(src.Interface())
+    if err != nil {
+        return nil, err
+    }
+    if replaced != nil && reflect.TypeOf(replaced) != r.newType {
+        return nil, fmt.Errorf("%w: replacement of type %T, want %s", ErrTypeMismatch, replaced, r.newType)
+    }
+    return replaced, nil
+}
+
+// allocate returns a pointer to a new zero value of type t, obtained from the
+// allocator if one is configured.
+func (cm *CloneManager) allocate(t reflect.Type) (reflect.Value, error) {
+    if cm.options.allocator != nil {
+        if ptr := cm.options.allocator(t); ptr.IsValid() {
+            if ptr.Type() != reflect.PointerTo(t) || ptr.IsNil() {
+                return reflect.Value{}, fmt.Errorf("%w: allocator returned %s for %s", ErrTypeMismatch, ptr.Type(), t)
+            }
+            return ptr, nil
+        }
+    }
+    return reflect.New(t), nil
+}
+
 // cloneSlice clones a slice value.
 func (cm *CloneManager) cloneSlice(src reflect.Value) (interface{}, error) {
     if src.IsNil() {
@@ -163,24 +769,31 @@ func (cm *CloneManager) cloneSlice(src reflect.Value) (interface{}, error) {
     }
 
     // Check if we've already cloned this slice
-    ptr := src.Pointer()
-    if cloned, found := cm.visited[ptr]; found {
+    ptr := memref.Of(src)
+    if cloned, found := cm.lookup(ptr); found {
         return cloned, nil
     }
+    if cloned, over, err := cm.charge(src); over {
+        return cloned, err
+    }
 
     // Create a new slice of the same type and length
     clone := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
-    cm.visited[ptr] = clone.Interface()
+    cm.record(ptr, clone.Interface())
 
-    // Iterate through the slice and deep clone each element
-    for i := 0; i < src.Len(); i++ {
-        clonedElem, err := cm.deepClone(src.Index(i))
-        if err != nil {
-            return nil, err
+    // Slices of plain values, such as []byte and json.RawMessage, are copied
+    // at once; other slices are cloned element by element
+    if elem := src.Type().Elem(); isFlat(elem.Kind()) && planOf(elem).plain && cm.copiesBasic(elem) {
+        reflect.Copy(clone, src)
+    } else {
+        for i := 0; i < src.Len(); i++ {
+            if err := cm.cloneElem(clone.Index(i), src.Index(i), i); err != nil {
+                return nil, err
+            }
         }
-        clone.Index(i).Set(reflect.ValueOf(clonedElem))
     }
     UpdateStats(src.Kind().String())
+    afterClone(clone)
     return clone.Interface(), nil
 }
 
@@ -188,18 +801,23 @@ func (cm *CloneManager) cloneSlice(src reflect.Value) (interface{}, error) {
 func (cm *CloneManager) cloneArray(src reflect.Value) (interface{}, error) {
     // Create a new array of the same type and length
     clone := reflect.New(src.Type()).Elem()
+    if err := cm.cloneElems(clone, src); err != nil {
+        return nil, err
+    }
+    return clone.Interface(), nil
+}
 
-    // Clone each element in the array
+// cloneElems clones the elements of the array src into clone, a settable
+// array of the same type.
+func (cm *CloneManager) cloneElems(clone, src reflect.Value) error {
     for i := 0; i < src.Len(); i++ {
-        elem := src.Index(i)
-        clonedElem, err := cm.deepClone(elem)
-        if err != nil {
-            return nil, err
+        if err := cm.cloneElem(clone.Index(i), src.Index(i), i); err != nil {
+            return err
         }
-        clone.Index(i).Set(reflect.ValueOf(clonedElem))
     }
     UpdateStats(src.Kind().String())
-    return clone.Interface(), nil
+    afterClone(clone)
+    return nil
 }
 
 // cloneMap clones a map value.
@@ -209,82 +827,406 @@ func (cm *CloneManager) cloneMap(src reflect.Value) (interface{}, error) {
     }
 
     // Use the map's underlying pointer as the key
-    ptr := src.Pointer()
+    ptr := memref.Of(src)
 
     // Check if we've already cloned this map
-    if cloned, found := cm.visited[ptr]; found {
+    if cloned, found := cm.lookup(ptr); found {
         return cloned, nil
     }
+    if cloned, over, err := cm.charge(src); over {
+        return cloned, err
+    }
 
     // Create a new map of the same type
     clone := reflect.MakeMapWithSize(src.Type(), src.Len())
-    cm.visited[ptr] = clone.Interface()
+    cm.record(ptr, clone.Interface())
 
-    // Deep clone each key-value pair in the map
-    for _, key := range src.MapKeys() {
-        clonedKey, err := cm.deepClone(key)
-        if err != nil {
-            return nil, err
+    var buf reflect.Value
+    if src.Type().Elem().Kind() == reflect.Struct {
+        buf = reflect.New(src.Type().Elem()).Elem()
+    }
+
+    // Deep clone each key-value pair in the map. Keys are cloned like any
+    // other value, so pointers inside keys map to the same clones as the
+    // pointers found elsewhere in the graph.
+    err := cm.rangeMap(src, false, func(key, value reflect.Value) error {
+        cm.pushKey(key)
+        defer cm.pop()
+        return cm.cloneEntry(clone, buf, key, value)
+    })
+    if err != nil {
+        return nil, err
+    }
+    UpdateStats(src.Kind().String())
+    afterClone(clone)
+    return clone.Interface(), nil
+}
+
+// cloneEntry clones the entry of key and value at the current path into the
+// map clone, cloning struct values into buf if it is valid.
+func (cm *CloneManager) cloneEntry(clone, buf, key, value reflect.Value) error {
+    if policy, ok := cm.pathPolicy(); ok {
+        if policy == Share {
+            clone.SetMapIndex(key, value)
         }
+        return nil
+    }
+    collected := len(cm.errs)
+    clonedKey, err := cm.deepClone(key)
+    if err != nil || len(cm.errs) > collected {
+        // Entries whose key cannot be cloned are dropped
+        return err
+    }
 
-        clonedValue, err := cm.deepClone(src.MapIndex(key))
-        if err != nil {
-            return nil, err
+    // Struct values are cloned into a buffer that SetMapIndex copies
+    var v reflect.Value
+    if buf.IsValid() {
+        if ok, err := cm.cloneInto(buf, value); err != nil {
+            return err
+        } else if ok {
+            v = buf
         }
+    }
+    var clonedValue interface{}
+    if !v.IsValid() {
+        if clonedValue, err = cm.deepClone(value); err != nil {
+            return err
+        }
+    }
 
-        clone.SetMapIndex(reflect.ValueOf(clonedKey), reflect.ValueOf(clonedValue))
+    k, err := cm.valueOf(clonedKey, key.Type(), key)
+    if err != nil {
+        return err
     }
-    UpdateStats(src.Kind().String())
-    return clone.Interface(), nil
+    if !v.IsValid() {
+        v, err = cm.valueOf(clonedValue, value.Type(), value)
+    }
+    if k.IsValid() && v.IsValid() {
+        clone.SetMapIndex(k, v)
+    }
+    return err
 }
 
 // cloneStruct clones a struct value.
 func (cm *CloneManager) cloneStruct(src reflect.Value) (interface{}, error) {
     // Create a new struct of the same type
     clone := reflect.New(src.Type()).Elem()
+    if err := cm.cloneFields(clone, src); err != nil {
+        return nil, err
+    }
+    return clone.Interface(), nil
+}
+
+// cloneFields clones the fields of the struct src into clone, a settable
+// struct of the same type, overwriting every field.
+func (cm *CloneManager) cloneFields(clone, src reflect.Value) error {
+    // The pointer being cloned calls AfterDeepClone once the clone is stored
+    var pointee reflect.Value
+    if cm.pointee.IsValid() && cm.pointeeDepth == cm.depth && cm.pointee.Type().Elem() == src.Type() {
+        pointee = cm.pointee
+    }
+    cm.pointee = reflect.Value{}
+
+    if cm.options.unexported == Share {
+        // Unexported fields keep the values of src; exported fields are
+        // replaced by their clones below
+        clone.Set(src)
+    } else {
+        clone.SetZero()
+    }
 
     // Clone each field of the struct
-    for i := 0; i < src.NumField(); i++ {
+    p := planOf(src.Type())
+    for i := range p.fields {
+        fp := &p.fields[i]
         field := src.Field(i)
         clonedFieldRef := clone.Field(i)
-        if clonedFieldRef.CanSet() {
-            clonedField, err := cm.deepClone(field)
-            if err != nil {
-                return nil, err
-            }
-            //clonedFieldRef.Set(reflect.ValueOf(clonedField))
-            // Ensure the cloned value is not zero
-            if !clonedFieldRef.IsValid() {
-                return nil, fmt.Errorf("cannot set invalid field at index %d", i)
-            }
+        cm.pushField(fp)
+        var err error
+        switch {
+        case !fp.exported:
+            err = cm.checkUnexported(field, fp.name)
+        case cm.isParent(fp):
+            cm.cloneParent(clonedFieldRef, field, pointee, i)
+        case cm.options.transfer && isResource(field):
+            err = cm.transfer(clonedFieldRef, field)
+        case fp.basic && cm.copiesBasic(fp.typ):
+            clonedFieldRef.Set(field)
+        default:
+            err = cm.cloneTo(clonedFieldRef, field)
+        }
+        cm.pop()
+        if err != nil {
+            return err
+        }
+    }
+    UpdateStats(p.stats)
+    if !pointee.IsValid() && p.afterCloner {
+        afterClone(clone)
+    }
+    return nil
+}
 
-            // Set the cloned field only if the value is valid
-            if clonedField != nil {
-                clonedFieldRef.Set(reflect.ValueOf(clonedField))
-            }
+// cloneInto clones the struct or array src into dst, a settable value of
+// its type, like deepClone but without boxing the clone in an interface{},
+// which allocates a copy of it. It reports false, without cloning src, for
+// values that deepClone may clone into another value: other kinds and the
+// values that a Cloneable, a Cloner or a replacement may clone. On errors,
+// dst is left at its zero value.
+func (cm *CloneManager) cloneInto(dst, src reflect.Value) (bool, error) {
+    if k := src.Kind(); k != reflect.Struct && k != reflect.Array || !cm.clonesFields(src.Type()) {
+        return false, nil
+    }
+    if cm.options.trace != nil {
+        defer cm.traceSubtree(src, time.Now(), cm.nodes, cm.depth)
+    }
+    if cm.options.typeStats {
+        defer cm.stopTiming(src, cm.startTiming())
+    }
+    if err := cm.enter(); err != nil {
+        dst.SetZero()
+        return true, cm.report(src, err)
+    }
+    cm.log(LogValues, "value entered", src)
+    err := cm.tryCloneFields(dst, src)
+    cm.depth--
+    if err != nil {
+        dst.SetZero()
+        return true, cm.report(src, err)
+    }
+    return true, nil
+}
+
+// clonesFields reports whether cloneValue clones the structs or arrays of
+// type t field by field with cloneStruct, or element by element with
+// cloneArray.
+func (cm *CloneManager) clonesFields(t reflect.Type) bool {
+    if cm.options.replace != nil || cm.options.guard != nil || cm.chain != nil ||
+        t == reflectValueType || planOf(t).cloneable {
+        return false
+    }
+    if _, ok := cm.options.replacements[t]; ok {
+        return false
+    }
+    if !cm.options.cloneAmbient && isAmbient(t) || valueTypes[t] || planOf(t).scalar || cm.scans(t) {
+        return false
+    }
+    if _, ok := cm.marshals(t); ok {
+        return false
+    }
+    if _, ok := containerOf(t); ok {
+        return false
+    }
+    _, ok := cm.lookupCloner(t)
+    return !ok
+}
+
+// tryCloneFields is like tryCloneValue for cloneFields and cloneElems.
+func (cm *CloneManager) tryCloneFields(dst, src reflect.Value) (err error) {
+    depth := len(cm.path)
+    defer func() {
+        if r := recover(); r != nil {
+            err = cm.cloneError(src, fmt.Errorf("panic: %v", r))
+            cm.path = cm.path[:depth]
         }
+    }()
+    if src.Kind() == reflect.Array {
+        return cm.cloneElems(dst, src)
     }
-    UpdateStats(src.Kind().String() + " " + src.Type().String())
-    return clone.Interface(), nil
+    return cm.cloneFields(dst, src)
 }
 
-func (cm *CloneManager) cloneInterface(src reflect.Value) (interface{}, error) {
-    // Get the underlying value
-    underlyingValue := src.Elem()
+// cloneParent sets dst, the clone of the field src tagged `deeper:"parent"`,
+// to the clone of the pointer src holds. If that pointer is not cloned yet
+// and the struct is the clone of the pointer pointee, field i of its value is
+// set once the clone is complete by setParents; otherwise dst is left nil.
+func (cm *CloneManager) cloneParent(dst, src, pointee reflect.Value, i int) {
+    if src.Kind() == reflect.Interface && !src.IsNil() {
+        src = src.Elem()
+    }
+    if src.Kind() != reflect.Ptr || src.IsNil() {
+        return
+    }
+    if cm.setParent(dst, memref.Of(src)) {
+        return
+    }
+    if pointee.IsValid() {
+        cm.parents = append(cm.parents, parentField{dst: pointee.Elem().Field(i), src: memref.Of(src)})
+    }
+}
+
+// setParents sets the fields tagged `deeper:"parent"` whose pointer was not
+// cloned yet when their struct was to the clone of that pointer, if the
+// pointer is part of the cloned graph.
+func (cm *CloneManager) setParents() {
+    for _, p := range cm.parents {
+        cm.setParent(p.dst, p.src)
+    }
+    cm.parents = nil
+}
+
+// setParent sets dst to the clone of the pointer src, reporting false if
+// src is not cloned.
+func (cm *CloneManager) setParent(dst reflect.Value, src memref.Ref) bool {
+    cloned, ok := cm.lookup(src)
+    if !ok || cloned == nil {
+        return false
+    }
+    if v := reflect.ValueOf(cloned); v.Type().AssignableTo(dst.Type()) {
+        dst.Set(v)
+    }
+    return true
+}
+
+// afterClone calls AfterDeepClone on the clone v if its type, or a pointer
+// to it if v is addressable, implements AfterCloner.
+func afterClone(v reflect.Value) {
+    if !planOf(v.Type()).afterCloner {
+        return
+    }
+    if v.CanAddr() {
+        v = v.Addr()
+    }
+    if after, ok := v.Interface().(AfterCloner); ok {
+        after.AfterDeepClone()
+    }
+}
 
-    // Check for nil underlying value
-    if !underlyingValue.IsValid() {
-        return nil, nil // Return nil for nil underlying value
+// isResource reports whether v holds a resource moved by WithTransfer.
+func isResource(v reflect.Value) bool {
+    if isNil(v) || !v.CanInterface() {
+        return false
     }
+    switch v.Interface().(type) {
+    case io.Closer, Transferable:
+        return true
+    }
+    return false
+}
+
+// transfer moves the resource held by the struct field src to the field dst
+// of the clone, clearing src.
+func (cm *CloneManager) transfer(dst, src reflect.Value) error {
+    if !src.CanSet() {
+        return cm.report(src, fmt.Errorf("%w: %s cannot be cleared in the source", ErrNotTransferable, src.Type()))
+    }
+    resource := src.Interface()
+    if v := reflect.ValueOf(resource); v.Kind() == reflect.Ptr {
+        cm.record(memref.Of(v), resource)
+    }
+    dst.Set(src)
+    src.Set(reflect.Zero(src.Type()))
+    if t, ok := resource.(Transferable); ok {
+        t.TransferOwnership()
+    }
+    return nil
+}
+
+// cloneInterface clones the dynamic value of the interface src. The clone
+// must implement the interface type; a Cloner returning a value that does not
+// is reported as ErrTypeMismatch. Clones of another dynamic type that still
+// implement the interface, such as wrappers, are accepted.
+func (cm *CloneManager) cloneInterface(src reflect.Value) (interface{}, error) {
     if src.IsNil() {
         return nil, nil
     }
-    // Clone the underlying value
-    clonedValue, err := cm.deepClone(underlyingValue)
+    // Clone the underlying value, which is never itself an interface
+    clonedValue, err := cm.deepClone(src.Elem())
     if err != nil {
         return nil, err
     }
+    if clonedValue == nil {
+        // Keep the dynamic type of typed nil values
+        clonedValue = reflect.Zero(src.Elem().Type()).Interface()
+    }
     UpdateStats(src.Kind().String() + " " + src.Type().String())
-    // Return as an interface type
-    return reflect.ValueOf(clonedValue).Convert(src.Type()).Interface(), nil
+    v, err := cm.valueOf(clonedValue, src.Type(), src)
+    if !v.IsValid() {
+        return nil, err
+    }
+    return v.Interface(), nil
+}
+
+// checkUnexported applies the Error policy for unexported fields to field,
+// logging the data that the Zero policy leaves out.
+func (cm *CloneManager) checkUnexported(field reflect.Value, name string) error {
+    if cm.options.unexported != Error || name == "_" || field.IsZero() {
+        if cm.options.unexported == Zero && name != "_" && cm.logs(LogSkips) && !field.IsZero() {
+            cm.log(LogSkips, "unexported field excluded", field)
+        }
+        return nil
+    }
+    return cm.report(field, fmt.Errorf("%w: %s is not copied to the clone", ErrUnexportedField, name))
+}
+
+// cloneUncloneable applies the configured policy to a channel or function.
+func (cm *CloneManager) cloneUncloneable(src reflect.Value) (interface{}, error) {
+    policy := cm.options.funcs
+    if src.Kind() == reflect.Chan {
+        policy = cm.options.chans
+    }
+    switch policy {
+    case Share:
+        cm.log(LogSkips, "value shared by policy", src)
+        return src.Interface(), nil
+    case Zero:
+        cm.skip("value excluded by policy", src)
+        return nil, nil
+    }
+    if src.Kind() == reflect.Chan {
+        return nil, fmt.Errorf("%w: channels cannot be cloned", ErrUncloneableKind)
+    }
+    return nil, fmt.Errorf("%w: functions cannot be cloned: %v", ErrUncloneableKind, src)
+}
+
+// cloneElem clones the slice or array element src at index i into dst.
+func (cm *CloneManager) cloneElem(dst, src reflect.Value, i int) error {
+    cm.pushIndex(i)
+    defer cm.pop()
+    return cm.cloneTo(dst, src)
+}
+
+// cloneTo clones src into dst, a settable value of its type. Basic values
+// are copied and structs and arrays cloned into dst directly when nothing
+// else applies to them, sparing the boxing of their clones in an
+// interface{}.
+func (cm *CloneManager) cloneTo(dst, src reflect.Value) error {
+    if isFlat(src.Kind()) && planOf(src.Type()).plain && cm.copiesBasic(src.Type()) {
+        dst.Set(src)
+        return nil
+    }
+    if ok, err := cm.cloneInto(dst, src); ok {
+        return err
+    }
+    cloned, err := cm.deepClone(src)
+    if err != nil {
+        return err
+    }
+    return cm.assign(dst, cloned, src)
+}
+
+// assign sets dst to cloned, the clone of src, leaving dst at its zero value
+// for nil results.
+func (cm *CloneManager) assign(dst reflect.Value, cloned interface{}, src reflect.Value) error {
+    v, err := cm.valueOf(cloned, dst.Type(), src)
+    if v.IsValid() {
+        dst.Set(v)
+    }
+    return err
+}
+
+// valueOf returns cloned, the clone of src, as a reflect.Value assignable to
+// t, or the zero value of t for nil results. A clone of another type, as
+// returned by a misbehaving Cloner, is reported as ErrTypeMismatch; if the
+// error is collected, the returned value is invalid.
+func (cm *CloneManager) valueOf(cloned interface{}, t reflect.Type, src reflect.Value) (reflect.Value, error) {
+    if cloned == nil {
+        return reflect.Zero(t), nil
+    }
+    v := reflect.ValueOf(cloned)
+    if !v.Type().AssignableTo(t) {
+        err := fmt.Errorf("%w: cannot use clone of type %s as %s", ErrTypeMismatch, v.Type(), t)
+        return reflect.Value{}, cm.report(src, err)
+    }
+    return v, nil
 }
@@ -1,7 +1,9 @@
 package cloner_test
 
 import (
+    "errors"
     "github.com/jayaprabhakar/go-deeper/cloner"
+    "math"
     "reflect"
     "testing"
 )
@@ -321,3 +323,666 @@ func TestCloneSliceOfPointers(t *testing.T) {
         t.Errorf("Cloned slice value is incorrect: got %d, want 400", *clonedStruct.Values[1])
     }
 }
+
+// Test for registering a function as a custom cloner
+func TestRegisterClonerFunc(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    calls := 0
+    cm.RegisterCloner(reflect.TypeOf(TestStruct{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        calls++
+        src := value.(TestStruct)
+        return TestStruct{A: src.A * 2}, nil
+    }))
+
+    cloned, err := cm.Clone([]TestStruct{{A: 1}, {A: 2}})
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    deepEqual(t, cloned, []TestStruct{{A: 2}, {A: 4}})
+    if calls != 2 {
+        t.Errorf("Registered cloner was called %d times, want 2", calls)
+    }
+}
+
+// Test that each call to Clone tracks references on its own
+func TestCloneTwice(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    original := &TestStruct{A: 1}
+    first, err := cm.Clone(original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    original.A = 2
+    second, err := cm.Clone(original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    if first == second {
+        t.Fatalf("Second clone returned the result of the first clone")
+    }
+    deepEqual(t, second, original)
+}
+
+// Test for the generic Clone with non-pointer types
+func TestCloneGenericValues(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    original := TestStruct{A: 42, B: new(int)}
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, cloned, original)
+    if cloned.B == original.B {
+        t.Errorf("Clone did not create a new pointer")
+    }
+
+    var nilSlice []int
+    clonedSlice, err := cloner.Clone(cm, nilSlice)
+    if err != nil || clonedSlice != nil {
+        t.Errorf("Cloning a nil slice should return nil")
+    }
+
+    var nilInterface interface{}
+    clonedInterface, err := cloner.Clone(cm, nilInterface)
+    if err != nil || clonedInterface != nil {
+        t.Errorf("Cloning a nil interface should return nil")
+    }
+}
+
+// Test for DeepCopy with the default manager
+func TestDeepCopy(t *testing.T) {
+    original := map[string][]int{"a": {1, 2}}
+
+    cloned := cloner.DeepCopy(original)
+    deepEqual(t, cloned, original)
+
+    // Ensure modifying the original does not affect the clone
+    original["a"][0] = 100
+    if cloned["a"][0] != 1 {
+        t.Errorf("Modifying the original affected the cloned map")
+    }
+}
+
+// Test that MustClone panics when the value cannot be cloned
+func TestMustClonePanics(t *testing.T) {
+    defer func() {
+        if r := recover(); r == nil {
+            t.Errorf("MustClone did not panic")
+        }
+    }()
+
+    cloner.MustClone(cloner.NewCloneManager(), struct {
+        C chan int
+    }{C: make(chan int)})
+}
+
+// Test for cloning nil elements and values
+func TestCloneNilElements(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    originalSlice := []*int{nil, new(int)}
+    clonedSlice, err := cm.Clone(originalSlice)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, clonedSlice, originalSlice)
+
+    originalMap := map[string][]int{"a": nil}
+    clonedMap, err := cm.Clone(originalMap)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, clonedMap, originalMap)
+
+    originalArray := [2]interface{}{nil, 1}
+    clonedArray, err := cm.Clone(originalArray)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, clonedArray, originalArray)
+}
+
+// Test for cloning pointer cycles
+func TestCloneCycle(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    type Ring struct {
+        Value int
+        Next  *Ring
+    }
+    first := &Ring{Value: 1}
+    second := &Ring{Value: 2, Next: first}
+    first.Next = second
+
+    cloned, err := cloner.Clone(cm, first)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    if cloned == first || cloned.Next == second {
+        t.Fatalf("Clone did not create new pointers")
+    }
+    if cloned.Next.Next != cloned {
+        t.Errorf("Cloned cycle does not lead back to the cloned root")
+    }
+    if cloned.Value != 1 || cloned.Next.Value != 2 {
+        t.Errorf("Cloned values are incorrect: got %d and %d", cloned.Value, cloned.Next.Value)
+    }
+}
+
+// Test for cloning maps with NaN keys
+func TestCloneMapNaNKeys(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    type Sample struct {
+        Value float64
+    }
+    original := map[float64]string{math.NaN(): "first", math.NaN(): "second", 1: "one"}
+    structKeys := map[Sample]int{{math.NaN()}: 1, {2}: 2}
+
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if len(cloned) != 3 || cloned[1] != "one" {
+        t.Fatalf("Cloned map is incorrect: got %v", cloned)
+    }
+    values := map[string]bool{}
+    for k, v := range cloned {
+        if k != 1 {
+            if !math.IsNaN(k) {
+                t.Errorf("Cloned key is not NaN: got %v", k)
+            }
+            values[v] = true
+        }
+    }
+    if !values["first"] || !values["second"] {
+        t.Errorf("Cloned NaN entries are incorrect: got %v", cloned)
+    }
+
+    clonedStructKeys, err := cloner.Clone(cm, structKeys)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    sum := 0
+    for k, v := range clonedStructKeys {
+        if v == 1 && !math.IsNaN(k.Value) {
+            t.Errorf("Cloned struct key is not NaN: got %v", k)
+        }
+        sum += v
+    }
+    if len(clonedStructKeys) != 2 || sum != 3 {
+        t.Errorf("Cloned map is incorrect: got %v", clonedStructKeys)
+    }
+}
+
+// Test for preserving the identity of pointers used as map keys
+func TestCloneMapPointerKeys(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    type User struct {
+        Name string
+    }
+    type Ref struct {
+        User *User
+        Role string
+    }
+    alice, bob := &User{"alice"}, &User{"bob"}
+    original := struct {
+        Users  []*User
+        Scores map[*User]int
+        Roles  map[Ref]bool
+    }{
+        Users:  []*User{alice, bob},
+        Scores: map[*User]int{alice: 1, bob: 2},
+        Roles:  map[Ref]bool{{alice, "admin"}: true, {bob, "admin"}: false},
+    }
+
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    clonedAlice, clonedBob := cloned.Users[0], cloned.Users[1]
+    if clonedAlice == alice || clonedBob == bob {
+        t.Fatalf("Clone did not create new users")
+    }
+
+    // Keys point to the same clones as the rest of the graph
+    if score, ok := cloned.Scores[clonedAlice]; !ok || score != 1 {
+        t.Errorf("Cloned pointer key for alice is incorrect: got %v", cloned.Scores)
+    }
+    if score, ok := cloned.Scores[clonedBob]; !ok || score != 2 {
+        t.Errorf("Cloned pointer key for bob is incorrect: got %v", cloned.Scores)
+    }
+    if _, ok := cloned.Scores[alice]; ok {
+        t.Errorf("Cloned map is keyed by the original pointer")
+    }
+    if !cloned.Roles[Ref{clonedAlice, "admin"}] || len(cloned.Roles) != 2 {
+        t.Errorf("Cloned struct keys are incorrect: got %v", cloned.Roles)
+    }
+    if _, ok := cloned.Roles[Ref{clonedBob, "admin"}]; !ok {
+        t.Errorf("Cloned struct key for bob is missing: got %v", cloned.Roles)
+    }
+}
+
+// Test for cloning interfaces nested in other interfaces and pointers
+func TestCloneNestedInterfaces(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    var inner interface{} = []interface{}{1, "two", map[string]interface{}{"three": []int{3}}}
+    original := map[string]interface{}{
+        "list": inner,
+        "ptr":  &inner,
+        "err":  error(nil),
+    }
+
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, cloned, original)
+
+    // The pointer to the interface points to a cloned interface
+    ptr := cloned["ptr"].(*interface{})
+    if ptr == original["ptr"] {
+        t.Fatalf("Clone did not create a new pointer")
+    }
+    inner.([]interface{})[2].(map[string]interface{})["three"].([]int)[0] = 0
+    if (*ptr).([]interface{})[2].(map[string]interface{})["three"].([]int)[0] != 3 {
+        t.Errorf("Modifying the original affected the cloned interface")
+    }
+}
+
+type Shape interface {
+    Area() int
+}
+
+type Square struct {
+    Side int
+}
+
+func (s Square) Area() int {
+    return s.Side * s.Side
+}
+
+// Scaled wraps a Shape and also implements Shape.
+type Scaled struct {
+    Shape
+}
+
+// Test for cloners returning a different type that implements the interface
+func TestCloneInterfaceWrapper(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(Square{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        return Scaled{value.(Square)}, nil
+    }))
+
+    original := []Shape{Square{Side: 2}}
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if _, ok := cloned[0].(Scaled); !ok || cloned[0].Area() != 4 {
+        t.Errorf("Cloned shape is incorrect: got %#v", cloned[0])
+    }
+}
+
+// Test for cloning into newly allocated values
+func TestClonePtr(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    original := TestStruct{A: 1, B: new(int)}
+    cloned, err := cm.ClonePtr(original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    ptr, ok := cloned.(*TestStruct)
+    if !ok {
+        t.Fatalf("Cloned value is not a *TestStruct: %T", cloned)
+    }
+    deepEqual(t, *ptr, original)
+
+    // The fields of the clone can be addressed and modified
+    ptr.A = 2
+    *ptr.B = 3
+    if original.A != 1 || *original.B != 0 {
+        t.Errorf("Modifying the clone affected the original")
+    }
+
+    if cloned, err := cm.ClonePtr(nil); cloned != nil || err != nil {
+        t.Errorf("got %v, %v, want nil", cloned, err)
+    }
+}
+
+// Test for cloning into newly allocated values of a given type
+func TestCloneNew(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    original := []int{1, 2}
+    cloned, err := cloner.CloneNew(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, *cloned, original)
+    (*cloned)[0] = 0
+    if original[0] != 1 {
+        t.Errorf("Modifying the clone affected the original")
+    }
+
+    // Interface types are kept
+    var value interface{} = 42
+    clonedValue, err := cloner.CloneNew(cm, value)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if *clonedValue != 42 {
+        t.Errorf("got %v, want 42", *clonedValue)
+    }
+
+    _, err = cloner.CloneNew(cm, Job{Done: make(chan bool)})
+    if err == nil {
+        t.Errorf("Clone did not report the channel")
+    }
+}
+
+// Test for cloning typed nil values stored in interfaces
+func TestCloneTypedNilInterface(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    original := []interface{}{(*TestStruct)(nil), []int(nil), nil}
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, cloned, original)
+}
+
+// Test for cloning distinct slices that start at the same address
+func TestCloneSlicesAtSameAddress(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    items := []int{1, 2, 3}
+    original := struct {
+        Floats []float64
+        Ints   []int
+        Head   []int
+        All    []int
+    }{Floats: []float64{}, Ints: []int{}, Head: items[:1], All: items}
+
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, cloned, original)
+}
+
+type Index struct {
+    Entries []string
+    byName  map[string]int
+    owner   *Index
+}
+
+func (x *Index) AfterDeepClone() {
+    x.byName = make(map[string]int, len(x.Entries))
+    for i, name := range x.Entries {
+        x.byName[name] = i
+    }
+    x.owner = x
+}
+
+type Catalog struct {
+    Primary *Index
+    Copies  []Index
+}
+
+// Test for rebuilding derived state of clones with AfterDeepClone
+func TestAfterDeepClone(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    original := Catalog{
+        Primary: &Index{Entries: []string{"a", "b"}},
+        Copies:  []Index{{Entries: []string{"c"}}},
+    }
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if got := cloned.Primary.byName; !reflect.DeepEqual(got, map[string]int{"a": 0, "b": 1}) {
+        t.Errorf("got index %v, want it rebuilt", got)
+    }
+    if cloned.Primary.owner != cloned.Primary {
+        t.Errorf("AfterDeepClone was not called on the cloned pointer")
+    }
+    if got := cloned.Copies[0].byName; !reflect.DeepEqual(got, map[string]int{"c": 0}) {
+        t.Errorf("got index %v, want it rebuilt", got)
+    }
+    if original.Primary.byName != nil {
+        t.Errorf("AfterDeepClone was called on the original")
+    }
+}
+
+type TreeNode struct {
+    Name     string
+    Parent   *TreeNode `deeper:"parent"`
+    Children []*TreeNode
+    Prev     *TreeNode
+    Next     *TreeNode
+}
+
+// newTree returns a root with two children linked in both directions.
+func newTree() *TreeNode {
+    root := &TreeNode{Name: "root"}
+    a := &TreeNode{Name: "a", Parent: root}
+    b := &TreeNode{Name: "b", Parent: root, Prev: a}
+    a.Next = b
+    root.Children = []*TreeNode{a, b}
+    return root
+}
+
+// Test for wiring fields tagged parent to the cloned parent
+func TestCloneParentTag(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    original := newTree()
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    a, b := cloned.Children[0], cloned.Children[1]
+    if cloned.Parent != nil || a.Parent != cloned || b.Parent != cloned {
+        t.Errorf("Cloned children do not point to the cloned root")
+    }
+    if a.Next != b || b.Prev != a || a == original.Children[0] {
+        t.Errorf("Cloned siblings are not linked to each other")
+    }
+
+    // Cloning a subtree detaches it from its parent
+    child, err := cloner.Clone(cm, original.Children[1])
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if child.Parent != nil || child.Prev.Parent != nil || child.Prev.Next != child {
+        t.Errorf("got subtree %+v, want it detached from the root", child)
+    }
+}
+
+type Vec3 struct {
+    X, Y, Z float64
+    Label   string
+}
+
+// Test that small values without references are cloned without allocating
+func TestCloneSmallValuesAllocationFree(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    for name, clone := range map[string]func(){
+        "int":    func() { cloner.MustClone(cm, 42) },
+        "struct": func() { cloner.MustClone(cm, Vec3{1, 2, 3, "v"}) },
+        "array":  func() { cloner.MustClone(cm, [4]int32{1, 2, 3, 4}) },
+    } {
+        if allocs := testing.AllocsPerRun(100, clone); allocs != 0 {
+            t.Errorf("Clone of %s allocated %v times, want 0", name, allocs)
+        }
+    }
+
+    // Registered cloners still apply
+    cm.RegisterCloner(reflect.TypeOf(Vec3{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        v := value.(Vec3)
+        v.Label = "cloned"
+        return v, nil
+    }))
+    if got := cloner.MustClone(cm, [1]Vec3{{Label: "v"}}); got[0].Label != "cloned" {
+        t.Errorf("got label %q, want the registered cloner to apply", got[0].Label)
+    }
+}
+
+// Benchmarks for cloning small values without references
+func BenchmarkCloneInt(b *testing.B) {
+    benchmarkClone(b, 42)
+}
+
+func BenchmarkCloneSmallStruct(b *testing.B) {
+    benchmarkClone(b, Vec3{1, 2, 3, "v"})
+}
+
+func BenchmarkCloneArray(b *testing.B) {
+    benchmarkClone(b, [8]int64{1, 2, 3, 4, 5, 6, 7, 8})
+}
+
+func benchmarkClone[T any](b *testing.B, v T) {
+    cm := cloner.NewCloneManager()
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        if _, err := cloner.Clone(cm, v); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+type Employee struct {
+    ID      int
+    Name    string
+    Salary  float64
+    Manager int
+    Tags    [4]string
+}
+
+// Benchmark for cloning a map of struct values
+func BenchmarkCloneMapOfStructs(b *testing.B) {
+    employees := make(map[int]Employee, 1000)
+    for i := 0; i < 1000; i++ {
+        employees[i] = Employee{ID: i, Name: "e", Salary: float64(i)}
+    }
+    benchmarkClone(b, employees)
+}
+
+// largeMap returns a map of 1M entries.
+func largeMap() map[int]int {
+    m := make(map[int]int, 1<<20)
+    for i := 0; i < 1<<20; i++ {
+        m[i] = i
+    }
+    return m
+}
+
+// Benchmark for cloning a map of 1M entries
+func BenchmarkCloneLargeMap(b *testing.B) {
+    m := largeMap()
+    b.ResetTimer()
+    benchmarkClone(b, m)
+}
+
+// Benchmark for cloning a large slice of structs
+func BenchmarkCloneSliceOfStructs(b *testing.B) {
+    employees := make([]Employee, 10000)
+    for i := range employees {
+        employees[i] = Employee{ID: i, Name: "e", Salary: float64(i), Tags: [4]string{"a"}}
+    }
+    benchmarkClone(b, employees)
+}
+
+// Test for deriving managers from a base policy
+func TestWithOverrides(t *testing.T) {
+    base := cloner.NewCloneManager(cloner.WithMaxDepth(8))
+    child := base.WithOverrides(cloner.WithFuncs(cloner.Share))
+    // Cloners registered with the base later apply to the child
+    base.RegisterCloner(reflect.TypeOf(&Person{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        return &Person{Name: "base"}, nil
+    }))
+
+    folder := Folder{Owner: &Person{Name: "ann"}, Attrs: map[string]interface{}{"hook": func() {}}}
+    cloned, err := cloner.Clone(child, folder)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Owner.(*Person).Name != "base" || cloned.Attrs["hook"] == nil {
+        t.Errorf("got clone %+v, want the base cloner and functions shared", cloned)
+    }
+    if cfg := child.Config(); cfg.MaxDepth != 8 || cfg.Funcs != cloner.Share {
+        t.Errorf("got config %+v, want the base depth and shared functions", cfg)
+    }
+    if _, err := base.Clone(folder); err == nil {
+        t.Errorf("the overrides of the child apply to the base")
+    }
+
+    // Cloners of the child take precedence and stay its own
+    child.RegisterCloner(reflect.TypeOf(&Person{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        return &Person{Name: "child"}, nil
+    }))
+    if cloned, _ := cloner.Clone(child, folder); cloned.Owner.(*Person).Name != "child" {
+        t.Errorf("got owner %v, want the child cloner", cloned.Owner)
+    }
+    if cloned, _ := cloner.Clone(base, &Person{}); cloned.Name != "base" {
+        t.Errorf("got %v, want the base cloner", cloned)
+    }
+}
+
+// Chunk clones its data itself and reports it.
+type Chunk struct {
+    Data  []byte
+    Cache map[string]int
+}
+
+func (c *Chunk) Clone(*cloner.CloneManager) (interface{}, error) {
+    return nil, errors.New("Clone called instead of CloneWithReport")
+}
+
+func (c *Chunk) CloneWithReport(*cloner.CloneManager) (interface{}, cloner.Report, error) {
+    cloned := &Chunk{Data: append([]byte(nil), c.Data...)}
+    // The value, its data and the cache left out
+    return cloned, cloner.Report{Nodes: 2, Bytes: int64(len(c.Data)), Skipped: 1}, nil
+}
+
+// Test for accounting for the clones made by a CloneableV2
+func TestCloneableV2(t *testing.T) {
+    chunks := []*Chunk{{Data: make([]byte, 100)}, {Data: make([]byte, 100)}}
+    var report cloner.Report
+    cm := cloner.NewCloneManager(cloner.WithOnClone(func(r cloner.Report) { report = r }))
+    cloned, err := cloner.Clone(cm, chunks)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if len(cloned[1].Data) != 100 || &cloned[1].Data[0] == &chunks[1].Data[0] {
+        t.Errorf("got clone %+v, want a copy of the data", cloned[1])
+    }
+    // The slice and its 2 elements, and 2 values reported by each element
+    if report.Nodes != 7 || report.Skipped != 2 {
+        t.Errorf("got report %+v, want 7 nodes and 2 skipped values", report)
+    }
+
+    // Reports count towards the limits of the clone
+    _, err = cloner.Clone(cm, chunks, cloner.WithMaxNodes(5))
+    if !errors.Is(err, cloner.ErrBudgetExceeded) {
+        t.Errorf("got error %v, want ErrBudgetExceeded for the nodes", err)
+    }
+    _, err = cloner.Clone(cm, chunks, cloner.WithMaxBytes(150))
+    if !errors.Is(err, cloner.ErrBudgetExceeded) {
+        t.Errorf("got error %v, want ErrBudgetExceeded for the bytes", err)
+    }
+}
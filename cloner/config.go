@@ -0,0 +1,407 @@
+package cloner
+
+import (
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "sort"
+    "time"
+)
+
+// Config is the configuration of a CloneManager as returned by its Config
+// method, for debug endpoints and tests asserting the configuration. It can
+// be marshaled to JSON, or YAML through its JSON tags, and stored so that a
+// validated clone policy can be shared by services, which create their
+// managers with NewCloneManagerFromConfig. Options taking functions or
+// values, such as WithReplace or WithAllocator, are not part of it.
+//
+// The zero Config reports unexported fields and timers as errors, unlike
+// NewCloneManager; decode configurations into DefaultConfig so that the
+// fields they leave out keep their defaults:
+//
+//	cfg := cloner.DefaultConfig()
+//	err := json.Unmarshal(data, &cfg)
+type Config struct {
+    CollectErrors      bool          `json:"collectErrors,omitempty"`
+    DeterministicOrder bool          `json:"deterministicOrder,omitempty"`
+    MaxDepth           int           `json:"maxDepth,omitempty"`
+    MaxNodes           int           `json:"maxNodes,omitempty"`
+    MaxBytes           int64         `json:"maxBytes,omitempty"`
+    OverBudget         Policy        `json:"overBudget"`
+    Timeout            time.Duration `json:"timeout,omitempty"`
+    Funcs              Policy        `json:"funcs"`
+    Chans              Policy        `json:"chans"`
+    Timers             Policy        `json:"timers"`
+    UnexportedFields   Policy        `json:"unexportedFields"`
+    Transfer           bool          `json:"transfer,omitempty"`
+    Valuers            bool          `json:"valuers,omitempty"`
+    Marshalers         bool          `json:"marshalers,omitempty"`
+    Identity           Identity      `json:"identity"`
+    ExcludePaths       []string      `json:"excludePaths,omitempty"`
+    SharedPaths        []string      `json:"sharedPaths,omitempty"`
+    // TagNames are the tags read by the manager, see WithTagName.
+    TagNames []string `json:"tagNames,omitempty"`
+    // FieldNames is the tag naming fields in paths, see WithFieldNames.
+    FieldNames string `json:"fieldNames,omitempty"`
+    // CloneAmbient reports whether the types shared by ShareType are
+    // cloned, see WithoutAmbientSharing.
+    CloneAmbient bool `json:"cloneAmbient,omitempty"`
+    // SharedBytes are the byte slice types copied as they are, see
+    // WithSharedBytes.
+    SharedBytes []string `json:"sharedBytes,omitempty"`
+    // TypeReplacements are the types replaced with WithTypeReplacement.
+    TypeReplacements []string `json:"typeReplacements,omitempty"`
+    // Cloners are the types cloned by a Cloner registered with the manager
+    // or in the default registry.
+    Cloners []string `json:"cloners,omitempty"`
+}
+
+// Config returns the configuration of cm. Lists of types are sorted by name.
+func (cm *CloneManager) Config() Config {
+    o := &cm.options
+    cfg := Config{
+        CollectErrors:      o.collectErrors,
+        DeterministicOrder: o.deterministicOrder,
+        MaxDepth:           o.maxDepth,
+        MaxNodes:           o.maxNodes,
+        MaxBytes:           o.maxBytes,
+        OverBudget:         o.overBudget,
+        Timeout:            o.timeout,
+        Funcs:              o.funcs,
+        Chans:              o.chans,
+        Timers:             o.timers,
+        UnexportedFields:   o.unexported,
+        Transfer:           o.transfer,
+        Valuers:            o.valuers,
+        Marshalers:         o.marshalers,
+        Identity:           o.identity,
+        ExcludePaths:       formatPatterns(o.excludePaths),
+        SharedPaths:        formatPatterns(o.sharedPaths),
+        TagNames:           o.tagNames,
+        FieldNames:         o.fieldNames,
+        CloneAmbient:       o.cloneAmbient,
+    }
+    for t := range o.sharedBytes {
+        cfg.SharedBytes = append(cfg.SharedBytes, t.String())
+    }
+    sort.Strings(cfg.SharedBytes)
+    for t := range o.replacements {
+        cfg.TypeReplacements = append(cfg.TypeReplacements, t.String())
+    }
+    sort.Strings(cfg.TypeReplacements)
+    cfg.Cloners = typeNames(cm.Cloners())
+    return cfg
+}
+
+// DefaultConfig returns the configuration of a manager created by
+// NewCloneManager without options.
+func DefaultConfig() Config {
+    return NewCloneManager().Config()
+}
+
+// NewCloneManagerFromConfig creates a CloneManager configured by cfg, with
+// opts applied on top of it. Cloners cannot be stored in a Config: the
+// types listed in cfg.Cloners must be registered in the default registry,
+// see Register, and cfg.TypeReplacements must be empty. Types cannot be
+// resolved by name either, so cfg.SharedBytes may only list byte slices
+// and json.RawMessage. Configurations that do not satisfy this or hold
+// malformed path patterns are reported as errors.
+func NewCloneManagerFromConfig(cfg Config, opts ...Option) (*CloneManager, error) {
+    cfgOpts, err := cfg.Options()
+    if err != nil {
+        return nil, err
+    }
+    return NewCloneManager(append(cfgOpts, opts...)...), nil
+}
+
+// Options returns the options configuring a manager like cfg, as described
+// by NewCloneManagerFromConfig.
+func (cfg Config) Options() ([]Option, error) {
+    if len(cfg.TypeReplacements) > 0 {
+        return nil, fmt.Errorf("cloner: type replacements of %v cannot be configured", cfg.TypeReplacements)
+    }
+    if missing := unregistered(cfg.Cloners); len(missing) > 0 {
+        return nil, fmt.Errorf("cloner: no cloner registered for %v", missing)
+    }
+    var sharedBytes map[reflect.Type]bool
+    for _, name := range cfg.SharedBytes {
+        t, ok := byteTypes[name]
+        if !ok {
+            return nil, fmt.Errorf("cloner: shared bytes of %s cannot be configured", name)
+        }
+        if sharedBytes == nil {
+            sharedBytes = make(map[reflect.Type]bool)
+        }
+        sharedBytes[t] = true
+    }
+    exclude, err := compilePatterns(cfg.ExcludePaths)
+    if err != nil {
+        return nil, err
+    }
+    shared, err := compilePatterns(cfg.SharedPaths)
+    if err != nil {
+        return nil, err
+    }
+    for _, p := range []Policy{cfg.OverBudget, cfg.Funcs, cfg.Chans, cfg.Timers, cfg.UnexportedFields} {
+        if _, err := p.MarshalText(); err != nil {
+            return nil, err
+        }
+    }
+    if _, err := cfg.Identity.MarshalText(); err != nil {
+        return nil, err
+    }
+
+    return []Option{func(o *options) {
+        o.collectErrors = cfg.CollectErrors
+        o.deterministicOrder = cfg.DeterministicOrder
+        o.maxDepth = cfg.MaxDepth
+        o.maxNodes = cfg.MaxNodes
+        o.maxBytes = cfg.MaxBytes
+        o.overBudget = cfg.OverBudget
+        o.timeout = cfg.Timeout
+        o.funcs = cfg.Funcs
+        o.chans = cfg.Chans
+        o.timers = cfg.Timers
+        o.unexported = cfg.UnexportedFields
+        o.transfer = cfg.Transfer
+        o.valuers = cfg.Valuers
+        o.marshalers = cfg.Marshalers
+        o.identity = cfg.Identity
+        o.excludePaths = exclude
+        o.sharedPaths = shared
+        o.tagNames = cfg.TagNames
+        o.fieldNames = cfg.FieldNames
+        o.cloneAmbient = cfg.CloneAmbient
+        o.sharedBytes = sharedBytes
+    }}, nil
+}
+
+// byteTypes are the byte slice types that SharedBytes of a Config can list,
+// by name.
+var byteTypes = func() map[string]reflect.Type {
+    types := make(map[string]reflect.Type)
+    for _, t := range []reflect.Type{reflect.TypeOf([]byte(nil)), reflect.TypeOf(json.RawMessage(nil))} {
+        types[t.String()] = t
+    }
+    return types
+}()
+
+// unregistered returns the names of types that are not the name of a type
+// of the default registry.
+func unregistered(names []string) []string {
+    registered := make(map[string]bool)
+    registryMutex.RLock()
+    for t := range registry {
+        registered[t.String()] = true
+    }
+    registryMutex.RUnlock()
+
+    var missing []string
+    for _, name := range names {
+        if !registered[name] {
+            missing = append(missing, name)
+        }
+    }
+    return missing
+}
+
+// Cloners returns the types cloned by a Cloner registered with cm, the
+// managers it derives from or in the default registry, sorted by name.
+func (cm *CloneManager) Cloners() []reflect.Type {
+    seen := make(map[reflect.Type]bool)
+    for m := cm; m != nil; m = m.parent {
+        for t := range m.cloners {
+            seen[t] = true
+        }
+    }
+    registryMutex.RLock()
+    for t := range registry {
+        seen[t] = true
+    }
+    registryMutex.RUnlock()
+
+    types := make([]reflect.Type, 0, len(seen))
+    for t := range seen {
+        types = append(types, t)
+    }
+    sort.Slice(types, func(i, j int) bool {
+        return types[i].String() < types[j].String()
+    })
+    return types
+}
+
+func typeNames(types []reflect.Type) []string {
+    var names []string
+    for _, t := range types {
+        names = append(names, t.String())
+    }
+    return names
+}
+
+func formatPatterns(patterns []pathPattern) []string {
+    var formatted []string
+    for _, p := range patterns {
+        formatted = append(formatted, p.String())
+    }
+    return formatted
+}
+
+// policyNames are the names of the policies, as written by String.
+var policyNames = [...]string{Error: "error", Share: "share", Zero: "zero"}
+
+// String returns the name of p: error, share or zero.
+func (p Policy) String() string {
+    if p < 0 || int(p) >= len(policyNames) {
+        return fmt.Sprintf("Policy(%d)", int(p))
+    }
+    return policyNames[p]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Policy) MarshalText() ([]byte, error) {
+    if p < 0 || int(p) >= len(policyNames) {
+        return nil, fmt.Errorf("cloner: invalid policy %d", int(p))
+    }
+    return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *Policy) UnmarshalText(text []byte) error {
+    for i, name := range policyNames {
+        if string(text) == name {
+            *p = Policy(i)
+            return nil
+        }
+    }
+    return fmt.Errorf("cloner: invalid policy %q", text)
+}
+
+// identityNames are the names of the identities, as written by String.
+var identityNames = [...]string{Exact: "exact", ValueDedup: "valueDedup"}
+
+// String returns the name of i: exact or valueDedup.
+func (i Identity) String() string {
+    if i < 0 || int(i) >= len(identityNames) {
+        return fmt.Sprintf("Identity(%d)", int(i))
+    }
+    return identityNames[i]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (i Identity) MarshalText() ([]byte, error) {
+    if i < 0 || int(i) >= len(identityNames) {
+        return nil, fmt.Errorf("cloner: invalid identity %d", int(i))
+    }
+    return []byte(i.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *Identity) UnmarshalText(text []byte) error {
+    for j, name := range identityNames {
+        if string(text) == name {
+            *i = Identity(j)
+            return nil
+        }
+    }
+    return fmt.Errorf("cloner: invalid identity %q", text)
+}
+
+// Handling says how a manager clones the values of a kind or a type, see
+// SupportedKinds and Policies.
+type Handling int
+
+const (
+    // HandleClone deep clones the values; copying basic values, such as
+    // numbers and strings, clones them.
+    HandleClone Handling = iota
+    // HandleShare copies the values as they are, so that the clone shares
+    // them with the source.
+    HandleShare
+    // HandleZero leaves the values at their zero value in the clone.
+    HandleZero
+    // HandleError reports the values as errors.
+    HandleError
+)
+
+// handlingNames are the names of the handlings, as written by String.
+var handlingNames = [...]string{HandleClone: "clone", HandleShare: "share", HandleZero: "zero", HandleError: "error"}
+
+// String returns the name of h: clone, share, zero or error.
+func (h Handling) String() string {
+    if h < 0 || int(h) >= len(handlingNames) {
+        return fmt.Sprintf("Handling(%d)", int(h))
+    }
+    return handlingNames[h]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (h Handling) MarshalText() ([]byte, error) {
+    if h < 0 || int(h) >= len(handlingNames) {
+        return nil, fmt.Errorf("cloner: invalid handling %d", int(h))
+    }
+    return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *Handling) UnmarshalText(text []byte) error {
+    for i, name := range handlingNames {
+        if string(text) == name {
+            *h = Handling(i)
+            return nil
+        }
+    }
+    return fmt.Errorf("cloner: invalid handling %q", text)
+}
+
+// handlingOf returns the handling of the values subject to policy p.
+func handlingOf(p Policy) Handling {
+    switch p {
+    case Share:
+        return HandleShare
+    case Zero:
+        return HandleZero
+    }
+    return HandleError
+}
+
+// SupportedKinds returns how cm clones the values of every reflect.Kind
+// but Invalid, when no Cloner, type replacement or other option applies to
+// them, so that tests can assert the configuration of a manager and
+// documentation can be generated from it. Channels and functions follow
+// WithChans and WithFuncs; unsafe pointers are shared.
+func (cm *CloneManager) SupportedKinds() map[reflect.Kind]Handling {
+    kinds := make(map[reflect.Kind]Handling, reflect.UnsafePointer)
+    for k := reflect.Bool; k <= reflect.UnsafePointer; k++ {
+        kinds[k] = HandleClone
+    }
+    kinds[reflect.Chan] = handlingOf(cm.options.chans)
+    kinds[reflect.Func] = handlingOf(cm.options.funcs)
+    kinds[reflect.UnsafePointer] = HandleShare
+    return kinds
+}
+
+// Policies returns how cm clones the values of the types it handles
+// specially: those cloned by a Cloner registered with cm or in the default
+// registry, cloned by it, those shared by ShareType, unless
+// WithoutAmbientSharing applies, timers, following WithTimers, and types
+// copied as they are, such as time.Time and the sql.Null types. Interface
+// types shared by ShareType stand for the types implementing them.
+func (cm *CloneManager) Policies() map[reflect.Type]Handling {
+    policies := make(map[reflect.Type]Handling)
+    for t := range valueTypes {
+        policies[t] = HandleShare
+    }
+    policies[timerType] = handlingOf(cm.options.timers)
+    policies[tickerType] = handlingOf(cm.options.timers)
+    if !cm.options.cloneAmbient {
+        ambientMutex.RLock()
+        for _, t := range ambientTypes {
+            policies[t] = HandleShare
+        }
+        ambientMutex.RUnlock()
+    }
+    for _, t := range cm.Cloners() {
+        policies[t] = HandleClone
+    }
+    return policies
+}
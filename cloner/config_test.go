@@ -0,0 +1,149 @@
+package cloner_test
+
+import (
+    "context"
+    "encoding/json"
+    "log/slog"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for exporting the configuration of a manager
+func TestConfig(t *testing.T) {
+    cm := cloner.NewCloneManager(
+        cloner.CollectErrors(),
+        cloner.WithMaxBytes(1<<20),
+        cloner.WithOverBudget(cloner.Share),
+        cloner.WithTimeout(time.Second),
+        cloner.WithChans(cloner.Zero),
+        cloner.WithIdentity(cloner.ValueDedup),
+        cloner.WithExcludePaths(".Items[*].Secret", `Labels["env"]`),
+        cloner.WithSharedPaths("Cache"),
+        cloner.WithTypeReplacement(reflect.TypeOf(&Person{}), reflect.TypeOf(&Person{}), nil),
+    )
+    cm.RegisterCloner(reflect.TypeOf(Vec3{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        return v, nil
+    }))
+
+    cfg := cm.Config()
+    if !cfg.CollectErrors || cfg.MaxBytes != 1<<20 || cfg.OverBudget != cloner.Share || cfg.Timeout != time.Second ||
+        cfg.Chans != cloner.Zero || cfg.Funcs != cloner.Error || cfg.UnexportedFields != cloner.Zero || cfg.Identity != cloner.ValueDedup {
+        t.Errorf("got config %+v", cfg)
+    }
+    deepEqual(t, cfg.ExcludePaths, []string{"Items[*].Secret", `Labels["env"]`})
+    deepEqual(t, cfg.SharedPaths, []string{"Cache"})
+    deepEqual(t, cfg.TypeReplacements, []string{"*cloner_test.Person"})
+
+    // Cloners of the default registry are listed too
+    found := false
+    for _, name := range cfg.Cloners {
+        found = found || name == "cloner_test.Vec3"
+    }
+    if !found || len(cfg.Cloners) != len(cm.Cloners()) {
+        t.Errorf("got cloners %v, want Vec3 and those of the registry", cfg.Cloners)
+    }
+
+    b, err := json.Marshal(cloner.Config{OverBudget: cloner.Zero, Identity: cloner.Exact})
+    if want := `{"overBudget":"zero","funcs":"error","chans":"error","timers":"error","unexportedFields":"error","identity":"exact"}`; err != nil || string(b) != want {
+        t.Errorf("got JSON %s and error %v, want %s", b, err, want)
+    }
+}
+
+// Test for the handling of kinds and types by a manager
+func TestSupportedKindsAndPolicies(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithFuncs(cloner.Share))
+    kinds := cm.SupportedKinds()
+    if len(kinds) != int(reflect.UnsafePointer) {
+        t.Errorf("got %d kinds, want every kind but Invalid", len(kinds))
+    }
+    for k, want := range map[reflect.Kind]cloner.Handling{
+        reflect.Int:           cloner.HandleClone,
+        reflect.Map:           cloner.HandleClone,
+        reflect.Func:          cloner.HandleShare,
+        reflect.Chan:          cloner.HandleError,
+        reflect.UnsafePointer: cloner.HandleShare,
+    } {
+        if kinds[k] != want {
+            t.Errorf("got %v for %v, want %v", kinds[k], k, want)
+        }
+    }
+
+    cm.RegisterCloner(reflect.TypeOf(&slog.Logger{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        return v, nil
+    }))
+    policies := cm.Policies()
+    if policies[reflect.TypeOf(&slog.Logger{})] != cloner.HandleClone || policies[reflect.TypeOf((*context.Context)(nil)).Elem()] != cloner.HandleShare {
+        t.Errorf("got policies %v, want the logger cloned and contexts shared", policies)
+    }
+    policies = cloner.NewCloneManager(cloner.WithoutAmbientSharing()).Policies()
+    if _, ok := policies[reflect.TypeOf((*context.Context)(nil)).Elem()]; ok {
+        t.Errorf("got policies %v, want contexts cloned", policies)
+    }
+
+    b, err := json.Marshal(map[string]cloner.Handling{"chan": kinds[reflect.Chan]})
+    if err != nil || string(b) != `{"chan":"error"}` {
+        t.Errorf("got JSON %s and error %v", b, err)
+    }
+}
+
+// Test for creating managers from stored configurations
+func TestNewCloneManagerFromConfig(t *testing.T) {
+    cm := cloner.NewCloneManager(
+        cloner.WithMaxDepth(4),
+        cloner.WithFuncs(cloner.Share),
+        cloner.WithIdentity(cloner.ValueDedup),
+        cloner.WithExcludePaths("Items[*].Secret"),
+        cloner.WithoutAmbientSharing(),
+        cloner.WithSharedBytes(reflect.TypeOf(json.RawMessage(nil)), reflect.TypeOf([]byte(nil))),
+    )
+    if cfg := cm.Config(); !cfg.CloneAmbient || len(cfg.SharedBytes) != 2 {
+        t.Errorf("got config %+v, want ambient types cloned and shared bytes", cfg)
+    }
+    data, err := json.Marshal(cm.Config())
+    if err != nil {
+        t.Fatalf("Marshal failed: %v", err)
+    }
+    var cfg cloner.Config
+    if err := json.Unmarshal(data, &cfg); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+    restored, err := cloner.NewCloneManagerFromConfig(cfg)
+    if err != nil {
+        t.Fatalf("NewCloneManagerFromConfig failed: %v", err)
+    }
+    deepEqual(t, restored.Config(), cm.Config())
+
+    // Left out fields keep their defaults
+    cfg = cloner.DefaultConfig()
+    if err := json.Unmarshal([]byte(`{"chans": "zero", "sharedPaths": ["Cache"]}`), &cfg); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+    restored, err = cloner.NewCloneManagerFromConfig(cfg, cloner.WithMaxNodes(10))
+    if err != nil {
+        t.Fatalf("NewCloneManagerFromConfig failed: %v", err)
+    }
+    got := restored.Config()
+    if got.Chans != cloner.Zero || got.UnexportedFields != cloner.Zero || got.MaxNodes != 10 || len(got.SharedPaths) != 1 {
+        t.Errorf("got config %+v", got)
+    }
+
+    for _, bad := range []string{
+        `{"funcs": "copy"}`,
+        `{"excludePaths": ["Items[]"]}`,
+        `{"cloners": ["main.Unregistered"]}`,
+        `{"typeReplacements": ["*main.Client"]}`,
+        `{"sharedBytes": ["main.Blob"]}`,
+    } {
+        cfg := cloner.DefaultConfig()
+        err := json.Unmarshal([]byte(bad), &cfg)
+        if err == nil {
+            _, err = cloner.NewCloneManagerFromConfig(cfg)
+        }
+        if err == nil {
+            t.Errorf("got no error for %s", bad)
+        }
+    }
+}
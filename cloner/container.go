@@ -0,0 +1,122 @@
+package cloner
+
+import (
+    "reflect"
+    "strings"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// ContainerAdapter gives managers access to the entries of the containers
+// of a generic type, such as ordered maps, sets or B-trees, which keep them
+// in unexported fields, so that one adapter registered with
+// RegisterContainer clones every instantiation of the type. Containers,
+// keys and values are passed as reflect.Values, since the type arguments are
+// only known at run time; keys are invalid for containers without keys,
+// such as sets and lists.
+type ContainerAdapter interface {
+    // Len returns the number of entries of c.
+    Len(c reflect.Value) int
+    // Iterate calls yield with the entries of c, in order, until it returns
+    // false.
+    Iterate(c reflect.Value, yield func(key, value reflect.Value) bool)
+    // Empty returns a new empty container of the type of c, configured like
+    // c, e.g. with the same ordering function.
+    Empty(c reflect.Value) reflect.Value
+    // Insert adds an entry to c, a container returned by Empty.
+    Insert(c, key, value reflect.Value)
+}
+
+// RegisterContainer registers adapter in the default registry for the
+// containers of the generic type named name, written as its package path
+// and type name without type arguments, e.g.
+// "github.com/google/btree.BTreeG". The containers are the values of the
+// type or pointers to them, whichever the graph holds. Their clone is a
+// container returned by Empty, holding clones of the entries inserted in
+// the order Iterate yields them. A Cloner registered for an instantiation
+// of the type takes precedence.
+//
+// Registering a second adapter for the same type replaces the first.
+func RegisterContainer(name string, adapter ContainerAdapter) {
+    registryMutex.Lock()
+    defer registryMutex.Unlock()
+    containers[name] = adapter
+}
+
+// genericName returns the name t is registered under with RegisterContainer
+// if it is an instantiated generic type, or a pointer to one; otherwise "".
+func genericName(t reflect.Type) string {
+    if t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+    name, _, generic := strings.Cut(t.Name(), "[")
+    if !generic {
+        return ""
+    }
+    return t.PkgPath() + "." + name
+}
+
+// containerOf returns the adapter of the containers of type t, if any.
+func containerOf(t reflect.Type) (ContainerAdapter, bool) {
+    name := planOf(t).generic
+    if name == "" {
+        return nil, false
+    }
+    registryMutex.RLock()
+    defer registryMutex.RUnlock()
+    adapter, ok := containers[name]
+    return adapter, ok
+}
+
+// cloneContainer clones src with its ContainerAdapter, reporting false if it
+// is not a container.
+func (cm *CloneManager) cloneContainer(src reflect.Value) (interface{}, bool, error) {
+    adapter, ok := containerOf(src.Type())
+    if !ok {
+        return nil, false, nil
+    }
+    if src.Kind() == reflect.Ptr && src.IsNil() {
+        return nil, true, nil
+    }
+
+    type entry struct{ key, value reflect.Value }
+    entries := make([]entry, 0, adapter.Len(src))
+    adapter.Iterate(src, func(key, value reflect.Value) bool {
+        entries = append(entries, entry{key, value})
+        return true
+    })
+    clone := adapter.Empty(src)
+    if src.Kind() == reflect.Ptr {
+        cm.record(memref.Of(src), clone.Interface())
+    }
+    for i, e := range entries {
+        var key reflect.Value
+        var err error
+        if e.key.IsValid() {
+            cm.pushKey(e.key)
+            key, err = cm.cloneItem(e.key)
+        } else {
+            cm.pushIndex(i)
+        }
+        var value reflect.Value
+        if err == nil {
+            value, err = cm.cloneItem(e.value)
+        }
+        cm.pop()
+        if err != nil {
+            return nil, true, err
+        }
+        adapter.Insert(clone, key, value)
+    }
+    UpdateStats(planOf(src.Type()).generic)
+    return clone.Interface(), true, nil
+}
+
+// cloneItem clones the key or value src of a container entry.
+func (cm *CloneManager) cloneItem(src reflect.Value) (reflect.Value, error) {
+    cloned, err := cm.deepClone(src)
+    if err != nil {
+        return reflect.Value{}, err
+    }
+    return cm.valueOf(cloned, src.Type(), src)
+}
@@ -0,0 +1,104 @@
+package cloner_test
+
+import (
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// OrderedMap is a generic container keeping its entries unexported. Its zero
+// value is an empty map.
+type OrderedMap[K comparable, V any] struct {
+    keys   []K
+    values map[K]V
+}
+
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+    return &OrderedMap[K, V]{values: make(map[K]V)}
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+    return len(m.keys)
+}
+
+func (m *OrderedMap[K, V]) Set(k K, v V) {
+    if m.values == nil {
+        m.values = make(map[K]V)
+    }
+    if _, ok := m.values[k]; !ok {
+        m.keys = append(m.keys, k)
+    }
+    m.values[k] = v
+}
+
+func (m *OrderedMap[K, V]) Get(k K) V {
+    return m.values[k]
+}
+
+func (m *OrderedMap[K, V]) Range(fn func(k K, v V) bool) {
+    for _, k := range m.keys {
+        if !fn(k, m.values[k]) {
+            return
+        }
+    }
+}
+
+// orderedMapAdapter gives access to every instantiation of OrderedMap
+// through its methods.
+type orderedMapAdapter struct{}
+
+func (orderedMapAdapter) Len(c reflect.Value) int {
+    return c.MethodByName("Len").Call(nil)[0].Interface().(int)
+}
+
+func (orderedMapAdapter) Iterate(c reflect.Value, yield func(key, value reflect.Value) bool) {
+    fn := reflect.MakeFunc(c.MethodByName("Range").Type().In(0), func(args []reflect.Value) []reflect.Value {
+        return []reflect.Value{reflect.ValueOf(yield(args[0], args[1]))}
+    })
+    c.MethodByName("Range").Call([]reflect.Value{fn})
+}
+
+func (orderedMapAdapter) Empty(c reflect.Value) reflect.Value {
+    return reflect.New(c.Type().Elem())
+}
+
+func (orderedMapAdapter) Insert(c, key, value reflect.Value) {
+    c.MethodByName("Set").Call([]reflect.Value{key, value})
+}
+
+type Directory struct {
+    People *OrderedMap[string, *Person]
+    Ages   *OrderedMap[int, int]
+    Empty  *OrderedMap[string, string]
+}
+
+// Test for cloning every instantiation of a generic container with an adapter
+func TestRegisterContainer(t *testing.T) {
+    cloner.RegisterContainer("github.com/jayaprabhakar/go-deeper/cloner_test.OrderedMap", orderedMapAdapter{})
+
+    ann := &Person{Name: "ann"}
+    original := Directory{People: NewOrderedMap[string, *Person](), Ages: NewOrderedMap[int, int]()}
+    original.People.Set("b", &Person{Name: "bob"})
+    original.People.Set("a", ann)
+    original.People.Set("z", ann)
+    original.Ages.Set(3, 30)
+
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.People == original.People || cloned.People.Len() != 3 || cloned.Ages.Get(3) != 30 || cloned.Empty != nil {
+        t.Fatalf("got clone %+v, want a clone of %+v", cloned, original)
+    }
+    var keys []string
+    cloned.People.Range(func(k string, p *Person) bool {
+        keys = append(keys, k)
+        return true
+    })
+    deepEqual(t, keys, []string{"b", "a", "z"})
+    a, z := cloned.People.Get("a"), cloned.People.Get("z")
+    if a == ann || a != z || a.Name != "ann" {
+        t.Errorf("got people %v and %v, want a single clone of ann", a, z)
+    }
+}
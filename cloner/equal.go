@@ -0,0 +1,439 @@
+package cloner
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "reflect"
+)
+
+// EqualOption configures Equal.
+type EqualOption func(*equalOptions)
+
+type equalOptions struct {
+    floats      *Floats
+    floatsOf    map[reflect.Type]Floats
+    floatsAt    []floatsAt
+    ignorePaths []pathPattern
+    ignoreTypes map[reflect.Type]bool
+    tagNames    []string
+    fieldNames  string
+}
+
+// floatsAt is a float comparison configured for a path pattern.
+type floatsAt struct {
+    pattern pathPattern
+    floats  Floats
+}
+
+// Floats configures the comparison of floats and complex numbers, whose
+// real and imaginary parts are compared separately. The zero value compares
+// them with ==.
+type Floats struct {
+    // Abs is the largest absolute difference of equal floats.
+    Abs float64
+    // Rel is the largest difference of equal floats relative to the larger
+    // magnitude of the two.
+    Rel float64
+    // NaNEqual makes NaN equal to NaN.
+    NaNEqual bool
+    // SignedZeros makes -0 differ from +0; == unifies them.
+    SignedZeros bool
+}
+
+func (f Floats) equal(a, b float64) bool {
+    switch {
+    case math.IsNaN(a) || math.IsNaN(b):
+        return f.NaNEqual && math.IsNaN(a) && math.IsNaN(b)
+    case a == b:
+        return !f.SignedZeros || math.Signbit(a) == math.Signbit(b)
+    case math.IsInf(a, 0) || math.IsInf(b, 0):
+        return false
+    }
+    diff := math.Abs(a - b)
+    return diff <= f.Abs || diff <= f.Rel*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// EqualFloats compares every float with f, unless EqualFloatsOf or
+// EqualFloatsAt configure another comparison for it.
+func EqualFloats(f Floats) EqualOption {
+    return func(o *equalOptions) {
+        o.floats = &f
+    }
+}
+
+// EqualFloatsOf compares the floats of type t, such as a named type
+// type Celsius float64, with f, unless EqualFloatsAt configures another
+// comparison for them.
+func EqualFloatsOf(t reflect.Type, f Floats) EqualOption {
+    return func(o *equalOptions) {
+        if o.floatsOf == nil {
+            o.floatsOf = make(map[reflect.Type]Floats)
+        }
+        o.floatsOf[t] = f
+    }
+}
+
+// EqualFloatsAt compares the floats whose path matches pattern with f. The
+// patterns are those of WithExcludePaths, e.g. .Weights[*]; the last option
+// matching a path applies. EqualFloatsAt panics if pattern is malformed.
+func EqualFloatsAt(pattern string, f Floats) EqualOption {
+    p := mustCompilePatterns([]string{pattern})[0]
+    return func(o *equalOptions) {
+        o.floatsAt = append(o.floatsAt, floatsAt{p, f})
+    }
+}
+
+// IgnorePaths makes Equal and Diff skip the values whose path matches one of
+// patterns, the patterns of WithExcludePaths. Struct fields tagged
+// `deeper:"ignoreeq"` are always skipped. IgnorePaths panics if a pattern is
+// malformed.
+func IgnorePaths(patterns ...string) EqualOption {
+    compiled := mustCompilePatterns(patterns)
+    return func(o *equalOptions) {
+        o.ignorePaths = append(o.ignorePaths[:len(o.ignorePaths):len(o.ignorePaths)], compiled...)
+    }
+}
+
+// IgnoreTypes makes Equal and Diff skip the values of types, for example
+// reflect.TypeOf(time.Time{}) to compare graphs regardless of timestamps.
+func IgnoreTypes(types ...reflect.Type) EqualOption {
+    return func(o *equalOptions) {
+        if o.ignoreTypes == nil {
+            o.ignoreTypes = make(map[reflect.Type]bool)
+        }
+        for _, t := range types {
+            o.ignoreTypes[t] = true
+        }
+    }
+}
+
+// Equal reports whether a and b are deeply equal, like reflect.DeepEqual,
+// with the comparisons configured by opts. Types with a comparer registered
+// with RegisterComparer are compared with it, and types with an Equal method
+// taking their own type, such as time.Time, with that method.
+func Equal(a, b interface{}, opts ...EqualOption) bool {
+    return newEqualer(opts).equal(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// Difference is a value that differs between the graphs compared by Diff.
+type Difference struct {
+    Path Path
+    // Steps is the structured form of Path, holding the map keys
+    // themselves.
+    Steps []Step
+    // A and B are the values in each graph, or nil if the value is missing
+    // from one of them or cannot be read, as for unexported fields.
+    A, B interface{}
+}
+
+func (d Difference) String() string {
+    path := string(d.Path)
+    if path == "" {
+        path = "(root)"
+    }
+    return fmt.Sprintf("%s: %#v != %#v", path, d.A, d.B)
+}
+
+// Diff returns the differences between a and b, compared like Equal, in a
+// deterministic order. Differences are reported at the deepest value that
+// differs: struct fields, slice and array elements, and map entries, with
+// entries missing from one map reported with a nil value. Slices of
+// different lengths are reported as a whole.
+func Diff(a, b interface{}, opts ...EqualOption) []Difference {
+    e := newEqualer(opts)
+    e.diffs = []Difference{}
+    e.equal(reflect.ValueOf(a), reflect.ValueOf(b))
+    return e.diffs
+}
+
+func newEqualer(opts []EqualOption) *equaler {
+    e := &equaler{visited: make(map[visit]bool)}
+    for _, opt := range opts {
+        opt(&e.options)
+    }
+    return e
+}
+
+// visit is a pair of references being compared, assumed equal while their
+// contents are compared so that cycles terminate.
+type visit struct {
+    a, b uintptr
+    typ  reflect.Type
+}
+
+type equaler struct {
+    options equalOptions
+    path    []step
+    visited map[visit]bool
+    diffs   []Difference // differences found by Diff; nil for Equal
+    // edits are the steps to the differences and the values of b there,
+    // kept with the differences if keepEdits is true, see ApplyBack
+    edits     []edit
+    keepEdits bool
+    // exact makes the equaler compare every field, ignoring ignoreeq tags,
+    // comparers and Equal methods, see ApplyBack
+    exact bool
+}
+
+// edit is a value of the second graph compared by Diff that differs from
+// the first, at the end of steps; it is invalid for map entries missing
+// from the second graph.
+type edit struct {
+    steps []step
+    value reflect.Value
+}
+
+// differ records that a and b differ at the current path and returns false.
+func (e *equaler) differ(a, b reflect.Value) bool {
+    if e.diffs != nil {
+        e.diffs = append(e.diffs, Difference{Path: Path(formatPath(e.path)), Steps: exportedSteps(e.path), A: interfaceOf(a), B: interfaceOf(b)})
+        if e.keepEdits {
+            e.edits = append(e.edits, edit{steps: append([]step(nil), e.path...), value: b})
+        }
+    }
+    return false
+}
+
+func interfaceOf(v reflect.Value) interface{} {
+    if !v.IsValid() || !v.CanInterface() {
+        return nil
+    }
+    return v.Interface()
+}
+
+// fieldStep returns the step to the field described by fp, labeled with
+// its name in the tag of EqualFieldNames, if any.
+func (e *equaler) fieldStep(fp fieldPlan) step {
+    s := step{field: fp.name}
+    if e.options.fieldNames != "" {
+        s.label = tagFieldName(&fp, e.options.fieldNames)
+    }
+    return s
+}
+
+// ignoresField reports whether the field described by fp is tagged
+// ignoreeq, with the tag names of EqualTagName, unless e compares exactly.
+func (e *equaler) ignoresField(fp fieldPlan) bool {
+    if e.exact {
+        return false
+    }
+    if e.options.tagNames == nil {
+        return fp.ignoreEq
+    }
+    return hasTagOption(fp.tag, e.options.tagNames, "ignoreeq")
+}
+
+// ignored reports whether values of type t at the current path are skipped.
+func (e *equaler) ignored(t reflect.Type) bool {
+    if e.options.ignoreTypes[t] {
+        return true
+    }
+    for _, p := range e.options.ignorePaths {
+        if p.match(e.path) {
+            return true
+        }
+    }
+    return false
+}
+
+func (e *equaler) equal(a, b reflect.Value) bool {
+    if !a.IsValid() || !b.IsValid() {
+        if a.IsValid() == b.IsValid() {
+            return true
+        }
+        return e.differ(a, b)
+    }
+    if a.Type() != b.Type() {
+        return e.differ(a, b)
+    }
+    if e.ignored(a.Type()) {
+        return true
+    }
+    if compare, found := registeredComparer(a.Type()); found && a.CanInterface() && b.CanInterface() {
+        if e.exact {
+            return e.equalExactly(a, b)
+        }
+        return compare(a, b) || e.differ(a, b)
+    }
+    if eq, ok := equalMethod(a, b); ok {
+        if e.exact {
+            return e.equalExactly(a, b)
+        }
+        return eq || e.differ(a, b)
+    }
+    reported := len(e.diffs)
+    if e.equalKind(a, b) {
+        return true
+    }
+    // Values whose contents differ have their differences reported already
+    if len(e.diffs) == reported {
+        e.differ(a, b)
+    }
+    return false
+}
+
+// equalExactly compares a and b, of a type with a comparer or an Equal
+// method, by their contents, reporting a difference for the values as a
+// whole rather than for the fields they are made of, which are typically
+// unexported.
+func (e *equaler) equalExactly(a, b reflect.Value) bool {
+    contents := &equaler{options: e.options, visited: e.visited, exact: true}
+    return contents.equalKind(a, b) || e.differ(a, b)
+}
+
+// equalKind compares a and b of the same type by kind.
+func (e *equaler) equalKind(a, b reflect.Value) bool {
+    switch a.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        if a.IsNil() || b.IsNil() {
+            return a.IsNil() == b.IsNil()
+        }
+        if a.Pointer() == b.Pointer() && (a.Kind() != reflect.Slice || a.Len() == b.Len()) {
+            return true
+        }
+        v := visit{a.Pointer(), b.Pointer(), a.Type()}
+        if e.visited[v] {
+            return true
+        }
+        e.visited[v] = true
+    }
+
+    switch a.Kind() {
+    case reflect.Ptr, reflect.Interface:
+        if a.IsNil() || b.IsNil() {
+            return a.IsNil() == b.IsNil()
+        }
+        return e.equal(a.Elem(), b.Elem())
+    case reflect.Slice, reflect.Array:
+        if a.Len() != b.Len() {
+            return false
+        }
+        equal := true
+        for i := 0; i < a.Len() && (equal || e.diffs != nil); i++ {
+            e.path = append(e.path, step{index: i})
+            equal = e.equal(a.Index(i), b.Index(i)) && equal
+            e.path = e.path[:len(e.path)-1]
+        }
+        return equal
+    case reflect.Map:
+        if a.Len() != b.Len() && e.diffs == nil {
+            return false
+        }
+        if e.diffs == nil {
+            return e.equalEntries(a, b)
+        }
+        // Entries are compared in order so that Diff is deterministic
+        cm := &CloneManager{options: options{deterministicOrder: true}}
+        equal := true
+        for _, entry := range cm.mapEntries(a) {
+            if !equal && e.diffs == nil {
+                break
+            }
+            e.path = append(e.path, step{key: entry.key})
+            equal = e.equal(entry.value, b.MapIndex(entry.key)) && equal
+            e.path = e.path[:len(e.path)-1]
+        }
+        for _, entry := range cm.mapEntries(b) {
+            if !equal && e.diffs == nil {
+                break
+            }
+            if !a.MapIndex(entry.key).IsValid() {
+                e.path = append(e.path, step{key: entry.key})
+                equal = e.differ(reflect.Value{}, entry.value)
+                e.path = e.path[:len(e.path)-1]
+            }
+        }
+        return equal
+    case reflect.Struct:
+        equal := true
+        for i, fp := range planOf(a.Type()).fields {
+            if !equal && e.diffs == nil {
+                break
+            }
+            if e.ignoresField(fp) {
+                continue
+            }
+            e.path = append(e.path, e.fieldStep(fp))
+            equal = e.equal(a.Field(i), b.Field(i)) && equal
+            e.path = e.path[:len(e.path)-1]
+        }
+        return equal
+    case reflect.Float32, reflect.Float64:
+        return e.floats(a.Type()).equal(a.Float(), b.Float())
+    case reflect.Complex64, reflect.Complex128:
+        f := e.floats(a.Type())
+        ca, cb := a.Complex(), b.Complex()
+        return f.equal(real(ca), real(cb)) && f.equal(imag(ca), imag(cb))
+    case reflect.Func:
+        // Like reflect.DeepEqual, functions are only equal if both are nil
+        return a.IsNil() && b.IsNil()
+    case reflect.Chan, reflect.UnsafePointer:
+        return a.Pointer() == b.Pointer()
+    case reflect.Bool:
+        return a.Bool() == b.Bool()
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return a.Int() == b.Int()
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return a.Uint() == b.Uint()
+    case reflect.String:
+        return a.String() == b.String()
+    }
+    return false
+}
+
+// errDiffers stops the iteration of equalEntries.
+var errDiffers = errors.New("differs")
+
+// equalEntries reports whether the entries of the map a are in the map b,
+// which has the same length. Entries are compared in any order, reading
+// them into buffers rather than copying each.
+func (e *equaler) equalEntries(a, b reflect.Value) bool {
+    err := (&CloneManager{}).rangeMap(a, true, func(key, value reflect.Value) error {
+        e.path = append(e.path, step{key: key})
+        equal := e.equal(value, b.MapIndex(key))
+        e.path = e.path[:len(e.path)-1]
+        if !equal {
+            return errDiffers
+        }
+        return nil
+    })
+    return err == nil
+}
+
+// floats returns the float comparison for a value of type t at the current
+// path.
+func (e *equaler) floats(t reflect.Type) Floats {
+    for i := len(e.options.floatsAt) - 1; i >= 0; i-- {
+        if e.options.floatsAt[i].pattern.match(e.path) {
+            return e.options.floatsAt[i].floats
+        }
+    }
+    if f, ok := e.options.floatsOf[t]; ok {
+        return f
+    }
+    if e.options.floats != nil {
+        return *e.options.floats
+    }
+    return Floats{}
+}
+
+// equalMethod compares a and b with the Equal method of their type, if it
+// has one taking its own type and returning a bool.
+func equalMethod(a, b reflect.Value) (bool, bool) {
+    if !a.CanInterface() || !b.CanInterface() || a.Kind() == reflect.Interface {
+        return false, false
+    }
+    method, ok := a.Type().MethodByName("Equal")
+    if !ok {
+        return false, false
+    }
+    t := method.Type
+    if t.NumIn() != 2 || t.In(1) != a.Type() || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Bool {
+        return false, false
+    }
+    if a.Kind() == reflect.Ptr && (a.IsNil() || b.IsNil()) {
+        return a.IsNil() == b.IsNil(), true
+    }
+    return method.Func.Call([]reflect.Value{a, b})[0].Bool(), true
+}
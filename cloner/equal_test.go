@@ -0,0 +1,187 @@
+package cloner_test
+
+import (
+    "math"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Celsius float64
+
+type Model struct {
+    Name    string
+    Weights []float64
+    Bias    float64
+    Temp    Celsius
+    Trained time.Time
+    Next    *Model
+}
+
+// Test for deep equality of cloned values
+func TestEqual(t *testing.T) {
+    model := &Model{Name: "m", Weights: []float64{1, 2}, Trained: time.Now()}
+    model.Next = model
+    clone := cloner.MustClone(cloner.NewCloneManager(cloner.WithUnexportedFields(cloner.Share)), model)
+    if !cloner.Equal(model, clone) {
+        t.Errorf("Equal reported a clone different from its source")
+    }
+
+    clone.Weights[1] = 3
+    if cloner.Equal(model, clone) {
+        t.Errorf("Equal reported different weights equal")
+    }
+
+    // Times are compared with their Equal method
+    other := *model
+    other.Next = nil
+    same := other
+    same.Trained = other.Trained.In(time.FixedZone("X", 3600))
+    if !cloner.Equal(other, same) {
+        t.Errorf("Equal reported the same instant in two zones different")
+    }
+
+    if cloner.Equal([]int(nil), []int{}) || !cloner.Equal(map[string]int{"a": 1}, map[string]int{"a": 1}) {
+        t.Errorf("Equal does not compare slices and maps like reflect.DeepEqual")
+    }
+    if cloner.Equal(1, int64(1)) {
+        t.Errorf("Equal reported values of different types equal")
+    }
+}
+
+// Test for the float comparisons of Equal
+func TestEqualFloats(t *testing.T) {
+    a := Model{Weights: []float64{1, math.NaN()}, Bias: 0.1 + 0.2, Temp: 20}
+    b := Model{Weights: []float64{1.001, math.NaN()}, Bias: 0.3, Temp: 20.4}
+
+    if cloner.Equal(a, b) {
+        t.Errorf("Equal reported different floats equal")
+    }
+    tolerant := []cloner.EqualOption{
+        cloner.EqualFloats(cloner.Floats{Abs: 1e-9}),
+        cloner.EqualFloatsAt(".Weights[*]", cloner.Floats{Rel: 0.01, NaNEqual: true}),
+        cloner.EqualFloatsOf(reflect.TypeOf(Celsius(0)), cloner.Floats{Abs: 0.5}),
+    }
+    if !cloner.Equal(a, b, tolerant...) {
+        t.Errorf("Equal reported floats within the tolerances different")
+    }
+    b.Temp = 21
+    if cloner.Equal(a, b, tolerant...) {
+        t.Errorf("Equal reported temperatures beyond the tolerance equal")
+    }
+
+    negative := math.Copysign(0, -1)
+    if !cloner.Equal(negative, 0.0) || cloner.Equal(negative, 0.0, cloner.EqualFloats(cloner.Floats{SignedZeros: true})) {
+        t.Errorf("Equal does not unify zeros by default only")
+    }
+    if cloner.Equal(math.Inf(1), math.MaxFloat64, cloner.EqualFloats(cloner.Floats{Rel: 1})) {
+        t.Errorf("Equal reported infinity equal to a finite float")
+    }
+}
+
+type Record struct {
+    ID      string `deeper:"ignoreeq"`
+    Name    string
+    Created time.Time
+    Labels  map[string]string
+    Scores  []int
+}
+
+// Test for skipping fields in Equal and Diff
+func TestEqualIgnore(t *testing.T) {
+    a := Record{ID: "1", Name: "r", Created: time.Now(), Labels: map[string]string{"env": "prod", "run": "a"}}
+    b := Record{ID: "2", Name: "r", Created: a.Created.Add(time.Hour), Labels: map[string]string{"env": "prod", "run": "b"}}
+
+    if cloner.Equal(a, b) {
+        t.Errorf("Equal reported records with different timestamps equal")
+    }
+    opts := []cloner.EqualOption{
+        cloner.IgnoreTypes(reflect.TypeOf(time.Time{})),
+        cloner.IgnorePaths(`.Labels["run"]`),
+    }
+    if !cloner.Equal(a, b, opts...) {
+        t.Errorf("Equal did not skip the ignored fields: %v", cloner.Diff(a, b, opts...))
+    }
+    if diffs := cloner.Diff(a, b, opts...); len(diffs) != 0 {
+        t.Errorf("got differences %v, want none", diffs)
+    }
+}
+
+// Test for the differences reported by Diff
+func TestDiff(t *testing.T) {
+    created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    a := Record{Name: "a", Created: created, Labels: map[string]string{"env": "prod", "tier": "1"}, Scores: []int{1, 2}}
+    b := Record{Name: "b", Created: created, Labels: map[string]string{"env": "dev", "zone": "x"}, Scores: []int{1, 3}}
+
+    var got []string
+    for _, d := range cloner.Diff(a, b) {
+        got = append(got, d.String())
+    }
+    want := []string{
+        `.Name: "a" != "b"`,
+        `.Labels["env"]: "prod" != "dev"`,
+        `.Labels["tier"]: "1" != <nil>`,
+        `.Labels["zone"]: <nil> != "x"`,
+        `.Scores[1]: 2 != 3`,
+    }
+    deepEqual(t, got, want)
+
+    diffs := cloner.Diff([]int{1}, []int{1, 2})
+    deepEqual(t, diffs, []cloner.Difference{{Path: "", A: []int{1}, B: []int{1, 2}}})
+    if diffs := cloner.Diff(a, a); len(diffs) != 0 {
+        t.Errorf("got differences %v, want none", diffs)
+    }
+}
+
+// Fixed is a decimal number whose representation varies: 1.50 may be
+// stored as {150, 2} or {15, 1}.
+type Fixed struct {
+    Units int64
+    Scale int
+}
+
+func (f Fixed) float() float64 {
+    return float64(f.Units) / math.Pow10(f.Scale)
+}
+
+func init() {
+    cloner.RegisterComparer(func(a, b Fixed) bool {
+        return a.float() == b.float()
+    })
+}
+
+// Test for comparing values with registered comparers
+func TestRegisterComparer(t *testing.T) {
+    type Price struct {
+        Amount  Fixed
+        Amounts map[string]Fixed
+    }
+    a := Price{Amount: Fixed{150, 2}, Amounts: map[string]Fixed{"tax": {1, 0}}}
+    b := Price{Amount: Fixed{15, 1}, Amounts: map[string]Fixed{"tax": {10, 1}}}
+    if !cloner.Equal(a, b) {
+        t.Errorf("Equal did not use the registered comparer: %v", cloner.Diff(a, b))
+    }
+
+    b.Amounts["tax"] = Fixed{2, 0}
+    diffs := cloner.Diff(a, b)
+    deepEqual(t, diffs, []cloner.Difference{{
+        Path:  `.Amounts["tax"]`,
+        Steps: []cloner.Step{cloner.Field("Amounts"), cloner.Key("tax")},
+        A:     Fixed{1, 0},
+        B:     Fixed{2, 0},
+    }})
+}
+
+// Benchmark for comparing maps of 1M entries
+func BenchmarkEqualLargeMap(b *testing.B) {
+    m1, m2 := largeMap(), largeMap()
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if !cloner.Equal(m1, m2) {
+            b.Fatal("maps differ")
+        }
+    }
+}
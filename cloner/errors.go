@@ -0,0 +1,107 @@
+package cloner
+
+import (
+    "errors"
+    "fmt"
+    "log/slog"
+    "reflect"
+)
+
+// Errors reported by CloneManager, wrapped in a *CloneError; test for them
+// with errors.Is.
+var (
+    // ErrUncloneableKind reports a channel or function, which cannot be deep
+    // cloned. See WithChans and WithFuncs.
+    ErrUncloneableKind = errors.New("uncloneable kind")
+
+    // ErrUncloneableTimer reports a *time.Timer or *time.Ticker, whose
+    // runtime state cannot be cloned. See WithTimers.
+    ErrUncloneableTimer = errors.New("uncloneable timer")
+
+    // ErrUnexportedField reports an unexported struct field holding data
+    // that the clone would lose. See WithUnexportedFields.
+    ErrUnexportedField = errors.New("unexported field")
+
+    // ErrDepthExceeded reports a value nested deeper than WithMaxDepth
+    // allows.
+    ErrDepthExceeded = errors.New("maximum depth exceeded")
+
+    // ErrBudgetExceeded reports a clone that visited more values than
+    // WithMaxNodes allows. It aborts the clone even with CollectErrors.
+    ErrBudgetExceeded = errors.New("clone budget exceeded")
+
+    // ErrTimeout reports a clone that took longer than WithTimeout allows.
+    // It aborts the clone even with CollectErrors.
+    ErrTimeout = errors.New("clone timed out")
+
+    // ErrNotTransferable reports a resource that WithTransfer cannot move
+    // because it cannot be cleared in the source.
+    ErrNotTransferable = errors.New("resource not transferable")
+
+    // ErrUnknownType reports an interface value whose dynamic type is not
+    // registered for streaming. See RegisterType.
+    ErrUnknownType = errors.New("unknown type")
+
+    // ErrInvalidStream reports a stream read by CloneFrom that was not
+    // written by CloneTo.
+    ErrInvalidStream = errors.New("invalid stream")
+
+    // ErrTypeMismatch reports a clone whose type cannot be used in place of
+    // the source value, typically returned by a misbehaving Cloner.
+    ErrTypeMismatch = errors.New("type mismatch")
+
+    // ErrNoSnapshot reports an ApplyBack of a value that is not the root of
+    // a clone made with WithApplyBack.
+    ErrNoSnapshot = errors.New("no snapshot of the clone")
+
+    // ErrSourceMismatch reports an edit that ApplyBack cannot write because
+    // the source has no value at its path, such as a nil pointer or a
+    // missing map entry.
+    ErrSourceMismatch = errors.New("source does not match the clone")
+
+    // ErrNoValue reports a path leading to no value, through a nil pointer,
+    // a missing map entry or an index out of range. See Get and Set.
+    ErrNoValue = errors.New("no value at path")
+
+    // ErrConflict reports an ApplyBack onto a source changed since its clone
+    // was taken. See OnConflict.
+    ErrConflict = errors.New("source changed since the clone")
+)
+
+// CloneError records the value that could not be cloned and why.
+type CloneError struct {
+    Path  string       // path from the root, e.g. .Items[2].Name; empty for the root
+    Steps []Step       // structured form of Path, holding the map keys themselves
+    Type  reflect.Type // type of the value; nil if unknown
+    Err   error
+}
+
+func (e *CloneError) Error() string {
+    typeName := "<nil>"
+    if e.Type != nil {
+        typeName = e.Type.String()
+    }
+    if e.Path == "" {
+        return typeName + ": " + e.Err.Error()
+    }
+    return fmt.Sprintf("%s (%s): %v", e.Path, typeName, e.Err)
+}
+
+func (e *CloneError) Unwrap() error {
+    return e.Err
+}
+
+// cloneError wraps err in a *CloneError for src at the current path, unless
+// err already describes a value deeper in the graph.
+func (cm *CloneManager) cloneError(src reflect.Value, err error) error {
+    var cloneErr *CloneError
+    if errors.As(err, &cloneErr) {
+        return err
+    }
+    cloneErr = &CloneError{Path: formatPath(cm.path), Steps: exportedSteps(cm.path), Err: err}
+    if src.IsValid() {
+        cloneErr.Type = src.Type()
+    }
+    cm.log(LogErrors, "clone failed", src, slog.Any("err", err))
+    return cloneErr
+}
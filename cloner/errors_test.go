@@ -0,0 +1,153 @@
+package cloner_test
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Money struct {
+    Cents int
+}
+
+type Order struct {
+    ID    int
+    Price Money
+    Lines []Money
+}
+
+// Label is stored in interfaces of type fmt.Stringer.
+type Label string
+
+func (l Label) String() string {
+    return string(l)
+}
+
+// Test for converting reflection panics into a CloneError
+func TestClonePanicRecovered(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    // Label is a string, so reflection cannot access its fields
+    cm.RegisterCloner(reflect.TypeOf(Label("")), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        return reflect.ValueOf(value).Field(0).Interface(), nil
+    }))
+
+    _, err := cm.Clone(struct {
+        Name fmt.Stringer
+    }{Name: Label("a")})
+
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) {
+        t.Fatalf("Error %v is not a CloneError", err)
+    }
+    if cloneErr.Path != ".Name" || cloneErr.Type != reflect.TypeOf(Label("")) {
+        t.Errorf("CloneError points to %q (%v), want .Name (cloner_test.Label)", cloneErr.Path, cloneErr.Type)
+    }
+    if !strings.Contains(err.Error(), "panic") {
+        t.Errorf("Error %q does not mention the panic", err)
+    }
+}
+
+// Test for reporting clones that do not implement the interface they are
+// stored in
+func TestCloneInterfaceTypeMismatch(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(Label("")), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        return 42, nil
+    }))
+
+    _, err := cm.Clone(struct {
+        Name fmt.Stringer
+    }{Name: Label("a")})
+    if !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Fatalf("got error %v, want ErrTypeMismatch", err)
+    }
+
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) {
+        t.Fatalf("Error %v is not a CloneError", err)
+    }
+    if cloneErr.Path != ".Name" || cloneErr.Type != reflect.TypeOf((*fmt.Stringer)(nil)).Elem() {
+        t.Errorf("CloneError points to %q (%v), want .Name (fmt.Stringer)", cloneErr.Path, cloneErr.Type)
+    }
+}
+
+// Test for reporting clones of the wrong type
+func TestCloneTypeMismatch(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(Money{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        return "not money", nil
+    }))
+
+    _, err := cm.Clone(Order{ID: 1, Lines: []Money{{Cents: 5}}})
+    if !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Fatalf("got error %v, want ErrTypeMismatch", err)
+    }
+
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || cloneErr.Path != ".Price" {
+        t.Errorf("got error %v, want a CloneError for .Price", err)
+    }
+}
+
+// Test for branching on sentinel errors
+func TestSentinelErrors(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    _, err := cm.Clone(Job{Done: make(chan bool)})
+    if !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUncloneableKind", err)
+    }
+
+    _, err = cloner.Clone(cm, func() {})
+    if !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUncloneableKind", err)
+    }
+}
+
+// Test for panics raised by custom cloners
+func TestCloneClonerPanicRecovered(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.CollectErrors())
+    cm.RegisterCloner(reflect.TypeOf(Money{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        if value.(Money).Cents < 0 {
+            panic("negative amount")
+        }
+        return value, nil
+    }))
+
+    cloned, err := cloner.Clone(cm, Order{ID: 1, Price: Money{Cents: 3}, Lines: []Money{{Cents: 5}, {Cents: -1}}})
+
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) {
+        t.Fatalf("Error %v is not a CloneError", err)
+    }
+    if cloneErr.Path != ".Lines[1]" || cloneErr.Type != reflect.TypeOf(Money{}) {
+        t.Errorf("CloneError points to %q (%v), want .Lines[1] (cloner_test.Money)", cloneErr.Path, cloneErr.Type)
+    }
+    if !strings.Contains(err.Error(), "negative amount") {
+        t.Errorf("Error %q does not contain the panic value", err)
+    }
+
+    // The clone continues after the panic
+    if cloned.ID != 1 || cloned.Price.Cents != 3 || cloned.Lines[0].Cents != 5 {
+        t.Errorf("Partial clone is incorrect: %+v", cloned)
+    }
+}
+
+// Test that errors for values in the graph carry their path and type
+func TestCloneErrorPath(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    _, err := cm.Clone(map[string]Job{"a": {Done: make(chan bool)}})
+
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) {
+        t.Fatalf("Error %v is not a CloneError", err)
+    }
+    if cloneErr.Path != `["a"].Done` || cloneErr.Type != reflect.TypeOf(make(chan bool)) {
+        t.Errorf("CloneError points to %q (%v), want [\"a\"].Done (chan bool)", cloneErr.Path, cloneErr.Type)
+    }
+}
@@ -0,0 +1,58 @@
+package cloner
+
+// Slice returns a copy of s with the same length and capacity, whose
+// elements are copied by clone, or assigned if clone is nil. Unlike Clone it
+// uses no reflection, for the shapes known at compile time, but follows the
+// same conventions: a nil slice is copied as nil and an empty slice as an
+// empty one. Nested containers are copied by passing a clone calling the
+// helpers, e.g.
+//
+//	rows := cloner.Slice(grid, func(row []int) []int {
+//	    return cloner.Slice(row, nil)
+//	})
+//
+// Unlike Clone, the helpers do not track shared references: elements shared
+// in s are copied separately.
+func Slice[S ~[]E, E any](s S, clone func(E) E) S {
+    if s == nil {
+        return nil
+    }
+    copied := make(S, len(s), cap(s))
+    if clone == nil {
+        copy(copied, s)
+        return copied
+    }
+    for i, e := range s {
+        copied[i] = clone(e)
+    }
+    return copied
+}
+
+// Map returns a copy of m whose values are copied by clone, or assigned if
+// clone is nil, like Slice. Keys are assigned.
+func Map[M ~map[K]V, K comparable, V any](m M, clone func(V) V) M {
+    if m == nil {
+        return nil
+    }
+    copied := make(M, len(m))
+    for k, v := range m {
+        if clone != nil {
+            v = clone(v)
+        }
+        copied[k] = v
+    }
+    return copied
+}
+
+// Ptr returns a pointer to a new copy of the value p points to, copied by
+// clone or assigned if clone is nil, like Slice. A nil p yields nil.
+func Ptr[T any](p *T, clone func(T) T) *T {
+    if p == nil {
+        return nil
+    }
+    v := *p
+    if clone != nil {
+        v = clone(v)
+    }
+    return &v
+}
@@ -0,0 +1,57 @@
+package cloner_test
+
+import (
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Grid [][]int
+
+// Test for copying slices with the generic helpers
+func TestSlice(t *testing.T) {
+    original := Grid{{1, 2}, append(make([]int, 0, 4), 3)}
+
+    shallow := cloner.Slice(original, nil)
+    deepEqual(t, shallow, original)
+    if &shallow[0][0] != &original[0][0] {
+        t.Errorf("Shallow copy does not share the rows")
+    }
+
+    deep := cloner.Slice(original, func(row []int) []int {
+        return cloner.Slice(row, nil)
+    })
+    deepEqual(t, deep, original)
+    if &deep[0][0] == &original[0][0] || cap(deep[1]) != 4 {
+        t.Errorf("Deep copy shares the rows or lost their capacity")
+    }
+
+    if got := cloner.Slice([]int(nil), nil); got != nil {
+        t.Errorf("got %v for a nil slice, want nil", got)
+    }
+    if got := cloner.Slice([]int{}, nil); got == nil {
+        t.Errorf("got nil for an empty slice, want an empty slice")
+    }
+}
+
+// Test for copying maps and pointers with the generic helpers
+func TestMapAndPtr(t *testing.T) {
+    original := map[string]*[]string{"a": {"x", "y"}}
+
+    copied := cloner.Map(original, func(p *[]string) *[]string {
+        return cloner.Ptr(p, func(s []string) []string {
+            return cloner.Slice(s, nil)
+        })
+    })
+    deepEqual(t, copied, original)
+    if copied["a"] == original["a"] || &(*copied["a"])[0] == &(*original["a"])[0] {
+        t.Errorf("Copied map shares its values with the original")
+    }
+
+    if got := cloner.Map(map[string]int(nil), nil); got != nil {
+        t.Errorf("got %v for a nil map, want nil", got)
+    }
+    if got := cloner.Ptr((*int)(nil), nil); got != nil {
+        t.Errorf("got %v for a nil pointer, want nil", got)
+    }
+}
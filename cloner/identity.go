@@ -0,0 +1,238 @@
+package cloner
+
+import (
+    "fmt"
+    "reflect"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Ref identifies the memory referenced by a pointer, slice or map of a
+// graph, the key of an IdentityTable.
+type Ref struct {
+    ref memref.Ref
+}
+
+// RefOf returns the Ref of v, which must be a pointer, slice or map.
+func RefOf(v interface{}) Ref {
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        return Ref{memref.Of(rv)}
+    }
+    panic(fmt.Sprintf("cloner: RefOf of %T, want a pointer, slice or map", v))
+}
+
+// IdentityTable records the clones of the pointers, slices and maps met by
+// a clone, so that every reference to one of them resolves to a single
+// clone. See WithIdentityTable.
+type IdentityTable interface {
+    // Load returns the clone recorded for ref.
+    Load(ref Ref) (clone interface{}, ok bool)
+    // Store records the clone of ref.
+    Store(ref Ref, clone interface{})
+}
+
+// IdentityMap is an IdentityTable backed by a map.
+type IdentityMap map[Ref]interface{}
+
+// Load returns the clone recorded for ref.
+func (m IdentityMap) Load(ref Ref) (interface{}, bool) {
+    clone, ok := m[ref]
+    return clone, ok
+}
+
+// Store records the clone of ref.
+func (m IdentityMap) Store(ref Ref, clone interface{}) {
+    m[ref] = clone
+}
+
+// WithIdentityTable makes the manager record the clones of pointers, slices
+// and maps in table instead of a table of its own. A table seeded with
+// entries mapping values to themselves, or to canonical instances, makes
+// clones share those values instead of cloning them, to clone everything
+// but a few singletons:
+//
+//	table := cloner.IdentityMap{cloner.RefOf(config): config}
+//	clone, err := cm.Clone(state, cloner.WithIdentityTable(table))
+//
+// The clones made with table stay recorded in it: clones sharing a table
+// share their clones too, like the elements of a CloneBatch. A table is used
+// by one clone at a time, unless it is safe for concurrent use.
+func WithIdentityTable(table IdentityTable) Option {
+    return func(o *options) {
+        o.identityTable = table
+    }
+}
+
+// WithMapping makes the manager keep the references of the source of the
+// last clone made by Clone, CloneValue, CloneBatch and the functions using
+// them with their clones, see Mapping.
+func WithMapping() Option {
+    return func(o *options) {
+        o.mapping = true
+    }
+}
+
+// mapping is the mapping of a clone made with WithMapping.
+type mapping struct {
+    clones  IdentityMap
+    sources map[memref.Ref]interface{} // by reference to their clone
+}
+
+// Mapping returns the clones of the pointers, slices and maps of the source
+// of the last successful clone made by cm with WithMapping, keyed by their
+// Ref, so that references into the source held by the caller, such as
+// indexes or selections, can be translated into references into the clone:
+//
+//	selected := cm.Mapping()[cloner.RefOf(node)].(*Node)
+//
+// It returns nil if no such clone was made. Of concurrent clones, the last
+// to finish is kept. The mapping is replaced by the next clone and must not
+// be changed; it keeps the clone it refers to alive until then. Clones made
+// with WithIdentityTable record their mapping in their table instead.
+func (cm *CloneManager) Mapping() IdentityMap {
+    if m := cm.lastMapping(); m != nil {
+        return m.clones
+    }
+    return nil
+}
+
+// SourceOf returns the pointer, slice or map of the source of the last
+// clone kept by Mapping that cloned is the clone of, so that tools cloning
+// a graph to inspect it safely can apply chosen edits back to the live
+// graph. It returns false if cloned is not part of that clone. The source
+// is kept alive with the mapping.
+func (cm *CloneManager) SourceOf(cloned interface{}) (interface{}, bool) {
+    v := reflect.ValueOf(cloned)
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+    default:
+        return nil, false
+    }
+    m := cm.lastMapping()
+    if m == nil {
+        return nil, false
+    }
+    src, ok := m.sources[memref.Of(v)]
+    return src, ok
+}
+
+// lastMapping returns the mapping kept by WithMapping, if any.
+func (cm *CloneManager) lastMapping() *mapping {
+    if cm.mapping == nil {
+        return nil
+    }
+    return cm.mapping.Load()
+}
+
+// noteSource notes src, if it is a pointer, slice or map, as a source of
+// the references recorded by the clone, for WithMapping.
+func (cm *CloneManager) noteSource(src reflect.Value) {
+    switch src.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        if src.IsNil() || !src.CanInterface() {
+            return
+        }
+        if cm.sources == nil {
+            cm.sources = make(map[memref.Ref]interface{})
+        }
+        cm.sources[memref.Of(src)] = src.Interface()
+    }
+}
+
+// keepMapping keeps the references recorded by the session cm as the
+// mapping of the last clone, with WithMapping.
+func (cm *CloneManager) keepMapping() {
+    if !cm.options.mapping || cm.mapping == nil || cm.options.identityTable != nil {
+        return
+    }
+    m := &mapping{clones: make(IdentityMap, len(cm.visited)), sources: make(map[memref.Ref]interface{}, len(cm.visited))}
+    for ref, cloned := range cm.visited {
+        if cloned == nil {
+            continue
+        }
+        m.clones[Ref{ref}] = cloned
+        if src, ok := cm.sources[ref]; ok {
+            if v := reflect.ValueOf(cloned); v.Kind() == reflect.Ptr || v.Kind() == reflect.Slice || v.Kind() == reflect.Map {
+                m.sources[memref.Of(v)] = src
+            }
+        }
+    }
+    cm.mapping.Store(m)
+}
+
+// WithSeed maps the pointers that are keys of seed to replacements: every
+// reference to one of them in the graph resolves to its replacement, used
+// as-is, instead of being cloned, for example to swap a context, a logger or
+// a database handle for another. A replacement must be assignable to the
+// places the source is stored in, otherwise ErrTypeMismatch is reported.
+// WithSeed panics if a key is not a pointer.
+func WithSeed(seed map[interface{}]interface{}) Option {
+    refs := make(map[memref.Ref]interface{}, len(seed))
+    for src, replacement := range seed {
+        if reflect.ValueOf(src).Kind() != reflect.Ptr {
+            panic(fmt.Sprintf("cloner: WithSeed key of %T, want a pointer", src))
+        }
+        refs[RefOf(src).ref] = replacement
+    }
+    return func(o *options) {
+        o.seed = refs
+    }
+}
+
+// plant records the replacements of WithSeed as clones.
+func (cm *CloneManager) plant() {
+    for ref, replacement := range cm.options.seed {
+        cm.record(ref, replacement)
+    }
+}
+
+// lookup returns the clone recorded for ref by the clone in progress.
+func (cm *CloneManager) lookup(ref memref.Ref) (interface{}, bool) {
+    if table := cm.options.identityTable; table != nil {
+        return table.Load(Ref{ref})
+    }
+    cloned, ok := cm.visited[ref]
+    return cloned, ok
+}
+
+// record records the clone of ref for the clone in progress.
+func (cm *CloneManager) record(ref memref.Ref, cloned interface{}) {
+    if table := cm.options.identityTable; table != nil {
+        table.Store(Ref{ref}, cloned)
+        return
+    }
+    cm.visited[ref] = cloned
+}
+
+// Cloning reports whether cm belongs to a clone in progress, as the manager
+// passed to Cloneable and Cloner implementations does.
+func (cm *CloneManager) Cloning() bool {
+    return cm.visited != nil
+}
+
+// Lookup returns the clone recorded for v, a pointer, slice or map, by the
+// clone in progress, for Cloneable and Cloner implementations cloning the
+// values they reference themselves, such as the code generated by
+// deeper-gen, so that references shared with the rest of the graph resolve
+// to a single clone. Outside of a clone it only finds the clones recorded
+// in the table of WithIdentityTable.
+func (cm *CloneManager) Lookup(v interface{}) (interface{}, bool) {
+    if cm.visited == nil && cm.options.identityTable == nil {
+        return nil, false
+    }
+    return cm.lookup(RefOf(v).ref)
+}
+
+// Record records clone as the clone of v, a pointer, slice or map, for the
+// clone in progress, so that the other references to v resolve to it.
+// Record it before cloning the values v references, so that cycles leading
+// back to v resolve too. Outside of a clone it only records in the table of
+// WithIdentityTable, if any.
+func (cm *CloneManager) Record(v, clone interface{}) {
+    if cm.visited == nil && cm.options.identityTable == nil {
+        return
+    }
+    cm.record(RefOf(v).ref, clone)
+}
@@ -0,0 +1,200 @@
+package cloner_test
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Settings struct {
+    Region string
+}
+
+type Worker struct {
+    Name     string
+    Settings *Settings
+    Peers    []*Worker
+}
+
+// Test for sharing seeded singletons with a user-provided identity table
+func TestWithIdentityTable(t *testing.T) {
+    settings := &Settings{Region: "eu"}
+    a := &Worker{Name: "a", Settings: settings}
+    b := &Worker{Name: "b", Settings: settings, Peers: []*Worker{a}}
+    a.Peers = []*Worker{b}
+
+    table := cloner.IdentityMap{cloner.RefOf(settings): settings}
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), a, cloner.WithIdentityTable(table))
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned == a || cloned.Settings != settings || cloned.Peers[0].Settings != settings {
+        t.Errorf("Clone did not share the seeded settings")
+    }
+    if cloned.Peers[0] == b || cloned.Peers[0].Peers[0] != cloned {
+        t.Errorf("Cloned workers are not linked to each other")
+    }
+
+    // The table records the clones
+    if got, ok := table.Load(cloner.RefOf(b)); !ok || got != cloned.Peers[0] {
+        t.Errorf("got %v for b in the table, want its clone", got)
+    }
+}
+
+// Test for swapping seeded references for replacements
+func TestWithSeed(t *testing.T) {
+    prod, test := &Settings{Region: "eu"}, &Settings{Region: "test"}
+    peers := []*Worker{{Name: "c", Settings: prod}}
+    a := &Worker{Name: "a", Settings: prod, Peers: peers}
+    b := &Worker{Name: "b", Settings: prod, Peers: peers}
+
+    seed := cloner.WithSeed(map[interface{}]interface{}{prod: test})
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), []*Worker{a, b}, seed)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned[0].Settings != test || cloned[1].Settings != test || cloned[0].Peers[0].Settings != test {
+        t.Errorf("Clone did not resolve the seeded settings to the replacement")
+    }
+    if cloned[0].Peers[0] == peers[0] || cloned[0].Peers[0] != cloned[1].Peers[0] {
+        t.Errorf("Clone did not clone the peers once")
+    }
+
+    // Replacements of another type are reported
+    _, err = cloner.Clone(cloner.NewCloneManager(), a, cloner.WithSeed(map[interface{}]interface{}{prod: "test"}))
+    if !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Errorf("got error %v, want ErrTypeMismatch", err)
+    }
+
+    defer func() {
+        if recover() == nil {
+            t.Errorf("WithSeed did not panic for a string key")
+        }
+    }()
+    cloner.WithSeed(map[interface{}]interface{}{"prod": test})
+}
+
+// Test for sharing the references cloned by a Cloner with the clone
+func TestLookupRecord(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    if _, ok := cm.Lookup(&Settings{}); ok || cm.Cloning() {
+        t.Errorf("Lookup found a clone outside of a clone")
+    }
+    cm.Record(&Settings{}, &Settings{})
+
+    // The Cloner clones the settings of workers itself
+    cm.RegisterCloner(reflect.TypeOf(Worker{}), cloner.ClonerFunc(func(v interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        w := v.(Worker)
+        if !manager.Cloning() {
+            t.Errorf("Cloner called with a manager outside of a clone")
+        }
+        if c, ok := manager.Lookup(w.Settings); ok {
+            w.Settings = c.(*Settings)
+        } else {
+            c := &Settings{Region: w.Settings.Region}
+            manager.Record(w.Settings, c)
+            w.Settings = c
+        }
+        return w, nil
+    }))
+    settings := &Settings{Region: "eu"}
+    original := struct {
+        Workers  []Worker
+        Settings *Settings
+    }{[]Worker{{Name: "a", Settings: settings}, {Name: "b", Settings: settings}}, settings}
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Settings == settings || cloned.Workers[0].Settings != cloned.Settings || cloned.Workers[1].Settings != cloned.Settings {
+        t.Errorf("Cloned settings are not shared by the cloned workers")
+    }
+}
+
+// Test that RefOf panics for values that are not references
+func TestRefOfPanics(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Errorf("RefOf did not panic for an int")
+        }
+    }()
+    cloner.RefOf(1)
+}
+
+// Test for translating references into the source into references into the
+// clone
+func TestMapping(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithMapping())
+    if cm.Mapping() != nil {
+        t.Errorf("got a mapping before any clone")
+    }
+    leaf := &Tree{Name: "leaf"}
+    root := &Tree{Name: "root", Children: []*Tree{leaf}, Attrs: map[string]interface{}{"x": 1}}
+    leaf.Parent = root
+    cloned, err := cloner.Clone(cm, root)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    mapping := cm.Mapping()
+    if got := mapping[cloner.RefOf(leaf)]; got != cloned.Children[0] {
+        t.Errorf("got %v for the leaf, want its clone", got)
+    }
+    if got := mapping[cloner.RefOf(root)]; got != cloned {
+        t.Errorf("got %v for the root, want its clone", got)
+    }
+    if got, ok := mapping[cloner.RefOf(root.Attrs)].(map[string]interface{}); !ok || got["x"] != 1 {
+        t.Errorf("got %v for the attributes, want their clone", got)
+    }
+
+    other := &Tree{Name: "other"}
+    cm.Clone(other)
+    if _, ok := cm.Mapping()[cloner.RefOf(leaf)]; ok || len(cm.Mapping()) != 1 {
+        t.Errorf("got mapping %v, want the mapping of the last clone", cm.Mapping())
+    }
+    plain := cloner.NewCloneManager()
+    plain.Clone(root)
+    if plain.Mapping() != nil {
+        t.Errorf("got a mapping without WithMapping")
+    }
+}
+
+// Test for resolving the nodes of a clone back to their source
+func TestSourceOf(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithMapping())
+    leaf := &Tree{Name: "leaf", Attrs: map[string]interface{}{"size": 1}}
+    root := &Tree{Name: "root", Children: []*Tree{leaf}}
+    if _, ok := cm.SourceOf(leaf); ok {
+        t.Errorf("got a source before any clone")
+    }
+    cloned, err := cloner.Clone(cm, root)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    // Edits made on the clone are applied back to the live graph
+    edited := cloned.Children[0]
+    edited.Name = "renamed"
+    src, ok := cm.SourceOf(edited)
+    if !ok || src != leaf {
+        t.Fatalf("got source %v, %v, want the leaf", src, ok)
+    }
+    src.(*Tree).Name = edited.Name
+    if leaf.Name != "renamed" {
+        t.Errorf("got leaf %q, want it renamed", leaf.Name)
+    }
+    if src, ok := cm.SourceOf(cloned.Children[0].Attrs); !ok || reflect.ValueOf(src).Pointer() != reflect.ValueOf(leaf.Attrs).Pointer() {
+        t.Errorf("got source %v, %v, want the attributes of the leaf", src, ok)
+    }
+    if src, ok := cm.SourceOf(cloned.Children); !ok || &src.([]*Tree)[0] != &root.Children[0] {
+        t.Errorf("got source %v, %v, want the children of the root", src, ok)
+    }
+    if _, ok := cm.SourceOf(leaf); ok {
+        t.Errorf("got a source for a node of the source")
+    }
+    if _, ok := cm.SourceOf(*cloned); ok {
+        t.Errorf("got a source for a struct value")
+    }
+}
@@ -0,0 +1,38 @@
+//go:build go1.23
+
+package cloner
+
+import (
+    "iter"
+    "slices"
+)
+
+// CollectCloned returns clones of the elements of seq, in order, cloned as a
+// single graph like CloneAll: values referenced by several elements are
+// cloned once. A nil seq yields nil. Options apply as in Clone.
+func CollectCloned[T any](cm *CloneManager, seq iter.Seq[T], opts ...Option) ([]T, error) {
+    if seq == nil {
+        return nil, nil
+    }
+    return CloneAll(cm, slices.Collect(seq), opts...)
+}
+
+// ClonedSeq returns a sequence yielding a clone of each element of seq as it
+// is consumed, with the error of its clone, so that the elements handed to
+// the consumer are isolated from the source without materializing the
+// whole sequence. Unlike CollectCloned, each element is cloned on its own:
+// values referenced by several elements are cloned for each of them.
+// Options apply to every clone as in Clone.
+//
+//	for order, err := range cloner.ClonedSeq(cm, maps.Values(orders)) {
+//	    ...
+//	}
+func ClonedSeq[T any](cm *CloneManager, seq iter.Seq[T], opts ...Option) iter.Seq2[T, error] {
+    return func(yield func(T, error) bool) {
+        for v := range seq {
+            if !yield(Clone(cm, v, opts...)) {
+                return
+            }
+        }
+    }
+}
@@ -0,0 +1,48 @@
+//go:build go1.23
+
+package cloner_test
+
+import (
+    "errors"
+    "slices"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for cloning the elements of iterators
+func TestClonedSeq(t *testing.T) {
+    ann := &Person{Name: "ann"}
+    people := []*Person{ann, {Name: "bob"}, ann}
+    cm := cloner.NewCloneManager()
+
+    collected, err := cloner.CollectCloned(cm, slices.Values(people))
+    if err != nil || len(collected) != 3 || collected[0] == ann || collected[0] != collected[2] || collected[1].Name != "bob" {
+        t.Errorf("got %v and error %v, want clones of %v sharing ann", collected, err, people)
+    }
+
+    var names []string
+    var clones []*Person
+    for p, err := range cloner.ClonedSeq(cm, slices.Values(people)) {
+        if err != nil {
+            t.Fatalf("Clone failed: %v", err)
+        }
+        if p == people[len(clones)] {
+            t.Errorf("got the source %v, want a clone", p)
+        }
+        names = append(names, p.Name)
+        clones = append(clones, p)
+        if len(clones) == 2 {
+            break
+        }
+    }
+    deepEqual(t, names, []string{"ann", "bob"})
+
+    // Errors are yielded with the element
+    funcs := []func(){func() {}}
+    for _, err := range cloner.ClonedSeq(cm, slices.Values(funcs)) {
+        if !errors.Is(err, cloner.ErrUncloneableKind) {
+            t.Errorf("got error %v, want ErrUncloneableKind", err)
+        }
+    }
+}
@@ -0,0 +1,83 @@
+package cloner
+
+import (
+    "container/list"
+    "reflect"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+var (
+    listType    = reflect.TypeOf((*list.List)(nil))
+    elementType = reflect.TypeOf((*list.Element)(nil))
+)
+
+// cloneList clones the lists of container/list, whose links are unexported
+// and would be lost by cloning them field by field. A *list.List is cloned
+// into a new list holding clones of its values, and a *list.Element into the
+// corresponding element of the clone of its list, so that references to
+// elements, as kept by LRU caches, resolve to the cloned list. It reports
+// false for other values.
+func (cm *CloneManager) cloneList(src reflect.Value) (interface{}, bool, error) {
+    if t := src.Type(); t != listType && t != elementType {
+        return nil, false, nil
+    }
+    if src.IsNil() {
+        return nil, true, nil
+    }
+    if src.Type() == listType {
+        clone, err := cm.cloneElements(memref.Of(src), src.Interface().(*list.List).Front())
+        return clone, true, err
+    }
+
+    e := src.Interface().(*list.Element)
+    // The list of an element is only reachable through reflection
+    owner := src.Elem().FieldByName("list")
+    if owner.IsNil() {
+        // Elements removed from their list hold a value only
+        value, err := cm.cloneElementValue(e, 0)
+        return &list.Element{Value: value}, true, err
+    }
+    front := e
+    for front.Prev() != nil {
+        front = front.Prev()
+    }
+    if _, err := cm.cloneElements(memref.Of(owner), front); err != nil {
+        return nil, true, err
+    }
+    cloned, _ := cm.lookup(memref.Of(src))
+    return cloned, true, nil
+}
+
+// cloneElements clones the list identified by ref whose first element is
+// front. The clones of the list and of every element are recorded before the
+// values are cloned, so that values referring back to them resolve to the
+// clones.
+func (cm *CloneManager) cloneElements(ref memref.Ref, front *list.Element) (*list.List, error) {
+    clone := list.New()
+    cm.record(ref, clone)
+    var elements []*list.Element
+    for e := front; e != nil; e = e.Next() {
+        elements = append(elements, e)
+        if cm.options.mapping {
+            cm.noteSource(reflect.ValueOf(e))
+        }
+        cm.record(memref.Of(reflect.ValueOf(e)), clone.PushBack(nil))
+    }
+    for i, cloned := 0, clone.Front(); cloned != nil; i, cloned = i+1, cloned.Next() {
+        value, err := cm.cloneElementValue(elements[i], i)
+        if err != nil {
+            return nil, err
+        }
+        cloned.Value = value
+    }
+    UpdateStats(listType.String())
+    return clone, nil
+}
+
+// cloneElementValue clones the value of e, the element at index i.
+func (cm *CloneManager) cloneElementValue(e *list.Element, i int) (interface{}, error) {
+    cm.pushIndex(i)
+    defer cm.pop()
+    return cm.deepClone(reflect.ValueOf(e).Elem().FieldByName("Value"))
+}
@@ -0,0 +1,136 @@
+package cloner_test
+
+import (
+    "container/list"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type ListNode struct {
+    Value      int
+    Prev, Next *ListNode
+}
+
+// Links are the links of an intrusive list of T.
+type Links[T any] struct {
+    Prev, Next *T
+}
+
+type Task struct {
+    Links[Task]
+    Name  string
+    Owner *TaskQueue
+}
+
+type TaskQueue struct {
+    Head, Tail *Task
+}
+
+// Test for cloning a long doubly-linked list
+func TestCloneDoublyLinkedList(t *testing.T) {
+    const n = 10000
+    var head, tail *ListNode
+    for i := 0; i < n; i++ {
+        node := &ListNode{Value: i, Prev: tail}
+        if tail == nil {
+            head = node
+        } else {
+            tail.Next = node
+        }
+        tail = node
+    }
+
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), head)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    count := 0
+    for node, prev := cloned, (*ListNode)(nil); node != nil; prev, node = node, node.Next {
+        if node.Prev != prev || node.Value != count {
+            t.Fatalf("Cloned node %d is not linked to its predecessor", count)
+        }
+        count++
+    }
+    if count != n {
+        t.Errorf("got %d nodes, want %d", count, n)
+    }
+}
+
+// Test for cloning an intrusive list with back-pointers to its owner
+func TestCloneIntrusiveList(t *testing.T) {
+    queue := &TaskQueue{}
+    a := &Task{Name: "a", Owner: queue}
+    b := &Task{Name: "b", Owner: queue, Links: Links[Task]{Prev: a}}
+    a.Next = b
+    queue.Head, queue.Tail = a, b
+
+    // Start in the middle of the list
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), b)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    q := cloned.Owner
+    if q == queue || q.Tail != cloned || q.Head != cloned.Prev || q.Head.Next != cloned {
+        t.Errorf("Cloned tasks and queue are not linked to each other")
+    }
+    if q.Head.Owner != q {
+        t.Errorf("Cloned head does not point to the cloned queue")
+    }
+}
+
+// Test for cloning a graph where every node points to every node
+func TestCloneDenseGraph(t *testing.T) {
+    type Vertex struct {
+        ID    int
+        Edges []*Vertex
+    }
+    vertices := make([]*Vertex, 20)
+    for i := range vertices {
+        vertices[i] = &Vertex{ID: i}
+    }
+    for _, v := range vertices {
+        v.Edges = vertices
+    }
+
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), vertices)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    for i, v := range cloned {
+        if v == vertices[i] || v.ID != i || &v.Edges[0] != &cloned[0] {
+            t.Fatalf("Cloned vertex %d does not share the cloned edges", i)
+        }
+    }
+}
+
+// Test for cloning lists of container/list and references to their elements
+func TestCloneContainerList(t *testing.T) {
+    type LRU struct {
+        Index map[string]*list.Element
+        Order *list.List
+    }
+    original := LRU{Index: map[string]*list.Element{}, Order: list.New()}
+    for _, key := range []string{"a", "b", "c"} {
+        original.Index[key] = original.Order.PushBack(key)
+    }
+
+    cm := cloner.NewCloneManager(cloner.WithDeterministicOrder())
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Order == original.Order || cloned.Order.Len() != 3 {
+        t.Fatalf("got list of %d values, want a new list of 3", cloned.Order.Len())
+    }
+    for key, e := range cloned.Index {
+        if e == original.Index[key] || e.Value != key {
+            t.Errorf("Cloned element %q does not hold its value", key)
+        }
+    }
+    // Elements of the index belong to the cloned list
+    cloned.Order.MoveToFront(cloned.Index["c"])
+    if cloned.Order.Front().Value != "c" || original.Order.Front().Value != "a" {
+        t.Errorf("Cloned elements are not part of the cloned list")
+    }
+}
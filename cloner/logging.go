@@ -0,0 +1,71 @@
+package cloner
+
+import (
+    "context"
+    "log/slog"
+    "reflect"
+)
+
+// Verbosity says which events of a clone are logged by WithLogger.
+type Verbosity int
+
+const (
+    // LogErrors logs the failures of the clone with the path of the failing
+    // value, including the errors collected with CollectErrors.
+    LogErrors Verbosity = iota + 1
+    // LogSkips also logs the values left out of the clone or shared with the
+    // source by a policy: excluded paths, unexported fields holding data,
+    // channels and functions, and values over budget.
+    LogSkips
+    // LogCloners also logs the values cloned by a Cloneable, a Cloner or a
+    // type replacement, the default.
+    LogCloners
+    // LogValues also logs every value the clone descends into, with its type
+    // and path. It is the most verbose level and slows clones down.
+    LogValues
+)
+
+// WithLogger makes the manager log what its clones do to logger, at debug
+// level, so that it can be told why a clone is slow or lacks data: the
+// events are selected by WithLogVerbosity and carry the path and type of the
+// value concerned. Nothing is logged unless logger is enabled for
+// slog.LevelDebug.
+func WithLogger(logger *slog.Logger) Option {
+    return func(o *options) {
+        o.logger = logger
+    }
+}
+
+// WithLogVerbosity sets the events logged by WithLogger, LogCloners by
+// default.
+func WithLogVerbosity(v Verbosity) Option {
+    return func(o *options) {
+        o.verbosity = v
+    }
+}
+
+// logs reports whether events of verbosity v are logged.
+func (cm *CloneManager) logs(v Verbosity) bool {
+    logger := cm.options.logger
+    if logger == nil {
+        return false
+    }
+    verbosity := cm.options.verbosity
+    if verbosity == 0 {
+        verbosity = LogCloners
+    }
+    return v <= verbosity && logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+// log logs msg for src at the current path if events of verbosity v are
+// logged.
+func (cm *CloneManager) log(v Verbosity, msg string, src reflect.Value, attrs ...slog.Attr) {
+    if !cm.logs(v) {
+        return
+    }
+    attrs = append(attrs, slog.String("path", formatPath(cm.path)))
+    if src.IsValid() {
+        attrs = append(attrs, slog.String("type", src.Type().String()))
+    }
+    cm.options.logger.LogAttrs(context.Background(), slog.LevelDebug, msg, attrs...)
+}
@@ -0,0 +1,65 @@
+package cloner_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "log/slog"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// events clones src with a logger at verbosity v and returns the logged
+// events as "message path" strings.
+func events(t *testing.T, src interface{}, v cloner.Verbosity, opts ...cloner.Option) []string {
+    var buf bytes.Buffer
+    logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+    cm := cloner.NewCloneManager(cloner.CollectErrors(), cloner.WithFuncs(cloner.Zero))
+    cm.RegisterCloner(reflect.TypeOf(&Person{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        return &Person{Name: v.(*Person).Name}, nil
+    }))
+    opts = append(opts, cloner.WithLogger(logger), cloner.WithLogVerbosity(v))
+    cm.Clone(src, opts...)
+
+    var got []string
+    for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+        var event struct{ Msg, Path string }
+        if err := json.Unmarshal([]byte(line), &event); err != nil {
+            t.Fatalf("invalid log line %q: %v", line, err)
+        }
+        got = append(got, event.Msg+" "+event.Path)
+    }
+    return got
+}
+
+// Test for logging the events of a clone at each verbosity
+func TestWithLogger(t *testing.T) {
+    folder := &Folder{
+        Name:   "root",
+        Files:  []string{"a"},
+        Owner:  &Person{Name: "ann"},
+        Attrs:  map[string]interface{}{"hook": func() {}, "ch": make(chan int)},
+        secret: "s",
+    }
+    exclude := cloner.WithExcludePaths("Files")
+
+    deepEqual(t, events(t, folder, cloner.LogErrors, exclude), []string{
+        `clone failed .Attrs["ch"]`,
+    })
+    deepEqual(t, events(t, folder, cloner.LogSkips, exclude, cloner.WithDeterministicOrder()), []string{
+        `value excluded by path .Files`,
+        `clone failed .Attrs["ch"]`,
+        `value excluded by policy .Attrs["hook"]`,
+        `unexported field excluded .secret`,
+    })
+    deepEqual(t, events(t, folder, cloner.LogCloners, exclude, cloner.WithDeterministicOrder()), []string{
+        `value excluded by path .Files`,
+        `clone failed .Attrs["ch"]`,
+        `value excluded by policy .Attrs["hook"]`,
+        `Cloner called .Owner`,
+        `unexported field excluded .secret`,
+    })
+    deepEqual(t, events(t, []int{1}, cloner.LogValues), []string{"value entered "})
+}
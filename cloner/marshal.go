@@ -0,0 +1,71 @@
+package cloner
+
+import (
+    "encoding"
+    "reflect"
+)
+
+var (
+    binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+    binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+    textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+    textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// WithMarshalers makes the manager clone the values of types implementing
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler, or else
+// encoding.TextMarshaler and encoding.TextUnmarshaler, by unmarshaling the
+// result of marshaling them into a new value, so that third-party types
+// whose state is kept in unexported fields are cloned through their own
+// encoding. The methods may have value or pointer receivers. Cloners,
+// Cloneable implementations and the other options changing how a type is
+// cloned take precedence.
+func WithMarshalers() Option {
+    return func(o *options) {
+        o.marshalers = true
+    }
+}
+
+// marshals reports whether the values of t are cloned by cloneMarshaler,
+// and whether they are through their binary encoding.
+func (cm *CloneManager) marshals(t reflect.Type) (binary, ok bool) {
+    if !cm.options.marshalers || t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+        return false, false
+    }
+    ptr := reflect.PointerTo(t)
+    if ptr.Implements(binaryMarshalerType) && ptr.Implements(binaryUnmarshalerType) {
+        return true, true
+    }
+    return false, ptr.Implements(textMarshalerType) && ptr.Implements(textUnmarshalerType)
+}
+
+// cloneMarshaler clones src through its encoding if WithMarshalers applies
+// to it, reporting whether it does.
+func (cm *CloneManager) cloneMarshaler(src reflect.Value) (interface{}, bool, error) {
+    binary, ok := cm.marshals(src.Type())
+    if !ok || !src.CanInterface() {
+        return nil, false, nil
+    }
+    // Marshal a copy, so that methods with pointer receivers can be called
+    // on values that are not addressable
+    copied := reflect.New(src.Type())
+    copied.Elem().Set(src)
+    clone := reflect.New(src.Type())
+    var err error
+    if binary {
+        var data []byte
+        if data, err = copied.Interface().(encoding.BinaryMarshaler).MarshalBinary(); err == nil {
+            err = clone.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+        }
+    } else {
+        var text []byte
+        if text, err = copied.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+            err = clone.Interface().(encoding.TextUnmarshaler).UnmarshalText(text)
+        }
+    }
+    if err != nil {
+        return nil, true, err
+    }
+    cm.log(LogCloners, "value unmarshaled", src)
+    return clone.Elem().Interface(), true, nil
+}
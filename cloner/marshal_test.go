@@ -0,0 +1,75 @@
+package cloner_test
+
+import (
+    "errors"
+    "fmt"
+    "net/netip"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Version is a version number, kept unexported and written as text.
+type Version struct {
+    major, minor int
+}
+
+func (v Version) MarshalText() ([]byte, error) {
+    return []byte(fmt.Sprintf("%d.%d", v.major, v.minor)), nil
+}
+
+func (v *Version) UnmarshalText(text []byte) error {
+    if _, err := fmt.Sscanf(string(text), "%d.%d", &v.major, &v.minor); err != nil {
+        return errors.New("malformed version")
+    }
+    return nil
+}
+
+type Release struct {
+    Version Version
+    Mirrors []netip.Addr
+}
+
+// Test for cloning values through their binary and text encodings
+func TestWithMarshalers(t *testing.T) {
+    original := Release{
+        Version: Version{major: 1, minor: 2},
+        Mirrors: []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")},
+    }
+
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil || cloned.Version != (Version{}) {
+        t.Fatalf("got clone %+v and error %v, want the version lost without WithMarshalers", cloned, err)
+    }
+
+    cm := cloner.NewCloneManager(cloner.WithMarshalers(), cloner.WithUnexportedFields(cloner.Error))
+    if err := cm.Validate(original); err != nil {
+        t.Fatalf("Validate failed: %v", err)
+    }
+    cloned, err = cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Version != original.Version || len(cloned.Mirrors) != 2 ||
+        cloned.Mirrors[0] != original.Mirrors[0] || cloned.Mirrors[1] != original.Mirrors[1] {
+        t.Errorf("got clone %+v, want %+v", cloned, original)
+    }
+
+    // Values whose encoding cannot be read back are reported
+    _, err = cm.Clone(struct{ V interface{} }{V: badText{}})
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || cloneErr.Path != ".V" {
+        t.Errorf("got error %v, want the text of .V reported", err)
+    }
+}
+
+// badText cannot read the text it writes.
+type badText struct{}
+
+func (badText) MarshalText() ([]byte, error) {
+    return []byte("?"), nil
+}
+
+func (*badText) UnmarshalText([]byte) error {
+    return errors.New("unreadable")
+}
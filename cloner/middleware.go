@@ -0,0 +1,73 @@
+package cloner
+
+import "reflect"
+
+// CloneFunc clones the value src of a graph with the manager cm of the
+// clone in progress, see Use.
+type CloneFunc func(cm *CloneManager, src reflect.Value) (interface{}, error)
+
+// Middleware wraps a CloneFunc with behavior of its own, see Use.
+type Middleware func(next CloneFunc) CloneFunc
+
+// Use wraps the function cloning every value of the graphs cloned by cm
+// with middleware, so that cross-cutting concerns such as timing, caching,
+// redaction or access checks can be layered over the engine. Middleware
+// registered first is the outermost. A middleware calls next to clone src
+// as it would be otherwise, or returns a clone of its own, e.g.
+//
+//	cm.Use(func(next cloner.CloneFunc) cloner.CloneFunc {
+//	    return func(cm *cloner.CloneManager, src reflect.Value) (interface{}, error) {
+//	        if src.Type() == secretType {
+//	            return nil, nil
+//	        }
+//	        return next(cm, src)
+//	    }
+//	})
+//
+// next clones the values referenced by src through the middleware too. Every
+// value is cloned through it, so small values are no longer copied in place
+// and clones are slower. Middleware of a manager applies to the managers
+// derived from it with WithOverrides, around their own. Use must not be
+// called concurrently with Clone.
+func (cm *CloneManager) Use(middleware ...Middleware) {
+    cm.middleware = append(cm.middleware, middleware...)
+}
+
+// Path returns the path of the value being cloned by cm, for use by
+// middleware, Cloners and Cloneable implementations. It is empty outside
+// of a clone.
+func (cm *CloneManager) Path() Path {
+    return Path(formatPath(cm.path))
+}
+
+// Steps returns the path of the value being cloned by cm as steps, holding
+// the map keys themselves rather than their Go literals.
+func (cm *CloneManager) Steps() []Step {
+    return exportedSteps(cm.path)
+}
+
+// chainOf returns the CloneFunc of the middleware of cm and the managers it
+// derives from, or nil if there is none.
+func chainOf(cm *CloneManager) CloneFunc {
+    var chain CloneFunc
+    for m := cm; m != nil; m = m.parent {
+        for i := len(m.middleware) - 1; i >= 0; i-- {
+            if chain == nil {
+                chain = (*CloneManager).cloneValue
+            }
+            chain = m.middleware[i](chain)
+        }
+    }
+    return chain
+}
+
+// hasMiddleware reports whether middleware is registered with cm or the
+// managers it derives from.
+func (cm *CloneManager) hasMiddleware() bool {
+    for m := cm; m != nil; m = m.parent {
+        if len(m.middleware) > 0 {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,66 @@
+package cloner_test
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for layering middleware over the clone of every value
+func TestUse(t *testing.T) {
+    var calls []string
+    trace := func(name string) cloner.Middleware {
+        return func(next cloner.CloneFunc) cloner.CloneFunc {
+            return func(cm *cloner.CloneManager, src reflect.Value) (interface{}, error) {
+                calls = append(calls, name+" "+string(cm.Path()))
+                return next(cm, src)
+            }
+        }
+    }
+    redact := func(next cloner.CloneFunc) cloner.CloneFunc {
+        return func(cm *cloner.CloneManager, src reflect.Value) (interface{}, error) {
+            if src.Kind() == reflect.String && strings.HasSuffix(string(cm.Path()), ".Name") {
+                return "***", nil
+            }
+            return next(cm, src)
+        }
+    }
+
+    cm := cloner.NewCloneManager()
+    cm.Use(trace("outer"), redact)
+    cm.Use(trace("inner"))
+    cloned, err := cloner.Clone(cm, Person{Name: "ann"})
+    if err != nil || cloned.Name != "***" {
+        t.Fatalf("got clone %+v and error %v, want the name redacted", cloned, err)
+    }
+    deepEqual(t, calls, []string{"outer ", "inner ", "outer .Name"})
+
+    // Middleware of a base applies around that of derived managers
+    calls = nil
+    child := cm.WithOverrides()
+    child.Use(trace("child"))
+    if _, err := cloner.Clone(child, Vec3{}); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if len(calls) != 15 || calls[0] != "outer " || calls[1] != "inner " || calls[2] != "child " {
+        t.Errorf("got calls %q, want the base middleware first", calls)
+    }
+
+    // Middleware can refuse to clone values
+    deny := cloner.NewCloneManager()
+    deny.Use(func(next cloner.CloneFunc) cloner.CloneFunc {
+        return func(cm *cloner.CloneManager, src reflect.Value) (interface{}, error) {
+            if src.Type() == reflect.TypeOf(Person{}) {
+                return nil, errors.New("access denied")
+            }
+            return next(cm, src)
+        }
+    })
+    var cloneErr *cloner.CloneError
+    if _, err := deny.Clone([]*Person{{}}); !errors.As(err, &cloneErr) || cloneErr.Path != "[0]" {
+        t.Errorf("got error %v, want access denied at [0]", err)
+    }
+}
@@ -0,0 +1,367 @@
+package cloner
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "reflect"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Option configures a CloneManager.
+type Option func(*options)
+
+// options holds the configuration shared by a CloneManager and the clones it
+// makes.
+type options struct {
+    collectErrors      bool
+    deterministicOrder bool
+    maxDepth           int
+    maxNodes           int
+    maxBytes           int64
+    overBudget         Policy
+    timeout            time.Duration
+    progress           func(nodes int, path Path)
+    progressInterval   int
+    funcs              Policy
+    chans              Policy
+    timers             Policy
+    timerRestart       func(interface{}) (time.Duration, bool)
+    unexported         Policy
+    transfer           bool
+    valuers            bool
+    marshalers         bool
+    identity           Identity
+    identityTable      IdentityTable
+    seed               map[memref.Ref]interface{}
+    cloneAmbient       bool
+    logger             *slog.Logger
+    verbosity          Verbosity
+    trace              func(Subtree)
+    traceThreshold     time.Duration
+    profileContext     context.Context
+    typeStats          bool
+    statsWindow        time.Duration
+    onClone            func(Report)
+    record             func(src, clone interface{})
+    provenance         bool
+    mapping            bool
+    applyBack          bool
+    tagNames           []string
+    fieldNames         string
+    sampling           int
+    allocator          func(reflect.Type) reflect.Value
+    replacements       map[reflect.Type]replacement
+    replace            func(Path, interface{}) (interface{}, bool)
+    guard              func(reflect.Type, Path) error
+    excludePaths       []pathPattern
+    sharedPaths        []pathPattern
+    sharedBytes        map[reflect.Type]bool
+}
+
+// replacement is a type substitution configured with WithTypeReplacement.
+type replacement struct {
+    newType   reflect.Type
+    converter func(interface{}) (interface{}, error)
+}
+
+// Policy says how values that cannot be deep cloned are handled.
+type Policy int
+
+const (
+    // Error fails the clone with an error.
+    Error Policy = iota
+    // Share copies the value itself, so the clone shares it with the source.
+    Share
+    // Zero leaves the value at its zero value in the clone.
+    Zero
+)
+
+// CollectErrors makes the manager finish a clone when parts of the value
+// cannot be cloned instead of aborting at the first failure. The failing parts
+// are left at their zero value, map entries whose key cannot be cloned are
+// dropped, and Clone returns the partial clone together with every error,
+// prefixed with the path of the failing value and joined with errors.Join.
+func CollectErrors() Option {
+    return func(o *options) {
+        o.collectErrors = true
+    }
+}
+
+// WithDeterministicOrder makes the manager visit map entries in a stable
+// order instead of Go's randomized map iteration order, so that errors,
+// custom cloners and other callbacks see the entries in the same order on
+// every run. Keys are sorted by value: numbers, strings and booleans
+// naturally, structs and arrays element by element, pointers by the values
+// they point to and other fmt.Stringer implementations by their string.
+// Sorting costs O(n log n) per map.
+func WithDeterministicOrder() Option {
+    return func(o *options) {
+        o.deterministicOrder = true
+    }
+}
+
+// WithMaxDepth limits how deeply nested the cloned values can be. The root
+// value has depth 0 and every struct field, element, map key or value,
+// pointer target and interface value is one level deeper than its parent.
+// Deeper values are reported as ErrDepthExceeded. Zero means no limit.
+func WithMaxDepth(n int) Option {
+    return func(o *options) {
+        o.maxDepth = n
+    }
+}
+
+// WithMaxNodes limits the number of values a single clone visits, protecting
+// against unexpectedly large graphs. A clone visiting more values fails with
+// ErrBudgetExceeded. Zero means no limit.
+func WithMaxNodes(n int) Option {
+    return func(o *options) {
+        o.maxNodes = n
+    }
+}
+
+// WithMaxBytes limits the memory a clone may allocate to about n bytes. The
+// memory is estimated from the sizes of the values that pointers, slices and
+// maps of the clone hold; strings are shared with the source and not
+// counted. By default, a clone exceeding the limit is aborted, even with
+// CollectErrors, with ErrBudgetExceeded for the value reached; see
+// WithOverBudget. Zero means no limit.
+func WithMaxBytes(n int64) Option {
+    return func(o *options) {
+        o.maxBytes = n
+    }
+}
+
+// WithOverBudget sets how a clone handles the pointers, slices and maps
+// that would exceed WithMaxBytes: Error aborts the clone, the default, Share
+// copies the references, so that the clone shares the rest of the graph
+// with the source instead of failing, and Zero leaves them nil.
+func WithOverBudget(p Policy) Option {
+    return func(o *options) {
+        o.overBudget = p
+    }
+}
+
+// WithTimeout limits the time a clone may take to d, measured from the
+// start of the call to Clone. A clone exceeding it is aborted, even with
+// CollectErrors, with ErrTimeout for the value reached. The clock is read
+// every few values, so the clone of a single value that takes longer, such
+// as one made by a slow Cloner, is not interrupted. Zero means no limit.
+func WithTimeout(d time.Duration) Option {
+    return func(o *options) {
+        o.timeout = d
+    }
+}
+
+// WithProgress makes the manager call fn every 10000 values of a clone, or
+// as often as set by WithProgressInterval, with the number of values cloned
+// so far and the path of the value being cloned, so that long clones can
+// report their progress. fn is called by the goroutine making the clone.
+func WithProgress(fn func(nodes int, path Path)) Option {
+    return func(o *options) {
+        o.progress = fn
+    }
+}
+
+// WithProgressInterval sets the number of values cloned between calls to the
+// WithProgress callback.
+func WithProgressInterval(n int) Option {
+    return func(o *options) {
+        o.progressInterval = n
+    }
+}
+
+// WithFuncs sets the policy for non-nil functions, which are reported as
+// ErrUncloneableKind by default. Share is safe for functions that do not
+// capture mutable state.
+func WithFuncs(p Policy) Option {
+    return func(o *options) {
+        o.funcs = p
+    }
+}
+
+// WithChans sets the policy for non-nil channels, which are reported as
+// ErrUncloneableKind by default.
+func WithChans(p Policy) Option {
+    return func(o *options) {
+        o.chans = p
+    }
+}
+
+// WithUnexportedFields sets the policy for unexported struct fields, which
+// reflection cannot set and are left at their zero value by default. Error
+// reports fields holding data as ErrUnexportedField; Share copies them as
+// they are, so pointers, slices and maps they hold are shared with the
+// source.
+func WithUnexportedFields(p Policy) Option {
+    return func(o *options) {
+        o.unexported = p
+    }
+}
+
+// Identity says which values of the clone are shared with each other.
+type Identity int
+
+const (
+    // Exact gives the clone the topology of the source: pointers are shared
+    // in the clone exactly where they are shared in the source.
+    Exact Identity = iota
+    // ValueDedup collapses pointers to equal booleans, numbers and strings,
+    // so that the clone holds a single copy of each such value, like an
+    // interning table. The clone has fewer allocations but writing through
+    // one of these pointers changes the value seen through the others.
+    // Floats are equal if they have the same bits, so that -0 and +0 stay
+    // distinct and NaNs can be collapsed.
+    ValueDedup
+)
+
+// WithIdentity sets which values of the clone are shared with each other,
+// Exact by default.
+func WithIdentity(i Identity) Option {
+    return func(o *options) {
+        o.identity = i
+    }
+}
+
+// WithTransfer makes the manager move resources held by struct fields to
+// the clone instead of cloning them: fields holding an io.Closer or a
+// Transferable are copied as they are and cleared in the source, so that
+// exactly one of the graphs owns the resource and it cannot be closed
+// twice. Other references to a moved pointer in the graph resolve to it.
+// The source must be reachable through pointers for its fields to be
+// cleared, e.g. Clone(&src); resources the manager cannot clear, like
+// fields of a struct passed by value, are reported as ErrNotTransferable.
+func WithTransfer() Option {
+    return func(o *options) {
+        o.transfer = true
+    }
+}
+
+// WithAllocator makes the manager obtain the values that cloned pointers and
+// ClonePtr results point to from alloc instead of reflect.New, for example to
+// take them from an object pool or an arena. alloc is called with the type of
+// the pointed value and returns a pointer to a zero value of that type, or an
+// invalid reflect.Value to let the manager allocate it. A pointer of another
+// type is reported as ErrTypeMismatch.
+func WithAllocator(alloc func(reflect.Type) reflect.Value) Option {
+    return func(o *options) {
+        o.allocator = alloc
+    }
+}
+
+// WithTypeReplacement makes the manager replace every value of type oldType
+// with the value of type newType returned by converter, for example to swap a
+// client for a fake in test fixtures while the rest of the graph is copied
+// normally. converter receives the original value and its result is used
+// as-is, without being cloned. References to the same pointer are converted
+// once. The replacement must be assignable to the places the original value
+// is stored in, such as interface fields; a result of another type than
+// newType, or one that cannot be stored, is reported as ErrTypeMismatch.
+func WithTypeReplacement(oldType, newType reflect.Type, converter func(interface{}) (interface{}, error)) Option {
+    return func(o *options) {
+        replacements := make(map[reflect.Type]replacement, len(o.replacements)+1)
+        for t, r := range o.replacements {
+            replacements[t] = r
+        }
+        replacements[oldType] = replacement{newType: newType, converter: converter}
+        o.replacements = replacements
+    }
+}
+
+// WithReplace makes the manager call replace for every value of the graph
+// with its path before cloning it. If replace returns true, its result is
+// used as the clone of the value, as-is, for example to swap a shared
+// singleton for another or to null out an environment-specific handle with
+// nil. The result must be assignable to the place the value is stored in,
+// otherwise ErrTypeMismatch is reported. Formatting the path of every value
+// makes clones slower; WithTypeReplacement is cheaper for substitutions
+// that only depend on the type.
+func WithReplace(replace func(path Path, v interface{}) (interface{}, bool)) Option {
+    return func(o *options) {
+        o.replace = replace
+    }
+}
+
+// WithGuard makes the manager call guard with the type and the path of
+// every value of the graph before cloning it, failing the clone with the
+// error guard returns, if any, so that multi-tenant systems can refuse to
+// materialize copies of values the caller may not access. Values excluded
+// or shared by WithExcludePaths and WithSharedPaths are not cloned and
+// not guarded. Formatting the path of every value makes clones slower.
+func WithGuard(guard func(t reflect.Type, path Path) error) Option {
+    return func(o *options) {
+        o.guard = guard
+    }
+}
+
+// WithExcludePaths makes the manager leave the values whose path matches one
+// of patterns at their zero value in the clone, without visiting them; map
+// entries are left out of the cloned map. A pattern is a path like
+// Cache.Entries or .Items[2].Name, where * matches any struct field and [*]
+// any slice or array index or map key, as in Items[*].Secret. Map keys are
+// written as Go literals, e.g. Labels["env"]. A pattern matches values at
+// exactly its depth; the parts of an excluded value are excluded with it.
+// WithExcludePaths panics if a pattern is malformed.
+func WithExcludePaths(patterns ...string) Option {
+    compiled := mustCompilePatterns(patterns)
+    return func(o *options) {
+        o.excludePaths = append(o.excludePaths[:len(o.excludePaths):len(o.excludePaths)], compiled...)
+    }
+}
+
+// WithSharedPaths is like WithExcludePaths but the matching values are
+// copied as they are, so that the clone shares them with the source. Values
+// matching both are excluded.
+func WithSharedPaths(patterns ...string) Option {
+    compiled := mustCompilePatterns(patterns)
+    return func(o *options) {
+        o.sharedPaths = append(o.sharedPaths[:len(o.sharedPaths):len(o.sharedPaths)], compiled...)
+    }
+}
+
+// WithSharedBytes makes the manager copy the byte slices of types, such as
+// json.RawMessage, as they are, so that the clone shares them with the
+// source, for payloads documented as immutable that would be expensive to
+// copy. Other byte slices are copied, keeping nil slices nil.
+// WithSharedBytes panics if a type is not a slice of bytes.
+//
+//	cloner.WithSharedBytes(reflect.TypeOf(json.RawMessage(nil)))
+func WithSharedBytes(types ...reflect.Type) Option {
+    for _, t := range types {
+        if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8 {
+            panic(fmt.Sprintf("cloner: WithSharedBytes of %v, want a byte slice type", t))
+        }
+    }
+    return func(o *options) {
+        shared := make(map[reflect.Type]bool, len(o.sharedBytes)+len(types))
+        for t := range o.sharedBytes {
+            shared[t] = true
+        }
+        for _, t := range types {
+            shared[t] = true
+        }
+        o.sharedBytes = shared
+    }
+}
+
+func mustCompilePatterns(patterns []string) []pathPattern {
+    compiled, err := compilePatterns(patterns)
+    if err != nil {
+        panic(err)
+    }
+    return compiled
+}
+
+func compilePatterns(patterns []string) ([]pathPattern, error) {
+    compiled := make([]pathPattern, len(patterns))
+    for i, pattern := range patterns {
+        p, err := compilePattern(pattern)
+        if err != nil {
+            return nil, err
+        }
+        compiled[i] = p
+    }
+    return compiled, nil
+}
@@ -0,0 +1,777 @@
+package cloner_test
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Job struct {
+    Name     string
+    Done     chan bool
+    Steps    []Step
+    Handlers map[string]func()
+}
+
+type Step struct {
+    ID    int
+    Abort func()
+}
+
+// Test for collecting every error instead of stopping at the first one
+func TestCollectErrors(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.CollectErrors())
+
+    original := Job{
+        Name:     "build",
+        Done:     make(chan bool),
+        Steps:    []Step{{ID: 1}, {ID: 2, Abort: func() {}}},
+        Handlers: map[string]func(){"start": func() {}},
+    }
+
+    cloned, err := cloner.Clone(cm, original)
+    if err == nil {
+        t.Fatalf("Clone did not report the uncloneable fields")
+    }
+
+    // Every failing path is reported
+    for _, want := range []string{
+        ".Done (chan bool): uncloneable kind: channels cannot be cloned",
+        ".Steps[1].Abort (func()): uncloneable kind: functions cannot be cloned",
+        `.Handlers["start"] (func()): uncloneable kind: functions cannot be cloned`,
+    } {
+        if !strings.Contains(err.Error(), want) {
+            t.Errorf("Error %q does not contain %q", err, want)
+        }
+    }
+
+    if strings.Contains(err.Error(), ".Steps[0]") {
+        t.Errorf("Nil functions should be cloned without error: %v", err)
+    }
+
+    // The rest of the value is cloned
+    if cloned.Name != "build" || len(cloned.Steps) != 2 || cloned.Steps[1].ID != 2 {
+        t.Errorf("Partial clone is incorrect: %+v", cloned)
+    }
+    if cloned.Done != nil || cloned.Steps[1].Abort != nil || cloned.Handlers["start"] != nil {
+        t.Errorf("Uncloneable fields should be left at their zero value: %+v", cloned)
+    }
+}
+
+// Test that the first error aborts the clone by default
+func TestCloneStopsAtFirstError(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    _, err := cm.Clone(Job{Done: make(chan bool), Steps: []Step{{Abort: func() {}}}})
+    if err == nil {
+        t.Fatalf("Clone did not report the uncloneable field")
+    }
+    if strings.Contains(err.Error(), "functions") {
+        t.Errorf("Clone did not stop at the first error: %v", err)
+    }
+}
+
+// Test for the function and channel policies
+func TestFuncAndChanPolicies(t *testing.T) {
+    original := Job{
+        Name:  "build",
+        Done:  make(chan bool),
+        Steps: []Step{{ID: 1, Abort: func() {}}},
+    }
+
+    cm := cloner.NewCloneManager(cloner.WithFuncs(cloner.Share), cloner.WithChans(cloner.Zero))
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Done != nil {
+        t.Errorf("Channel should be left at its zero value")
+    }
+    if cloned.Steps[0].Abort == nil {
+        t.Errorf("Function should be shared with the source")
+    }
+}
+
+type Account struct {
+    Name    string
+    balance *int
+}
+
+// Test for the unexported field policies
+func TestUnexportedFieldPolicies(t *testing.T) {
+    balance := 10
+    original := Account{Name: "a", balance: &balance}
+
+    // Unexported fields are left at their zero value by default
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Name != "a" || cloned.balance != nil {
+        t.Errorf("got = %+v, want only the exported fields", cloned)
+    }
+
+    cloned, err = cloner.Clone(cloner.NewCloneManager(cloner.WithUnexportedFields(cloner.Share)), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Name != "a" || cloned.balance != &balance {
+        t.Errorf("got = %+v, want the unexported fields shared", cloned)
+    }
+
+    _, err = cloner.Clone(cloner.NewCloneManager(cloner.WithUnexportedFields(cloner.Error)), original)
+    if !errors.Is(err, cloner.ErrUnexportedField) {
+        t.Errorf("got error %v, want ErrUnexportedField", err)
+    }
+
+    // Zero unexported fields lose no data
+    _, err = cloner.Clone(cloner.NewCloneManager(cloner.WithUnexportedFields(cloner.Error)), Account{Name: "b"})
+    if err != nil {
+        t.Errorf("Clone failed: %v", err)
+    }
+}
+
+type Chain struct {
+    Next *Chain
+}
+
+// Test for limiting the depth and size of a clone
+func TestCloneLimits(t *testing.T) {
+    original := &Chain{Next: &Chain{Next: &Chain{}}}
+
+    // Pointers and the structs they point to are separate levels, down to
+    // the nil .Next of the last link at depth 6
+    if _, err := cloner.NewCloneManager(cloner.WithMaxDepth(6)).Clone(original); err != nil {
+        t.Errorf("Clone failed: %v", err)
+    }
+    _, err := cloner.NewCloneManager(cloner.WithMaxDepth(5)).Clone(original)
+    if !errors.Is(err, cloner.ErrDepthExceeded) {
+        t.Errorf("got error %v, want ErrDepthExceeded", err)
+    }
+
+    if _, err := cloner.NewCloneManager(cloner.WithMaxNodes(7)).Clone(original); err != nil {
+        t.Errorf("Clone failed: %v", err)
+    }
+    _, err = cloner.NewCloneManager(cloner.WithMaxNodes(6), cloner.CollectErrors()).Clone(original)
+    if !errors.Is(err, cloner.ErrBudgetExceeded) {
+        t.Errorf("got error %v, want ErrBudgetExceeded", err)
+    }
+}
+
+// Test for allocating cloned pointer targets with a custom allocator
+func TestWithAllocator(t *testing.T) {
+    // A pool of preallocated links
+    pool := []*Chain{{}, {}}
+    allocated := 0
+    cm := cloner.NewCloneManager(cloner.WithAllocator(func(t reflect.Type) reflect.Value {
+        if t != reflect.TypeOf(Chain{}) || allocated == len(pool) {
+            return reflect.Value{}
+        }
+        allocated++
+        return reflect.ValueOf(pool[allocated-1])
+    }))
+
+    original := &Chain{Next: &Chain{Next: &Chain{}}}
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned != pool[0] || cloned.Next != pool[1] {
+        t.Errorf("Clone did not use the pooled links")
+    }
+    // Once the pool is exhausted, links are allocated by the manager
+    if cloned.Next.Next == nil || cloned.Next.Next == original.Next.Next {
+        t.Errorf("Clone did not allocate the last link")
+    }
+
+    ptr, err := cm.ClonePtr(Step{ID: 1})
+    if err != nil || ptr.(*Step).ID != 1 {
+        t.Errorf("got %v, %v, want a clone of the step", ptr, err)
+    }
+
+    // Allocators returning the wrong type are reported
+    cm = cloner.NewCloneManager(cloner.WithAllocator(func(t reflect.Type) reflect.Value {
+        return reflect.ValueOf(new(int))
+    }))
+    _, err = cm.Clone(original)
+    if !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Errorf("got error %v, want ErrTypeMismatch", err)
+    }
+}
+
+type Client interface {
+    Get(key string) string
+}
+
+type realClient struct {
+    addr string
+}
+
+func (c *realClient) Get(key string) string {
+    return c.addr + "/" + key
+}
+
+type fakeClient struct {
+    data map[string]string
+}
+
+func (c *fakeClient) Get(key string) string {
+    return c.data[key]
+}
+
+type Service struct {
+    Name    string
+    Primary Client
+    Backup  Client
+}
+
+// Test for replacing values of a type during the clone
+func TestWithTypeReplacement(t *testing.T) {
+    converted := 0
+    cm := cloner.NewCloneManager(cloner.WithTypeReplacement(
+        reflect.TypeOf(&realClient{}), reflect.TypeOf(&fakeClient{}),
+        func(v interface{}) (interface{}, error) {
+            converted++
+            return &fakeClient{data: map[string]string{"k": "fake " + v.(*realClient).addr}}, nil
+        }))
+
+    client := &realClient{addr: "db"}
+    original := []Service{{Name: "a", Primary: client, Backup: client}}
+
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if got := cloned[0].Primary.Get("k"); got != "fake db" {
+        t.Errorf("Cloned client is not the fake: got %q", got)
+    }
+    if cloned[0].Primary != cloned[0].Backup || converted != 1 {
+        t.Errorf("Shared client was converted %d times", converted)
+    }
+    if cloned[0].Name != "a" || original[0].Primary != client {
+        t.Errorf("Clone is incorrect: got %+v, original %+v", cloned, original)
+    }
+
+    // Replacements that cannot be stored in place of the original are reported
+    _, err = cloner.Clone(cm, struct{ Client *realClient }{client})
+    if !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Errorf("got error %v, want ErrTypeMismatch", err)
+    }
+
+    // Converters returning another type are reported
+    cm = cloner.NewCloneManager(cloner.WithTypeReplacement(
+        reflect.TypeOf(&realClient{}), reflect.TypeOf(&fakeClient{}),
+        func(v interface{}) (interface{}, error) {
+            return v, nil
+        }))
+    _, err = cloner.Clone(cm, original)
+    if !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Errorf("got error %v, want ErrTypeMismatch", err)
+    }
+}
+
+// Test for substituting values by path and value
+func TestWithReplace(t *testing.T) {
+    defaultClient := &realClient{addr: "default"}
+    testClient := &realClient{addr: "test"}
+    var paths []cloner.Path
+    cm := cloner.NewCloneManager(cloner.WithReplace(func(path cloner.Path, v interface{}) (interface{}, bool) {
+        switch {
+        case v == defaultClient:
+            paths = append(paths, path)
+            return testClient, true
+        case path == ".Job.Steps[0].Abort":
+            return nil, true
+        }
+        return nil, false
+    }), cloner.WithUnexportedFields(cloner.Share))
+
+    original := struct {
+        Services []Service
+        Job      Job
+    }{
+        Services: []Service{{Primary: defaultClient, Backup: &realClient{addr: "backup"}}},
+        Job:      Job{Name: "a", Steps: []Step{{ID: 1, Abort: func() {}}}},
+    }
+
+    if err := cm.Validate(original); err != nil {
+        t.Fatalf("Validate failed: %v", err)
+    }
+    paths = nil
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    if cloned.Services[0].Primary != testClient {
+        t.Errorf("Cloned client was not replaced: got %v", cloned.Services[0].Primary)
+    }
+    if backup := cloned.Services[0].Backup; backup == original.Services[0].Backup || backup.Get("k") != "backup/k" {
+        t.Errorf("Cloned backup client is incorrect: got %v", backup)
+    }
+    if cloned.Job.Steps[0].Abort != nil || cloned.Job.Steps[0].ID != 1 {
+        t.Errorf("Cloned step is incorrect: got %+v", cloned.Job.Steps[0])
+    }
+    if len(paths) != 1 || paths[0] != ".Services[0].Primary" {
+        t.Errorf("Replaced paths are incorrect: got %q", paths)
+    }
+}
+
+type Cache struct {
+    Entries map[string][]byte
+    Hits    int
+}
+
+type Inventory struct {
+    Name  string
+    Cache *Cache
+    Items []Item
+    Index map[string]*Item
+}
+
+type Item struct {
+    SKU    string
+    Secret string
+    Hook   func()
+}
+
+// Test for excluding and sharing values by path pattern
+func TestWithExcludePaths(t *testing.T) {
+    item := &Item{SKU: "b", Secret: "t"}
+    original := Inventory{
+        Name:  "main",
+        Cache: &Cache{Entries: map[string][]byte{"a": {1}}, Hits: 3},
+        Items: []Item{{SKU: "a", Secret: "s", Hook: func() {}}},
+        Index: map[string]*Item{"a": item, "b": item},
+    }
+
+    cm := cloner.NewCloneManager(
+        cloner.WithExcludePaths("Cache.*", "Items[*].Secret", `.Index["a"]`),
+        cloner.WithSharedPaths("Items[*].Hook", "Index[*]"),
+    )
+    if err := cm.Validate(original); err != nil {
+        t.Fatalf("Validate failed: %v", err)
+    }
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    if cloned.Cache == original.Cache || cloned.Cache.Entries != nil || cloned.Cache.Hits != 0 {
+        t.Errorf("Cloned cache is incorrect: got %+v", cloned.Cache)
+    }
+    if cloned.Items[0].SKU != "a" || cloned.Items[0].Secret != "" || cloned.Items[0].Hook == nil {
+        t.Errorf("Cloned item is incorrect: got %+v", cloned.Items[0])
+    }
+    if _, ok := cloned.Index["a"]; ok || cloned.Index["b"] != item {
+        t.Errorf("Cloned index is incorrect: got %v", cloned.Index)
+    }
+    if cloned.Name != "main" {
+        t.Errorf("Cloned name is incorrect: got %q", cloned.Name)
+    }
+
+    for _, pattern := range []string{"", ".", "Items[", "Items..Name", "Items.[0]", "Items[]"} {
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("WithExcludePaths(%q) did not panic", pattern)
+                }
+            }()
+            cloner.WithExcludePaths(pattern)
+        }()
+    }
+}
+
+// Test for path patterns with keys holding brackets
+func TestPathPatternKeys(t *testing.T) {
+    type pod struct {
+        Labels map[string]string
+    }
+    original := pod{Labels: map[string]string{"a]b": "x", "a": "y", "[c]": "z"}}
+    cm := cloner.NewCloneManager(cloner.WithExcludePaths(`Labels["a]b"]`, `Labels["[c]"]`))
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if !reflect.DeepEqual(cloned.Labels, map[string]string{"a": "y"}) {
+        t.Errorf("got labels %v, want the keys holding brackets excluded", cloned.Labels)
+    }
+
+    other := pod{Labels: map[string]string{"a]b": "w", "a": "y", "[c]": "z"}}
+    if !cloner.Equal(original, other, cloner.IgnorePaths(`.Labels["a]b"]`)) {
+        t.Errorf("Equal compared an ignored key holding a bracket")
+    }
+
+    for _, pattern := range []string{`Labels["a]`, `Labels["a]b"`, "Labels[(]"} {
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("WithExcludePaths(%q) did not panic", pattern)
+                }
+            }()
+            cloner.WithExcludePaths(pattern)
+        }()
+    }
+}
+
+// Test for options given to a single clone
+func TestPerCallOptions(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    original := Step{ID: 1, Abort: func() {}}
+
+    // The options apply to the call only
+    cloned, err := cloner.Clone(cm, original, cloner.WithFuncs(cloner.Zero))
+    if err != nil || cloned.ID != 1 || cloned.Abort != nil {
+        t.Errorf("got %+v, %v, want a clone without Abort", cloned, err)
+    }
+    if _, err := cm.Clone(original); !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUncloneableKind", err)
+    }
+    if err := cm.Validate(original, cloner.WithFuncs(cloner.Share)); err != nil {
+        t.Errorf("Validate failed: %v", err)
+    }
+    chain := &Chain{Next: &Chain{Next: &Chain{}}}
+    if _, err := cm.Clone(chain, cloner.WithMaxDepth(3), cloner.WithFuncs(cloner.Share)); !errors.Is(err, cloner.ErrDepthExceeded) {
+        t.Errorf("got error %v, want ErrDepthExceeded", err)
+    }
+
+    // Options given by a Cloner apply to the part of the graph it clones
+    cm.RegisterCloner(reflect.TypeOf(Job{}), cloner.ClonerFunc(func(v interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        job := v.(Job)
+        steps, err := manager.Clone(job.Steps, cloner.WithFuncs(cloner.Share))
+        if err != nil {
+            return nil, err
+        }
+        handlers, err := manager.Clone(job.Handlers)
+        if err != nil {
+            return nil, err
+        }
+        return Job{Steps: steps.([]Step), Handlers: handlers.(map[string]func())}, nil
+    }))
+    _, err = cm.Clone(Job{Steps: []Step{original}, Handlers: map[string]func(){"a": func() {}}})
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || cloneErr.Path != `["a"]` {
+        t.Errorf("got error %v, want an error for [\"a\"]", err)
+    }
+}
+
+// Test for aborting clones that take too long
+func TestWithTimeout(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithTimeout(20*time.Millisecond), cloner.CollectErrors())
+    cm.RegisterCloner(reflect.TypeOf(Step{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        time.Sleep(time.Millisecond)
+        return v, nil
+    }))
+
+    steps := make([]Step, 1000)
+    _, err := cm.Clone(Job{Steps: steps})
+    if !errors.Is(err, cloner.ErrTimeout) {
+        t.Fatalf("got error %v, want ErrTimeout", err)
+    }
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || !strings.HasPrefix(cloneErr.Path, ".Steps[") {
+        t.Errorf("got error %v, want an error for a step", err)
+    }
+
+    // Clones within the budget succeed
+    if _, err := cm.Clone(Job{Steps: steps[:5]}); err != nil {
+        t.Errorf("Clone failed: %v", err)
+    }
+    if _, err := cloner.NewCloneManager().Clone(Job{Steps: steps}, cloner.WithTimeout(time.Minute)); err != nil {
+        t.Errorf("Clone failed: %v", err)
+    }
+}
+
+
+// Test for aborting or sharing clones that exceed the memory budget
+func TestWithMaxBytes(t *testing.T) {
+    type Buffers struct {
+        Small []int64
+        Large []int64
+    }
+    original := Buffers{Small: make([]int64, 4), Large: make([]int64, 1000)}
+
+    cm := cloner.NewCloneManager(cloner.WithMaxBytes(1024), cloner.CollectErrors())
+    _, err := cm.Clone(original)
+    if !errors.Is(err, cloner.ErrBudgetExceeded) {
+        t.Fatalf("got error %v, want ErrBudgetExceeded", err)
+    }
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || cloneErr.Path != ".Large" {
+        t.Errorf("got error %v, want an error at .Large", err)
+    }
+    if err := cm.Validate(original); !errors.Is(err, cloner.ErrBudgetExceeded) {
+        t.Errorf("got validation error %v, want ErrBudgetExceeded", err)
+    }
+
+    // Values over the budget are shared instead
+    cloned, err := cm.Clone(original, cloner.WithOverBudget(cloner.Share))
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    buffers := cloned.(Buffers)
+    if &buffers.Small[0] == &original.Small[0] {
+        t.Errorf("Cloned slice Small shares the original")
+    }
+    if &buffers.Large[0] != &original.Large[0] {
+        t.Errorf("Cloned slice Large does not share the original")
+    }
+
+    // Each clone has its own budget
+    if _, err := cm.Clone(original.Small); err != nil {
+        t.Errorf("Clone failed: %v", err)
+    }
+}
+
+type Conn struct {
+    Addr   string
+    closed bool
+    owners int
+}
+
+func (c *Conn) Close() error {
+    c.closed = true
+    return nil
+}
+
+func (c *Conn) TransferOwnership() {
+    c.owners++
+}
+
+type Session struct {
+    User   string
+    Conn   *Conn
+    Backup interface{}
+    Pool   []*Conn
+}
+
+// Test for moving resources to the clone
+func TestWithTransfer(t *testing.T) {
+    conn := &Conn{Addr: "db:5432"}
+    original := &Session{User: "ann", Conn: conn, Backup: conn, Pool: []*Conn{conn}}
+
+    cloned, err := cloner.Clone(cloner.NewCloneManager(cloner.WithTransfer()), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Conn != conn || cloned.Backup != conn || cloned.Pool[0] != conn {
+        t.Errorf("Clone does not own the original connection")
+    }
+    if original.Conn != nil || original.Backup != nil {
+        t.Errorf("got original %+v, want the connections moved", original)
+    }
+    if conn.owners != 2 {
+        t.Errorf("got %d transfers, want 2", conn.owners)
+    }
+    if original.User != "ann" || cloned.User != "ann" {
+        t.Errorf("got users %q and %q, want ann", original.User, cloned.User)
+    }
+
+    // Fields of values passed by value cannot be cleared
+    _, err = cloner.NewCloneManager(cloner.WithTransfer()).Clone(Session{Conn: conn})
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || cloneErr.Path != ".Conn" || !errors.Is(err, cloner.ErrNotTransferable) {
+        t.Errorf("got error %v, want ErrNotTransferable at .Conn", err)
+    }
+}
+
+// Test for collapsing pointers to equal values
+func TestWithIdentity(t *testing.T) {
+    type Labels struct {
+        Env, Region, Tier *string
+        Zero, NegZero     *float64
+    }
+    prod, eu, negZero := "prod", "eu", math.Copysign(0, -1)
+    original := Labels{Env: &prod, Region: &eu, Tier: new(string), Zero: new(float64), NegZero: &negZero}
+    *original.Tier = "prod"
+
+    // Exact keeps distinct pointers distinct
+    exact, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if exact.Env == exact.Tier {
+        t.Errorf("Exact clone collapsed equal strings")
+    }
+
+    dedup, err := cloner.Clone(cloner.NewCloneManager(cloner.WithIdentity(cloner.ValueDedup)), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if dedup.Env != dedup.Tier || *dedup.Env != "prod" || dedup.Env == original.Env {
+        t.Errorf("ValueDedup clone did not collapse equal strings")
+    }
+    if dedup.Region == dedup.Env || dedup.Zero == dedup.NegZero || !math.Signbit(*dedup.NegZero) {
+        t.Errorf("ValueDedup clone collapsed distinct values")
+    }
+}
+
+// Test for reporting the progress of a clone
+func TestWithProgress(t *testing.T) {
+    var reports []string
+    cm := cloner.NewCloneManager(
+        cloner.WithProgress(func(nodes int, path cloner.Path) {
+            reports = append(reports, fmt.Sprintf("%d %s", nodes, path))
+        }),
+        cloner.WithProgressInterval(4),
+    )
+
+    // The job, its Name, Done and Steps, and 3 steps with an ID and an Abort
+    original := Job{Name: "a", Steps: []Step{{ID: 1}, {ID: 2}, {ID: 3}}}
+    if _, err := cm.Clone(original); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    want := []string{"4 .Steps", "8 .Steps[1]", "12 .Steps[2].ID"}
+    if !reflect.DeepEqual(reports, want) {
+        t.Errorf("got reports %q, want %q", reports, want)
+    }
+
+    reports = nil
+    if _, err := cloner.NewCloneManager().Clone(make([]int, 25000), cloner.WithProgress(func(nodes int, path cloner.Path) {
+        reports = append(reports, fmt.Sprintf("%d %s", nodes, path))
+    })); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    want = []string{"10000 [9998]", "20000 [19998]"}
+    if !reflect.DeepEqual(reports, want) {
+        t.Errorf("got reports %q, want %q", reports, want)
+    }
+}
+
+// Test for refusing to clone values with a guard
+func TestWithGuard(t *testing.T) {
+    errDenied := errors.New("access denied")
+    var guarded []cloner.Path
+    cm := cloner.NewCloneManager(cloner.WithGuard(func(typ reflect.Type, path cloner.Path) error {
+        guarded = append(guarded, path)
+        if typ == reflect.TypeOf(Cache{}) {
+            return errDenied
+        }
+        return nil
+    }))
+
+    original := Inventory{Name: "a", Items: []Item{{SKU: "1"}}}
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil || cloned.Name != "a" || cloned.Items[0].SKU != "1" {
+        t.Fatalf("got clone %+v and error %v, want a clone", cloned, err)
+    }
+    if len(guarded) == 0 || guarded[0] != "" || guarded[len(guarded)-1] != ".Index" {
+        t.Errorf("got guarded paths %q, want every value guarded", guarded)
+    }
+
+    original.Cache = &Cache{Hits: 1}
+    var cloneErr *cloner.CloneError
+    _, err = cm.Clone(original)
+    for _, err := range []error{cm.Validate(original), err} {
+        if !errors.Is(err, errDenied) || !errors.As(err, &cloneErr) || cloneErr.Path != ".Cache" {
+            t.Errorf("got error %v, want access denied at .Cache", err)
+        }
+    }
+
+    // Excluded values are not guarded
+    if _, err := cm.Clone(original, cloner.WithExcludePaths("Cache")); err != nil {
+        t.Errorf("Clone excluding the cache failed: %v", err)
+    }
+}
+
+type Poller struct {
+    Name     string
+    Interval *time.Ticker
+    Deadline *time.Timer
+}
+
+// Test for the policies of timers and tickers
+func TestWithTimers(t *testing.T) {
+    original := Poller{Name: "a", Interval: time.NewTicker(time.Hour), Deadline: time.NewTimer(time.Hour)}
+    defer original.Interval.Stop()
+    defer original.Deadline.Stop()
+
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil || cloned.Name != "a" || cloned.Interval != nil || cloned.Deadline != nil {
+        t.Errorf("got clone %+v and error %v, want the timers left out", cloned, err)
+    }
+
+    cm := cloner.NewCloneManager(cloner.WithTimers(cloner.Error))
+    _, err = cm.Clone(original)
+    for _, err := range []error{err, cm.Validate(original)} {
+        var cloneErr *cloner.CloneError
+        if !errors.Is(err, cloner.ErrUncloneableTimer) || !errors.As(err, &cloneErr) || cloneErr.Path != ".Interval" {
+            t.Errorf("got error %v, want ErrUncloneableTimer at .Interval", err)
+        }
+    }
+
+    cloned, err = cloner.Clone(cloner.NewCloneManager(cloner.WithTimers(cloner.Share)), original)
+    if err != nil || cloned.Interval != original.Interval || cloned.Deadline != original.Deadline {
+        t.Errorf("got clone %+v and error %v, want the timers shared", cloned, err)
+    }
+
+    // Timers whose duration is known are restarted
+    durations := map[interface{}]time.Duration{original.Interval: time.Millisecond}
+    cm = cloner.NewCloneManager(cloner.WithTimerRestart(func(timer interface{}) (time.Duration, bool) {
+        d, ok := durations[timer]
+        return d, ok
+    }))
+    if err := cm.Validate(original); err != nil {
+        t.Errorf("Validate failed: %v", err)
+    }
+    cloned, err = cloner.Clone(cm, original)
+    if err != nil || cloned.Interval == nil || cloned.Interval == original.Interval || cloned.Deadline != nil {
+        t.Fatalf("got clone %+v and error %v, want the ticker restarted", cloned, err)
+    }
+    defer cloned.Interval.Stop()
+    select {
+    case <-cloned.Interval.C:
+    case <-time.After(time.Second):
+        t.Error("restarted ticker did not tick")
+    }
+}
+
+type Envelope struct {
+    Kind    string
+    Body    json.RawMessage
+    Meta    json.RawMessage
+    Extra   json.RawMessage
+    Digest  []byte
+    Headers []json.RawMessage
+}
+
+// Test for copying and sharing byte slices
+func TestWithSharedBytes(t *testing.T) {
+    original := Envelope{
+        Kind:    "event",
+        Body:    json.RawMessage(`{"id":1}`),
+        Meta:    json.RawMessage{},
+        Digest:  []byte{1, 2, 3},
+        Headers: []json.RawMessage{json.RawMessage(`"a"`), nil},
+    }
+
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil || !reflect.DeepEqual(cloned, original) {
+        t.Fatalf("got clone %+v and error %v, want %+v", cloned, err, original)
+    }
+    if &cloned.Body[0] == &original.Body[0] || &cloned.Digest[0] == &original.Digest[0] ||
+        cloned.Meta == nil || cloned.Extra != nil || cloned.Headers[1] != nil {
+        t.Errorf("got clone %+v, want the bytes copied and nil slices kept", cloned)
+    }
+
+    cm := cloner.NewCloneManager(cloner.WithSharedBytes(reflect.TypeOf(json.RawMessage(nil))))
+    cloned, err = cloner.Clone(cm, original)
+    if err != nil || &cloned.Body[0] != &original.Body[0] || &cloned.Headers[0][0] != &original.Headers[0][0] ||
+        &cloned.Digest[0] == &original.Digest[0] || &cloned.Headers[0] == &original.Headers[0] {
+        t.Errorf("got clone %+v and error %v, want the JSON shared", cloned, err)
+    }
+
+    defer func() {
+        if recover() == nil {
+            t.Error("WithSharedBytes of a string slice did not panic")
+        }
+    }()
+    cloner.WithSharedBytes(reflect.TypeOf([]string(nil)))
+}
@@ -0,0 +1,156 @@
+package cloner
+
+import (
+    "cmp"
+    "fmt"
+    "reflect"
+    "slices"
+    "strings"
+)
+
+// mapEntry is a key-value pair of a map.
+type mapEntry struct {
+    key, value reflect.Value
+}
+
+// mapEntries returns the entries of the map src, sorted by key with
+// WithDeterministicOrder. Entries are read with a map iterator rather than
+// MapIndex so that values stored under NaN keys, which never compare equal to
+// themselves, are not lost.
+func (cm *CloneManager) mapEntries(src reflect.Value) []mapEntry {
+    entries := make([]mapEntry, 0, src.Len())
+    iter := src.MapRange()
+    for iter.Next() {
+        entries = append(entries, mapEntry{iter.Key(), iter.Value()})
+    }
+    if cm.options.deterministicOrder {
+        slices.SortStableFunc(entries, func(a, b mapEntry) int {
+            return compareKeys(a.key, b.key)
+        })
+    }
+    return entries
+}
+
+// rangeMap calls fn for each entry of the map src, in the order of
+// mapEntries with WithDeterministicOrder, until fn returns an error.
+// Otherwise the map is iterated directly, without collecting its entries
+// first. With buffered, keys and values other than structs and arrays are
+// read into reusable buffers, which fn must not retain, instead of being
+// copied one by one. Buffers only pay off for callers reading the entries
+// with methods like Int or String: Value.Interface copies values read from
+// an addressable buffer, and the fields and elements of structs and arrays
+// are addressable in a buffer too.
+func (cm *CloneManager) rangeMap(src reflect.Value, buffered bool, fn func(key, value reflect.Value) error) error {
+    if cm.options.deterministicOrder {
+        for _, entry := range cm.mapEntries(src) {
+            if err := fn(entry.key, entry.value); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+    var keyBuf, valueBuf reflect.Value
+    if buffered {
+        keyBuf, valueBuf = mapBuffer(src.Type().Key()), mapBuffer(src.Type().Elem())
+    }
+    iter := src.MapRange()
+    for iter.Next() {
+        key, value := keyBuf, valueBuf
+        if key.IsValid() {
+            key.SetIterKey(iter)
+        } else {
+            key = iter.Key()
+        }
+        if value.IsValid() {
+            value.SetIterValue(iter)
+        } else {
+            value = iter.Value()
+        }
+        if err := fn(key, value); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// mapBuffer returns a buffer for the keys or values of type t read by
+// rangeMap, or an invalid value if they are copied instead.
+func mapBuffer(t reflect.Type) reflect.Value {
+    switch t.Kind() {
+    case reflect.Struct, reflect.Array:
+        return reflect.Value{}
+    }
+    return reflect.New(t).Elem()
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// compareKeys orders map keys: numbers, strings and booleans by value,
+// pointers by the values they point to, arrays and structs element by
+// element, other fmt.Stringer implementations by their string, interfaces by
+// the name of their dynamic type first, and anything else by its formatted
+// value.
+func compareKeys(a, b reflect.Value) int {
+    if a.Kind() == reflect.Interface {
+        if a.IsNil() || b.IsNil() {
+            return cmp.Compare(boolRank(!a.IsNil()), boolRank(!b.IsNil()))
+        }
+        a, b = a.Elem(), b.Elem()
+        if a.Type() != b.Type() {
+            return strings.Compare(a.Type().String(), b.Type().String())
+        }
+    }
+
+    switch a.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return cmp.Compare(a.Int(), b.Int())
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return cmp.Compare(a.Uint(), b.Uint())
+    case reflect.Float32, reflect.Float64:
+        return cmp.Compare(a.Float(), b.Float())
+    case reflect.Complex64, reflect.Complex128:
+        if c := cmp.Compare(real(a.Complex()), real(b.Complex())); c != 0 {
+            return c
+        }
+        return cmp.Compare(imag(a.Complex()), imag(b.Complex()))
+    case reflect.String:
+        return strings.Compare(a.String(), b.String())
+    case reflect.Bool:
+        return cmp.Compare(boolRank(a.Bool()), boolRank(b.Bool()))
+    case reflect.Ptr:
+        if a.IsNil() || b.IsNil() {
+            return cmp.Compare(boolRank(!a.IsNil()), boolRank(!b.IsNil()))
+        }
+        if c := compareKeys(a.Elem(), b.Elem()); c != 0 {
+            return c
+        }
+        return cmp.Compare(a.Pointer(), b.Pointer())
+    case reflect.Chan, reflect.UnsafePointer:
+        return cmp.Compare(a.Pointer(), b.Pointer())
+    case reflect.Array:
+        for i := 0; i < a.Len(); i++ {
+            if c := compareKeys(a.Index(i), b.Index(i)); c != 0 {
+                return c
+            }
+        }
+        return 0
+    case reflect.Struct:
+        if a.Type().Implements(stringerType) && a.CanInterface() {
+            return strings.Compare(a.Interface().(fmt.Stringer).String(), b.Interface().(fmt.Stringer).String())
+        }
+        for i := 0; i < a.NumField(); i++ {
+            if c := compareKeys(a.Field(i), b.Field(i)); c != 0 {
+                return c
+            }
+        }
+        return 0
+    }
+    return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func boolRank(b bool) int {
+    if b {
+        return 1
+    }
+    return 0
+}
@@ -0,0 +1,71 @@
+package cloner_test
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Visit struct {
+    Name string
+}
+
+type Coord struct {
+    X, Y int
+}
+
+// Test for visiting map entries in a stable order
+func TestDeterministicOrder(t *testing.T) {
+    var visited []string
+    cm := cloner.NewCloneManager(cloner.WithDeterministicOrder())
+    cm.RegisterCloner(reflect.TypeOf(Visit{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        visited = append(visited, v.(Visit).Name)
+        return v, nil
+    }))
+
+    original := struct {
+        ByID    map[int]Visit
+        ByCoord map[Coord]Visit
+        ByKey   map[interface{}]Visit
+    }{
+        ByID:    map[int]Visit{10: {"ten"}, -1: {"minus one"}, 2: {"two"}, 7: {"seven"}},
+        ByCoord: map[Coord]Visit{{1, 2}: {"1,2"}, {0, 5}: {"0,5"}, {1, 0}: {"1,0"}},
+        ByKey:   map[interface{}]Visit{"b": {"b"}, 3: {"3"}, "a": {"a"}, 1: {"1"}},
+    }
+
+    want := []string{"minus one", "two", "seven", "ten", "0,5", "1,0", "1,2", "1", "3", "a", "b"}
+    for i := 0; i < 5; i++ {
+        visited = nil
+        if _, err := cm.Clone(original); err != nil {
+            t.Fatalf("Clone failed: %v", err)
+        }
+        if !reflect.DeepEqual(visited, want) {
+            t.Fatalf("Visit order is incorrect: got %q, want %q", visited, want)
+        }
+    }
+}
+
+// Test for reporting map errors in a stable order
+func TestDeterministicOrderErrors(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.CollectErrors(), cloner.WithDeterministicOrder())
+
+    original := map[string]func(){"c": func() {}, "a": func() {}, "b": func() {}}
+
+    for i := 0; i < 5; i++ {
+        err := cm.Validate(original)
+        var paths []string
+        for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+            var cloneErr *cloner.CloneError
+            if !errors.As(e, &cloneErr) {
+                t.Fatalf("Error is not a CloneError: %v", e)
+            }
+            paths = append(paths, cloneErr.Path)
+        }
+        if got, want := strings.Join(paths, " "), `["a"] ["b"] ["c"]`; got != want {
+            t.Fatalf("Error order is incorrect: got %s, want %s", got, want)
+        }
+    }
+}
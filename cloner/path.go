@@ -0,0 +1,529 @@
+package cloner
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// Path identifies a value in the graph being cloned, formatted like a Go
+// selector expression, e.g. .Items[2].Name or .Labels["env"]. The root value
+// has an empty path. Its Steps method gives the structured form of the path,
+// which MakePath formats back and Get and Set resolve.
+type Path string
+
+// StepKind is the kind of a Step.
+type StepKind int
+
+const (
+    FieldStep StepKind = iota // a struct field
+    IndexStep                 // a slice or array index
+    KeyStep                   // a map key
+)
+
+// Step is one element of the structured form of a Path.
+type Step struct {
+    Kind  StepKind
+    Field string      // field name, for a FieldStep
+    Index int         // index, for an IndexStep
+    Key   interface{} // key, for a KeyStep
+}
+
+// Field returns the Step selecting the struct field name.
+func Field(name string) Step {
+    return Step{Kind: FieldStep, Field: name}
+}
+
+// Index returns the Step selecting element i of a slice or array.
+func Index(i int) Step {
+    return Step{Kind: IndexStep, Index: i}
+}
+
+// Key returns the Step selecting the entry of a map for key.
+func Key(key interface{}) Step {
+    return Step{Kind: KeyStep, Key: key}
+}
+
+// KeyLiteral is the Key of a Step parsed from a path whose map key is not a
+// string, boolean or number, such as a struct, an array or a pointer: it is
+// the Go literal of the key as formatted in paths, and selects the key of
+// a map formatted alike.
+type KeyLiteral string
+
+// String formats s as in paths, e.g. .Name, [2] or ["env"].
+func (s Step) String() string {
+    switch s.Kind {
+    case FieldStep:
+        return "." + s.Field
+    case IndexStep:
+        return "[" + strconv.Itoa(s.Index) + "]"
+    default:
+        if literal, ok := s.Key.(KeyLiteral); ok {
+            return "[" + string(literal) + "]"
+        }
+        return fmt.Sprintf("[%#v]", s.Key)
+    }
+}
+
+// MakePath returns the Path made of steps.
+func MakePath(steps ...Step) Path {
+    var b strings.Builder
+    for _, s := range steps {
+        b.WriteString(s.String())
+    }
+    return Path(b.String())
+}
+
+// Steps parses p into its steps, which MakePath formats back to p. Paths
+// format map keys as Go literals, which do not always tell their type:
+// bracketed non-negative integers are parsed as IndexSteps, which Get and
+// Set also accept for maps with numeric keys; other numbers, strings and
+// booleans as KeySteps holding an int, uint64, float64, complex128, string
+// or bool, which are converted to the key type of the map; and any other
+// key, such as a struct, as a KeySteps holding its KeyLiteral. The steps
+// of values carried by a CloneError or a Difference hold the keys
+// themselves.
+func (p Path) Steps() ([]Step, error) {
+    steps := []Step{}
+    rest := string(p)
+    for rest != "" {
+        switch rest[0] {
+        case '.':
+            end := strings.IndexAny(rest[1:], ".[") + 1
+            if end == 0 {
+                end = len(rest)
+            }
+            if end == 1 {
+                return nil, fmt.Errorf("cloner: invalid path %q", p)
+            }
+            steps = append(steps, Field(rest[1:end]))
+            rest = rest[end:]
+        case '[':
+            end := literalEnd(rest[1:]) + 1
+            if end < 2 || end >= len(rest) || rest[end] != ']' {
+                return nil, fmt.Errorf("cloner: invalid path %q", p)
+            }
+            steps = append(steps, parseStep(rest[1:end]))
+            rest = rest[end+1:]
+        default:
+            return nil, fmt.Errorf("cloner: invalid path %q", p)
+        }
+    }
+    return steps, nil
+}
+
+// literalEnd returns the length of the Go literal starting s, up to the
+// first unmatched closing bracket, brace or parenthesis outside of quotes,
+// or -1 if its quotes or brackets are unbalanced.
+func literalEnd(s string) int {
+    var open []byte
+    for i := 0; i < len(s); i++ {
+        switch c := s[i]; c {
+        case '"', '`', '\'':
+            quoted, err := strconv.QuotedPrefix(s[i:])
+            if err != nil {
+                return -1
+            }
+            i += len(quoted) - 1
+        case '[', '{', '(':
+            open = append(open, c)
+        case ']', '}', ')':
+            if len(open) == 0 {
+                return i
+            }
+            if strings.IndexByte("[{(", open[len(open)-1]) != strings.IndexByte("]})", c) {
+                return -1
+            }
+            open = open[:len(open)-1]
+        }
+    }
+    if len(open) > 0 {
+        return -1
+    }
+    return len(s)
+}
+
+// parseStep parses the index or key literal of a bracketed step.
+func parseStep(literal string) Step {
+    if literal[0] == '"' || literal[0] == '`' {
+        if s, err := strconv.Unquote(literal); err == nil {
+            return Key(s)
+        }
+    }
+    if i, err := strconv.Atoi(literal); err == nil {
+        if i < 0 {
+            return Key(i)
+        }
+        return Index(i)
+    }
+    if literal == "true" || literal == "false" {
+        return Key(literal == "true")
+    }
+    // Unsigned integers are formatted in hexadecimal
+    if strings.HasPrefix(literal, "0x") {
+        if u, err := strconv.ParseUint(literal[2:], 16, 64); err == nil {
+            return Key(u)
+        }
+    }
+    if f, err := strconv.ParseFloat(literal, 64); err == nil {
+        return Key(f)
+    }
+    if strings.HasPrefix(literal, "(") {
+        if c, err := strconv.ParseComplex(literal, 128); err == nil {
+            return Key(c)
+        }
+    }
+    return Key(KeyLiteral(literal))
+}
+
+// Get returns the value at path from root, following pointers and
+// interfaces. Numeric and string map keys are converted to the key type of
+// the map. Get returns ErrNoValue, wrapped in a *CloneError for the path,
+// if there is no value at path.
+func Get(root interface{}, path []Step) (reflect.Value, error) {
+    steps := internalSteps(path)
+    v := reflect.ValueOf(root)
+    for i := 0; ; i++ {
+        for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+            if v.IsNil() {
+                return reflect.Value{}, &CloneError{Path: formatPath(steps[:i]), Steps: path[:i], Type: v.Type(), Err: ErrNoValue}
+            }
+            v = v.Elem()
+        }
+        if i == len(steps) {
+            return v, nil
+        }
+        next := elemOf(v, steps[i])
+        if !next.IsValid() {
+            return reflect.Value{}, &CloneError{Path: formatPath(steps[:i+1]), Steps: path[:i+1], Err: ErrNoValue}
+        }
+        v = next
+    }
+}
+
+// Set sets the value at path from root to value, following pointers and
+// interfaces, converting map keys as Get does. A nil value sets the zero
+// value, or removes the entry if path leads to a map entry. root must be a
+// pointer, a slice or a map, so that its contents can be set. Set returns
+// ErrNoValue, wrapped in a *CloneError for the path, if there is no value at
+// path, ErrTypeMismatch if value cannot be assigned there, and
+// ErrUnexportedField if the path leads through an unexported field.
+func Set(root interface{}, path []Step, value interface{}) error {
+    v := reflect.ValueOf(root)
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+    default:
+        return fmt.Errorf("cloner: Set needs a pointer, slice or map, got %T", root)
+    }
+    steps := internalSteps(path)
+    for _, write := range []bool{false, true} {
+        if err := writeBack(v, steps, reflect.ValueOf(value), write); err != nil {
+            return &CloneError{Path: formatPath(steps), Steps: path, Err: err}
+        }
+    }
+    return nil
+}
+
+// internalSteps converts path to the steps tracked while cloning.
+func internalSteps(path []Step) []step {
+    steps := make([]step, len(path))
+    for i, s := range path {
+        switch s.Kind {
+        case FieldStep:
+            steps[i] = step{field: s.Field}
+        case IndexStep:
+            steps[i] = step{index: s.Index}
+        default:
+            key := reflect.ValueOf(s.Key)
+            if !key.IsValid() {
+                // A nil key of an interface type
+                key = reflect.Zero(reflect.TypeOf((*interface{})(nil)).Elem())
+            }
+            steps[i] = step{key: key}
+        }
+    }
+    return steps
+}
+
+// exportedSteps converts steps to the structured form of their path.
+func exportedSteps(steps []step) []Step {
+    if len(steps) == 0 {
+        return nil
+    }
+    path := make([]Step, len(steps))
+    for i, s := range steps {
+        switch {
+        case s.field != "":
+            path[i] = Field(s.field)
+        case s.key.IsValid():
+            path[i] = Key(interfaceOf(s.key))
+        default:
+            path[i] = Index(s.index)
+        }
+    }
+    return path
+}
+
+// elemOf returns the field, element or map entry of v that s selects, or an
+// invalid value if there is none.
+func elemOf(v reflect.Value, s step) reflect.Value {
+    switch {
+    case s.field != "":
+        if v.Kind() != reflect.Struct {
+            return reflect.Value{}
+        }
+        return v.FieldByName(s.field)
+    case v.Kind() == reflect.Map:
+        key, ok := mapKey(v, s)
+        if !ok {
+            return reflect.Value{}
+        }
+        return v.MapIndex(key)
+    case s.key.IsValid():
+        return reflect.Value{}
+    case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+        if s.index < 0 || s.index >= v.Len() {
+            return reflect.Value{}
+        }
+        return v.Index(s.index)
+    }
+    return reflect.Value{}
+}
+
+// mapKey returns the key of the map m that s selects: its key converted to
+// the key type of m if both are numbers or both strings and the conversion
+// loses nothing, or else the key of m formatted like it in paths, which
+// also matches the keys of KeyLiterals. It returns the converted key for a
+// missing entry, so that it can be set.
+func mapKey(m reflect.Value, s step) (reflect.Value, bool) {
+    if s.field != "" {
+        return reflect.Value{}, false
+    }
+    key := s.key
+    if !key.IsValid() {
+        key = reflect.ValueOf(s.index)
+    }
+    var direct reflect.Value
+    kt := m.Type().Key()
+    if key.Type() == keyLiteralType {
+        // KeyLiterals only match formatted keys
+    } else if key.Type().AssignableTo(kt) {
+        direct = key
+    } else if sameClass(key.Kind(), kt.Kind()) && key.CanConvert(kt) {
+        converted := key.Convert(kt)
+        if converted.Convert(key.Type()).Interface() == key.Interface() {
+            direct = converted
+        }
+    }
+    if direct.IsValid() && (m.IsNil() || m.MapIndex(direct).IsValid()) {
+        return direct, true
+    }
+    literal := formatKey(key)
+    if key.Type() == keyLiteralType {
+        literal = key.String()
+    }
+    if !m.IsNil() {
+        iter := m.MapRange()
+        for iter.Next() {
+            if formatKey(iter.Key()) == literal {
+                return iter.Key(), true
+            }
+        }
+    }
+    return direct, direct.IsValid()
+}
+
+var keyLiteralType = reflect.TypeOf(KeyLiteral(""))
+
+// sameClass reports whether a and b are both kinds of numbers, or both
+// strings.
+func sameClass(a, b reflect.Kind) bool {
+    isNumber := func(k reflect.Kind) bool {
+        return k >= reflect.Int && k <= reflect.Float64
+    }
+    return isNumber(a) && isNumber(b) || a == reflect.String && b == reflect.String
+}
+
+// step is one element of the path from the root to the value being cloned.
+// Steps are only formatted when a path is reported, so tracking them costs
+// no allocations for indexes and field names.
+type step struct {
+    field string        // struct field name, if not empty
+    label string        // name of the field in paths, if not its Go name
+    key   reflect.Value // map key, if valid
+    index int           // slice or array index otherwise
+}
+
+// name returns the name of the field of s in paths.
+func (s step) name() string {
+    if s.label != "" {
+        return s.label
+    }
+    return s.field
+}
+
+func (cm *CloneManager) pushField(fp *fieldPlan) {
+    s := step{field: fp.name}
+    if cm.options.fieldNames != "" {
+        s.label = tagFieldName(fp, cm.options.fieldNames)
+    }
+    cm.path = append(cm.path, s)
+}
+
+func (cm *CloneManager) pushIndex(i int) {
+    cm.path = append(cm.path, step{index: i})
+}
+
+func (cm *CloneManager) pushKey(key reflect.Value) {
+    cm.path = append(cm.path, step{key: key})
+}
+
+func (cm *CloneManager) pop() {
+    cm.path = cm.path[:len(cm.path)-1]
+}
+
+// formatPath formats steps like a Go selector expression, e.g. .Items[2].Name
+// or .Labels["env"]. The root value has an empty path.
+func formatPath(steps []step) string {
+    var b strings.Builder
+    for _, s := range steps {
+        switch {
+        case s.field != "":
+            b.WriteString(".")
+            b.WriteString(s.name())
+        case s.key.IsValid():
+            b.WriteString("[")
+            b.WriteString(formatKey(s.key))
+            b.WriteString("]")
+        default:
+            b.WriteString("[")
+            b.WriteString(strconv.Itoa(s.index))
+            b.WriteString("]")
+        }
+    }
+    return b.String()
+}
+
+// formatKey formats a map key as a Go literal.
+func formatKey(key reflect.Value) string {
+    if key.CanInterface() {
+        return fmt.Sprintf("%#v", key.Interface())
+    }
+    return key.Type().String()
+}
+
+// pathPattern is a compiled path pattern, see WithExcludePaths.
+type pathPattern []patternStep
+
+// patternStep matches a step of a path: a struct field if elem is false, a
+// slice or array index or a map key otherwise. The text is the field name,
+// or the index or key as formatted in paths, without brackets; "*" matches
+// any field, index or key.
+type patternStep struct {
+    elem bool
+    text string
+}
+
+// compilePattern parses a path pattern like Items[*].Name or
+// .Labels["env"], with or without the leading dot.
+func compilePattern(pattern string) (pathPattern, error) {
+    var p pathPattern
+    rest := strings.TrimPrefix(pattern, ".")
+    for rest != "" {
+        if rest[0] == '[' {
+            end := literalEnd(rest[1:]) + 1
+            if end < 2 || end >= len(rest) || rest[end] != ']' {
+                return nil, fmt.Errorf("cloner: invalid path pattern %q", pattern)
+            }
+            p = append(p, patternStep{elem: true, text: rest[1:end]})
+            rest = rest[end+1:]
+        } else {
+            end := strings.IndexAny(rest, ".[")
+            if end < 0 {
+                end = len(rest)
+            }
+            if end == 0 {
+                return nil, fmt.Errorf("cloner: invalid path pattern %q", pattern)
+            }
+            p = append(p, patternStep{text: rest[:end]})
+            rest = rest[end:]
+        }
+        if strings.HasPrefix(rest, ".") {
+            rest = rest[1:]
+            if rest == "" || rest[0] == '[' || rest[0] == '.' {
+                return nil, fmt.Errorf("cloner: invalid path pattern %q", pattern)
+            }
+        }
+    }
+    if len(p) == 0 {
+        return nil, fmt.Errorf("cloner: empty path pattern")
+    }
+    return p, nil
+}
+
+// String formats p as a pattern like Items[*].Name.
+func (p pathPattern) String() string {
+    var b strings.Builder
+    for i, ps := range p {
+        switch {
+        case ps.elem:
+            b.WriteString("[")
+            b.WriteString(ps.text)
+            b.WriteString("]")
+        case i > 0:
+            b.WriteString(".")
+            fallthrough
+        default:
+            b.WriteString(ps.text)
+        }
+    }
+    return b.String()
+}
+
+// match reports whether p matches the path given by steps.
+func (p pathPattern) match(steps []step) bool {
+    if len(p) != len(steps) {
+        return false
+    }
+    for i, s := range steps {
+        ps := p[i]
+        if ps.elem != (s.field == "") {
+            return false
+        }
+        if ps.text == "*" {
+            continue
+        }
+        switch {
+        case s.field != "":
+            if ps.text != s.name() {
+                return false
+            }
+        case s.key.IsValid():
+            if ps.text != formatKey(s.key) {
+                return false
+            }
+        default:
+            if ps.text != strconv.Itoa(s.index) {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// pathPolicy returns the policy configured for the path of the value being
+// cloned with WithExcludePaths or WithSharedPaths, if any.
+func (cm *CloneManager) pathPolicy() (Policy, bool) {
+    for _, p := range cm.options.excludePaths {
+        if p.match(cm.path) {
+            return Zero, true
+        }
+    }
+    for _, p := range cm.options.sharedPaths {
+        if p.match(cm.path) {
+            return Share, true
+        }
+    }
+    return 0, false
+}
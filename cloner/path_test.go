@@ -0,0 +1,193 @@
+package cloner_test
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Listing struct {
+    Items   []*Item
+    Prices  map[string]float64
+    Ranks   map[int8]string
+    Extra   interface{}
+    Grid    [2][2]int
+    private int
+}
+
+// Test for parsing and formatting paths
+func TestPathSteps(t *testing.T) {
+    steps := []cloner.Step{
+        cloner.Field("Items"), cloner.Index(2), cloner.Field("SKU"),
+        cloner.Key("a]\"b"), cloner.Key(-1), cloner.Key(true), cloner.Key(1.5),
+    }
+    path := cloner.MakePath(steps...)
+    if path != `.Items[2].SKU["a]\"b"][-1][true][1.5]` {
+        t.Errorf("got path %s", path)
+    }
+    parsed, err := path.Steps()
+    if err != nil {
+        t.Fatalf("Steps failed: %v", err)
+    }
+    deepEqual(t, parsed, steps)
+    if root, err := cloner.Path("").Steps(); err != nil || len(root) != 0 {
+        t.Errorf("got steps %v, %v for the root", root, err)
+    }
+
+    for _, invalid := range []cloner.Path{"Items", ".", ".Items.", "[", "[]", `["a"`, "[main.K{]", "[(1]", "[a)]"} {
+        if steps, err := invalid.Steps(); err == nil {
+            t.Errorf("%q parsed as %v", invalid, steps)
+        }
+    }
+}
+
+// Test for parsing the paths of map entries back to their keys
+func TestPathKeys(t *testing.T) {
+    type Color string
+    type Point struct {
+        X, Y int
+        Tag  string
+    }
+    n := 1
+    ch := make(chan int)
+    for _, m := range []interface{}{
+        map[string]int{"a]b": 1},
+        map[Color]int{"red": 1},
+        map[int]int{-3: 1, 4: 2},
+        map[int8]int{7: 1},
+        map[uint]int{5: 1},
+        map[uint8]int{255: 1},
+        map[uintptr]int{9: 1},
+        map[float64]int{1.5: 1, 2: 2},
+        map[float32]int{-0.25: 1},
+        map[complex128]int{1 + 2i: 1},
+        map[bool]int{true: 1},
+        map[rune]int{'x': 1},
+        map[[2]int]int{{1, 2}: 1},
+        map[Point]int{{1, 2, "a]"}: 1},
+        map[*int]int{&n: 1},
+        map[chan int]int{ch: 1},
+        map[interface{}]int{"a": 1, 3: 2, 2.5: 3, Point{X: 1}: 4, nil: 5},
+    } {
+        // Every entry differs from the zero map
+        empty := reflect.MakeMap(reflect.TypeOf(m)).Interface()
+        diffs := cloner.Diff(empty, m)
+        if len(diffs) != reflect.ValueOf(m).Len() {
+            t.Errorf("%T: got differences %v, want one per entry", m, diffs)
+        }
+        for _, d := range diffs {
+            steps, err := d.Path.Steps()
+            if err != nil {
+                t.Errorf("%T: Steps(%s) failed: %v", m, d.Path, err)
+                continue
+            }
+            if formatted := cloner.MakePath(steps...); formatted != d.Path {
+                t.Errorf("%T: %s formatted back as %s", m, d.Path, formatted)
+            }
+            for _, path := range [][]cloner.Step{steps, d.Steps} {
+                v, err := cloner.Get(m, path)
+                if err != nil || v.Interface() != d.B {
+                    t.Errorf("%T: Get(%v) returned %v, %v, want %v", m, path, v, err, d.B)
+                }
+            }
+        }
+    }
+}
+
+// Test for getting and setting values by path
+func TestGetSet(t *testing.T) {
+    listing := &Listing{
+        Items:  []*Item{{SKU: "a"}, nil},
+        Prices: map[string]float64{"a": 1},
+        Ranks:  map[int8]string{1: "first"},
+        Extra:  Item{SKU: "x"},
+    }
+    get := func(path cloner.Path) interface{} {
+        steps, err := path.Steps()
+        if err != nil {
+            t.Fatal(err)
+        }
+        v, err := cloner.Get(listing, steps)
+        if err != nil {
+            t.Fatalf("Get(%s) failed: %v", path, err)
+        }
+        return v.Interface()
+    }
+    if got := get(".Items[0].SKU"); got != "a" {
+        t.Errorf("got %v, want a", got)
+    }
+    // Integer keys are written like indexes
+    if got := get(".Ranks[1]"); got != "first" {
+        t.Errorf("got %v, want first", got)
+    }
+
+    for _, set := range []struct {
+        path  []cloner.Step
+        value interface{}
+    }{
+        {[]cloner.Step{cloner.Field("Items"), cloner.Index(0), cloner.Field("SKU")}, "b"},
+        {[]cloner.Step{cloner.Field("Prices"), cloner.Key("c")}, 3.0},
+        {[]cloner.Step{cloner.Field("Prices"), cloner.Key("a")}, nil},
+        {[]cloner.Step{cloner.Field("Ranks"), cloner.Index(2)}, "second"},
+        {[]cloner.Step{cloner.Field("Extra"), cloner.Field("SKU")}, "y"},
+        {[]cloner.Step{cloner.Field("Grid"), cloner.Index(1), cloner.Index(0)}, 5},
+        {[]cloner.Step{cloner.Field("Items"), cloner.Index(1)}, &Item{SKU: "d"}},
+    } {
+        if err := cloner.Set(listing, set.path, set.value); err != nil {
+            t.Errorf("Set(%s) failed: %v", cloner.MakePath(set.path...), err)
+        }
+    }
+    want := &Listing{
+        Items:  []*Item{{SKU: "b"}, {SKU: "d"}},
+        Prices: map[string]float64{"c": 3},
+        Ranks:  map[int8]string{1: "first", 2: "second"},
+        Extra:  Item{SKU: "y"},
+        Grid:   [2][2]int{{}, {5, 0}},
+    }
+    if diffs := cloner.Diff(listing, want); len(diffs) > 0 {
+        t.Errorf("got listing differing by %v", diffs)
+    }
+
+    for _, tc := range []struct {
+        path  []cloner.Step
+        value interface{}
+        err   error
+    }{
+        {[]cloner.Step{cloner.Field("Missing")}, 1, cloner.ErrNoValue},
+        {[]cloner.Step{cloner.Field("Items"), cloner.Index(5)}, &Item{}, cloner.ErrNoValue},
+        {[]cloner.Step{cloner.Field("Ranks"), cloner.Index(300)}, "x", cloner.ErrNoValue},
+        {[]cloner.Step{cloner.Field("Prices"), cloner.Key("c")}, "x", cloner.ErrTypeMismatch},
+        {[]cloner.Step{cloner.Field("private")}, 1, cloner.ErrUnexportedField},
+    } {
+        err := cloner.Set(listing, tc.path, tc.value)
+        var cloneErr *cloner.CloneError
+        if !errors.Is(err, tc.err) || !errors.As(err, &cloneErr) || cloneErr.Path != string(cloner.MakePath(tc.path...)) {
+            t.Errorf("Set(%s) returned %v, want %v", cloner.MakePath(tc.path...), err, tc.err)
+        }
+    }
+    listing.Items[1] = nil
+    if _, err := cloner.Get(listing, []cloner.Step{cloner.Field("Items"), cloner.Index(1), cloner.Field("SKU")}); !errors.Is(err, cloner.ErrNoValue) {
+        t.Errorf("got error %v, want ErrNoValue through a nil pointer", err)
+    }
+    if err := cloner.Set(*listing, nil, 1); err == nil {
+        t.Errorf("Set accepted a struct")
+    }
+}
+
+// Test for the steps of the value being cloned
+func TestManagerSteps(t *testing.T) {
+    var steps [][]cloner.Step
+    cm := cloner.NewCloneManager()
+    cm.Use(func(next cloner.CloneFunc) cloner.CloneFunc {
+        return func(cm *cloner.CloneManager, src reflect.Value) (interface{}, error) {
+            if src.Kind() == reflect.String {
+                steps = append(steps, cm.Steps())
+            }
+            return next(cm, src)
+        }
+    })
+    cloner.Clone(cm, map[int8]Item{3: {SKU: "a"}})
+    deepEqual(t, steps, [][]cloner.Step{{cloner.Key(int8(3)), cloner.Field("SKU")}, {cloner.Key(int8(3)), cloner.Field("Secret")}})
+}
@@ -0,0 +1,40 @@
+package cloner
+
+import "context"
+
+// CloneStream sends a clone of every message received from in to out until
+// in is closed, so that the stages of a pipeline downstream of out cannot
+// see the changes made to the messages by the stages upstream, nor by each
+// other when several streams fan out the same messages. It returns nil once
+// in is closed, the error of the first message that cannot be cloned, or
+// the error of ctx if it is done first. out is not closed, so that several
+// streams can send to it. Options apply to every clone as in Clone.
+//
+//	go func() {
+//	    defer close(out)
+//	    err := cloner.CloneStream(ctx, cm, in, out)
+//	    ...
+//	}()
+func CloneStream[T any](ctx context.Context, cm *CloneManager, in <-chan T, out chan<- T, opts ...Option) error {
+    for {
+        var msg T
+        var ok bool
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case msg, ok = <-in:
+            if !ok {
+                return nil
+            }
+        }
+        cloned, err := Clone(cm, msg, opts...)
+        if err != nil {
+            return err
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case out <- cloned:
+        }
+    }
+}
@@ -0,0 +1,41 @@
+package cloner_test
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for cloning the messages flowing through a pipeline
+func TestCloneStream(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    shared := &Person{Name: "ann"}
+    in := make(chan *Person, 2)
+    in <- shared
+    in <- shared
+    close(in)
+
+    out := make(chan *Person, 2)
+    if err := cloner.CloneStream(context.Background(), cm, in, out); err != nil {
+        t.Fatalf("CloneStream failed: %v", err)
+    }
+    first, second := <-out, <-out
+    if first == shared || second == shared || first == second || first.Name != "ann" {
+        t.Errorf("got %p and %p, want separate clones of %p", first, second, shared)
+    }
+
+    // Cancellation stops a stream waiting for a message or a receiver
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if err := cloner.CloneStream(ctx, cm, make(chan *Person), out); !errors.Is(err, context.Canceled) {
+        t.Errorf("got error %v, want context.Canceled", err)
+    }
+
+    funcs := make(chan func(), 1)
+    funcs <- func() {}
+    if err := cloner.CloneStream(context.Background(), cm, funcs, make(chan func(), 1)); !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUncloneableKind", err)
+    }
+}
@@ -0,0 +1,216 @@
+package cloner
+
+import (
+    "reflect"
+    "sync"
+)
+
+// plan is what the manager needs to know about a type to clone its values,
+// computed once per type and shared by every manager, so that short-lived
+// managers do not analyze the same types again.
+type plan struct {
+    // fields describes the fields of a struct type
+    fields []fieldPlan
+    // exported are the indexes of its exported fields
+    exported []int
+    // afterCloner is true if the type or a pointer to it implements
+    // AfterCloner
+    afterCloner bool
+    // stats is the key of the values of the type in the stats, see
+    // UpdateStats
+    stats string
+    // cloneable is true if the type implements Cloneable or CloneableV2
+    cloneable bool
+    // scalar is true for types registered with RegisterScalar
+    scalar bool
+    // generic is the name of the generic type the type instantiates, or
+    // points to an instantiation of, see RegisterContainer
+    generic string
+    // plain is true for small types without references, unexported fields
+    // or methods changing how they are cloned, whose values are their own
+    // clones unless a cloner is registered for one of types, the types the
+    // values are made of
+    plain bool
+    types []reflect.Type
+}
+
+// maxPlainSize is the size of the largest types copied as they are by
+// Clone.
+const maxPlainSize = 128
+
+// fieldPlan describes a struct field.
+type fieldPlan struct {
+    name     string
+    typ      reflect.Type
+    exported bool
+    tag      reflect.StructTag
+    parent   bool // tagged `deeper:"parent"`
+    ignoreEq bool // tagged `deeper:"ignoreeq"`
+    // basic is true for fields holding a plain boolean, number, string or
+    // array of them, which are copied as they are unless an option or a
+    // cloner applies to their type, see copiesBasic
+    basic bool
+}
+
+// plans caches the plans of the types seen so far, by reflect.Type.
+var plans sync.Map
+
+var (
+    afterClonerType = reflect.TypeOf((*AfterCloner)(nil)).Elem()
+    cloneableType   = reflect.TypeOf((*Cloneable)(nil)).Elem()
+    cloneableV2Type = reflect.TypeOf((*CloneableV2)(nil)).Elem()
+)
+
+// planOf returns the plan of t, computing it the first time t is seen.
+func planOf(t reflect.Type) *plan {
+    if p, ok := plans.Load(t); ok {
+        return p.(*plan)
+    }
+    p := &plan{
+        afterCloner: t.Implements(afterClonerType) || reflect.PointerTo(t).Implements(afterClonerType),
+        cloneable:   t.Implements(cloneableType) || t.Implements(cloneableV2Type),
+        stats:       t.Kind().String() + " " + t.String(),
+        scalar:      isScalar(t),
+        generic:     genericName(t),
+    }
+    p.plain = (t.Size() <= maxPlainSize || p.scalar) && plainType(t, &p.types)
+    if t.Kind() == reflect.Struct {
+        p.fields = make([]fieldPlan, t.NumField())
+        for i := range p.fields {
+            field := t.Field(i)
+            p.fields[i] = fieldPlan{
+                name:     field.Name,
+                typ:      field.Type,
+                exported: field.IsExported(),
+                tag:      field.Tag,
+                parent:   hasTagOption(field.Tag, []string{tagName}, "parent"),
+                ignoreEq: hasTagOption(field.Tag, []string{tagName}, "ignoreeq"),
+                basic:    isFlat(field.Type.Kind()) && planOf(field.Type).plain,
+            }
+            if field.IsExported() {
+                p.exported = append(p.exported, i)
+            }
+        }
+    }
+    // Concurrent computations of the same plan are equivalent
+    actual, _ := plans.LoadOrStore(t, p)
+    return actual.(*plan)
+}
+
+// isBasic reports whether k is the kind of booleans, numbers and strings.
+func isBasic(k reflect.Kind) bool {
+    switch k {
+    case reflect.Bool, reflect.String,
+        reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+        reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+        return true
+    }
+    return false
+}
+
+// isFlat reports whether the values of plain types of kind k are copied
+// with a single assignment: booleans, numbers, strings and arrays of them,
+// such as [16]byte UUIDs.
+func isFlat(k reflect.Kind) bool {
+    return isBasic(k) || k == reflect.Array
+}
+
+// plainType reports whether the values of t are made of booleans, numbers,
+// strings, values of valueTypes and scalars only, in arrays and exported
+// struct fields, whose types do not implement Cloneable, CloneableV2 or
+// AfterCloner. The types making up t are appended to types.
+func plainType(t reflect.Type, types *[]reflect.Type) bool {
+    if t.Implements(cloneableType) || t.Implements(cloneableV2Type) ||
+        t.Implements(afterClonerType) || reflect.PointerTo(t).Implements(afterClonerType) {
+        return false
+    }
+    *types = append(*types, t)
+    if isBasic(t.Kind()) || valueTypes[t] || isScalar(t) {
+        return true
+    }
+    switch t.Kind() {
+    case reflect.Array:
+        return plainType(t.Elem(), types)
+    case reflect.Struct:
+        for i := 0; i < t.NumField(); i++ {
+            if field := t.Field(i); !field.IsExported() || !plainType(field.Type, types) {
+                return false
+            }
+        }
+        return true
+    }
+    return false
+}
+
+// copiesPlain reports whether the values of the plain type of p are their
+// own clones with the configuration of cm: no option or cloner applies to
+// them.
+func (cm *CloneManager) copiesPlain(p *plan) bool {
+    o := &cm.options
+    if o.replace != nil || o.guard != nil || len(o.excludePaths) > 0 || len(o.sharedPaths) > 0 ||
+        o.transfer || o.maxDepth > 0 || o.maxNodes > 0 || o.progress != nil || o.trace != nil ||
+        cm.chain != nil || cm.hasMiddleware() {
+        return false
+    }
+    for _, t := range p.types {
+        if _, ok := o.replacements[t]; ok {
+            return false
+        }
+        if _, ok := cm.lookupCloner(t); ok {
+            return false
+        }
+    }
+    return true
+}
+
+// copiesBasic reports whether the values of the plain type t of a struct
+// field or an element, whose kind is flat, are copied as they are, as told
+// by copiesPlain, remembering the answer for the clone in progress.
+func (cm *CloneManager) copiesBasic(t reflect.Type) bool {
+    copies, ok := cm.basicCopies[t]
+    if !ok {
+        copies = cm.copiesPlain(planOf(t))
+        if cm.basicCopies == nil {
+            cm.basicCopies = make(map[reflect.Type]bool)
+        }
+        cm.basicCopies[t] = copies
+    }
+    return copies
+}
+
+// Precompile computes the plans of types and of the types they contain, so
+// that the first clone of their values on a latency-critical path does not
+// pay for analyzing them. Plans are shared by every manager.
+func (cm *CloneManager) Precompile(types ...reflect.Type) {
+    seen := make(map[reflect.Type]bool)
+    for _, t := range types {
+        precompile(t, seen)
+    }
+}
+
+// Precompile is like CloneManager.Precompile for type T.
+func Precompile[T any]() {
+    precompile(reflect.TypeOf((*T)(nil)).Elem(), make(map[reflect.Type]bool))
+}
+
+// precompile computes the plans of t and of the types it contains that are
+// not in seen.
+func precompile(t reflect.Type, seen map[reflect.Type]bool) {
+    if seen[t] {
+        return
+    }
+    seen[t] = true
+    planOf(t)
+    switch t.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Array:
+        precompile(t.Elem(), seen)
+    case reflect.Map:
+        precompile(t.Key(), seen)
+        precompile(t.Elem(), seen)
+    case reflect.Struct:
+        for i := 0; i < t.NumField(); i++ {
+            precompile(t.Field(i).Type, seen)
+        }
+    }
+}
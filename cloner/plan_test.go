@@ -0,0 +1,70 @@
+package cloner_test
+
+import (
+    "reflect"
+    "sync"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Request struct {
+    ID      int
+    Headers map[string][]string
+    Body    *[]byte
+    trace   string
+}
+
+// Test for short-lived managers cloning the same types concurrently
+func TestPlansSharedAcrossManagers(t *testing.T) {
+    body := []byte("payload")
+    original := Request{ID: 1, Headers: map[string][]string{"Accept": {"*/*"}}, Body: &body, trace: "t"}
+
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 100; j++ {
+                cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+                if err != nil {
+                    t.Errorf("Clone failed: %v", err)
+                    return
+                }
+                if cloned.ID != 1 || string(*cloned.Body) != "payload" || cloned.trace != "" {
+                    t.Errorf("got %+v, want a clone of %+v", cloned, original)
+                    return
+                }
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+// Test for precompiling the plans of recursive types
+func TestPrecompile(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    cm.Precompile(reflect.TypeOf(Request{}), reflect.TypeOf(&TreeNode{}))
+    cloner.Precompile[map[string][]*Request]()
+
+    original := newTree()
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Children[0].Parent != cloned {
+        t.Errorf("Cloned children do not point to the cloned root")
+    }
+}
+
+// Benchmark for cloning with a new manager per clone
+func BenchmarkShortLivedManagers(b *testing.B) {
+    body := []byte("payload")
+    original := Request{ID: 1, Headers: map[string][]string{"Accept": {"*/*"}}, Body: &body}
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        if _, err := cloner.Clone(cloner.NewCloneManager(), original); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
@@ -0,0 +1,91 @@
+package cloner
+
+import (
+    "encoding/json"
+    "hash/fnv"
+    "reflect"
+    "sync"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Provenance describes where a clone comes from, see WithProvenance.
+type Provenance struct {
+    // Source is the address of the root of the source graph, which
+    // identifies it while it is alive; it does not keep it alive. It is
+    // zero for sources that are not pointers, slices or maps.
+    Source uintptr
+    // Type is the type of the source.
+    Type reflect.Type
+    // Time is when the clone was made.
+    Time time.Time
+    // Config is a hash of the configuration of the manager that made the
+    // clone, as returned by Config, with the options of the call applied,
+    // so that clones made alike have the same hash.
+    Config uint64
+}
+
+// WithProvenance makes the manager record the Provenance of the clones made
+// by Clone, CloneValue, CloneBatch and the functions using them, so that
+// ProvenanceOf tells where a copy comes from. Provenances are kept in a
+// side table keyed by the root of the clone, which must be a pointer, a
+// slice or a map; they do not keep clones alive and are dropped once their
+// clone is garbage collected, with Go 1.24 or later.
+func WithProvenance() Option {
+    return func(o *options) {
+        o.provenance = true
+    }
+}
+
+// provenances holds the Provenance of clones by reference to their root.
+var provenances sync.Map
+
+// ProvenanceOf returns the Provenance of cloned, the root of a clone made
+// with WithProvenance, and false if it has none.
+func ProvenanceOf(cloned interface{}) (Provenance, bool) {
+    v := reflect.ValueOf(cloned)
+    if !tracksProvenance(v) {
+        return Provenance{}, false
+    }
+    p, ok := provenances.Load(memref.Of(v))
+    if !ok {
+        return Provenance{}, false
+    }
+    return *p.(*Provenance), true
+}
+
+// tracksProvenance reports whether clones rooted at v can have a
+// Provenance: non-nil pointers and maps, and slices with elements.
+func tracksProvenance(v reflect.Value) bool {
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Map:
+        return !v.IsNil()
+    case reflect.Slice:
+        return v.Cap() > 0
+    }
+    return false
+}
+
+// recordProvenance records the Provenance of clone, the clone of src made
+// by the session cm.
+func (cm *CloneManager) recordProvenance(src, clone reflect.Value) {
+    if !cm.options.provenance || !tracksProvenance(clone) {
+        return
+    }
+    p := &Provenance{Type: src.Type(), Time: time.Now(), Config: cm.configHash()}
+    switch src.Kind() {
+    case reflect.Ptr, reflect.Map, reflect.Slice:
+        p.Source = src.Pointer()
+    }
+    key := memref.Of(clone)
+    provenances.Store(key, p)
+    dropWhenUnreachable(&provenances, clone, key, p)
+}
+
+// configHash returns the FNV-1a hash of the configuration of cm.
+func (cm *CloneManager) configHash() uint64 {
+    h := fnv.New64a()
+    json.NewEncoder(h).Encode(cm.Config())
+    return h.Sum64()
+}
@@ -0,0 +1,21 @@
+//go:build go1.24
+
+package cloner
+
+import (
+    "reflect"
+    "runtime"
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// dropWhenUnreachable removes the entry of the clone rooted at v, whose key
+// is key and value is value, from the side table once v is garbage
+// collected, unless the address of v was reused by a later clone by then.
+// value must not refer to v.
+func dropWhenUnreachable(table *sync.Map, v reflect.Value, key memref.Ref, value interface{}) {
+    runtime.AddCleanup((*byte)(v.UnsafePointer()), func(value interface{}) {
+        table.CompareAndDelete(key, value)
+    }, value)
+}
@@ -0,0 +1,14 @@
+//go:build !go1.24
+
+package cloner
+
+import (
+    "reflect"
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// dropWhenUnreachable does nothing before Go 1.24: the entries of side
+// tables are kept for the life of the program.
+func dropWhenUnreachable(table *sync.Map, v reflect.Value, key memref.Ref, value interface{}) {}
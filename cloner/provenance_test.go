@@ -0,0 +1,47 @@
+package cloner_test
+
+import (
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for retrieving the provenance of clones
+func TestProvenanceOf(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithProvenance())
+    person := &Person{Name: "Ann"}
+    before := time.Now()
+    cloned, err := cloner.Clone(cm, person)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    p, ok := cloner.ProvenanceOf(cloned)
+    if !ok {
+        t.Fatalf("got no provenance for the clone")
+    }
+    if p.Source != reflect.ValueOf(person).Pointer() || p.Type != reflect.TypeOf(person) || p.Time.Before(before) {
+        t.Errorf("got provenance %+v, want the person cloned after %v", p, before)
+    }
+
+    again, _ := cloner.Clone(cm, map[string]int{"a": 1})
+    if q, ok := cloner.ProvenanceOf(again); !ok || q.Config != p.Config {
+        t.Errorf("got provenance %+v, %v, want the config hash %x", q, ok, p.Config)
+    }
+    limited, _ := cloner.Clone(cm, person, cloner.WithMaxDepth(3))
+    if q, ok := cloner.ProvenanceOf(limited); !ok || q.Config == p.Config {
+        t.Errorf("got provenance %+v, %v, want another config hash than %x", q, ok, p.Config)
+    }
+
+    if _, ok := cloner.ProvenanceOf(person); ok {
+        t.Errorf("got a provenance for the source")
+    }
+    if _, ok := cloner.ProvenanceOf(&cloned.Name); ok {
+        t.Errorf("got a provenance for a field of the clone")
+    }
+    plain, _ := cloner.Clone(cloner.NewCloneManager(), person)
+    if _, ok := cloner.ProvenanceOf(plain); ok {
+        t.Errorf("got a provenance without WithProvenance")
+    }
+}
@@ -0,0 +1,64 @@
+package cloner
+
+import (
+    "errors"
+    "reflect"
+)
+
+var (
+    reflectTypeType  = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+    reflectValueType = reflect.TypeOf(reflect.Value{})
+)
+
+// CloneValue is like Clone for callers already working with reflection. The
+// clone is returned as an addressable value of the type of src, so that an
+// interface type or the addressability of src is not lost by boxing it in an
+// interface{}. An invalid src yields an invalid value. Options apply to this
+// call as in Clone.
+func (cm *CloneManager) CloneValue(src reflect.Value, opts ...Option) (reflect.Value, error) {
+    if !src.IsValid() {
+        return reflect.Value{}, nil
+    }
+    if cm.visited == nil {
+        session := cm.session(opts...)
+        clone, err := session.CloneValue(src)
+        session.setParents()
+        if err == nil && len(session.errs) > 0 {
+            err = errors.Join(session.errs...)
+        }
+        session.reportClone(src, err)
+        if err == nil && src.CanInterface() && clone.CanInterface() {
+            session.recordClone(src.Interface(), clone.Interface())
+        }
+        return clone, err
+    }
+    defer cm.withOptions(opts)()
+    cloned, err := cm.deepClone(src)
+    if err != nil {
+        return reflect.Value{}, err
+    }
+    ptr, err := cm.allocate(src.Type())
+    if err != nil {
+        return reflect.Value{}, cm.report(src, err)
+    }
+    clone := ptr.Elem()
+    if err := cm.assign(clone, cloned, src); err != nil {
+        return reflect.Value{}, err
+    }
+    return clone, nil
+}
+
+// cloneReflect handles values of the reflect package found in the graph:
+// a reflect.Type describes a type rather than holding data and is shared, and
+// a reflect.Value is cloned through the value it holds. It reports false for
+// other values.
+func (cm *CloneManager) cloneReflect(src reflect.Value) (interface{}, bool, error) {
+    switch {
+    case src.Type().Implements(reflectTypeType):
+        return src.Interface(), true, nil
+    case src.Type() == reflectValueType:
+        clone, err := cm.CloneValue(src.Interface().(reflect.Value))
+        return clone, true, err
+    }
+    return nil, false, nil
+}
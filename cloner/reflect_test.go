@@ -0,0 +1,89 @@
+package cloner_test
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for cloning reflect.Value inputs
+func TestCloneValue(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    original := &TestStruct{A: 1, B: new(int)}
+    cloned, err := cm.CloneValue(reflect.ValueOf(original).Elem())
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Type() != reflect.TypeOf(TestStruct{}) || !cloned.CanAddr() {
+        t.Fatalf("Cloned value is not an addressable TestStruct: %v", cloned)
+    }
+    deepEqual(t, cloned.Interface(), *original)
+
+    // Fields of the clone can be set directly
+    cloned.Field(0).SetInt(2)
+    *original.B = 3
+    if original.A != 1 || *cloned.Interface().(TestStruct).B != 0 {
+        t.Errorf("The clone shares memory with the original")
+    }
+
+    // The static interface type of src is kept
+    var stringer fmt.Stringer = Label("a")
+    clonedStringer, err := cm.CloneValue(reflect.ValueOf(&stringer).Elem())
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if clonedStringer.Type() != reflect.TypeOf(&stringer).Elem() || clonedStringer.Interface() != Label("a") {
+        t.Errorf("Cloned value is incorrect: got %v (%v)", clonedStringer, clonedStringer.Type())
+    }
+
+    // Invalid values clone to invalid values
+    if invalid, err := cm.CloneValue(reflect.Value{}); err != nil || invalid.IsValid() {
+        t.Errorf("got %v, %v, want an invalid value", invalid, err)
+    }
+
+    _, err = cm.CloneValue(reflect.ValueOf(make(chan int)))
+    if !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUncloneableKind", err)
+    }
+}
+
+// Test for sharing reflect.Type and cloning reflect.Value values in a graph
+func TestCloneReflectValues(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithUnexportedFields(cloner.Error))
+
+    type Field struct {
+        Type  reflect.Type
+        Value reflect.Value
+    }
+    items := []int{1, 2}
+    original := []Field{
+        {Type: reflect.TypeOf(0), Value: reflect.ValueOf(items)},
+        {Type: reflect.TypeOf(Field{})},
+    }
+
+    if err := cm.Validate(original); err != nil {
+        t.Fatalf("Validate failed: %v", err)
+    }
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    if cloned[0].Type != reflect.TypeOf(0) || cloned[1].Type != reflect.TypeOf(Field{}) {
+        t.Errorf("Cloned types are incorrect: got %v and %v", cloned[0].Type, cloned[1].Type)
+    }
+    if cloned[1].Value.IsValid() {
+        t.Errorf("Cloned zero reflect.Value is valid: %v", cloned[1].Value)
+    }
+
+    clonedItems := cloned[0].Value.Interface().([]int)
+    deepEqual(t, clonedItems, items)
+    items[0] = 0
+    if clonedItems[0] != 1 {
+        t.Errorf("Modifying the original affected the cloned reflect.Value")
+    }
+}
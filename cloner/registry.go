@@ -0,0 +1,141 @@
+package cloner
+
+import (
+    "fmt"
+    "reflect"
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+var (
+    registry      = make(map[reflect.Type]Cloner)
+    scalars       = make(map[reflect.Type]bool)
+    containers    = make(map[string]ContainerAdapter)
+    comparers     = make(map[reflect.Type]func(a, b reflect.Value) bool)
+    registryMutex sync.RWMutex // Mutex for concurrent access
+
+    defaultManager     *CloneManager
+    defaultManagerOnce sync.Once
+)
+
+// Register registers fn as the cloner for values of type T in the default
+// registry. Every CloneManager consults the registry for types that have no
+// cloner registered with the manager itself, so packages can ship cloners for
+// their own types by calling Register from an init function.
+//
+// Registering a second cloner for the same type replaces the first.
+func Register[T any](fn func(src T, manager *CloneManager) (T, error)) {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    registryMutex.Lock()
+    defer registryMutex.Unlock()
+    registry[t] = ClonerFunc(func(value interface{}, manager *CloneManager) (interface{}, error) {
+        return fn(value.(T), manager)
+    })
+}
+
+// RegisterSliceCloner registers fn as a fast path cloning slices of type S,
+// such as []Point, in the default registry, for slices whose elements are
+// cheaper to copy with code written for them than by reflection. fn is only
+// called for non-nil slices and returns their clone; the manager still finds
+// S wherever it is nested in a graph, and references to the same slice
+// resolve to a single clone.
+//
+//	cloner.RegisterSliceCloner[[]Point](func(src []Point) []Point {
+//	    return append([]Point(nil), src...)
+//	})
+func RegisterSliceCloner[S ~[]E, E any](fn func(src S) S) {
+    registerFast(fn)
+}
+
+// RegisterMapCloner is like RegisterSliceCloner for maps of type M.
+func RegisterMapCloner[M ~map[K]V, K comparable, V any](fn func(src M) M) {
+    registerFast(fn)
+}
+
+// registerFast registers fn as the cloner of the non-nil slices or maps of
+// type T, tracking the references it clones.
+func registerFast[T any](fn func(T) T) {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    registryMutex.Lock()
+    defer registryMutex.Unlock()
+    registry[t] = ClonerFunc(func(value interface{}, manager *CloneManager) (interface{}, error) {
+        v := reflect.ValueOf(value)
+        if v.IsNil() {
+            return value, nil
+        }
+        ref := memref.Of(v)
+        if cloned, ok := manager.lookup(ref); ok {
+            return cloned, nil
+        }
+        cloned := fn(value.(T))
+        manager.record(ref, cloned)
+        return cloned, nil
+    })
+}
+
+// RegisterScalar makes every manager copy the values of T, a named array
+// type such as a hash or an ID, with a single assignment, so that they are
+// cloned as one value rather than element by element and do not inflate
+// node counts and stats. Arrays of booleans, numbers and strings of up to
+// 128 bytes, such as [16]byte UUIDs, are copied this way already. The
+// references held by the elements of T, if any, are shared with the source.
+// Types must be registered before their values are first cloned, typically
+// from an init function. RegisterScalar panics if T is not an array type.
+func RegisterScalar[T any]() {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    if t.Kind() != reflect.Array {
+        panic(fmt.Sprintf("cloner: RegisterScalar of %v, want an array type", t))
+    }
+    registryMutex.Lock()
+    defer registryMutex.Unlock()
+    scalars[t] = true
+}
+
+// isScalar reports whether t is registered with RegisterScalar.
+func isScalar(t reflect.Type) bool {
+    registryMutex.RLock()
+    defer registryMutex.RUnlock()
+    return scalars[t]
+}
+
+// registered returns the Cloner for t from the default registry.
+func registered(t reflect.Type) (Cloner, bool) {
+    registryMutex.RLock()
+    defer registryMutex.RUnlock()
+    cloner, found := registry[t]
+    return cloner, found
+}
+
+// RegisterComparer registers fn as the comparison of values of type T for
+// Equal and Diff, so that types whose representation varies, such as
+// *big.Float or decimals, compare by value rather than field by field.
+// Comparers take precedence over Equal methods.
+//
+// Registering a second comparer for the same type replaces the first.
+func RegisterComparer[T any](fn func(a, b T) bool) {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    registryMutex.Lock()
+    defer registryMutex.Unlock()
+    comparers[t] = func(a, b reflect.Value) bool {
+        return fn(a.Interface().(T), b.Interface().(T))
+    }
+}
+
+// registeredComparer returns the comparer registered for t.
+func registeredComparer(t reflect.Type) (func(a, b reflect.Value) bool, bool) {
+    registryMutex.RLock()
+    defer registryMutex.RUnlock()
+    compare, found := comparers[t]
+    return compare, found
+}
+
+// Default returns the process-wide CloneManager. It has no cloners of its own
+// and relies on the default registry, see Register. It is safe for concurrent
+// use; cloners should be added with Register rather than RegisterCloner.
+func Default() *CloneManager {
+    defaultManagerOnce.Do(func() {
+        defaultManager = NewCloneManager()
+    })
+    return defaultManager
+}
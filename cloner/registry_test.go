@@ -0,0 +1,185 @@
+package cloner_test
+
+import (
+    "reflect"
+    "sync"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Handle is cloned by a cloner registered in the default registry.
+type Handle struct {
+    ID     int
+    Shared *int
+}
+
+// Point has a fast path cloner for []Point and map[string]Point.
+type Point struct {
+    X, Y int
+}
+
+var fastClones int
+
+// Digest is a large array registered as a scalar.
+type Digest [64]uint32
+
+// Blob is identified by a UUID and a digest.
+type Blob struct {
+    ID     [16]byte
+    Digest Digest
+    Chunks []Digest
+}
+
+// Overridden has a registered cloner that a manager overrides.
+type Overridden struct {
+    A int
+}
+
+func init() {
+    cloner.Register(func(src Handle, manager *cloner.CloneManager) (Handle, error) {
+        // Handles keep pointing to the shared value
+        return Handle{ID: src.ID + 1000, Shared: src.Shared}, nil
+    })
+    cloner.Register(func(src Overridden, manager *cloner.CloneManager) (Overridden, error) {
+        return Overridden{A: -1}, nil
+    })
+    cloner.RegisterSliceCloner[[]Point](func(src []Point) []Point {
+        fastClones++
+        return append([]Point(nil), src...)
+    })
+    cloner.RegisterMapCloner[map[string]Point](func(src map[string]Point) map[string]Point {
+        fastClones++
+        return cloner.Map(src, nil)
+    })
+    cloner.RegisterScalar[Digest]()
+}
+
+// Test for cloners registered in the default registry
+func TestRegister(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    shared := 7
+    original := struct {
+        Handles []Handle
+    }{Handles: []Handle{{ID: 1, Shared: &shared}}}
+
+    cloned, err := cm.Clone(original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    clonedStruct := cloned.(struct {
+        Handles []Handle
+    })
+
+    got := clonedStruct.Handles[0]
+    if got.ID != 1001 {
+        t.Errorf("Registered cloner was not used: got ID %d, want 1001", got.ID)
+    }
+    if got.Shared != &shared {
+        t.Errorf("Registered cloner result was not kept")
+    }
+}
+
+// Test for fast path cloners of slices and maps
+func TestRegisterSliceCloner(t *testing.T) {
+    path := []Point{{1, 2}, {3, 4}}
+    original := struct {
+        Path, Same []Point
+        Marks      map[string]Point
+        Empty      []Point
+    }{Path: path, Same: path, Marks: map[string]Point{"a": {5, 6}}}
+
+    fastClones = 0
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, cloned, original)
+    if fastClones != 2 {
+        t.Errorf("got %d fast path clones, want 2", fastClones)
+    }
+    if &cloned.Path[0] == &path[0] || &cloned.Same[0] != &cloned.Path[0] {
+        t.Errorf("Cloned slices do not share a single clone")
+    }
+}
+
+// Test that cloners registered with a manager take precedence
+func TestRegisterOverriddenByManager(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(Overridden{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+        return Overridden{A: 1}, nil
+    }))
+
+    cloned, err := cm.Clone(Overridden{A: 42})
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, cloned, Overridden{A: 1})
+
+    cloned, err = cloner.Default().Clone(Overridden{A: 42})
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, cloned, Overridden{A: -1})
+}
+
+// Test for concurrent use of the default manager
+func TestDefaultConcurrent(t *testing.T) {
+    if cloner.Default() != cloner.Default() {
+        t.Fatalf("Default returned different managers")
+    }
+
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            original := &TestStruct{A: i, B: new(int)}
+            *original.B = i * 10
+            cloned, err := cloner.Default().Clone(original)
+            if err != nil {
+                t.Errorf("Clone failed: %v", err)
+                return
+            }
+            clonedStruct := cloned.(*TestStruct)
+            if clonedStruct == original || clonedStruct.B == original.B {
+                t.Errorf("Clone did not create new pointers")
+            }
+            if clonedStruct.A != i || *clonedStruct.B != i*10 {
+                t.Errorf("got = %+v, want = %+v", clonedStruct, original)
+            }
+        }(i)
+    }
+    wg.Wait()
+}
+
+// Test for arrays copied with a single assignment
+func TestRegisterScalar(t *testing.T) {
+    original := Blob{ID: [16]byte{1}, Digest: Digest{2}, Chunks: []Digest{{3}, {4}}}
+    var nodes int
+    cm := cloner.NewCloneManager(cloner.WithOnClone(func(r cloner.Report) {
+        nodes = r.Nodes
+    }))
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil || !reflect.DeepEqual(cloned, original) || &cloned.Chunks[0] == &original.Chunks[0] {
+        t.Fatalf("got clone %+v and error %v, want a deep clone", cloned, err)
+    }
+    // The struct and the slice of chunks
+    if nodes != 2 {
+        t.Errorf("got %d nodes, want 2", nodes)
+    }
+
+    // Scalars count as one value with budgets too
+    chunks, err := cloner.Clone(cm, original.Chunks, cloner.WithMaxNodes(3))
+    if err != nil || nodes != 3 || chunks[1] != original.Chunks[1] {
+        t.Errorf("got clone %v, %d nodes and error %v, want 3 nodes", chunks, nodes, err)
+    }
+
+    defer func() {
+        if recover() == nil {
+            t.Error("RegisterScalar of a struct did not panic")
+        }
+    }()
+    cloner.RegisterScalar[Point]()
+}
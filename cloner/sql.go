@@ -0,0 +1,72 @@
+package cloner
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "reflect"
+    "time"
+)
+
+// valueTypes are struct types whose unexported fields hold immutable data
+// the clone can share, so that their values are copied as they are. Values
+// of the generic sql.Null[T] are cloned field by field like other structs.
+var valueTypes = map[reflect.Type]bool{
+    reflect.TypeOf(time.Time{}):       true,
+    reflect.TypeOf(sql.NullBool{}):    true,
+    reflect.TypeOf(sql.NullByte{}):    true,
+    reflect.TypeOf(sql.NullFloat64{}): true,
+    reflect.TypeOf(sql.NullInt16{}):   true,
+    reflect.TypeOf(sql.NullInt32{}):   true,
+    reflect.TypeOf(sql.NullInt64{}):   true,
+    reflect.TypeOf(sql.NullString{}):  true,
+    reflect.TypeOf(sql.NullTime{}):    true,
+}
+
+// sqlPackage is the import path of the types of database/sql.
+const sqlPackage = "database/sql"
+
+var (
+    valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+    scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// WithValuers makes the manager clone the values of types implementing
+// driver.Valuer, whose pointers implement sql.Scanner, by scanning the
+// result of their Value method into a new value, the way they round-trip
+// through a database, so that ORM model fields holding JSON documents,
+// arrays or encrypted strings are cloned through their own encoding. Byte
+// slices returned by Value are copied before being scanned. The types of
+// database/sql, such as sql.Null[T], and types implementing driver.Valuer
+// on their pointer only are cloned as usual.
+func WithValuers() Option {
+    return func(o *options) {
+        o.valuers = true
+    }
+}
+
+// scans reports whether the values of t are cloned by cloneValuer.
+func (cm *CloneManager) scans(t reflect.Type) bool {
+    return cm.options.valuers && t.Kind() != reflect.Ptr && t.PkgPath() != sqlPackage &&
+        t.Implements(valuerType) && reflect.PointerTo(t).Implements(scannerType)
+}
+
+// cloneValuer clones src through its Value and Scan methods if WithValuers
+// applies to it, reporting whether it does.
+func (cm *CloneManager) cloneValuer(src reflect.Value) (interface{}, bool, error) {
+    if !cm.scans(src.Type()) || !src.CanInterface() {
+        return nil, false, nil
+    }
+    value, err := src.Interface().(driver.Valuer).Value()
+    if err != nil {
+        return nil, true, err
+    }
+    if b, ok := value.([]byte); ok {
+        value = append([]byte(nil), b...)
+    }
+    clone := reflect.New(src.Type())
+    if err := clone.Interface().(sql.Scanner).Scan(value); err != nil {
+        return nil, true, err
+    }
+    cm.log(LogCloners, "value scanned", src)
+    return clone.Elem().Interface(), true, nil
+}
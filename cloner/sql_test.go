@@ -0,0 +1,100 @@
+package cloner_test
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "encoding/json"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Attributes is a JSON document stored in a database column.
+type Attributes map[string]string
+
+func (a Attributes) Value() (driver.Value, error) {
+    return json.Marshal(a)
+}
+
+func (a *Attributes) Scan(value interface{}) error {
+    b, ok := value.([]byte)
+    if !ok {
+        return errors.New("attributes must be bytes")
+    }
+    return json.Unmarshal(b, a)
+}
+
+// Token is a string stored in a database column, kept unexported.
+type Token struct {
+    value string
+}
+
+func (t Token) Value() (driver.Value, error) {
+    return t.value, nil
+}
+
+func (t *Token) Scan(value interface{}) error {
+    s, ok := value.(string)
+    if !ok {
+        return errors.New("token must be a string")
+    }
+    t.value = s
+    return nil
+}
+
+type Customer struct {
+    ID       sql.NullInt64
+    Email    sql.NullString
+    Created  time.Time
+    Verified sql.NullTime
+    Closed   sql.Null[time.Time]
+    Tags     sql.Null[[]string]
+    Attrs    Attributes
+    Token    Token
+}
+
+// Test for cloning the database/sql Null types and driver.Valuer types
+func TestSQLTypes(t *testing.T) {
+    now := time.Now()
+    original := Customer{
+        ID:       sql.NullInt64{Int64: 1, Valid: true},
+        Email:    sql.NullString{String: "a@example.com", Valid: true},
+        Created:  now,
+        Verified: sql.NullTime{Time: now, Valid: true},
+        Closed:   sql.Null[time.Time]{V: now, Valid: true},
+        Tags:     sql.Null[[]string]{V: []string{"new"}, Valid: true},
+        Attrs:    Attributes{"plan": "pro"},
+        Token:    Token{value: "t"},
+    }
+
+    cm := cloner.NewCloneManager()
+    if err := cm.Validate(original, cloner.WithUnexportedFields(cloner.Error)); err == nil {
+        t.Error("Validate succeeded, want the token reported")
+    }
+    cloned, err := cloner.Clone(cm, original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if !cloned.Created.Equal(now) || !cloned.Verified.Time.Equal(now) || !cloned.Closed.V.Equal(now) {
+        t.Errorf("got times %v, %v and %v, want %v", cloned.Created, cloned.Verified.Time, cloned.Closed.V, now)
+    }
+    if cloned.Email != original.Email || cloned.Tags.V[0] != "new" || &cloned.Tags.V[0] == &original.Tags.V[0] {
+        t.Errorf("got clone %+v, want a deep clone of %+v", cloned, original)
+    }
+
+    // Valuers are cloned through their encoding
+    cm = cloner.NewCloneManager(cloner.WithValuers(), cloner.WithUnexportedFields(cloner.Error))
+    if err := cm.Validate(original); err != nil {
+        t.Fatalf("Validate failed: %v", err)
+    }
+    cloned, err = cloner.Clone(cm, original)
+    if err != nil || cloned.Attrs["plan"] != "pro" || cloned.Token.value != "t" || !cloner.Equal(original, cloned) {
+        t.Errorf("got clone %+v and error %v, want the attributes scanned", cloned, err)
+    }
+    cloned.Attrs["plan"] = "free"
+    if original.Attrs["plan"] != "pro" {
+        t.Error("clone of the attributes shares the source")
+    }
+}
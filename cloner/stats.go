@@ -0,0 +1,364 @@
+package cloner
+
+import (
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "text/tabwriter"
+    "time"
+)
+
+var (
+    stats       = make(map[string]int)
+    statsWindow *window[string] // nil unless SetStatsWindow is called
+    statsMutex  sync.Mutex      // Mutex for concurrent access
+)
+
+// UpdateStats increments the count for the given type in the stats map.
+func UpdateStats(typeName string) {
+    statsMutex.Lock()
+    defer statsMutex.Unlock()
+    stats[typeName]++
+    if statsWindow != nil {
+        statsWindow.add(time.Now(), typeName, 0)
+    }
+}
+
+// Stats returns a copy of the counts of UpdateStats.
+func Stats() map[string]int {
+    statsMutex.Lock()
+    defer statsMutex.Unlock()
+    counts := make(map[string]int, len(stats))
+    for k, v := range stats {
+        counts[k] = v
+    }
+    return counts
+}
+
+// FormatStats formats the counts of UpdateStats as "name: count" lines,
+// sorted by name.
+func FormatStats() string {
+    return FormatStatsAs(FormatText)
+}
+
+// Format is a format of FormatStatsAs.
+type Format int
+
+const (
+    // FormatText writes "name: count" lines, as FormatStats.
+    FormatText Format = iota
+    // FormatJSON writes a JSON object mapping names to counts.
+    FormatJSON
+    // FormatTSV writes a "name\tcount" header line and a line per count,
+    // separated by tabs.
+    FormatTSV
+    // FormatTable writes a table with a header and aligned columns.
+    FormatTable
+)
+
+// FormatStatsAs formats the counts of UpdateStats in format f, sorted by
+// name so that the output is stable, e.g. to be compared in tests or served
+// by debug endpoints.
+func FormatStatsAs(f Format) string {
+    statsMutex.Lock()
+    names := make([]string, 0, len(stats))
+    for k := range stats {
+        names = append(names, k)
+    }
+    sort.Strings(names)
+    counts := make([]int, len(names))
+    for i, k := range names {
+        counts[i] = stats[k]
+    }
+    statsMutex.Unlock()
+
+    b := strings.Builder{}
+    switch f {
+    case FormatJSON:
+        b.WriteString("{")
+        for i, k := range names {
+            if i > 0 {
+                b.WriteString(",")
+            }
+            name, _ := json.Marshal(k)
+            b.Write(name)
+            b.WriteString(":")
+            b.WriteString(strconv.Itoa(counts[i]))
+        }
+        b.WriteString("}\n")
+    case FormatTSV:
+        b.WriteString("name\tcount\n")
+        for i, k := range names {
+            b.WriteString(fmt.Sprintf("%s\t%d\n", k, counts[i]))
+        }
+    case FormatTable:
+        w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(w, "NAME\tCOUNT")
+        for i, k := range names {
+            fmt.Fprintf(w, "%s\t%d\n", k, counts[i])
+        }
+        w.Flush()
+    default:
+        for i, k := range names {
+            b.WriteString(fmt.Sprintf("%s: %d\n", k, counts[i]))
+        }
+    }
+    return b.String()
+}
+
+// ResetStats clears the counts of UpdateStats, including those of the
+// window set by SetStatsWindow, so that long-running processes can observe
+// the clones made since a point in time.
+func ResetStats() {
+    statsMutex.Lock()
+    defer statsMutex.Unlock()
+    stats = make(map[string]int)
+    if statsWindow != nil {
+        statsWindow = newWindow[string](statsWindow.size)
+    }
+}
+
+// SetStatsWindow makes UpdateStats also keep the counts of the last d,
+// reported by RecentStats, so that the recent behavior of clones can be told
+// from the counts since the process started. The counts are kept in 60
+// buckets, so SetStatsWindow(time.Minute) counts the last minute to within a
+// second. Zero stops keeping them.
+func SetStatsWindow(d time.Duration) {
+    statsMutex.Lock()
+    defer statsMutex.Unlock()
+    statsWindow = nil
+    if d > 0 {
+        statsWindow = newWindow[string](d)
+    }
+}
+
+// RecentStats returns the counts of UpdateStats over the window set by
+// SetStatsWindow, or nil if none is set.
+func RecentStats() map[string]int {
+    statsMutex.Lock()
+    defer statsMutex.Unlock()
+    if statsWindow == nil {
+        return nil
+    }
+    recent := make(map[string]int)
+    for k, c := range statsWindow.sum(time.Now()) {
+        recent[k] = c.count
+    }
+    return recent
+}
+
+// TypeStats are the statistics of the clones of a type collected with
+// WithTypeStats.
+type TypeStats struct {
+    Type reflect.Type
+    // Count is the number of values of the type cloned.
+    Count int
+    // Time is the time spent cloning them, not counting the time spent
+    // cloning the values they reference.
+    Time time.Duration
+}
+
+// WithTypeStats makes the manager collect the number of values of each
+// type it clones and the time it spends on them, reported by HotTypes.
+// Values copied as they are, such as numbers and strings, are not counted.
+// Timing every value makes clones slower.
+func WithTypeStats() Option {
+    return func(o *options) {
+        o.typeStats = true
+    }
+}
+
+// HotTypes returns the statistics of the n types the manager spent the most
+// time cloning, most expensive first, as collected with WithTypeStats. They
+// tell where a Cloner or a type registered with Precompile would pay off.
+// n <= 0 returns every type.
+func (cm *CloneManager) HotTypes(n int) []TypeStats {
+    if cm.types == nil {
+        return nil
+    }
+    cm.types.mutex.Lock()
+    hot := make([]TypeStats, 0, len(cm.types.stats))
+    for _, s := range cm.types.stats {
+        hot = append(hot, *s)
+    }
+    cm.types.mutex.Unlock()
+    return hottest(hot, n)
+}
+
+// RecentHotTypes is like HotTypes for the clones made over the window set by
+// WithStatsWindow.
+func (cm *CloneManager) RecentHotTypes(n int) []TypeStats {
+    if cm.types == nil {
+        return nil
+    }
+    cm.types.mutex.Lock()
+    var hot []TypeStats
+    if cm.types.window != nil {
+        for t, c := range cm.types.window.sum(time.Now()) {
+            hot = append(hot, TypeStats{Type: t, Count: c.count, Time: c.time})
+        }
+    }
+    cm.types.mutex.Unlock()
+    return hottest(hot, n)
+}
+
+// WithStatsWindow makes a manager collecting WithTypeStats also keep the
+// statistics of the last d, reported by RecentHotTypes, in 60 buckets like
+// SetStatsWindow.
+func WithStatsWindow(d time.Duration) Option {
+    return func(o *options) {
+        o.statsWindow = d
+    }
+}
+
+// ResetStats clears the statistics collected by the manager with
+// WithTypeStats.
+func (cm *CloneManager) ResetStats() {
+    if cm.types == nil {
+        return
+    }
+    cm.types.mutex.Lock()
+    defer cm.types.mutex.Unlock()
+    cm.types.stats, cm.types.window = nil, nil
+}
+
+// hottest sorts stats, most expensive first, and returns the first n.
+func hottest(stats []TypeStats, n int) []TypeStats {
+    sort.Slice(stats, func(i, j int) bool {
+        switch {
+        case stats[i].Time != stats[j].Time:
+            return stats[i].Time > stats[j].Time
+        case stats[i].Count != stats[j].Count:
+            return stats[i].Count > stats[j].Count
+        }
+        return stats[i].Type.String() < stats[j].Type.String()
+    })
+    if n > 0 && n < len(stats) {
+        stats = stats[:n]
+    }
+    return stats
+}
+
+// typeStats holds the TypeStats of a manager, shared by its clones.
+type typeStats struct {
+    mutex  sync.Mutex
+    stats  map[reflect.Type]*TypeStats
+    window *window[reflect.Type] // nil unless WithStatsWindow is used
+}
+
+// add accounts for the clone of a value of type t that took d, keeping it
+// in a window of the given size if it is positive.
+func (ts *typeStats) add(t reflect.Type, d, size time.Duration) {
+    ts.mutex.Lock()
+    defer ts.mutex.Unlock()
+    s, ok := ts.stats[t]
+    if !ok {
+        if ts.stats == nil {
+            ts.stats = make(map[reflect.Type]*TypeStats)
+        }
+        s = &TypeStats{Type: t}
+        ts.stats[t] = s
+    }
+    s.Count++
+    s.Time += d
+
+    if size > 0 {
+        if ts.window == nil || ts.window.size != size {
+            ts.window = newWindow[reflect.Type](size)
+        }
+        ts.window.add(time.Now(), t, d)
+    }
+}
+
+// windowBuckets is the number of buckets the counts of a window are kept in.
+const windowBuckets = 60
+
+// window keeps the counts of keys over the last size, in windowBuckets
+// buckets of consecutive periods.
+type window[K comparable] struct {
+    size    time.Duration
+    period  time.Duration
+    buckets [windowBuckets]bucket[K]
+}
+
+// bucket holds the counts of a period of a window.
+type bucket[K comparable] struct {
+    n      int64 // number of the period since the Unix epoch
+    counts map[K]*counter
+}
+
+// counter counts values and the time spent on them.
+type counter struct {
+    count int
+    time  time.Duration
+}
+
+func newWindow[K comparable](size time.Duration) *window[K] {
+    period := size / windowBuckets
+    if period <= 0 {
+        period = 1
+    }
+    return &window[K]{size: size, period: period}
+}
+
+// add counts key, which took d, at time now.
+func (w *window[K]) add(now time.Time, key K, d time.Duration) {
+    n := now.UnixNano() / int64(w.period)
+    b := &w.buckets[n%windowBuckets]
+    if b.counts == nil || b.n != n {
+        b.n, b.counts = n, make(map[K]*counter)
+    }
+    c, ok := b.counts[key]
+    if !ok {
+        c = &counter{}
+        b.counts[key] = c
+    }
+    c.count++
+    c.time += d
+}
+
+// sum returns the counts of the window ending at time now.
+func (w *window[K]) sum(now time.Time) map[K]counter {
+    n := now.UnixNano() / int64(w.period)
+    sum := make(map[K]counter)
+    for _, b := range w.buckets {
+        if b.counts == nil || b.n <= n-windowBuckets {
+            continue
+        }
+        for key, c := range b.counts {
+            s := sum[key]
+            s.count += c.count
+            s.time += c.time
+            sum[key] = s
+        }
+    }
+    return sum
+}
+
+// timing is the state of the clone of a value timed for WithTypeStats.
+type timing struct {
+    start    time.Time
+    children time.Duration // time spent on the values cloned before
+}
+
+// startTiming starts timing the clone of a value.
+func (cm *CloneManager) startTiming() timing {
+    t := timing{start: time.Now(), children: cm.children}
+    cm.children = 0
+    return t
+}
+
+// stopTiming accounts for the clone of src started at t, excluding the time
+// spent cloning the values it references.
+func (cm *CloneManager) stopTiming(src reflect.Value, t timing) {
+    total := time.Since(t.start)
+    self := total - cm.children
+    cm.children = t.children + total
+    if src.IsValid() {
+        cm.types.add(src.Type(), self, cm.options.statsWindow)
+    }
+}
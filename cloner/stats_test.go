@@ -0,0 +1,129 @@
+package cloner_test
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for reporting the types that clones spend the most time on
+func TestHotTypes(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithTypeStats())
+    cm.RegisterCloner(reflect.TypeOf(&Person{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        time.Sleep(2 * time.Millisecond)
+        return &Person{}, nil
+    }))
+    folders := []Folder{{Owner: &Person{}}, {Owner: &Person{}}, {Owner: &Person{}}}
+    for i := 0; i < 2; i++ {
+        if _, err := cm.Clone(folders); err != nil {
+            t.Fatalf("Clone failed: %v", err)
+        }
+    }
+
+    hot := cm.HotTypes(1)
+    if len(hot) != 1 || hot[0].Type != reflect.TypeOf(&Person{}) || hot[0].Count != 6 || hot[0].Time < 12*time.Millisecond {
+        t.Fatalf("got hot types %+v, want *Person cloned 6 times in 12ms or more", hot)
+    }
+    counts := map[reflect.Type]int{}
+    for _, s := range cm.HotTypes(0) {
+        counts[s.Type] = s.Count
+        if s.Type != hot[0].Type && s.Time >= hot[0].Time {
+            t.Errorf("got %v for %v, want less than *Person without the time of its values", s.Time, s.Type)
+        }
+    }
+    // Nil fields are cloned values too
+    want := map[reflect.Type]int{
+        reflect.TypeOf(folders):                    2,
+        reflect.TypeOf(Folder{}):                   6,
+        reflect.TypeOf(&Folder{}):                  6,
+        reflect.TypeOf([]string{}):                 6,
+        reflect.TypeOf(map[string]interface{}{}):   6,
+        reflect.TypeOf((*interface{})(nil)).Elem(): 6,
+        reflect.TypeOf(&Person{}):                  6,
+    }
+    deepEqual(t, counts, want)
+
+    // Stats are not collected by default
+    if hot := cloner.NewCloneManager().HotTypes(0); len(hot) != 0 {
+        t.Errorf("got hot types %+v without WithTypeStats", hot)
+    }
+}
+
+// Test for resetting stats and counting the clones of a recent window
+func TestResetStats(t *testing.T) {
+    defer cloner.SetStatsWindow(0)
+    cloner.SetStatsWindow(60 * time.Millisecond)
+    cloner.ResetStats()
+    if _, err := cloner.NewCloneManager().Clone([]*Person{{}}); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    want := map[string]int{"slice": 1, "ptr": 1, "struct cloner_test.Person": 1}
+    deepEqual(t, cloner.RecentStats(), want)
+    if got := cloner.FormatStats(); !strings.Contains(got, "slice: 1\n") {
+        t.Errorf("got stats %q, want 1 slice", got)
+    }
+
+    time.Sleep(80 * time.Millisecond)
+    deepEqual(t, cloner.RecentStats(), map[string]int{})
+    cloner.ResetStats()
+    if got := cloner.FormatStats(); got != "" {
+        t.Errorf("got stats %q after ResetStats, want none", got)
+    }
+    cloner.SetStatsWindow(0)
+    if got := cloner.RecentStats(); got != nil {
+        t.Errorf("got recent stats %v without a window", got)
+    }
+}
+
+// Test for resetting the stats of a manager and its recent hot types
+func TestRecentHotTypes(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithTypeStats(), cloner.WithStatsWindow(60*time.Millisecond))
+    if _, err := cm.Clone(&Person{}); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if hot := cm.RecentHotTypes(0); len(hot) != 2 {
+        t.Errorf("got recent hot types %+v, want *Person and Person", hot)
+    }
+
+    time.Sleep(80 * time.Millisecond)
+    if hot := cm.RecentHotTypes(0); len(hot) != 0 {
+        t.Errorf("got recent hot types %+v, want none", hot)
+    }
+    if hot := cm.HotTypes(0); len(hot) != 2 {
+        t.Errorf("got hot types %+v, want *Person and Person", hot)
+    }
+    cm.ResetStats()
+    if hot := cm.HotTypes(0); len(hot) != 0 {
+        t.Errorf("got hot types %+v after ResetStats, want none", hot)
+    }
+}
+
+// Test for formatting stats in a stable order
+func TestFormatStatsAs(t *testing.T) {
+    cloner.ResetStats()
+    defer cloner.ResetStats()
+    cloner.UpdateStats("struct b.Point")
+    cloner.UpdateStats("map")
+    cloner.UpdateStats("map")
+
+    tests := []struct {
+        format cloner.Format
+        want   string
+    }{
+        {cloner.FormatText, "map: 2\nstruct b.Point: 1\n"},
+        {cloner.FormatJSON, `{"map":2,"struct b.Point":1}` + "\n"},
+        {cloner.FormatTSV, "name\tcount\nmap\t2\nstruct b.Point\t1\n"},
+        {cloner.FormatTable, "NAME            COUNT\nmap             2\nstruct b.Point  1\n"},
+    }
+    for _, tt := range tests {
+        if got := cloner.FormatStatsAs(tt.format); got != tt.want {
+            t.Errorf("got %q for format %d, want %q", got, tt.format, tt.want)
+        }
+    }
+    if got := cloner.FormatStats(); got != tests[0].want {
+        t.Errorf("got %q, want %q", got, tests[0].want)
+    }
+}
@@ -0,0 +1,574 @@
+package cloner
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "reflect"
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Encoder writes the tokens of a streamed clone. *gob.Encoder and
+// *json.Encoder implement it.
+type Encoder interface {
+    Encode(v interface{}) error
+}
+
+// Decoder reads the tokens of a streamed clone. *gob.Decoder and
+// *json.Decoder implement it.
+type Decoder interface {
+    Decode(v interface{}) error
+}
+
+// TokenKind identifies the part of a value described by a Token.
+type TokenKind int
+
+const (
+    TokenNil       TokenKind = iota // nil pointer, slice, map or interface
+    TokenValue                      // boolean, number or string in Bool, Int, Uint, Float, Imag or String
+    TokenPtr                        // new pointer Ref, followed by the pointed value
+    TokenSlice                      // new slice Ref, followed by its Len elements
+    TokenArray                      // array, followed by its Len elements
+    TokenMap                        // new map Ref, followed by its Len keys and values
+    TokenStruct                     // struct, followed by its Len exported fields
+    TokenInterface                  // interface holding a value of Type, followed by the value
+    TokenRef                        // pointer, slice or map Ref written earlier in the stream
+)
+
+var tokenKinds = []string{"nil", "value", "pointer", "slice", "array", "map", "struct", "interface", "reference"}
+
+func (k TokenKind) String() string {
+    if k < 0 || int(k) >= len(tokenKinds) {
+        return fmt.Sprintf("TokenKind(%d)", int(k))
+    }
+    return tokenKinds[k]
+}
+
+// Token is one part of a value streamed by CloneTo. A value is written as a
+// depth-first sequence of tokens; every pointer, slice and map is written
+// once and referenced by its Ref, numbered from 1, wherever else it appears.
+type Token struct {
+    Kind   TokenKind
+    Ref    int     `json:",omitempty"`
+    Len    int     `json:",omitempty"`
+    Type   string  `json:",omitempty"`
+    Bool   bool    `json:",omitempty"`
+    Int    int64   `json:",omitempty"`
+    Uint   uint64  `json:",omitempty"`
+    Float  float64 `json:",omitempty"`
+    Imag   float64 `json:",omitempty"`
+    String string  `json:",omitempty"`
+}
+
+var (
+    streamTypes      = make(map[string]reflect.Type)
+    streamTypesMutex sync.RWMutex
+)
+
+func init() {
+    for _, v := range []interface{}{
+        false, 0, int8(0), int16(0), int32(0), int64(0),
+        uint(0), uint8(0), uint16(0), uint32(0), uint64(0), uintptr(0),
+        float32(0), float64(0), complex64(0), complex128(0), "",
+        []interface{}{}, map[string]interface{}{}, []string{}, []byte{},
+    } {
+        registerType(reflect.TypeOf(v))
+    }
+}
+
+// RegisterType registers T for streaming: the values of T held in interfaces
+// are written with the name of T, so that the stream can be read back into
+// the same type. Predeclared types are registered already.
+func RegisterType[T any]() {
+    registerType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func registerType(t reflect.Type) {
+    streamTypesMutex.Lock()
+    defer streamTypesMutex.Unlock()
+    streamTypes[typeName(t)] = t
+}
+
+// registeredType returns the type registered with RegisterType as name.
+func registeredType(name string) (reflect.Type, bool) {
+    streamTypesMutex.RLock()
+    defer streamTypesMutex.RUnlock()
+    t, found := streamTypes[name]
+    return t, found
+}
+
+// typeName returns the name under which t is streamed, qualified by the
+// import path of its package for named types and pointers and slices of
+// them.
+func typeName(t reflect.Type) string {
+    switch {
+    case t.Name() == "" && t.Kind() == reflect.Ptr:
+        return "*" + typeName(t.Elem())
+    case t.Name() == "" && t.Kind() == reflect.Slice:
+        return "[]" + typeName(t.Elem())
+    case t.Name() != "" && t.PkgPath() != "":
+        return t.PkgPath() + "." + t.Name()
+    }
+    return t.String()
+}
+
+// CloneTo streams a deep clone of src to enc as a sequence of Tokens,
+// without building the clone in memory, e.g. to checkpoint a large graph to
+// a file through a *gob.Encoder. Shared references and cycles are written
+// once, and the dynamic types of interface values must be registered with
+// RegisterType.
+//
+// The stream is written like Clone builds a clone: Cloneable values,
+// registered Cloners and WithReplace apply, and excluded paths are written
+// as nil. Channels and functions shared by their policy are written as nil.
+// With CollectErrors, values that cannot be cloned are written as nil and
+// the collected errors are returned once the stream is complete.
+func (cm *CloneManager) CloneTo(enc Encoder, src interface{}, opts ...Option) error {
+    s := &streamer{
+        CloneManager: cm.session(opts...),
+        enc:          enc,
+        refs:         make(map[memref.Ref]int),
+    }
+    err := s.stream(reflect.ValueOf(src))
+    if err == nil && len(s.errs) > 0 {
+        err = errors.Join(s.errs...)
+    }
+    return err
+}
+
+// streamer writes the tokens of a single CloneTo.
+type streamer struct {
+    *CloneManager
+    enc  Encoder
+    refs map[memref.Ref]int // Ref of the pointers, slices and maps written
+    next int                // Ref of the last reference written
+    err  error              // error of enc, which ends the stream
+}
+
+func (s *streamer) emit(token Token) error {
+    if err := s.enc.Encode(token); err != nil {
+        s.err = err
+    }
+    return s.err
+}
+
+// stream writes src, reporting failures as a *CloneError. With
+// CollectErrors, src is written as nil instead.
+func (s *streamer) stream(src reflect.Value) error {
+    err := s.enter()
+    if err == nil {
+        err = s.write(src)
+        s.depth--
+    }
+    if err == nil || s.err != nil {
+        return err
+    }
+    if err = s.report(src, err); err == nil {
+        return s.emit(Token{Kind: TokenNil})
+    }
+    return err
+}
+
+// write applies the hooks of the manager to src and writes the result.
+func (s *streamer) write(src reflect.Value) error {
+    if !src.IsValid() {
+        return s.emit(Token{Kind: TokenNil})
+    }
+    if policy, ok := s.pathPolicy(); ok && policy != Share {
+        return s.emit(Token{Kind: TokenNil})
+    }
+    if replaced, ok := s.replaceValue(src); ok {
+        return s.writeClone(replaced, src)
+    }
+    if isNil(src) {
+        return s.emit(Token{Kind: TokenNil})
+    }
+    switch src.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        if ref, ok := s.refs[memref.Of(src)]; ok {
+            return s.emit(Token{Kind: TokenRef, Ref: ref})
+        }
+    }
+
+    // Values cloned by hooks are written from their clone
+    var cloned interface{}
+    var err error
+    if r, ok := s.options.replacements[src.Type()]; ok {
+        cloned, err = s.replace(src, r)
+    } else if c, ok, cerr := s.cloneCloneable(src); ok {
+        cloned, err = c, cerr
+    } else if cloner, found := s.lookupCloner(src.Type()); found {
+        cloned, err = cloner.Clone(src.Interface(), s.CloneManager)
+    } else {
+        return s.writeValue(src)
+    }
+    if err != nil {
+        return err
+    }
+    return s.writeClone(cloned, src)
+}
+
+// writeClone writes cloned, the clone of src made by a hook, with the type
+// of src. References to src resolve to the written clone.
+func (s *streamer) writeClone(cloned interface{}, src reflect.Value) error {
+    v, err := s.valueOf(cloned, src.Type(), src)
+    if err != nil {
+        return err
+    }
+    if !v.IsValid() || isNil(v) {
+        return s.emit(Token{Kind: TokenNil})
+    }
+    if err := s.writeValue(v); err != nil {
+        return err
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        s.refs[memref.Of(src)] = s.refs[memref.Of(v)]
+    }
+    return nil
+}
+
+// writeValue writes the tokens of src by kind.
+func (s *streamer) writeValue(src reflect.Value) error {
+    switch src.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        if src.IsNil() {
+            return s.emit(Token{Kind: TokenNil})
+        }
+        if ref, ok := s.refs[memref.Of(src)]; ok {
+            return s.emit(Token{Kind: TokenRef, Ref: ref})
+        }
+        s.next++
+        s.refs[memref.Of(src)] = s.next
+    }
+
+    switch src.Kind() {
+    case reflect.Ptr:
+        if err := s.emit(Token{Kind: TokenPtr, Ref: s.next}); err != nil {
+            return err
+        }
+        return s.stream(src.Elem())
+    case reflect.Slice, reflect.Array:
+        token := Token{Kind: TokenArray, Len: src.Len()}
+        if src.Kind() == reflect.Slice {
+            token = Token{Kind: TokenSlice, Ref: s.next, Len: src.Len()}
+        }
+        if err := s.emit(token); err != nil {
+            return err
+        }
+        for i := 0; i < src.Len(); i++ {
+            s.pushIndex(i)
+            err := s.stream(src.Index(i))
+            s.pop()
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    case reflect.Map:
+        return s.writeMap(src)
+    case reflect.Struct:
+        return s.writeStruct(src)
+    case reflect.Interface:
+        if src.IsNil() {
+            return s.emit(Token{Kind: TokenNil})
+        }
+        t := src.Elem().Type()
+        if t.Kind() == reflect.Chan || t.Kind() == reflect.Func {
+            // Written as nil by their policy
+            return s.stream(src.Elem())
+        }
+        name := typeName(t)
+        if registered, found := registeredType(name); !found || registered != t {
+            return fmt.Errorf("%w: %v is not registered for streaming, see RegisterType", ErrUnknownType, t)
+        }
+        if err := s.emit(Token{Kind: TokenInterface, Type: name}); err != nil {
+            return err
+        }
+        return s.stream(src.Elem())
+    case reflect.Chan, reflect.Func:
+        if src.IsNil() {
+            return s.emit(Token{Kind: TokenNil})
+        }
+        if _, err := s.cloneUncloneable(src); err != nil {
+            return err
+        }
+        return s.emit(Token{Kind: TokenNil})
+    case reflect.Bool:
+        return s.emit(Token{Kind: TokenValue, Bool: src.Bool()})
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return s.emit(Token{Kind: TokenValue, Int: src.Int()})
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return s.emit(Token{Kind: TokenValue, Uint: src.Uint()})
+    case reflect.Float32, reflect.Float64:
+        return s.emit(Token{Kind: TokenValue, Float: src.Float()})
+    case reflect.Complex64, reflect.Complex128:
+        c := src.Complex()
+        return s.emit(Token{Kind: TokenValue, Float: real(c), Imag: imag(c)})
+    case reflect.String:
+        return s.emit(Token{Kind: TokenValue, String: src.String()})
+    }
+    return fmt.Errorf("%w: %v values cannot be streamed", ErrUncloneableKind, src.Kind())
+}
+
+// writeMap writes the entries of the map src, leaving out excluded keys.
+func (s *streamer) writeMap(src reflect.Value) error {
+    var entries []mapEntry
+    for _, entry := range s.mapEntries(src) {
+        s.pushKey(entry.key)
+        policy, ok := s.pathPolicy()
+        s.pop()
+        if !ok || policy == Share {
+            entries = append(entries, entry)
+        }
+    }
+    if err := s.emit(Token{Kind: TokenMap, Ref: s.next, Len: len(entries)}); err != nil {
+        return err
+    }
+    for _, entry := range entries {
+        s.pushKey(entry.key)
+        err := s.stream(entry.key)
+        if err == nil {
+            err = s.stream(entry.value)
+        }
+        s.pop()
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// writeStruct writes the exported fields of the struct src. Unexported
+// fields are not written; the Error policy still reports those holding data.
+func (s *streamer) writeStruct(src reflect.Value) error {
+    p := planOf(src.Type())
+    if err := s.emit(Token{Kind: TokenStruct, Len: len(p.exported)}); err != nil {
+        return err
+    }
+    for i, fp := range p.fields {
+        s.pushField(&fp)
+        var err error
+        if fp.exported {
+            err = s.stream(src.Field(i))
+        } else {
+            err = s.checkUnexported(src.Field(i), fp.name)
+        }
+        s.pop()
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// CloneFrom reads a value streamed by CloneTo from dec into the value dst
+// points to, restoring shared references and cycles. The dynamic types of
+// interface values must be registered with RegisterType. The unexported
+// fields of the structs read are left at their zero value.
+//
+// Reading a value from an exhausted stream returns io.EOF, so that a
+// sequence of streamed values can be read back until the end. A stream that
+// does not describe a value of the type of dst is reported as a *CloneError
+// wrapping ErrTypeMismatch. The limits of the manager, such as WithMaxNodes
+// and WithTimeout, apply to the value read.
+func (cm *CloneManager) CloneFrom(dec Decoder, dst interface{}, opts ...Option) error {
+    v := reflect.ValueOf(dst)
+    if v.Kind() != reflect.Ptr || v.IsNil() {
+        return fmt.Errorf("%w: CloneFrom needs a non-nil pointer, got %T", ErrTypeMismatch, dst)
+    }
+    r := &reader{
+        CloneManager: cm.session(opts...),
+        dec:          dec,
+        refs:         make(map[int]reflect.Value),
+    }
+    return r.read(v.Elem())
+}
+
+// reader reads the tokens of a single CloneFrom.
+type reader struct {
+    *CloneManager
+    dec  Decoder
+    refs map[int]reflect.Value // pointers, slices and maps read by Ref
+    err  error                 // error of dec, which ends the stream
+}
+
+func (r *reader) next() (Token, error) {
+    var token Token
+    if err := r.dec.Decode(&token); err != nil {
+        if err == io.EOF && r.nodes > 1 {
+            err = io.ErrUnexpectedEOF
+        }
+        r.err = err
+    }
+    return token, r.err
+}
+
+// read reads the next value of the stream into dst, reporting failures as a
+// *CloneError.
+func (r *reader) read(dst reflect.Value) error {
+    err := r.enter()
+    if err == nil {
+        err = r.readValue(dst)
+        r.depth--
+    }
+    if err == nil || r.err != nil {
+        return err
+    }
+    return r.cloneError(dst, err)
+}
+
+// readValue reads the tokens of a value of the type of dst into dst.
+func (r *reader) readValue(dst reflect.Value) error {
+    token, err := r.next()
+    if err != nil {
+        return err
+    }
+    switch token.Kind {
+    case TokenNil:
+        dst.Set(reflect.Zero(dst.Type()))
+        return nil
+    case TokenRef:
+        ref, ok := r.refs[token.Ref]
+        if !ok {
+            return fmt.Errorf("%w: reference %d is not defined", ErrInvalidStream, token.Ref)
+        }
+        if !ref.Type().AssignableTo(dst.Type()) {
+            return fmt.Errorf("%w: reference %d is a %v", ErrTypeMismatch, token.Ref, ref.Type())
+        }
+        dst.Set(ref)
+        return nil
+    }
+
+    t := dst.Type()
+    if want := tokenKind(t); token.Kind != want {
+        return fmt.Errorf("%w: got %v, want %v", ErrTypeMismatch, token.Kind, want)
+    }
+    switch t.Kind() {
+    case reflect.Ptr:
+        p := reflect.New(t.Elem())
+        r.refs[token.Ref] = p
+        dst.Set(p)
+        return r.read(p.Elem())
+    case reflect.Slice, reflect.Array:
+        if t.Kind() == reflect.Slice {
+            slice := reflect.MakeSlice(t, token.Len, token.Len)
+            r.refs[token.Ref] = slice
+            dst.Set(slice)
+        } else if token.Len != t.Len() {
+            return fmt.Errorf("%w: got %d elements, want %d", ErrTypeMismatch, token.Len, t.Len())
+        }
+        for i := 0; i < token.Len; i++ {
+            r.pushIndex(i)
+            err := r.read(dst.Index(i))
+            r.pop()
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    case reflect.Map:
+        m := reflect.MakeMapWithSize(t, token.Len)
+        r.refs[token.Ref] = m
+        dst.Set(m)
+        for i := 0; i < token.Len; i++ {
+            key := reflect.New(t.Key()).Elem()
+            if err := r.read(key); err != nil {
+                return err
+            }
+            value := reflect.New(t.Elem()).Elem()
+            r.pushKey(key)
+            err := r.read(value)
+            r.pop()
+            if err != nil {
+                return err
+            }
+            m.SetMapIndex(key, value)
+        }
+        return nil
+    case reflect.Struct:
+        return r.readStruct(dst, token)
+    case reflect.Interface:
+        dynamic, found := registeredType(token.Type)
+        if !found {
+            return fmt.Errorf("%w: %s is not registered for streaming, see RegisterType", ErrUnknownType, token.Type)
+        }
+        if !dynamic.Implements(t) {
+            return fmt.Errorf("%w: %v does not implement %v", ErrTypeMismatch, dynamic, t)
+        }
+        v := reflect.New(dynamic).Elem()
+        if err := r.read(v); err != nil {
+            return err
+        }
+        dst.Set(v)
+        return nil
+    }
+    return r.setValue(dst, token)
+}
+
+// readStruct reads the exported fields of a struct described by token into
+// dst.
+func (r *reader) readStruct(dst reflect.Value, token Token) error {
+    p := planOf(dst.Type())
+    if token.Len != len(p.exported) {
+        return fmt.Errorf("%w: got %d fields, want %d", ErrTypeMismatch, token.Len, len(p.exported))
+    }
+    dst.SetZero()
+    for _, i := range p.exported {
+        r.pushField(&p.fields[i])
+        err := r.read(dst.Field(i))
+        r.pop()
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// setValue sets dst to the boolean, number or string held by token.
+func (r *reader) setValue(dst reflect.Value, token Token) error {
+    switch dst.Kind() {
+    case reflect.Bool:
+        dst.SetBool(token.Bool)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        if dst.OverflowInt(token.Int) {
+            return fmt.Errorf("%w: %d overflows %v", ErrTypeMismatch, token.Int, dst.Type())
+        }
+        dst.SetInt(token.Int)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        if dst.OverflowUint(token.Uint) {
+            return fmt.Errorf("%w: %d overflows %v", ErrTypeMismatch, token.Uint, dst.Type())
+        }
+        dst.SetUint(token.Uint)
+    case reflect.Float32, reflect.Float64:
+        dst.SetFloat(token.Float)
+    case reflect.Complex64, reflect.Complex128:
+        dst.SetComplex(complex(token.Float, token.Imag))
+    case reflect.String:
+        dst.SetString(token.String)
+    }
+    return nil
+}
+
+// tokenKind returns the kind of the token describing a non-nil value of t.
+func tokenKind(t reflect.Type) TokenKind {
+    switch t.Kind() {
+    case reflect.Ptr:
+        return TokenPtr
+    case reflect.Slice:
+        return TokenSlice
+    case reflect.Array:
+        return TokenArray
+    case reflect.Map:
+        return TokenMap
+    case reflect.Struct:
+        return TokenStruct
+    case reflect.Interface:
+        return TokenInterface
+    case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+        return TokenNil
+    }
+    return TokenValue
+}
@@ -0,0 +1,211 @@
+package cloner_test
+
+import (
+    "bytes"
+    "encoding/gob"
+    "encoding/json"
+    "errors"
+    "io"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Folder struct {
+    Name   string
+    Parent *Folder
+    Files  []string
+    Attrs  map[string]interface{}
+    Owner  interface{}
+    secret string
+}
+
+type Person struct {
+    Name string
+}
+
+func init() {
+    cloner.RegisterType[*Person]()
+}
+
+// tokens records the tokens written by CloneTo.
+type tokens []cloner.Token
+
+func (ts *tokens) Encode(v interface{}) error {
+    *ts = append(*ts, v.(cloner.Token))
+    return nil
+}
+
+// Test for streaming a graph with shared references and a cycle
+func TestCloneTo(t *testing.T) {
+    owner := &Person{Name: "ann"}
+    root := &Folder{Name: "root", Owner: owner, secret: "s"}
+    root.Parent = root
+    root.Attrs = map[string]interface{}{"size": 2, "owner": owner}
+
+    var got tokens
+    if err := cloner.NewCloneManager(cloner.WithDeterministicOrder()).CloneTo(&got, root); err != nil {
+        t.Fatalf("CloneTo failed: %v", err)
+    }
+    personType := "*github.com/jayaprabhakar/go-deeper/cloner_test.Person"
+    want := tokens{
+        {Kind: cloner.TokenPtr, Ref: 1},
+        {Kind: cloner.TokenStruct, Len: 5},
+        {Kind: cloner.TokenValue, String: "root"},
+        {Kind: cloner.TokenRef, Ref: 1},
+        {Kind: cloner.TokenNil},
+        {Kind: cloner.TokenMap, Ref: 2, Len: 2},
+        {Kind: cloner.TokenValue, String: "owner"},
+        {Kind: cloner.TokenInterface, Type: personType},
+        {Kind: cloner.TokenPtr, Ref: 3},
+        {Kind: cloner.TokenStruct, Len: 1},
+        {Kind: cloner.TokenValue, String: "ann"},
+        {Kind: cloner.TokenValue, String: "size"},
+        {Kind: cloner.TokenInterface, Type: "int"},
+        {Kind: cloner.TokenValue, Int: 2},
+        {Kind: cloner.TokenInterface, Type: personType},
+        {Kind: cloner.TokenRef, Ref: 3},
+    }
+    deepEqual(t, got, want)
+
+    // Streams can be written with encoding/json
+    var buf bytes.Buffer
+    if err := cloner.NewCloneManager().CloneTo(json.NewEncoder(&buf), []int{1, 2}); err != nil {
+        t.Fatalf("CloneTo failed: %v", err)
+    }
+    wantJSON := `{"Kind":3,"Ref":1,"Len":2}` + "\n" + `{"Kind":1,"Int":1}` + "\n" + `{"Kind":1,"Int":2}` + "\n"
+    if buf.String() != wantJSON {
+        t.Errorf("got JSON %q, want %q", buf.String(), wantJSON)
+    }
+}
+
+// Test for streaming errors
+func TestCloneToErrors(t *testing.T) {
+    type Unregistered struct{ N int }
+    folder := Folder{Name: "f", Owner: Unregistered{1}, Attrs: map[string]interface{}{"x": func() {}}}
+
+    var got tokens
+    err := cloner.NewCloneManager().CloneTo(&got, folder)
+    if !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUncloneableKind", err)
+    }
+
+    // Collected errors are written as nil
+    got = nil
+    err = cloner.NewCloneManager(cloner.CollectErrors()).CloneTo(&got, folder)
+    if !errors.Is(err, cloner.ErrUnknownType) || !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUnknownType and ErrUncloneableKind", err)
+    }
+    if owner := got[len(got)-1]; owner.Kind != cloner.TokenNil {
+        t.Errorf("got token %+v for Owner, want nil", owner)
+    }
+
+    // Failures of the encoder end the stream
+    failure := errors.New("disk full")
+    err = cloner.NewCloneManager().CloneTo(failingEncoder{failure}, folder)
+    if err != failure {
+        t.Errorf("got error %v, want %v", err, failure)
+    }
+}
+
+// Test for reading a streamed graph back with its shared references
+func TestCloneFrom(t *testing.T) {
+    owner := &Person{Name: "ann"}
+    root := &Folder{Name: "root", Files: []string{"a", "b"}, Owner: owner}
+    root.Parent = root
+    root.Attrs = map[string]interface{}{"size": 2, "owner": owner}
+
+    cm := cloner.NewCloneManager()
+    var buf bytes.Buffer
+    enc := gob.NewEncoder(&buf)
+    if err := cm.CloneTo(enc, root); err != nil {
+        t.Fatalf("CloneTo failed: %v", err)
+    }
+    if err := cm.CloneTo(enc, []int{1, 2, 3}); err != nil {
+        t.Fatalf("CloneTo failed: %v", err)
+    }
+
+    dec := gob.NewDecoder(&buf)
+    var folder *Folder
+    if err := cm.CloneFrom(dec, &folder); err != nil {
+        t.Fatalf("CloneFrom failed: %v", err)
+    }
+    deepEqual(t, folder, root)
+    if folder == root || folder.Parent != folder {
+        t.Errorf("Cloned parent does not point to the cloned folder")
+    }
+    if folder.Owner == owner || folder.Owner != folder.Attrs["owner"] {
+        t.Errorf("Cloned owners do not point to the same cloned person")
+    }
+
+    // Streams hold a sequence of values
+    var ints []int
+    if err := cm.CloneFrom(dec, &ints); err != nil {
+        t.Fatalf("CloneFrom failed: %v", err)
+    }
+    deepEqual(t, ints, []int{1, 2, 3})
+    if err := cm.CloneFrom(dec, &ints); err != io.EOF {
+        t.Errorf("got error %v, want io.EOF", err)
+    }
+}
+
+// Test for reading streams into values of another type
+func TestCloneFromErrors(t *testing.T) {
+    stream := func(v interface{}) tokens {
+        var ts tokens
+        if err := cloner.NewCloneManager().CloneTo(&ts, v); err != nil {
+            t.Fatalf("CloneTo failed: %v", err)
+        }
+        return ts
+    }
+    cm := cloner.NewCloneManager()
+
+    var person Person
+    err := cm.CloneFrom(stream(Folder{}).reader(), &person)
+    if !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Errorf("got error %v, want ErrTypeMismatch", err)
+    }
+    var small []int8
+    err = cm.CloneFrom(stream([]int{1, 1000}).reader(), &small)
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || cloneErr.Path != "[1]" || !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Errorf("got error %v, want ErrTypeMismatch at [1]", err)
+    }
+    if err := cm.CloneFrom(stream(1).reader(), person); !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Errorf("got error %v, want ErrTypeMismatch", err)
+    }
+
+    truncated := stream([]int{1, 2})[:2]
+    if err := cm.CloneFrom(truncated.reader(), &small); err != io.ErrUnexpectedEOF {
+        t.Errorf("got error %v, want io.ErrUnexpectedEOF", err)
+    }
+    dangling := tokens{{Kind: cloner.TokenRef, Ref: 7}}
+    if err := cm.CloneFrom(dangling.reader(), &small); !errors.Is(err, cloner.ErrInvalidStream) {
+        t.Errorf("got error %v, want ErrInvalidStream", err)
+    }
+}
+
+// reader returns a Decoder reading the recorded tokens.
+func (ts tokens) reader() cloner.Decoder {
+    return &tokenReader{ts}
+}
+
+type tokenReader struct {
+    tokens tokens
+}
+
+func (r *tokenReader) Decode(v interface{}) error {
+    if len(r.tokens) == 0 {
+        return io.EOF
+    }
+    *v.(*cloner.Token), r.tokens = r.tokens[0], r.tokens[1:]
+    return nil
+}
+
+type failingEncoder struct {
+    err error
+}
+
+func (e failingEncoder) Encode(interface{}) error {
+    return e.err
+}
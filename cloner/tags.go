@@ -0,0 +1,104 @@
+package cloner
+
+import (
+    "reflect"
+    "strings"
+)
+
+// tagName is the key of the struct tags configuring how fields are handled,
+// e.g. `deeper:"ignoreeq"`, unless WithTagName or EqualTagName names others.
+// A tag holds a comma-separated list of options.
+const tagName = "deeper"
+
+// WithTagName makes the manager read the options of struct fields, such as
+// `deeper:"parent"`, from the first of the tags named by names that a field
+// has, rather than from its deeper tag, so that types already tagged for
+// other tools need not be tagged again. For example, with
+//
+//	cloner.WithTagName("deeper", "copy", "json")
+//
+// fields are configured by their deeper tag, or their copy tag if they have
+// none, or their json tag if they have neither. The options of tags other
+// than deeper follow the name of the field, as in `json:"up,parent"`.
+// Options unknown to the manager, such as omitempty, are ignored.
+func WithTagName(names ...string) Option {
+    return func(o *options) {
+        o.tagNames = append([]string(nil), names...)
+    }
+}
+
+// EqualTagName makes Equal and Diff read the options of struct fields, such
+// as `deeper:"ignoreeq"`, from the tags named by names, as WithTagName does.
+func EqualTagName(names ...string) EqualOption {
+    return func(o *equalOptions) {
+        o.tagNames = append([]string(nil), names...)
+    }
+}
+
+// hasTagOption reports whether the first of the tags named by names that tag
+// holds lists option. Tags other than deeper, such as json, start with the
+// name of the field, which is not an option.
+func hasTagOption(tag reflect.StructTag, names []string, option string) bool {
+    for _, name := range names {
+        value, ok := tag.Lookup(name)
+        if !ok {
+            continue
+        }
+        opts := strings.Split(value, ",")
+        if name != tagName {
+            opts = opts[1:]
+        }
+        for _, opt := range opts {
+            if strings.TrimSpace(opt) == option {
+                return true
+            }
+        }
+        return false
+    }
+    return false
+}
+
+// isParent reports whether the field described by fp is tagged parent, as
+// read by cm.
+func (cm *CloneManager) isParent(fp *fieldPlan) bool {
+    if cm.options.tagNames == nil {
+        return fp.parent
+    }
+    return hasTagOption(fp.tag, cm.options.tagNames, "parent")
+}
+
+// WithFieldNames makes the manager name struct fields in paths by their name
+// in the tag named tag, such as "json", rather than by their Go name, so
+// that the paths of errors, hooks, Walk and WithExcludePaths patterns match
+// the wire format of the values. Fields without a name in the tag, or
+// omitted from it with "-", keep their Go name.
+//
+// Such paths are for display: the fields their Steps name cannot be
+// resolved by Get and Set. The Steps of a CloneError and the Steps method
+// of the manager keep naming fields by their Go name.
+func WithFieldNames(tag string) Option {
+    return func(o *options) {
+        o.fieldNames = tag
+    }
+}
+
+// EqualFieldNames makes Diff name struct fields in paths by their name in
+// the tag named tag, as WithFieldNames does. IgnorePaths and EqualFloatsAt
+// patterns match these names. As with WithFieldNames, the Path of a
+// Difference is for display, while its Steps name fields by their Go name.
+func EqualFieldNames(tag string) EqualOption {
+    return func(o *equalOptions) {
+        o.fieldNames = tag
+    }
+}
+
+// tagFieldName returns the name of the field described by fp in the tag
+// named tag, the text before the first comma, or its Go name if it has
+// none.
+func tagFieldName(fp *fieldPlan, tag string) string {
+    name, _, _ := strings.Cut(fp.tag.Get(tag), ",")
+    if name == "" || name == "-" {
+        return fp.name
+    }
+    return name
+}
@@ -0,0 +1,114 @@
+package cloner_test
+
+import (
+    "errors"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Section struct {
+    Title string
+    Up    *Section `copy:",parent"`
+    Down  *Section `deeper:"" copy:",parent"`
+    Rev   int      `json:"rev,ignoreeq"`
+}
+
+// Test for reading field options from other tags than deeper
+func TestWithTagName(t *testing.T) {
+    root := &Section{Title: "root"}
+    child := &Section{Title: "child", Up: root}
+    root.Down = child
+
+    cm := cloner.NewCloneManager(cloner.WithTagName("deeper", "copy", "json"))
+    cloned, err := cloner.Clone(cm, child)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Up != nil {
+        t.Errorf("got clone %+v, want Up detached", cloned)
+    }
+    // The deeper tag of Down comes first in the chain
+    clonedRoot := cloner.MustClone(cm, root)
+    if clonedRoot.Down == nil || clonedRoot.Down == child || clonedRoot.Down.Up != clonedRoot {
+        t.Errorf("got clone %+v, want Down cloned and pointing back", clonedRoot)
+    }
+    plain := cloner.MustClone(cloner.NewCloneManager(), child)
+    if plain.Up == nil || plain.Up == root {
+        t.Errorf("got clone %+v, want Up cloned without WithTagName", plain)
+    }
+
+    cfg := cm.Config()
+    deepEqual(t, cfg.TagNames, []string{"deeper", "copy", "json"})
+    restored, err := cloner.NewCloneManagerFromConfig(cfg)
+    if err != nil {
+        t.Fatalf("NewCloneManagerFromConfig failed: %v", err)
+    }
+    if c := cloner.MustClone(restored, child); c.Up != nil {
+        t.Errorf("got clone %+v, want Up detached with the restored manager", c)
+    }
+
+    a, b := Section{Title: "a", Rev: 1}, Section{Title: "a", Rev: 2}
+    if cloner.Equal(a, b) || !cloner.Equal(a, b, cloner.EqualTagName("deeper", "json")) {
+        t.Errorf("Equal compared Rev despite its json tag")
+    }
+}
+
+// Test for fields of other tags named like the options of deeper
+func TestWithTagNameFieldNames(t *testing.T) {
+    type Named struct {
+        Parent   *Named `json:"parent"`
+        IgnoreEq int    `json:"ignoreeq"`
+    }
+    src := &Named{Parent: &Named{IgnoreEq: 1}}
+    cloned := cloner.MustClone(cloner.NewCloneManager(cloner.WithTagName("deeper", "json")), src)
+    if cloned.Parent == nil || cloned.Parent == src.Parent {
+        t.Errorf("got clone %+v, want the field named parent cloned", cloned)
+    }
+    if cloner.Equal(Named{IgnoreEq: 1}, Named{IgnoreEq: 2}, cloner.EqualTagName("deeper", "json")) {
+        t.Errorf("Equal skipped the field named ignoreeq")
+    }
+}
+
+type Login struct {
+    UserName string            `json:"user_name"`
+    Labels   map[string]string `json:"labels,omitempty"`
+    Token    string            `json:"-"`
+    Notify   func()            `json:",omitempty"`
+}
+
+// Test for naming fields in paths by their JSON names
+func TestFieldNames(t *testing.T) {
+    a := Login{UserName: "ann", Labels: map[string]string{"env": "dev"}, Token: "a"}
+    b := Login{UserName: "bob", Labels: map[string]string{"env": "prod"}, Token: "b"}
+    var paths []cloner.Path
+    for _, d := range cloner.Diff(a, b, cloner.EqualFieldNames("json"), cloner.IgnorePaths("labels[*]")) {
+        paths = append(paths, d.Path)
+        // The steps name fields by their Go name, which Get resolves
+        if v, err := cloner.Get(&b, d.Steps); err != nil || v.Interface() != d.B {
+            t.Errorf("Get(%v) returned %v, %v, want %v", d.Steps, v, err, d.B)
+        }
+    }
+    deepEqual(t, paths, []cloner.Path{".user_name", ".Token"})
+    // Paths naming fields by tag are for display only
+    steps, _ := cloner.Path(".user_name").Steps()
+    if _, err := cloner.Get(&b, steps); !errors.Is(err, cloner.ErrNoValue) {
+        t.Errorf("got error %v, want ErrNoValue for a tag name", err)
+    }
+
+    cm := cloner.NewCloneManager(cloner.WithFieldNames("json"), cloner.WithExcludePaths(".user_name"))
+    a.Notify = func() {}
+    _, err := cm.Clone(a)
+    var cloneErr *cloner.CloneError
+    if !errors.As(err, &cloneErr) || cloneErr.Path != ".Notify" || cloneErr.Steps[0] != cloner.Field("Notify") {
+        t.Errorf("got error %v, want one at .Notify", err)
+    }
+    a.Notify = nil
+    cloned := cloner.MustClone(cm, a)
+    if cloned.UserName != "" || cloned.Labels["env"] != "dev" {
+        t.Errorf("got clone %+v, want user_name excluded", cloned)
+    }
+    if cm.Config().FieldNames != "json" {
+        t.Errorf("got config %+v, want the json field names", cm.Config())
+    }
+}
@@ -0,0 +1,87 @@
+package cloner
+
+import (
+    "fmt"
+    "reflect"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+var (
+    timerType  = reflect.TypeOf((*time.Timer)(nil))
+    tickerType = reflect.TypeOf((*time.Ticker)(nil))
+)
+
+// WithTimers sets the policy for non-nil *time.Timer and *time.Ticker
+// values, which hold a channel and runtime state that cannot be cloned and
+// are left nil in the clone by default. Error reports them as
+// ErrUncloneableTimer; Share copies the pointers, so that the clone uses the
+// timers of the source. See WithTimerRestart to start new timers instead.
+func WithTimers(p Policy) Option {
+    return func(o *options) {
+        o.timers = p
+    }
+}
+
+// WithTimerRestart makes the manager clone a *time.Timer or *time.Ticker as
+// a new timer or ticker started with the duration returned by duration, when
+// it returns true, so that the clone fires on its own channel like the
+// source does; duration is typically backed by a table recording the
+// durations of the timers of an application, which the runtime does not
+// expose. Timers created by time.AfterFunc, whose function cannot be
+// recovered, and timers duration returns false for follow WithTimers.
+func WithTimerRestart(duration func(timer interface{}) (time.Duration, bool)) Option {
+    return func(o *options) {
+        o.timerRestart = duration
+    }
+}
+
+// isTimer reports whether t is *time.Timer or *time.Ticker.
+func isTimer(t reflect.Type) bool {
+    return t == timerType || t == tickerType
+}
+
+// cloneTimer clones src if it is a non-nil *time.Timer or *time.Ticker,
+// reporting whether it is one.
+func (cm *CloneManager) cloneTimer(src reflect.Value) (interface{}, bool, error) {
+    if !isTimer(src.Type()) || src.IsNil() {
+        return nil, false, nil
+    }
+    if d, ok := cm.restartsTimer(src); ok {
+        var cloned interface{} = time.NewTimer(d)
+        if src.Type() == tickerType {
+            cloned = time.NewTicker(d)
+        }
+        cm.log(LogCloners, "timer restarted", src)
+        cm.record(memref.Of(src), cloned)
+        return cloned, true, nil
+    }
+    switch cm.options.timers {
+    case Share:
+        cm.log(LogSkips, "timer shared by policy", src)
+        return src.Interface(), true, nil
+    case Zero:
+        cm.skip("timer excluded by policy", src)
+        return nil, true, nil
+    }
+    return nil, true, errUncloneableTimer(src)
+}
+
+// errUncloneableTimer returns the error reporting the timer or ticker src.
+func errUncloneableTimer(src reflect.Value) error {
+    return fmt.Errorf("%w: %v cannot be cloned", ErrUncloneableTimer, src.Type())
+}
+
+// restartsTimer returns the duration of the timer or ticker src to restart
+// it with, if WithTimerRestart applies to it.
+func (cm *CloneManager) restartsTimer(src reflect.Value) (time.Duration, bool) {
+    if cm.options.timerRestart == nil {
+        return 0, false
+    }
+    if t, ok := src.Interface().(*time.Timer); ok && t.C == nil {
+        return 0, false
+    }
+    d, ok := cm.options.timerRestart(src.Interface())
+    return d, ok && d > 0
+}
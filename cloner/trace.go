@@ -0,0 +1,158 @@
+package cloner
+
+import (
+    "context"
+    "reflect"
+    "runtime/pprof"
+    "time"
+)
+
+// Subtree describes the clone of a value together with the values it
+// references, as reported by WithTrace.
+type Subtree struct {
+    // Path is the path of the value, empty for the root of a clone.
+    Path Path
+    // Type is the type of the value.
+    Type reflect.Type
+    // Nodes is the number of values cloned, the value itself included.
+    Nodes int
+    // Duration is the time taken by the clone.
+    Duration time.Duration
+}
+
+// WithTrace makes the manager call fn with the Subtree of the root value of
+// every clone once it is cloned, and with those of the other values whose
+// clone takes at least threshold, so that the cost of a clone and the parts
+// of the graph it comes from can be traced. Subtrees are reported once
+// cloned, so the subtrees of a value are reported before it. A threshold
+// of zero reports the roots only. Timing every value makes clones slower.
+func WithTrace(threshold time.Duration, fn func(Subtree)) Option {
+    return func(o *options) {
+        o.traceThreshold, o.trace = threshold, fn
+    }
+}
+
+// traceSubtree reports the clone of src to the WithTrace callback if src is
+// a root or took longer than the threshold. start and nodes are the time and
+// the number of values cloned when the clone of src started, at the given
+// depth.
+func (cm *CloneManager) traceSubtree(src reflect.Value, start time.Time, nodes, depth int) {
+    d := time.Since(start)
+    threshold := cm.options.traceThreshold
+    if depth > 0 && (threshold <= 0 || d < threshold) {
+        return
+    }
+    subtree := Subtree{Path: Path(formatPath(cm.path)), Nodes: cm.nodes - nodes, Duration: d}
+    if src.IsValid() {
+        subtree.Type = src.Type()
+    }
+    cm.options.trace(subtree)
+}
+
+// ProfileLabel is the pprof label set by WithProfileLabels to the type of the
+// value being cloned.
+const ProfileLabel = "deeper.type"
+
+// WithProfileLabels makes the manager clone each root value with the pprof
+// label ProfileLabel set to its type, so that CPU profiles attribute the
+// time spent in clones to the types cloned, e.g. with
+// go tool pprof -tagfocus=deeper.type=*app.Order. The labels of ctx, those of
+// the calling goroutine, are kept during the clone and restored after it.
+func WithProfileLabels(ctx context.Context) Option {
+    return func(o *options) {
+        o.profileContext = ctx
+    }
+}
+
+// cloneLabeled is deepClone for the root value src of a clone made with
+// WithProfileLabels.
+func (cm *CloneManager) cloneLabeled(src reflect.Value) (cloned interface{}, err error) {
+    ctx := cm.options.profileContext
+    cm.options.profileContext = nil
+    defer func() {
+        cm.options.profileContext = ctx
+    }()
+    pprof.Do(ctx, pprof.Labels(ProfileLabel, src.Type().String()), func(context.Context) {
+        cloned, err = cm.deepClone(src)
+    })
+    return cloned, err
+}
+
+// Report describes a clone, as reported by WithOnClone, or the part of a
+// clone made by a CloneableV2.
+type Report struct {
+    // Type is the type of the cloned value, or of the slice of values
+    // cloned by CloneBatch and CloneAll; nil for a nil value.
+    Type reflect.Type
+    // Nodes is the number of values cloned.
+    Nodes int
+    // Bytes is the memory allocated by the clone, as estimated by
+    // WithMaxBytes; it is only counted with WithMaxBytes.
+    Bytes int64
+    // Skipped is the number of values left out of the clone by
+    // WithExcludePaths, WithFuncs, WithChans or WithOverBudget.
+    Skipped int
+    // Duration is the time taken by the clone.
+    Duration time.Duration
+    // Err is the error returned by the clone, if any.
+    Err error
+}
+
+// WithOnClone makes the manager call fn with the Report of every call to
+// Clone, CloneValue, CloneBatch and the functions using them once it
+// returns, so that clone metrics can be fed to other telemetry. Clones made
+// by a Cloner during a clone are part of the enclosing clone and not
+// reported. See WithSampling to report a fraction of the clones only.
+func WithOnClone(fn func(Report)) Option {
+    return func(o *options) {
+        o.onClone = fn
+    }
+}
+
+// WithRecorder makes the manager call fn with the source and the clone of
+// every successful call to Clone, CloneValue, CloneBatch and the functions
+// using them before it returns, so that the snapshots made by a service can
+// be recorded, e.g. by a replay.Recorder. As with WithOnClone, clones made
+// by a Cloner during a clone are not recorded. fn must not change the clone.
+func WithRecorder(fn func(src, clone interface{})) Option {
+    return func(o *options) {
+        o.record = fn
+    }
+}
+
+// WithSampling makes the WithOnClone callback report one clone in n. The
+// clones of a manager are counted together, including concurrent ones.
+func WithSampling(n int) Option {
+    return func(o *options) {
+        o.sampling = n
+    }
+}
+
+// reportClone calls the WithOnClone callback for the clone of src made by
+// the session cm, which returned err.
+func (cm *CloneManager) reportClone(src reflect.Value, err error) {
+    if cm.options.onClone == nil {
+        return
+    }
+    if n := cm.options.sampling; n > 1 && cm.clones.Add(1)%uint64(n) != 1 {
+        return
+    }
+    report := Report{Nodes: cm.nodes, Bytes: cm.bytes, Skipped: cm.skipped, Duration: time.Since(cm.started), Err: err}
+    if src.IsValid() {
+        report.Type = src.Type()
+    }
+    cm.options.onClone(report)
+}
+
+// recordClone records the Provenance of the clone of src with
+// WithProvenance, its mapping with WithMapping and its snapshot with
+// WithApplyBack, and calls the WithRecorder callback with src and its
+// clone.
+func (cm *CloneManager) recordClone(src, clone interface{}) {
+    cm.recordProvenance(reflect.ValueOf(src), reflect.ValueOf(clone))
+    cm.keepMapping()
+    cm.keepSnapshot(reflect.ValueOf(clone))
+    if cm.options.record != nil {
+        cm.options.record(src, clone)
+    }
+}
@@ -0,0 +1,138 @@
+package cloner_test
+
+import (
+    "bytes"
+    "context"
+    "reflect"
+    "runtime/pprof"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for tracing the root of clones and their slow subtrees
+func TestWithTrace(t *testing.T) {
+    folder := &Folder{Name: "root", Files: []string{"a", "b"}, Attrs: map[string]interface{}{"slow": &Person{}}}
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(&Person{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        time.Sleep(10 * time.Millisecond)
+        return &Person{}, nil
+    }))
+
+    var roots []cloner.Subtree
+    if _, err := cm.Clone(folder, cloner.WithTrace(0, func(s cloner.Subtree) { roots = append(roots, s) })); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if len(roots) != 1 || roots[0].Path != "" || roots[0].Type != reflect.TypeOf(folder) || roots[0].Duration < 10*time.Millisecond {
+        t.Fatalf("got subtrees %+v, want the root", roots)
+    }
+    // The pointer, struct, name, parent, files and its 2 elements, attrs
+    // and its entry, owner
+    if roots[0].Nodes != 12 {
+        t.Errorf("got %d nodes, want 12", roots[0].Nodes)
+    }
+
+    var slow []string
+    trace := cloner.WithTrace(5*time.Millisecond, func(s cloner.Subtree) { slow = append(slow, string(s.Path)) })
+    if _, err := cm.Clone(folder, trace); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    deepEqual(t, slow, []string{`.Attrs["slow"]`, `.Attrs["slow"]`, ".Attrs", "", ""})
+}
+
+// Test for labeling clones in profiles with the root type
+func TestWithProfileLabels(t *testing.T) {
+    var profile bytes.Buffer
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(&Person{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        // Labels show in the goroutine profile
+        pprof.Lookup("goroutine").WriteTo(&profile, 1)
+        return &Person{}, nil
+    }))
+
+    ctx := pprof.WithLabels(context.Background(), pprof.Labels("request", "r1"))
+    pprof.SetGoroutineLabels(ctx)
+    defer pprof.SetGoroutineLabels(context.Background())
+    if _, err := cm.Clone([]*Person{{}}, cloner.WithProfileLabels(ctx)); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if want := `"deeper.type":"[]*cloner_test.Person", "request":"r1"`; !strings.Contains(profile.String(), want) {
+        t.Errorf("got goroutine profile without labels %s:\n%s", want, profile.String())
+    }
+}
+
+// Test for reporting clones, one in n
+func TestWithOnClone(t *testing.T) {
+    var reports []cloner.Report
+    cm := cloner.NewCloneManager(cloner.WithOnClone(func(r cloner.Report) { reports = append(reports, r) }))
+    if _, err := cloner.Clone(cm, Vec3{}); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if _, err := cm.Clone(Folder{Owner: func() {}}); err == nil {
+        t.Fatalf("Clone did not fail")
+    }
+    if _, err := cloner.CloneAll(cm, []*Person{{}, {}}); err != nil {
+        t.Fatalf("CloneAll failed: %v", err)
+    }
+    if len(reports) != 3 {
+        t.Fatalf("got %d reports, want 3", len(reports))
+    }
+    if r := reports[0]; r.Type != reflect.TypeOf(Vec3{}) || r.Nodes != 1 || r.Err != nil {
+        t.Errorf("got report %+v, want Vec3", r)
+    }
+    if r := reports[1]; r.Type != reflect.TypeOf(Folder{}) || r.Err == nil {
+        t.Errorf("got report %+v, want Folder with an error", r)
+    }
+    if r := reports[2]; r.Type != reflect.TypeOf([]*Person{}) || r.Nodes != 4 {
+        t.Errorf("got report %+v, want 2 persons", r)
+    }
+
+    reports = nil
+    sampled := cloner.NewCloneManager(
+        cloner.WithOnClone(func(r cloner.Report) { reports = append(reports, r) }),
+        cloner.WithSampling(3),
+    )
+    for i := 0; i < 7; i++ {
+        sampled.Clone(&Person{})
+    }
+    if len(reports) != 3 {
+        t.Errorf("got %d reports of 7 clones sampled 1 in 3, want 3", len(reports))
+    }
+}
+
+// Test for recording the sources and clones of successful clones
+func TestWithRecorder(t *testing.T) {
+    var sources, clones []interface{}
+    cm := cloner.NewCloneManager(cloner.WithRecorder(func(src, clone interface{}) {
+        sources, clones = append(sources, src), append(clones, clone)
+    }))
+    person := &Person{Name: "Ann"}
+    if _, err := cloner.Clone(cm, Vec3{X: 1}); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if _, err := cm.Clone(Folder{Owner: func() {}}); err == nil {
+        t.Fatalf("Clone did not fail")
+    }
+    cloned, err := cm.CloneValue(reflect.ValueOf(person))
+    if err != nil {
+        t.Fatalf("CloneValue failed: %v", err)
+    }
+    if _, err := cloner.CloneAll(cm, []*Person{person}); err != nil {
+        t.Fatalf("CloneAll failed: %v", err)
+    }
+
+    if len(clones) != 3 {
+        t.Fatalf("got %d recorded clones, want 3", len(clones))
+    }
+    if sources[0] != (Vec3{X: 1}) || clones[0] != (Vec3{X: 1}) {
+        t.Errorf("got %v cloned to %v, want the Vec3", sources[0], clones[0])
+    }
+    if sources[1] != person || clones[1] != cloned.Interface() {
+        t.Errorf("got %v cloned to %v, want the person", sources[1], clones[1])
+    }
+    if all, ok := clones[2].([]*Person); !ok || len(all) != 1 || all[0] == person || all[0].Name != "Ann" {
+        t.Errorf("got %v, want a clone of the persons", clones[2])
+    }
+}
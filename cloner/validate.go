@@ -0,0 +1,158 @@
+package cloner
+
+import (
+    "errors"
+    "reflect"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Validate reports whether Clone would succeed for src, without cloning it.
+// It traverses src the way Clone does, applying the policies and limits of
+// the manager, but allocates no clones. Values handled by a Cloneable, a
+// registered Cloner or a replacement option are assumed to clone
+// successfully, since only their clone methods know how to copy them. With
+// CollectErrors, every failure is reported, joined with errors.Join. Options
+// apply to this call as in Clone.
+func (cm *CloneManager) Validate(src interface{}, opts ...Option) error {
+    session := cm.session(opts...)
+    err := session.validate(reflect.ValueOf(src))
+    if err == nil && len(session.errs) > 0 {
+        err = errors.Join(session.errs...)
+    }
+    return err
+}
+
+// validate is the dry-run counterpart of deepClone.
+func (cm *CloneManager) validate(src reflect.Value) error {
+    if err := cm.enter(); err != nil {
+        return cm.report(src, err)
+    }
+    err := cm.validateValue(src)
+    cm.depth--
+    if err != nil {
+        return cm.report(src, err)
+    }
+    return nil
+}
+
+// validateValue is the dry-run counterpart of cloneValue.
+func (cm *CloneManager) validateValue(src reflect.Value) error {
+    if !src.IsValid() {
+        return nil
+    }
+
+    if _, ok := cm.pathPolicy(); ok {
+        return nil
+    }
+    if err := cm.checkGuard(src); err != nil {
+        return err
+    }
+    if _, ok := cm.replaceValue(src); ok {
+        return nil
+    }
+
+    // Shared references are validated once, which also ends cycles
+    switch src.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        if src.IsNil() {
+            return nil
+        }
+        ptr := memref.Of(src)
+        if _, ok := cm.visited[ptr]; ok {
+            return nil
+        }
+        cm.visited[ptr] = nil
+    }
+
+    if _, ok := cm.options.replacements[src.Type()]; ok {
+        return nil
+    }
+    if src.CanInterface() {
+        switch src.Interface().(type) {
+        case Cloneable, CloneableV2:
+            return nil
+        }
+    }
+    if _, found := cm.lookupCloner(src.Type()); found {
+        return nil
+    }
+    if cm.shares(src) {
+        return nil
+    }
+    if valueTypes[src.Type()] || cm.options.sharedBytes[src.Type()] || planOf(src.Type()).scalar || cm.scans(src.Type()) {
+        return nil
+    }
+    if _, ok := cm.marshals(src.Type()); ok {
+        return nil
+    }
+    if _, ok := containerOf(src.Type()); ok {
+        return nil
+    }
+    if isTimer(src.Type()) && !src.IsNil() {
+        if _, ok := cm.restartsTimer(src); !ok && cm.options.timers == Error {
+            return errUncloneableTimer(src)
+        }
+        return nil
+    }
+    switch {
+    case src.Type().Implements(reflectTypeType):
+        return nil
+    case src.Type() == reflectValueType:
+        return cm.validate(src.Interface().(reflect.Value))
+    }
+
+    switch src.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        if _, over, err := cm.charge(src); over {
+            return err
+        }
+    }
+
+    switch src.Kind() {
+    case reflect.Ptr, reflect.Interface:
+        return cm.validate(src.Elem())
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < src.Len(); i++ {
+            cm.pushIndex(i)
+            err := cm.validate(src.Index(i))
+            cm.pop()
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Map:
+        for _, entry := range cm.mapEntries(src) {
+            cm.pushKey(entry.key)
+            err := cm.validate(entry.key)
+            if err == nil {
+                err = cm.validate(entry.value)
+            }
+            cm.pop()
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Struct:
+        for i, fp := range planOf(src.Type()).fields {
+            field := src.Field(i)
+            cm.pushField(&fp)
+            var err error
+            if fp.exported {
+                err = cm.validate(field)
+            } else {
+                err = cm.checkUnexported(field, fp.name)
+            }
+            cm.pop()
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Chan, reflect.Func:
+        if !src.IsNil() {
+            _, err := cm.cloneUncloneable(src)
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,70 @@
+package cloner_test
+
+import (
+    "errors"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Test for validating values that can be cloned
+func TestValidate(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    a := 1
+    original := struct {
+        A, B  *int
+        Items []TestStruct
+        Tags  map[string][]string
+        Any   interface{}
+    }{A: &a, B: &a, Items: []TestStruct{{A: 1}}, Tags: map[string][]string{"x": {"y"}}, Any: &TestStruct{}}
+
+    if err := cm.Validate(original); err != nil {
+        t.Errorf("Validate failed: %v", err)
+    }
+    if err := cm.Validate(nil); err != nil {
+        t.Errorf("Validate failed: %v", err)
+    }
+}
+
+// Test that Validate reports what Clone would report
+func TestValidateReportsErrors(t *testing.T) {
+    original := map[string]Job{"a": {Done: make(chan bool), Steps: []Step{{Abort: func() {}}}}}
+
+    err := cloner.NewCloneManager().Validate(original)
+    if !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Fatalf("got error %v, want ErrUncloneableKind", err)
+    }
+    _, cloneErr := cloner.NewCloneManager().Clone(original)
+    if err.Error() != cloneErr.Error() {
+        t.Errorf("Validate reported %q, Clone reported %q", err, cloneErr)
+    }
+
+    err = cloner.NewCloneManager(cloner.CollectErrors()).Validate(original)
+    for _, want := range []string{`["a"].Done`, `["a"].Steps[0].Abort`} {
+        if err == nil || !strings.Contains(err.Error(), want) {
+            t.Errorf("Error %v does not contain %q", err, want)
+        }
+    }
+
+    // Policies are applied
+    cm := cloner.NewCloneManager(cloner.WithFuncs(cloner.Share), cloner.WithChans(cloner.Zero))
+    if err := cm.Validate(original); err != nil {
+        t.Errorf("Validate failed: %v", err)
+    }
+    err = cloner.NewCloneManager(cloner.WithFuncs(cloner.Share), cloner.WithChans(cloner.Zero), cloner.WithMaxDepth(2)).Validate(original)
+    if !errors.Is(err, cloner.ErrDepthExceeded) {
+        t.Errorf("got error %v, want ErrDepthExceeded", err)
+    }
+}
+
+// Test that Validate terminates on cycles
+func TestValidateCycle(t *testing.T) {
+    original := &Chain{}
+    original.Next = original
+
+    if err := cloner.NewCloneManager().Validate(original); err != nil {
+        t.Errorf("Validate failed: %v", err)
+    }
+}
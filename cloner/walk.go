@@ -0,0 +1,134 @@
+package cloner
+
+import (
+    "errors"
+    "reflect"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Node is a value visited by Walk.
+type Node struct {
+    // Path is the path of the value from the root of the walk. Pointed
+    // values have the path of their pointer.
+    Path Path
+    // Value is the visited value. Interfaces are visited through their
+    // dynamic value unless they are nil.
+    Value reflect.Value
+    // Parent is the pointer, slice, array, map or struct holding Value, or
+    // an invalid value for the root.
+    Parent reflect.Value
+    // Key reports that Value is a map key. Keys have the path of their
+    // entry, like the entry's value that follows them.
+    Key bool
+    // Shared reports that Value is a pointer, slice or map referencing
+    // memory already visited through another path. Its contents are not
+    // walked again.
+    Shared bool
+    // Cycle reports that the memory referenced by Value is being walked, so
+    // Value leads back to one of its own ancestors. Cycle implies Shared.
+    Cycle bool
+}
+
+// WalkFunc is called by Walk for every value. If it returns SkipChildren,
+// the contents of the value are not walked; any other error stops the walk.
+type WalkFunc func(node Node) error
+
+// SkipChildren is returned by a WalkFunc to skip the contents of a value.
+var SkipChildren = errors.New("skip children")
+
+// Walk traverses v depth first, the way Clone traverses it: through
+// pointers, slices, arrays, map keys and values, exported struct fields and
+// interfaces, calling fn for every value. Map entries are visited in the
+// order of WithDeterministicOrder, and memory referenced more than once is
+// walked once, so cycles terminate. Walk returns the error returned by fn, if
+// any, other than SkipChildren.
+func Walk(v interface{}, fn WalkFunc) error {
+    if v == nil {
+        return nil
+    }
+    w := &walker{
+        cm:   (&CloneManager{options: options{deterministicOrder: true}}).session(),
+        fn:   fn,
+        seen: make(map[memref.Ref]bool),
+    }
+    return w.walk(reflect.ValueOf(v), reflect.Value{}, false)
+}
+
+type walker struct {
+    cm *CloneManager // session tracking the path
+    fn WalkFunc
+    // seen holds the references visited so far; those being walked map to
+    // true.
+    seen map[memref.Ref]bool
+}
+
+func (w *walker) walk(v, parent reflect.Value, key bool) error {
+    if v.Kind() == reflect.Interface && !v.IsNil() {
+        v = v.Elem()
+    }
+    node := Node{Path: Path(formatPath(w.cm.path)), Value: v, Parent: parent, Key: key}
+
+    var ref memref.Ref
+    isRef := false
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        if !v.IsNil() {
+            ref, isRef = memref.Of(v), true
+            active, seen := w.seen[ref]
+            node.Shared, node.Cycle = seen, active
+        }
+    }
+
+    if err := w.fn(node); err != nil || node.Shared {
+        if err == SkipChildren {
+            return nil
+        }
+        return err
+    }
+    if isRef {
+        w.seen[ref] = true
+        defer func() { w.seen[ref] = false }()
+    }
+
+    switch v.Kind() {
+    case reflect.Ptr:
+        if !v.IsNil() {
+            return w.walk(v.Elem(), v, false)
+        }
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            w.cm.pushIndex(i)
+            err := w.walk(v.Index(i), v, false)
+            w.cm.pop()
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Map:
+        for _, entry := range w.cm.mapEntries(v) {
+            w.cm.pushKey(entry.key)
+            err := w.walk(entry.key, v, true)
+            if err == nil {
+                err = w.walk(entry.value, v, false)
+            }
+            w.cm.pop()
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Struct:
+        for i, fp := range planOf(v.Type()).fields {
+            if !fp.exported {
+                continue
+            }
+            w.cm.pushField(&fp)
+            err := w.walk(v.Field(i), v, false)
+            w.cm.pop()
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
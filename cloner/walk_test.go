@@ -0,0 +1,90 @@
+package cloner_test
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Tree struct {
+    Name     string
+    Children []*Tree
+    Parent   *Tree
+    Attrs    map[string]interface{}
+    secret   string
+}
+
+// Test for walking a graph with shared pointers and cycles
+func TestWalk(t *testing.T) {
+    root := &Tree{Name: "root", Attrs: map[string]interface{}{"b": 2, "a": nil}, secret: "s"}
+    child := &Tree{Name: "child", Parent: root}
+    root.Children = []*Tree{child, child}
+
+    var visited []string
+    err := cloner.Walk(root, func(node cloner.Node) error {
+        desc := fmt.Sprintf("%s:%s", node.Path, node.Value.Kind())
+        if node.Cycle {
+            desc += " cycle"
+        } else if node.Shared {
+            desc += " shared"
+        }
+        visited = append(visited, desc)
+        if node.Path == ".Children[1]" && node.Parent.Interface().([]*Tree)[1] != child {
+            t.Errorf("Parent of %s is incorrect: %v", desc, node.Parent)
+        }
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("Walk failed: %v", err)
+    }
+
+    want := []string{
+        ":ptr", ":struct", ".Name:string", ".Children:slice",
+        ".Children[0]:ptr", ".Children[0]:struct", ".Children[0].Name:string", ".Children[0].Children:slice",
+        ".Children[0].Parent:ptr cycle", ".Children[0].Attrs:map",
+        ".Children[1]:ptr shared",
+        ".Parent:ptr", ".Attrs:map",
+        `.Attrs["a"]:string`, `.Attrs["a"]:interface`, `.Attrs["b"]:string`, `.Attrs["b"]:int`,
+    }
+    if got := strings.Join(visited, "\n"); got != strings.Join(want, "\n") {
+        t.Errorf("Walk order is incorrect:\ngot:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+    }
+}
+
+// Test for skipping children and stopping a walk
+func TestWalkSkipAndStop(t *testing.T) {
+    root := &Tree{Name: "root", Children: []*Tree{{Name: "a"}, {Name: "b"}}}
+
+    var names []string
+    err := cloner.Walk(root, func(node cloner.Node) error {
+        if node.Value.Kind() == reflect.Slice {
+            return cloner.SkipChildren
+        }
+        if node.Value.Kind() == reflect.String {
+            names = append(names, node.Value.String())
+        }
+        return nil
+    })
+    if err != nil || len(names) != 1 || names[0] != "root" {
+        t.Errorf("got %q, %v, want [root]", names, err)
+    }
+
+    stop := errors.New("stop")
+    err = cloner.Walk(root, func(node cloner.Node) error {
+        if node.Path == ".Children[1]" {
+            return stop
+        }
+        return nil
+    })
+    if err != stop {
+        t.Errorf("got error %v, want %v", err, stop)
+    }
+
+    if err := cloner.Walk(nil, nil); err != nil {
+        t.Errorf("Walk of nil failed: %v", err)
+    }
+}
@@ -0,0 +1,120 @@
+// Package clonetest provides helpers for testing that values are deep cloned
+// correctly.
+package clonetest
+
+import (
+    "fmt"
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Check clones v with a new CloneManager and fails t unless the clone is
+// correct:
+//   - the clone is deeply equal to v, as reported by reflect.DeepEqual;
+//   - memory referenced from several places in v is referenced from the same
+//     places in the clone, and only there;
+//   - the clone references no memory of v, so that modifying one never
+//     affects the other.
+func Check(t testing.TB, v interface{}) {
+    t.Helper()
+    CheckWith(t, cloner.NewCloneManager(), v)
+}
+
+// CheckWith is like Check but clones v with cm.
+func CheckWith(t testing.TB, cm *cloner.CloneManager, v interface{}) {
+    t.Helper()
+    for _, problem := range check(cm, v) {
+        t.Error(problem)
+    }
+}
+
+// check clones v with cm and returns the problems found in the clone.
+func check(cm *cloner.CloneManager, v interface{}) []string {
+    clone, err := cm.Clone(v)
+    if err != nil {
+        return []string{fmt.Sprintf("Clone failed: %v", err)}
+    }
+    if clone == nil && v != nil {
+        // Clone returns typed nil roots as nil
+        clone = reflect.Zero(reflect.TypeOf(v)).Interface()
+    }
+    if !reflect.DeepEqual(clone, v) {
+        return []string{fmt.Sprintf("Clone is not deeply equal to the original:\ngot:  %#v\nwant: %#v", clone, v)}
+    }
+
+    original, cloned := references(v), references(clone)
+    if len(original) != len(cloned) {
+        return []string{fmt.Sprintf("Clone has %d references, the original has %d", len(cloned), len(original))}
+    }
+
+    var problems []string
+    inOriginal := make(map[memref.Ref]bool)
+    for _, r := range original {
+        inOriginal[r.Ref] = true
+    }
+    clonedTo := make(map[memref.Ref]memref.Ref)
+    clonedFrom := make(map[memref.Ref]memref.Ref)
+    for i, r := range original {
+        c := cloned[i]
+        if r.path != c.path {
+            return append(problems, fmt.Sprintf("Clone has a reference at %s where the original has one at %s", c.path, r.path))
+        }
+        if inOriginal[c.Ref] {
+            problems = append(problems, fmt.Sprintf("Clone shares the memory of the original at %s (%s)", pathName(c.path), c.Type()))
+            continue
+        }
+        if to, ok := clonedTo[r.Ref]; ok && to != c.Ref {
+            problems = append(problems, fmt.Sprintf("Clone does not preserve the sharing of %s at %s", r.Type(), pathName(r.path)))
+        }
+        if from, ok := clonedFrom[c.Ref]; ok && from != r.Ref {
+            problems = append(problems, fmt.Sprintf("Clone shares %s at %s that is not shared in the original", c.Type(), pathName(c.path)))
+        }
+        clonedTo[r.Ref], clonedFrom[c.Ref] = c.Ref, r.Ref
+    }
+    return problems
+}
+
+// pathReference is a reference found at a path.
+type pathReference struct {
+    memref.Ref
+    path cloner.Path
+}
+
+// references returns the references in v to memory that can be modified, in
+// the order of cloner.Walk. References to zero-sized memory, which Go may
+// share between unrelated variables, are left out.
+func references(v interface{}) []pathReference {
+    var refs []pathReference
+    cloner.Walk(v, func(node cloner.Node) error {
+        v := node.Value
+        switch v.Kind() {
+        case reflect.Ptr:
+            if v.IsNil() || v.Type().Elem().Size() == 0 {
+                return nil
+            }
+        case reflect.Slice:
+            if v.IsNil() || v.Cap() == 0 || v.Type().Elem().Size() == 0 {
+                return nil
+            }
+        case reflect.Map:
+            if v.IsNil() {
+                return nil
+            }
+        default:
+            return nil
+        }
+        refs = append(refs, pathReference{memref.Of(v), node.Path})
+        return nil
+    })
+    return refs
+}
+
+func pathName(path cloner.Path) string {
+    if path == "" {
+        return "the root"
+    }
+    return string(path)
+}
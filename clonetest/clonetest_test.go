@@ -0,0 +1,161 @@
+package clonetest_test
+
+import (
+    "fmt"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/clonetest"
+)
+
+// recorder records the failures of a test instead of failing it.
+type recorder struct {
+    testing.TB
+    errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) {
+    r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+    r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// Test for checking correct clones
+func TestCheck(t *testing.T) {
+    type node struct {
+        Name  *string
+        Links []int
+        Self  *node
+    }
+    name := "alice"
+    cyclic := &node{Name: &name, Links: []int{1, 2}}
+    cyclic.Self = cyclic
+    clonetest.Check(t, cyclic)
+    clonetest.Check(t, map[string][]*node{"a": {cyclic, nil}})
+    clonetest.Check(t, 42)
+    clonetest.Check(t, nil)
+    clonetest.Check(t, (*node)(nil))
+
+    // Shared pointers, and prefixes of one array, which are distinct
+    balance := 1
+    clonetest.Check(t, []*int{&balance, &balance})
+    links := []int{1, 2, 3}
+    clonetest.Check(t, [][]int{links, links[:2]})
+
+    // Zero-sized memory may be shared by unrelated variables
+    clonetest.Check(t, []*struct{}{{}, {}})
+}
+
+// Test for reporting incorrect clones
+func TestCheckFailures(t *testing.T) {
+    tests := []struct {
+        name  string
+        setup func(cm *cloner.CloneManager)
+        value interface{}
+        want  string
+    }{
+        {
+            name: "error",
+            value: struct {
+                Done chan bool
+            }{make(chan bool)},
+            want: "Clone failed",
+        },
+        {
+            name: "not equal",
+            setup: func(cm *cloner.CloneManager) {
+                cm.RegisterCloner(reflect.TypeOf(""), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+                    return "bob", nil
+                }))
+            },
+            value: struct{ Owner *string }{new(string)},
+            want:  "not deeply equal",
+        },
+        {
+            name: "aliased",
+            setup: func(cm *cloner.CloneManager) {
+                cm.RegisterCloner(reflect.TypeOf([]int{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+                    return v, nil
+                }))
+            },
+            value: struct {
+                Balance *int
+                History []int
+            }{new(int), []int{1, 2}},
+            want: "shares the memory of the original at .History",
+        },
+        {
+            name: "sharing lost",
+            setup: func(cm *cloner.CloneManager) {
+                cm.RegisterCloner(reflect.TypeOf(new(int)), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+                    clone := *v.(*int)
+                    return &clone, nil
+                }))
+            },
+            value: func() interface{} {
+                balance := 1
+                return []*int{&balance, &balance}
+            }(),
+            want: "does not preserve the sharing of *int at [1]",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cm := cloner.NewCloneManager()
+            if tt.setup != nil {
+                tt.setup(cm)
+            }
+            r := &recorder{TB: t}
+            clonetest.CheckWith(r, cm, tt.value)
+            if len(r.errors) == 0 || !strings.Contains(r.errors[0], tt.want) {
+                t.Errorf("got failures %q, want %q", r.errors, tt.want)
+            }
+        })
+    }
+}
+
+type Shape interface {
+    Area() float64
+}
+
+type Circle struct {
+    Radius float64
+}
+
+func (c *Circle) Area() float64 {
+    return 3 * c.Radius * c.Radius
+}
+
+type Drawing struct {
+    Shapes  []Shape
+    ByName  map[string]Shape
+    Related map[interface{}]*Drawing
+    Root    *Drawing
+}
+
+// Test for fuzzing the built-in and user types
+func TestFuzz(t *testing.T) {
+    clonetest.Fuzz(t)
+    clonetest.Fuzz(t, Drawing{}, &Circle{}, [3][]*int{})
+}
+
+// Test that fuzzing reports clones that are incorrect
+func TestFuzzFailures(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(""), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        return v.(string) + "!", nil
+    }))
+
+    r := &recorder{TB: t}
+    clonetest.FuzzWith(r, cm, []string{})
+    if len(r.errors) == 0 || !strings.Contains(r.errors[0], "seed") {
+        t.Errorf("got failures %q, want a failure with the seed", r.errors)
+    }
+}
@@ -0,0 +1,185 @@
+package clonetest
+
+import (
+    "math/rand"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// fuzzValues is the number of values generated per type by Fuzz.
+const fuzzValues = 50
+
+// maxFuzzDepth limits the nesting of the values generated by Fuzz.
+const maxFuzzDepth = 4
+
+// FuzzNode is the type Fuzz generates values of when it is given no seeds.
+// It combines pointers, which may be shared or form cycles, with slices,
+// arrays, maps and interfaces.
+type FuzzNode struct {
+    Name     string
+    Value    interface{}
+    Next     *FuzzNode
+    Children []*FuzzNode
+    Index    map[string]*FuzzNode
+    Weights  map[int][]float64
+    Pair     [2]*int
+    Any      []interface{}
+}
+
+// Fuzz generates random values of the types of seeds and checks, as Check
+// does, that each of them is cloned correctly by a new CloneManager.
+// Generated values fill exported fields with random data: pointers are
+// nil, newly allocated or shared with a pointer generated before, which
+// creates cycles; slices and maps have up to three elements; and interfaces
+// hold values of basic types, containers of interfaces, or the seed types.
+// Without seeds, values of type *FuzzNode are generated. Failures report
+// the random seed and the failing value.
+func Fuzz(t testing.TB, seeds ...interface{}) {
+    t.Helper()
+    FuzzWith(t, cloner.NewCloneManager(), seeds...)
+}
+
+// FuzzWith is like Fuzz but clones the values with cm.
+func FuzzWith(t testing.TB, cm *cloner.CloneManager, seeds ...interface{}) {
+    t.Helper()
+    if len(seeds) == 0 {
+        seeds = []interface{}{&FuzzNode{}}
+    }
+    seed := time.Now().UnixNano()
+    g := newGenerator(seed, seeds)
+    for _, s := range seeds {
+        typ := reflect.TypeOf(s)
+        if typ == nil {
+            continue
+        }
+        for i := 0; i < fuzzValues; i++ {
+            v := g.generate(typ)
+            if problems := check(cm, v); len(problems) > 0 {
+                t.Errorf("seed %d, value %#v:\n%s", seed, v, strings.Join(problems, "\n"))
+                break
+            }
+        }
+    }
+}
+
+// generator generates random values.
+type generator struct {
+    rand *rand.Rand
+    // ifaces are the types of the values stored in interfaces.
+    ifaces []reflect.Type
+    // ptrs holds the pointers of the value being generated by type, which
+    // are reused to create sharing and cycles.
+    ptrs map[reflect.Type][]reflect.Value
+}
+
+// keyTypes are the types of the values stored in interface map keys, which
+// must be hashable.
+var keyTypes = []reflect.Type{reflect.TypeOf(0), reflect.TypeOf("")}
+
+func newGenerator(seed int64, seeds []interface{}) *generator {
+    g := &generator{
+        rand: rand.New(rand.NewSource(seed)),
+        ifaces: []reflect.Type{
+            reflect.TypeOf(0),
+            reflect.TypeOf(""),
+            reflect.TypeOf(0.0),
+            reflect.TypeOf([]interface{}{}),
+            reflect.TypeOf(map[string]interface{}{}),
+        },
+    }
+    for _, s := range seeds {
+        if t := reflect.TypeOf(s); t != nil {
+            g.ifaces = append(g.ifaces, t)
+        }
+    }
+    return g
+}
+
+// generate returns a random value of type t.
+func (g *generator) generate(t reflect.Type) interface{} {
+    g.ptrs = make(map[reflect.Type][]reflect.Value)
+    v := reflect.New(t).Elem()
+    g.fill(v, 0, false)
+    return v.Interface()
+}
+
+// fill sets v to a random value. Interfaces in map keys only hold hashable
+// values.
+func (g *generator) fill(v reflect.Value, depth int, key bool) {
+    r := g.rand
+    switch v.Kind() {
+    case reflect.Bool:
+        v.SetBool(r.Intn(2) == 0)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        v.SetInt(r.Int63n(200) - 100)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        v.SetUint(uint64(r.Intn(100)))
+    case reflect.Float32, reflect.Float64:
+        v.SetFloat(r.NormFloat64())
+    case reflect.Complex64, reflect.Complex128:
+        v.SetComplex(complex(r.NormFloat64(), r.NormFloat64()))
+    case reflect.String:
+        v.SetString(string(rune('a' + r.Intn(26))))
+    case reflect.Ptr:
+        ptrs := g.ptrs[v.Type()]
+        switch {
+        case len(ptrs) > 0 && r.Intn(3) == 0:
+            v.Set(ptrs[r.Intn(len(ptrs))])
+        case depth < maxFuzzDepth && r.Intn(4) != 0:
+            ptr := reflect.New(v.Type().Elem())
+            // Registered before it is filled, so that it can point to itself
+            g.ptrs[v.Type()] = append(ptrs, ptr)
+            v.Set(ptr)
+            g.fill(ptr.Elem(), depth+1, key)
+        }
+    case reflect.Slice:
+        if depth < maxFuzzDepth && r.Intn(4) != 0 {
+            n := r.Intn(4)
+            v.Set(reflect.MakeSlice(v.Type(), n, n))
+            for i := 0; i < n; i++ {
+                g.fill(v.Index(i), depth+1, key)
+            }
+        }
+    case reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            g.fill(v.Index(i), depth+1, key)
+        }
+    case reflect.Map:
+        if depth < maxFuzzDepth && r.Intn(4) != 0 {
+            v.Set(reflect.MakeMap(v.Type()))
+            for i := r.Intn(4); i > 0; i-- {
+                k := reflect.New(v.Type().Key()).Elem()
+                g.fill(k, depth+1, true)
+                e := reflect.New(v.Type().Elem()).Elem()
+                g.fill(e, depth+1, key)
+                v.SetMapIndex(k, e)
+            }
+        }
+    case reflect.Struct:
+        for i := 0; i < v.NumField(); i++ {
+            if v.Field(i).CanSet() {
+                g.fill(v.Field(i), depth+1, key)
+            }
+        }
+    case reflect.Interface:
+        candidates := g.ifaces
+        if key {
+            candidates = keyTypes
+        }
+        var fits []reflect.Type
+        for _, t := range candidates {
+            if t.Implements(v.Type()) {
+                fits = append(fits, t)
+            }
+        }
+        if len(fits) > 0 && depth < maxFuzzDepth && r.Intn(4) != 0 {
+            e := reflect.New(fits[r.Intn(len(fits))]).Elem()
+            g.fill(e, depth+1, key)
+            v.Set(e)
+        }
+    }
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "go/ast"
+    "go/format"
+    "go/parser"
+    "go/token"
+    "go/types"
+    "path"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+const (
+    directive  = "//deeper:generate"
+    clonerPath = "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// valueTypes lists types from other packages that are safe to copy by
+// assignment even though their definition is not visible to the generator.
+var valueTypes = map[string]bool{
+    "time.Time":     true,
+    "time.Duration": true,
+    "time.Month":    true,
+    "time.Weekday":  true,
+}
+
+var basicTypes = map[string]bool{
+    "bool": true, "string": true, "byte": true, "rune": true, "uintptr": true,
+    "int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+    "uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+    "float32": true, "float64": true, "complex64": true, "complex128": true,
+}
+
+// generator emits Clone methods for the annotated types of one package.
+type generator struct {
+    pkgName   string
+    specs     map[string]*ast.TypeSpec
+    files     map[string]*ast.File // type name -> declaring file
+    annotated []string
+    imports   map[string]string // package name -> import path used by generated code
+
+    file *ast.File // file declaring the type being generated
+    buf  bytes.Buffer
+    tmp  int
+}
+
+// generate parses the package in dir and returns the formatted source of the
+// generated file, or nil if the package has no annotated types. The file
+// named output is ignored so that regeneration is not affected by stale code.
+func generate(dir, output string) ([]byte, error) {
+    names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+    if err != nil {
+        return nil, err
+    }
+    g := &generator{
+        specs:   make(map[string]*ast.TypeSpec),
+        files:   make(map[string]*ast.File),
+        imports: make(map[string]string),
+    }
+    fset := token.NewFileSet()
+    for _, name := range names {
+        base := filepath.Base(name)
+        if base == output || strings.HasSuffix(base, "_test.go") {
+            continue
+        }
+        f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+        if err != nil {
+            return nil, err
+        }
+        if g.pkgName == "" {
+            g.pkgName = f.Name.Name
+        } else if g.pkgName != f.Name.Name {
+            return nil, fmt.Errorf("%s: found packages %s and %s", dir, g.pkgName, f.Name.Name)
+        }
+        if err := g.collect(fset, f); err != nil {
+            return nil, err
+        }
+    }
+    if len(g.annotated) == 0 {
+        return nil, nil
+    }
+    sort.Strings(g.annotated)
+
+    var body bytes.Buffer
+    g.buf.Reset()
+    g.genRegister()
+    body.Write(g.buf.Bytes())
+    for _, name := range g.annotated {
+        g.file = g.files[name]
+        g.buf.Reset()
+        g.genType(name)
+        body.Write(g.buf.Bytes())
+    }
+
+    var out bytes.Buffer
+    fmt.Fprintf(&out, "// Code generated by deeper-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", g.pkgName)
+    specs := []string{`"reflect"`}
+    for name, p := range g.imports {
+        switch {
+        case p == clonerPath:
+        case path.Base(p) == name:
+            specs = append(specs, strconv.Quote(p))
+        default:
+            specs = append(specs, name+" "+strconv.Quote(p))
+        }
+    }
+    sort.Strings(specs)
+    fmt.Fprintf(&out, "%s\n\n%q\n", strings.Join(specs, "\n"), clonerPath)
+    out.WriteString(")\n")
+    out.Write(body.Bytes())
+
+    src, err := format.Source(out.Bytes())
+    if err != nil {
+        return nil, fmt.Errorf("formatting generated code: %v", err)
+    }
+    return src, nil
+}
+
+// collect records the type declarations of f and the annotated ones among
+// them.
+func (g *generator) collect(fset *token.FileSet, f *ast.File) error {
+    for _, decl := range f.Decls {
+        gen, ok := decl.(*ast.GenDecl)
+        if !ok || gen.Tok != token.TYPE {
+            continue
+        }
+        for _, spec := range gen.Specs {
+            ts := spec.(*ast.TypeSpec)
+            g.specs[ts.Name.Name] = ts
+            g.files[ts.Name.Name] = f
+            doc := ts.Doc
+            if doc == nil && len(gen.Specs) == 1 {
+                doc = gen.Doc
+            }
+            if !hasDirective(doc) {
+                continue
+            }
+            pos := fset.Position(ts.Pos())
+            if ts.TypeParams != nil {
+                return fmt.Errorf("%s: generic type %s cannot be annotated", pos, ts.Name.Name)
+            }
+            if _, ok := ts.Type.(*ast.StructType); !ok {
+                return fmt.Errorf("%s: %s is not a struct type", pos, ts.Name.Name)
+            }
+            g.annotated = append(g.annotated, ts.Name.Name)
+        }
+    }
+    return nil
+}
+
+func hasDirective(doc *ast.CommentGroup) bool {
+    if doc == nil {
+        return false
+    }
+    for _, c := range doc.List {
+        if strings.TrimSpace(c.Text) == directive {
+            return true
+        }
+    }
+    return false
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+    fmt.Fprintf(&g.buf, format, args...)
+}
+
+// temp returns a fresh identifier with the given prefix.
+func (g *generator) temp(prefix string) string {
+    g.tmp++
+    return prefix + strconv.Itoa(g.tmp)
+}
+
+// genRegister emits RegisterGenerated, which registers a Cloner for every
+// annotated type and its pointer type.
+func (g *generator) genRegister() {
+    g.printf(`
+// RegisterGenerated registers the generated cloners of this package with cm,
+// so that annotated types are cloned without reflection wherever they occur,
+// including as struct fields, slice elements and map values.
+func RegisterGenerated(cm *cloner.CloneManager) {
+`)
+    for _, name := range g.annotated {
+        g.printf(`cm.RegisterCloner(reflect.TypeOf((*%[1]s)(nil)), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+    return value.(*%[1]s).Clone(manager)
+}))
+cm.RegisterCloner(reflect.TypeOf(%[1]s{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+    t := value.(%[1]s)
+    var c %[1]s
+    if err := t.deeperCloneInto(&c, manager); err != nil {
+        return nil, err
+    }
+    return c, nil
+}))
+`, name)
+    }
+    g.printf("}\n")
+}
+
+// genType emits the Clone method and its helpers for the annotated type name.
+func (g *generator) genType(name string) {
+    g.tmp = 0
+    g.printf(`
+// Clone implements cloner.Cloneable for *%[1]s.
+func (t *%[1]s) Clone(manager *cloner.CloneManager) (interface{}, error) {
+    if manager == nil {
+        manager = cloner.NewCloneManager()
+    }
+    if t != nil && !manager.Cloning() {
+        // Start a clone, which calls back with a manager tracking the
+        // references met
+        return manager.Clone(t)
+    }
+    return t.deeperClone(manager)
+}
+
+// deeperClone returns the clone of t recorded by the clone in progress,
+// cloning t first if it has not been seen yet.
+func (t *%[1]s) deeperClone(manager *cloner.CloneManager) (*%[1]s, error) {
+    if t == nil {
+        return nil, nil
+    }
+    if c, ok := manager.Lookup(t); ok {
+        return c.(*%[1]s), nil
+    }
+    c := new(%[1]s)
+    manager.Record(t, c)
+    if err := t.deeperCloneInto(c, manager); err != nil {
+        return nil, err
+    }
+    return c, nil
+}
+
+// deeperCloneInto sets every field of c to a deep clone of the same field of t.
+func (t *%[1]s) deeperCloneInto(c *%[1]s, manager *cloner.CloneManager) error {
+`, name)
+    st := g.specs[name].Type.(*ast.StructType)
+    for _, field := range st.Fields.List {
+        names := field.Names
+        if len(names) == 0 {
+            names = []*ast.Ident{embeddedName(field.Type)}
+        }
+        for _, n := range names {
+            if n.Name == "_" {
+                continue
+            }
+            g.clone("c."+n.Name, "t."+n.Name, field.Type)
+        }
+    }
+    g.printf("return nil\n}\n")
+}
+
+// embeddedName returns the field name of an embedded field of type expr.
+func embeddedName(expr ast.Expr) *ast.Ident {
+    switch e := expr.(type) {
+    case *ast.StarExpr:
+        return embeddedName(e.X)
+    case *ast.SelectorExpr:
+        return e.Sel
+    case *ast.IndexExpr:
+        return embeddedName(e.X)
+    case *ast.IndexListExpr:
+        return embeddedName(e.X)
+    }
+    return expr.(*ast.Ident)
+}
+
+// clone emits statements that set dst, a zero-valued addressable expression,
+// to a deep clone of src, an addressable expression of type typ.
+func (g *generator) clone(dst, src string, typ ast.Expr) {
+    if g.isValue(typ, make(map[string]bool)) {
+        g.printf("%s = %s\n", dst, src)
+        return
+    }
+    switch u := g.underlying(typ).(type) {
+    case *ast.StarExpr:
+        elem := g.typeString(u.X)
+        if _, named := typ.(*ast.Ident); named {
+            // Named pointer types have no methods; use the unnamed form.
+            src = "(*" + elem + ")(" + src + ")"
+        }
+        if id, ok := u.X.(*ast.Ident); ok && g.isAnnotated(id.Name) {
+            p := g.temp("p")
+            g.printf("%s, err := %s.deeperClone(manager)\nif err != nil {\nreturn err\n}\n", p, src)
+            g.printf("%s = %s\n", dst, p)
+            return
+        }
+        p, seen := g.temp("p"), g.temp("seen")
+        g.printf("if %s != nil {\n", src)
+        g.printf("if %s, ok := manager.Lookup(%s); ok {\n%s = %s.(*%s)\n} else {\n", seen, src, dst, seen, elem)
+        g.printf("%s := new(%s)\nmanager.Record(%s, %s)\n", p, elem, src, p)
+        g.clone("*"+p, "(*"+src+")", u.X)
+        g.printf("%s = %s\n}\n}\n", dst, p)
+    case *ast.ArrayType:
+        i := g.temp("i")
+        if u.Len != nil {
+            g.printf("for %s := range %s {\n", i, src)
+            g.clone(dst+"["+i+"]", src+"["+i+"]", u.Elt)
+            g.printf("}\n")
+            return
+        }
+        g.printf("if %s != nil {\n%s = make(%s, len(%s), cap(%s))\n", src, dst, g.typeString(typ), src, src)
+        if g.isValue(u.Elt, make(map[string]bool)) {
+            g.printf("copy(%s, %s)\n", dst, src)
+        } else {
+            g.printf("for %s := range %s {\n", i, src)
+            g.clone(dst+"["+i+"]", src+"["+i+"]", u.Elt)
+            g.printf("}\n")
+        }
+        g.printf("}\n")
+    case *ast.MapType:
+        k, v := g.temp("k"), g.temp("v")
+        g.printf("if %s != nil {\n%s = make(%s, len(%s))\n", src, dst, g.typeString(typ), src)
+        g.printf("for %s, %s := range %s {\n", k, v, src)
+        ck, cv := k, v
+        if !g.isValue(u.Key, make(map[string]bool)) {
+            ck = g.temp("ck")
+            g.printf("var %s %s\n", ck, g.typeString(u.Key))
+            g.clone(ck, k, u.Key)
+        }
+        if !g.isValue(u.Value, make(map[string]bool)) {
+            cv = g.temp("cv")
+            g.printf("var %s %s\n", cv, g.typeString(u.Value))
+            g.clone(cv, v, u.Value)
+        }
+        g.printf("%s[%s] = %s\n}\n}\n", dst, ck, cv)
+    default:
+        if id, ok := typ.(*ast.Ident); ok && g.isAnnotated(id.Name) {
+            g.printf("if err := %s.deeperCloneInto(&%s, manager); err != nil {\nreturn err\n}\n", src, dst)
+            return
+        }
+        v := g.temp("v")
+        g.printf("if %s, err := manager.Clone(%s); err != nil {\nreturn err\n} else if %s != nil {\n%s = %s.(%s)\n}\n",
+            v, src, v, dst, v, g.typeString(typ))
+    }
+}
+
+func (g *generator) isAnnotated(name string) bool {
+    for _, a := range g.annotated {
+        if a == name {
+            return true
+        }
+    }
+    return false
+}
+
+// underlying resolves named types declared in the package to their
+// definition. Types from other packages are returned unchanged.
+func (g *generator) underlying(typ ast.Expr) ast.Expr {
+    seen := make(map[string]bool)
+    for {
+        switch t := typ.(type) {
+        case *ast.ParenExpr:
+            typ = t.X
+        case *ast.Ident:
+            spec, ok := g.specs[t.Name]
+            if !ok || spec.TypeParams != nil || seen[t.Name] {
+                return typ
+            }
+            if _, ok := spec.Type.(*ast.StructType); ok {
+                return typ
+            }
+            seen[t.Name] = true
+            typ = spec.Type
+        default:
+            return typ
+        }
+    }
+}
+
+// isValue reports whether values of type typ contain no references, so that
+// assignment is a deep copy.
+func (g *generator) isValue(typ ast.Expr, seen map[string]bool) bool {
+    switch t := typ.(type) {
+    case *ast.ParenExpr:
+        return g.isValue(t.X, seen)
+    case *ast.Ident:
+        if basicTypes[t.Name] {
+            return true
+        }
+        spec, ok := g.specs[t.Name]
+        if !ok || spec.TypeParams != nil || seen[t.Name] {
+            return false
+        }
+        seen[t.Name] = true
+        return g.isValue(spec.Type, seen)
+    case *ast.SelectorExpr:
+        return valueTypes[types.ExprString(t)]
+    case *ast.ArrayType:
+        return t.Len != nil && g.isValue(t.Elt, seen)
+    case *ast.StructType:
+        for _, field := range t.Fields.List {
+            if !g.isValue(field.Type, seen) {
+                return false
+            }
+        }
+        return true
+    }
+    return false
+}
+
+// typeString formats typ as Go source and records the imports it needs.
+func (g *generator) typeString(typ ast.Expr) string {
+    ast.Inspect(typ, func(n ast.Node) bool {
+        sel, ok := n.(*ast.SelectorExpr)
+        if !ok {
+            return true
+        }
+        if id, ok := sel.X.(*ast.Ident); ok {
+            if p := g.importPath(id.Name); p != "" {
+                g.imports[id.Name] = p
+            }
+        }
+        return false
+    })
+    return types.ExprString(typ)
+}
+
+// importPath returns the path of the package imported as name by the file
+// being generated.
+func (g *generator) importPath(name string) string {
+    if g.file == nil {
+        return ""
+    }
+    for _, imp := range g.file.Imports {
+        p, err := strconv.Unquote(imp.Path.Value)
+        if err != nil {
+            continue
+        }
+        if imp.Name != nil {
+            if imp.Name.Name == name {
+                return p
+            }
+            continue
+        }
+        if path.Base(p) == name {
+            return p
+        }
+    }
+    return ""
+}
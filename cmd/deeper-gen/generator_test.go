@@ -0,0 +1,68 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// Test that the checked-in generated code is up to date
+func TestGenerateGolden(t *testing.T) {
+    dir := filepath.Join("..", "..", "internal", "gentest")
+    got, err := generate(dir, "deeper_gen.go")
+    if err != nil {
+        t.Fatalf("generate failed: %v", err)
+    }
+    want, err := os.ReadFile(filepath.Join(dir, "deeper_gen.go"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(got) != string(want) {
+        t.Errorf("generated code differs from %s; run go generate ./internal/gentest", dir)
+    }
+}
+
+// Test for rejecting annotated types that cannot be generated
+func TestGenerateErrors(t *testing.T) {
+    tests := []struct {
+        name string
+        src  string
+        want string
+    }{
+        {
+            name: "generic",
+            src:  "package p\n\n//deeper:generate\ntype Box[T any] struct{ V T }\n",
+            want: "generic type Box cannot be annotated",
+        },
+        {
+            name: "not a struct",
+            src:  "package p\n\n//deeper:generate\ntype List []int\n",
+            want: "List is not a struct type",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(tt.src), 0o644); err != nil {
+                t.Fatal(err)
+            }
+            _, err := generate(dir, "deeper_gen.go")
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Errorf("got error %v, want %q", err, tt.want)
+            }
+        })
+    }
+}
+
+// Test that packages without annotations produce no output
+func TestGenerateNoAnnotations(t *testing.T) {
+    dir := t.TempDir()
+    if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte("package p\n\ntype T struct{}\n"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    src, err := generate(dir, "deeper_gen.go")
+    if err != nil || src != nil {
+        t.Errorf("got (%q, %v), want no output", src, err)
+    }
+}
@@ -0,0 +1,66 @@
+// Command deeper-gen generates reflection-free Clone methods.
+//
+// Types are selected by a //deeper:generate comment in their documentation:
+//
+//	//deeper:generate
+//	type Node struct {
+//	    Value    int
+//	    Children []*Node
+//	}
+//
+// For every annotated struct type T, deeper-gen emits a
+//
+//	func (t *T) Clone(manager *cloner.CloneManager) (interface{}, error)
+//
+// method, so *T implements cloner.Cloneable and the CloneManager delegates to
+// it. Pointers, slices, arrays and maps are copied by generated code and
+// shared pointers, including cycles, are tracked in a visited table created
+// per clone. Fields of other kinds (interfaces, types from other packages,
+// functions, ...) are cloned through the CloneManager passed to Clone.
+//
+// The generated file also defines
+//
+//	func RegisterGenerated(cm *cloner.CloneManager)
+//
+// which registers a Cloner for every annotated type and its pointer type, so
+// that values of those types are cloned by generated code wherever the
+// CloneManager finds them and reflection is only used for the other types.
+//
+// Typical use is a go:generate directive in the package:
+//
+//	//go:generate deeper-gen
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+)
+
+var (
+    dir    = flag.String("dir", ".", "directory of the package to generate Clone methods for")
+    output = flag.String("output", "deeper_gen.go", "name of the generated file, relative to -dir")
+)
+
+func main() {
+    flag.Usage = func() {
+        fmt.Fprintf(os.Stderr, "usage: deeper-gen [-dir dir] [-output file]\n")
+        flag.PrintDefaults()
+    }
+    flag.Parse()
+
+    src, err := generate(*dir, *output)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "deeper-gen: %v\n", err)
+        os.Exit(1)
+    }
+    if src == nil {
+        fmt.Fprintf(os.Stderr, "deeper-gen: no //deeper:generate types found in %s\n", *dir)
+        return
+    }
+    if err := os.WriteFile(filepath.Join(*dir, *output), src, 0o644); err != nil {
+        fmt.Fprintf(os.Stderr, "deeper-gen: %v\n", err)
+        os.Exit(1)
+    }
+}
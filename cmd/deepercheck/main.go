@@ -0,0 +1,16 @@
+// Command deepercheck reports cloner.Clone calls whose argument type contains
+// channels, functions or sync primitives.
+//
+// It can be run directly or as a vet tool:
+//
+//	go vet -vettool=$(which deepercheck) ./...
+package main
+
+import (
+    "github.com/jayaprabhakar/go-deeper/deepercheck"
+    "golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+    singlechecker.Main(deepercheck.Analyzer)
+}
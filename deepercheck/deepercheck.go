@@ -0,0 +1,306 @@
+// Package deepercheck defines an Analyzer that reports calls to cloner.Clone,
+// MustClone and DeepCopy whose argument type cannot be deep cloned safely.
+//
+// The cloner rejects channels and functions at runtime, and sync primitives
+// are silently reset to their zero value in the clone. Both are usually
+// discovered only when a clone fails in production. The analyzer walks the
+// static type of every argument passed to cloner.Clone, cloner.MustClone,
+// cloner.DeepCopy or (*cloner.CloneManager).Clone the same way the cloner
+// walks values, and reports the offending locations unless the type has a
+// policy: it implements cloner.Cloneable, it is registered in the same
+// package with RegisterCloner, Register, RegisterSliceCloner or
+// RegisterMapCloner, or it is listed in the -allow flag.
+package deepercheck
+
+import (
+    "go/ast"
+    "go/types"
+    "strings"
+
+    "golang.org/x/tools/go/analysis"
+    "golang.org/x/tools/go/analysis/passes/inspect"
+    "golang.org/x/tools/go/ast/inspector"
+    "golang.org/x/tools/go/types/typeutil"
+)
+
+const clonerPath = "github.com/jayaprabhakar/go-deeper/cloner"
+
+const doc = `report cloner.Clone calls whose argument contains uncloneable types
+
+The deepercheck analyzer flags call sites of cloner.Clone, cloner.MustClone,
+cloner.DeepCopy and (*cloner.CloneManager).Clone whose static argument type
+contains channels, functions or sync primitives reachable through exported
+fields, pointers, slices, arrays or maps. Types implementing
+cloner.Cloneable, types registered in the same package with
+RegisterCloner(reflect.TypeOf(...)), cloner.Register, RegisterSliceCloner or
+RegisterMapCloner, and types named in the -allow flag are not inspected.`
+
+// Analyzer reports cloner.Clone calls whose argument type contains channels,
+// functions or sync primitives without a registered policy.
+var Analyzer = &analysis.Analyzer{
+    Name:     "deepercheck",
+    Doc:      doc,
+    Requires: []*analysis.Analyzer{inspect.Analyzer},
+    Run:      run,
+}
+
+var allow string
+
+func init() {
+    Analyzer.Flags.StringVar(&allow, "allow", "",
+        "comma-separated list of fully qualified types (e.g. example.com/pkg.T) that have a clone policy")
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+    inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+    c := &checker{
+        pass:    pass,
+        allowed: make(map[string]bool),
+    }
+    for _, name := range strings.Split(allow, ",") {
+        if name = strings.TrimSpace(name); name != "" {
+            c.allowed[name] = true
+        }
+    }
+    if pkg := findPackage(pass.Pkg, clonerPath); pkg != nil {
+        if obj, ok := pkg.Scope().Lookup("Cloneable").(*types.TypeName); ok {
+            c.cloneable, _ = obj.Type().Underlying().(*types.Interface)
+        }
+    }
+
+    // Registrations are collected before any call is checked so that the
+    // order of declarations in the package does not matter.
+    var calls []*ast.CallExpr
+    inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+        call := n.(*ast.CallExpr)
+        fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+        if !ok || fn.Pkg() == nil || fn.Pkg().Path() != clonerPath {
+            return
+        }
+        switch fn.Name() {
+        case "RegisterCloner":
+            if isManagerMethod(fn) && len(call.Args) > 0 {
+                if t := registeredType(pass.TypesInfo, call.Args[0]); t != nil {
+                    c.registered.Set(t, true)
+                }
+            }
+        case "Register", "RegisterSliceCloner", "RegisterMapCloner":
+            if t := typeArgument(pass.TypesInfo, call); t != nil && !isMethod(fn) {
+                c.registered.Set(t, true)
+            }
+        case "Clone", "MustClone", "DeepCopy":
+            calls = append(calls, call)
+        }
+    })
+
+    for _, call := range calls {
+        fn := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+        switch {
+        case isManagerMethod(fn) || fn.Name() == "DeepCopy":
+            if len(call.Args) > 0 {
+                c.check(call.Args[0])
+            }
+        case !isMethod(fn) && len(call.Args) > 1:
+            c.check(call.Args[1])
+        }
+    }
+    return nil, nil
+}
+
+// findPackage returns the package with the given path among pkg and its
+// transitive imports, or nil if it is not imported.
+func findPackage(pkg *types.Package, path string) *types.Package {
+    seen := make(map[*types.Package]bool)
+    var find func(p *types.Package) *types.Package
+    find = func(p *types.Package) *types.Package {
+        if p.Path() == path {
+            return p
+        }
+        if seen[p] {
+            return nil
+        }
+        seen[p] = true
+        for _, imp := range p.Imports() {
+            if found := find(imp); found != nil {
+                return found
+            }
+        }
+        return nil
+    }
+    return find(pkg)
+}
+
+func isMethod(fn *types.Func) bool {
+    return fn.Type().(*types.Signature).Recv() != nil
+}
+
+// isManagerMethod reports whether fn is a method of cloner.CloneManager.
+func isManagerMethod(fn *types.Func) bool {
+    recv := fn.Type().(*types.Signature).Recv()
+    if recv == nil {
+        return false
+    }
+    t := recv.Type()
+    if ptr, ok := t.(*types.Pointer); ok {
+        t = ptr.Elem()
+    }
+    named, ok := t.(*types.Named)
+    return ok && named.Obj().Name() == "CloneManager"
+}
+
+// registeredType returns the type described by a reflect.TypeOf(x) or
+// reflect.TypeFor[T]() expression, or nil if expr is neither.
+func registeredType(info *types.Info, expr ast.Expr) types.Type {
+    call, ok := ast.Unparen(expr).(*ast.CallExpr)
+    if !ok {
+        return nil
+    }
+    fn, ok := typeutil.Callee(info, call).(*types.Func)
+    if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "reflect" {
+        return nil
+    }
+    switch fn.Name() {
+    case "TypeOf":
+        if len(call.Args) == 1 {
+            return info.TypeOf(call.Args[0])
+        }
+    case "TypeFor":
+        if index, ok := ast.Unparen(call.Fun).(*ast.IndexExpr); ok {
+            return info.TypeOf(index.Index)
+        }
+    }
+    return nil
+}
+
+// typeArgument returns the first type argument of the generic function
+// called by call, whether it is written or inferred, such as T in
+// cloner.Register(func(src T, ...) (T, error) {...}), or nil if there is
+// none.
+func typeArgument(info *types.Info, call *ast.CallExpr) types.Type {
+    fun := ast.Unparen(call.Fun)
+    switch e := fun.(type) {
+    case *ast.IndexExpr:
+        fun = e.X
+    case *ast.IndexListExpr:
+        fun = e.X
+    }
+    var id *ast.Ident
+    switch e := ast.Unparen(fun).(type) {
+    case *ast.Ident:
+        id = e
+    case *ast.SelectorExpr:
+        id = e.Sel
+    default:
+        return nil
+    }
+    inst, ok := info.Instances[id]
+    if !ok || inst.TypeArgs.Len() == 0 {
+        return nil
+    }
+    return inst.TypeArgs.At(0)
+}
+
+// checker walks argument types and reports the uncloneable parts.
+type checker struct {
+    pass       *analysis.Pass
+    allowed    map[string]bool
+    registered typeutil.Map
+    cloneable  *types.Interface
+}
+
+func (c *checker) check(arg ast.Expr) {
+    t := c.pass.TypesInfo.TypeOf(arg)
+    if t == nil {
+        return
+    }
+    root := types.TypeString(t, types.RelativeTo(c.pass.Pkg))
+    c.walk(t, "", make(map[types.Type]bool), func(path, problem string) {
+        if path == "" {
+            c.pass.Reportf(arg.Pos(), "cloner.Clone argument of type %s is %s", root, problem)
+            return
+        }
+        c.pass.Reportf(arg.Pos(), "cloner.Clone argument of type %s contains %s at %s", root, problem, path)
+    })
+}
+
+// walk mirrors the traversal performed by the cloner: Cloneable and
+// registered types stop the descent, unexported struct fields are skipped
+// and interfaces are not inspected because their dynamic type is unknown.
+func (c *checker) walk(t types.Type, path string, seen map[types.Type]bool, report func(path, problem string)) {
+    if c.hasPolicy(t) {
+        return
+    }
+    if named, ok := t.(*types.Named); ok {
+        if seen[named] {
+            return
+        }
+        seen[named] = true
+        if name := syncPrimitive(named); name != "" {
+            report(path, "sync primitive "+name+" (reset to its zero value by the clone)")
+            return
+        }
+    }
+
+    switch u := t.Underlying().(type) {
+    case *types.Chan:
+        report(path, "a channel (channels cannot be cloned)")
+    case *types.Signature:
+        report(path, "a function (functions cannot be cloned)")
+    case *types.Pointer:
+        c.walk(u.Elem(), path, seen, report)
+    case *types.Slice:
+        c.walk(u.Elem(), path+"[]", seen, report)
+    case *types.Array:
+        c.walk(u.Elem(), path+"[]", seen, report)
+    case *types.Map:
+        c.walk(u.Key(), path+"[key]", seen, report)
+        c.walk(u.Elem(), path+"[]", seen, report)
+    case *types.Struct:
+        for i := 0; i < u.NumFields(); i++ {
+            field := u.Field(i)
+            if !field.Exported() {
+                continue
+            }
+            c.walk(field.Type(), path+"."+field.Name(), seen, report)
+        }
+    }
+}
+
+// hasPolicy reports whether the cloner handles t without reflection, either
+// through a registered Cloner or the Cloneable interface.
+func (c *checker) hasPolicy(t types.Type) bool {
+    if c.registered.At(t) != nil {
+        return true
+    }
+    if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+        if c.allowed[named.Obj().Pkg().Path()+"."+named.Obj().Name()] {
+            return true
+        }
+    }
+    if ptr, ok := t.(*types.Pointer); ok {
+        if named, ok := ptr.Elem().(*types.Named); ok && named.Obj().Pkg() != nil {
+            if c.allowed["*"+named.Obj().Pkg().Path()+"."+named.Obj().Name()] {
+                return true
+            }
+        }
+    }
+    return c.cloneable != nil && types.Implements(t, c.cloneable)
+}
+
+// syncPrimitive returns the qualified name of t if it is a type from sync or
+// sync/atomic that must not be copied, or "" otherwise.
+func syncPrimitive(t *types.Named) string {
+    obj := t.Obj()
+    if obj.Pkg() == nil {
+        return ""
+    }
+    if _, ok := t.Underlying().(*types.Struct); !ok {
+        return ""
+    }
+    switch obj.Pkg().Path() {
+    case "sync", "sync/atomic":
+        return obj.Pkg().Name() + "." + obj.Name()
+    }
+    return ""
+}
@@ -0,0 +1,13 @@
+package deepercheck_test
+
+import (
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/deepercheck"
+    "golang.org/x/tools/go/analysis/analysistest"
+)
+
+// Test for reporting uncloneable argument types
+func TestAnalyzer(t *testing.T) {
+    analysistest.Run(t, analysistest.TestData(), deepercheck.Analyzer, "a")
+}
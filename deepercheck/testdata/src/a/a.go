@@ -0,0 +1,111 @@
+package a
+
+import (
+    "reflect"
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+type Plain struct {
+    Name  string
+    Items []*Plain
+    Tags  map[string]int
+}
+
+type WithChan struct {
+    Events chan int
+    done   chan struct{} // unexported fields are not cloned
+}
+
+type WithFunc struct {
+    Nested struct {
+        OnChange func()
+    }
+}
+
+type WithMutex struct {
+    Mu   sync.Mutex
+    Data map[string]string
+}
+
+type Handlers struct {
+    ByName map[string]func() error
+}
+
+type Self struct {
+    Copy *Self
+}
+
+func (s *Self) Clone(manager *cloner.CloneManager) (interface{}, error) {
+    return &Self{}, nil
+}
+
+type Owner struct {
+    S  *Self
+    Fn Registered
+}
+
+type Registered struct {
+    Callback func()
+}
+
+type Generic struct {
+    Callback func()
+}
+
+type Explicit struct {
+    Callback func()
+}
+
+type Callbacks []func()
+
+type Hooks map[string]func()
+
+type Unregistered []func()
+
+func init() {
+    cloner.Register(func(src Generic, manager *cloner.CloneManager) (Generic, error) {
+        return src, nil
+    })
+    cloner.Register[*Explicit](func(src *Explicit, manager *cloner.CloneManager) (*Explicit, error) {
+        return src, nil
+    })
+    cloner.RegisterSliceCloner(func(src Callbacks) Callbacks {
+        return append(Callbacks(nil), src...)
+    })
+    cloner.RegisterMapCloner[Hooks](func(src Hooks) Hooks {
+        return src
+    })
+}
+
+type registeredCloner struct{}
+
+func (registeredCloner) Clone(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+    return value, nil
+}
+
+func calls() {
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(Registered{}), registeredCloner{})
+
+    cm.Clone(Plain{})
+    cm.Clone(&WithChan{})                 // want `cloner.Clone argument of type \*WithChan contains a channel \(channels cannot be cloned\) at .Events`
+    cloner.Clone(cm, WithFunc{})          // want `contains a function \(functions cannot be cloned\) at .Nested.OnChange`
+    cloner.Clone(cm, []WithMutex{})       // want `contains sync primitive sync.Mutex \(reset to its zero value by the clone\) at \[\].Mu`
+    cm.Clone(Handlers{})                  // want `contains a function \(functions cannot be cloned\) at .ByName\[\]`
+    cm.Clone(make(chan int))              // want `cloner.Clone argument of type chan int is a channel`
+    cm.Clone(&Self{})
+    cm.Clone(Owner{})
+    cloner.MustClone(cm, Handlers{})      // want `contains a function \(functions cannot be cloned\) at .ByName\[\]`
+    cloner.DeepCopy(map[string]WithChan{}) // want `contains a channel \(channels cannot be cloned\) at \[\].Events`
+    cloner.DeepCopy(Plain{})
+    cm.Clone(Generic{})
+    cm.Clone(&Explicit{})
+    cm.Clone(Explicit{})                  // want `contains a function \(functions cannot be cloned\) at .Callback`
+    cloner.Clone(cm, Callbacks{})
+    cloner.DeepCopy(struct{ H Hooks }{})
+    cm.Clone(Unregistered{})              // want `contains a function \(functions cannot be cloned\) at \[\]`
+    var v interface{} = WithChan{}
+    cm.Clone(v)
+}
@@ -0,0 +1,33 @@
+package cloner
+
+import "reflect"
+
+type Cloneable interface {
+    Clone(manager *CloneManager) (interface{}, error)
+}
+
+type Cloner interface {
+    Clone(value interface{}, manager *CloneManager) (interface{}, error)
+}
+
+type CloneManager struct{}
+
+type Option func()
+
+func NewCloneManager() *CloneManager { return &CloneManager{} }
+
+func (cm *CloneManager) RegisterCloner(t reflect.Type, cloner Cloner) {}
+
+func (cm *CloneManager) Clone(src interface{}, opts ...Option) (interface{}, error) { return src, nil }
+
+func Clone[T any](cm *CloneManager, src T, opts ...Option) (T, error) { return src, nil }
+
+func MustClone[T any](cm *CloneManager, src T, opts ...Option) T { return src }
+
+func DeepCopy[T any](src T) T { return src }
+
+func Register[T any](fn func(src T, manager *CloneManager) (T, error)) {}
+
+func RegisterSliceCloner[S ~[]E, E any](fn func(src S) S) {}
+
+func RegisterMapCloner[M ~map[K]V, K comparable, V any](fn func(src M) M) {}
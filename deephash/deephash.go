@@ -0,0 +1,240 @@
+// Package deephash computes hashes of object graphs that agree with
+// cloner.Equal: equal graphs, such as a value and its clone, have the same
+// hash regardless of where they are stored or of the iteration order of
+// their maps.
+//
+// cloner.Equal compares types with an Equal method using it. Hash knows the
+// values that time.Time and net.IP consider equal, but hashes the values of
+// other such types alike, as nothing tells it which of them are equal; they
+// need a hasher registered with RegisterHasher to be told apart. So do types
+// compared with cloner.RegisterComparer, such as decimals, and types whose
+// representation varies between equal values, such as *big.Float, for their
+// hashes to agree with cloner.Equal at all.
+package deephash
+
+import (
+    "encoding/binary"
+    "hash"
+    "hash/fnv"
+    "math"
+    "net"
+    "reflect"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+var (
+    hashers      = make(map[reflect.Type]func(reflect.Value, hash.Hash64))
+    hashersMutex sync.RWMutex
+)
+
+// RegisterHasher registers fn as the hasher of values of type T: Hash calls
+// it to write T values to the hash, instead of walking them. Equal values
+// must be written identically.
+//
+// Registering a second hasher for the same type replaces the first.
+func RegisterHasher[T any](fn func(v T, h hash.Hash64)) {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    hashersMutex.Lock()
+    defer hashersMutex.Unlock()
+    hashers[t] = func(v reflect.Value, h hash.Hash64) {
+        fn(v.Interface().(T), h)
+    }
+}
+
+func registeredHasher(t reflect.Type) (func(reflect.Value, hash.Hash64), bool) {
+    hashersMutex.RLock()
+    defer hashersMutex.RUnlock()
+    fn, found := hashers[t]
+    return fn, found
+}
+
+// Hash returns the 64-bit FNV-1a hash of v. Struct fields tagged
+// `deeper:"ignoreeq"` are left out, like cloner.Equal does; time.Time
+// values are hashed by instant and net.IP values by their 16-byte form; and
+// floats are hashed so that -0 and +0 hash alike.
+func Hash(v interface{}) uint64 {
+    h := &hasher{h: fnv.New64a(), active: make(map[memref.Ref]int)}
+    h.hash(reflect.ValueOf(v))
+    return h.h.Sum64()
+}
+
+type hasher struct {
+    h hash.Hash64
+    // active holds the depth of the references being hashed; a cycle is
+    // hashed as the distance to the ancestor it leads back to
+    active map[memref.Ref]int
+    depth  int
+    buf    [8]byte
+}
+
+func (h *hasher) byte(b byte) {
+    h.buf[0] = b
+    h.h.Write(h.buf[:1])
+}
+
+func (h *hasher) uint(n uint64) {
+    binary.LittleEndian.PutUint64(h.buf[:], n)
+    h.h.Write(h.buf[:])
+}
+
+func (h *hasher) string(s string) {
+    h.uint(uint64(len(s)))
+    h.h.Write([]byte(s))
+}
+
+// Markers distinguishing the kinds of values hashed.
+const (
+    markNil byte = iota
+    markValue
+    markCycle
+    markHasher
+)
+
+func (h *hasher) hash(v reflect.Value) {
+    if !v.IsValid() {
+        h.byte(markNil)
+        return
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+        if v.IsNil() {
+            h.byte(markNil)
+            return
+        }
+    }
+    if fn, found := registeredHasher(v.Type()); found && v.CanInterface() {
+        h.byte(markHasher)
+        fn(v, h.h)
+        return
+    }
+    if h.equaler(v) {
+        return
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        ref := memref.Of(v)
+        if depth, ok := h.active[ref]; ok {
+            h.byte(markCycle)
+            h.uint(uint64(h.depth - depth))
+            return
+        }
+        h.active[ref] = h.depth
+        h.depth++
+        defer func() {
+            h.depth--
+            delete(h.active, ref)
+        }()
+    }
+
+    h.byte(markValue)
+    switch v.Kind() {
+    case reflect.Ptr:
+        h.hash(v.Elem())
+    case reflect.Interface:
+        // Values of different types are never equal
+        h.string(v.Elem().Type().String())
+        h.hash(v.Elem())
+    case reflect.Slice, reflect.Array:
+        h.uint(uint64(v.Len()))
+        for i := 0; i < v.Len(); i++ {
+            h.hash(v.Index(i))
+        }
+    case reflect.Map:
+        // Entries are hashed separately and summed, so that the order in
+        // which they are visited does not matter
+        var sum uint64
+        iter := v.MapRange()
+        for iter.Next() {
+            entry := &hasher{h: fnv.New64a(), active: h.active, depth: h.depth}
+            entry.hash(iter.Key())
+            entry.hash(iter.Value())
+            sum += entry.h.Sum64()
+        }
+        h.uint(uint64(v.Len()))
+        h.uint(sum)
+    case reflect.Struct:
+        for i := 0; i < v.NumField(); i++ {
+            if !ignored(v.Type().Field(i)) {
+                h.hash(v.Field(i))
+            }
+        }
+    case reflect.Bool:
+        if v.Bool() {
+            h.byte(1)
+        } else {
+            h.byte(0)
+        }
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        h.uint(uint64(v.Int()))
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        h.uint(v.Uint())
+    case reflect.Float32, reflect.Float64:
+        h.float(v.Float())
+    case reflect.Complex64, reflect.Complex128:
+        h.float(real(v.Complex()))
+        h.float(imag(v.Complex()))
+    case reflect.String:
+        h.string(v.String())
+    case reflect.Chan, reflect.UnsafePointer:
+        h.uint(uint64(v.Pointer()))
+    }
+    // Functions are only equal when nil, which is hashed above
+}
+
+// equaler hashes v if its type has an Equal method taking its own type, as
+// cloner.Equal compares such values with the method, and reports whether it
+// did.
+func (h *hasher) equaler(v reflect.Value) bool {
+    if !v.CanInterface() || v.Kind() == reflect.Interface {
+        return false
+    }
+    method, ok := v.Type().MethodByName("Equal")
+    if !ok {
+        return false
+    }
+    t := method.Type
+    if t.NumIn() != 2 || t.In(1) != v.Type() || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Bool {
+        return false
+    }
+    switch x := v.Interface().(type) {
+    case time.Time:
+        h.byte(markValue)
+        h.uint(uint64(x.UnixNano()))
+    case net.IP:
+        if len(x) == 0 {
+            // Empty addresses equal nil ones
+            h.byte(markNil)
+            break
+        }
+        if ip := x.To16(); ip != nil {
+            x = ip
+        }
+        h.byte(markValue)
+        h.string(string(x))
+    default:
+        // Nothing tells which values the method finds equal
+        h.byte(markValue)
+    }
+    return true
+}
+
+func (h *hasher) float(f float64) {
+    if f == 0 {
+        f = 0 // -0 equals +0
+    }
+    h.uint(math.Float64bits(f))
+}
+
+// ignored reports whether field is tagged `deeper:"ignoreeq"`.
+func ignored(field reflect.StructField) bool {
+    for _, opt := range strings.Split(field.Tag.Get("deeper"), ",") {
+        if strings.TrimSpace(opt) == "ignoreeq" {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,161 @@
+package deephash_test
+
+import (
+    "hash"
+    "math"
+    "math/big"
+    "net"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/deephash"
+)
+
+// Test for hashes of equal graphs
+func TestHash(t *testing.T) {
+    type item struct {
+        Name string
+        Tags []string
+    }
+    a := map[string]*item{"x": {Name: "x", Tags: []string{"a"}}, "y": {Name: "y"}}
+    b := map[string]*item{"y": {Name: "y"}, "x": {Name: "x", Tags: []string{"a"}}}
+    if deephash.Hash(a) != deephash.Hash(b) {
+        t.Errorf("Equal maps have different hashes")
+    }
+    if deephash.Hash(cloner.MustClone(cloner.NewCloneManager(), a)) != deephash.Hash(a) {
+        t.Errorf("A clone has a different hash than its source")
+    }
+
+    b["x"].Tags[0] = "b"
+    if deephash.Hash(a) == deephash.Hash(b) {
+        t.Errorf("Different maps have the same hash")
+    }
+}
+
+// Test for leaving out fields tagged ignoreeq
+func TestHashIgnoredFields(t *testing.T) {
+    type entry struct {
+        Key   string
+        Cache []byte `deeper:"ignoreeq"`
+        Stamp int    `deeper:" ignoreeq"`
+    }
+    a := entry{Key: "k", Cache: []byte("a"), Stamp: 1}
+    b := entry{Key: "k", Stamp: 2}
+    if deephash.Hash(a) != deephash.Hash(b) {
+        t.Errorf("Entries differing in ignored fields have different hashes")
+    }
+    if deephash.Hash(a) == deephash.Hash(entry{Key: "j"}) {
+        t.Errorf("Entries with different keys have the same hash")
+    }
+}
+
+// Test for hashing times by instant and zeros alike
+func TestHashTimesAndZeros(t *testing.T) {
+    type reading struct {
+        At    time.Time
+        Value float64
+        Phase complex128
+    }
+    at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    a := reading{At: at}
+    b := reading{At: at.In(time.FixedZone("X", 3600)), Value: math.Copysign(0, -1), Phase: complex(math.Copysign(0, -1), 0)}
+    if deephash.Hash(a) != deephash.Hash(b) {
+        t.Errorf("Equal readings have different hashes")
+    }
+    if deephash.Hash(a) == deephash.Hash(reading{At: at.Add(time.Nanosecond)}) {
+        t.Errorf("Readings at different instants have the same hash")
+    }
+}
+
+// Test for hashes agreeing with cloner.Equal on types with an Equal method
+func TestHashEqualMethods(t *testing.T) {
+    type host struct {
+        Addr net.IP
+        Seen time.Time
+    }
+    seen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    a := host{Addr: net.IPv4(10, 0, 0, 1), Seen: seen}
+    b := host{Addr: net.IP{10, 0, 0, 1}, Seen: seen.In(time.FixedZone("X", 3600))}
+    if !cloner.Equal(a, b) || deephash.Hash(a) != deephash.Hash(b) {
+        t.Errorf("Hosts equal under cloner.Equal have different hashes")
+    }
+    if deephash.Hash(a) == deephash.Hash(host{Addr: net.IP{10, 0, 0, 2}, Seen: seen}) {
+        t.Errorf("Hosts with different addresses have the same hash")
+    }
+    if !cloner.Equal(host{}, host{Addr: net.IP{}}) || deephash.Hash(host{}) != deephash.Hash(host{Addr: net.IP{}}) {
+        t.Errorf("Hosts with nil and empty addresses have different hashes")
+    }
+
+    // Only the method knows that versions differing in case are equal
+    type version struct{ Name string }
+    if deephash.Hash(Version{"V1"}) != deephash.Hash(Version{"v1"}) {
+        t.Errorf("Versions equal under their Equal method have different hashes")
+    }
+    if deephash.Hash(version{"V1"}) == deephash.Hash(version{"v1"}) {
+        t.Errorf("Different versions without an Equal method have the same hash")
+    }
+}
+
+// Version is compared regardless of case.
+type Version struct{ Name string }
+
+func (v Version) Equal(w Version) bool {
+    return strings.EqualFold(v.Name, w.Name)
+}
+
+// Test for hashing cycles by the ancestor they lead back to
+func TestHashCycles(t *testing.T) {
+    type node struct {
+        Name string
+        Next *node
+    }
+    ring := func() *node {
+        a, b := &node{Name: "a"}, &node{Name: "b"}
+        a.Next, b.Next = b, a
+        return a
+    }
+    if deephash.Hash(ring()) != deephash.Hash(ring()) {
+        t.Errorf("Equal rings have different hashes")
+    }
+
+    // Both have the same names along the way, but b leads back to itself
+    loop := &node{Name: "a", Next: &node{Name: "b"}}
+    loop.Next.Next = loop.Next
+    if deephash.Hash(ring()) == deephash.Hash(loop) {
+        t.Errorf("Cycles leading back to different ancestors have the same hash")
+    }
+}
+
+// Test for hashing values with a registered hasher
+func TestRegisterHasher(t *testing.T) {
+    a := big.NewFloat(10)
+    b := new(big.Float).SetPrec(200).SetFloat64(10)
+    deephash.RegisterHasher(func(f *big.Float, h hash.Hash64) {
+        h.Write([]byte(f.Text('g', -1)))
+    })
+    if deephash.Hash(a) != deephash.Hash(b) {
+        t.Errorf("Equal floats of different precisions have different hashes")
+    }
+    if deephash.Hash(a) == deephash.Hash(big.NewFloat(11)) {
+        t.Errorf("Different floats have the same hash")
+    }
+}
+
+// Test for hashes of values that differ in type or shape only
+func TestHashDistinguishes(t *testing.T) {
+    type person struct{ Name string }
+    pairs := [][2]interface{}{
+        {1, "1"},
+        {[]interface{}{int32(1)}, []interface{}{int64(1)}},
+        {[]string{"ab", "c"}, []string{"a", "bc"}},
+        {map[string]int{"a": 1, "b": 2}, map[string]int{"a": 2, "b": 1}},
+        {(*person)(nil), &person{}},
+    }
+    for _, pair := range pairs {
+        if deephash.Hash(pair[0]) == deephash.Hash(pair[1]) {
+            t.Errorf("%#v and %#v have the same hash", pair[0], pair[1])
+        }
+    }
+}
@@ -0,0 +1,147 @@
+// Package deepzero resets object graphs to their zero value for reuse, the
+// write-side sibling of cloning: a value taken from a sync.Pool can be
+// cleared deeply while keeping the memory it already allocated.
+package deepzero
+
+import (
+    "fmt"
+    "reflect"
+    "sync"
+)
+
+// Resetter is implemented by types that reset themselves, such as
+// bytes.Buffer and strings.Builder. Clear calls Reset instead of walking
+// their contents.
+type Resetter interface {
+    Reset()
+}
+
+var (
+    resetters      = make(map[reflect.Type]func(reflect.Value))
+    resettersMutex sync.RWMutex
+)
+
+// Register registers reset as the way to clear values of type T, for types
+// that cannot implement Resetter or that must release resources when
+// cleared. It takes precedence over a Reset method.
+//
+// Registering a second function for the same type replaces the first.
+func Register[T any](reset func(*T)) {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    resettersMutex.Lock()
+    defer resettersMutex.Unlock()
+    resetters[t] = func(v reflect.Value) {
+        reset(v.Addr().Interface().(*T))
+    }
+}
+
+func registered(t reflect.Type) (func(reflect.Value), bool) {
+    resettersMutex.RLock()
+    defer resettersMutex.RUnlock()
+    reset, found := resetters[t]
+    return reset, found
+}
+
+var resetterType = reflect.TypeOf((*Resetter)(nil)).Elem()
+
+// Option configures Clear.
+type Option func(*options)
+
+type options struct {
+    keepCapacity bool
+}
+
+// KeepCapacity makes Clear empty maps in place and truncate slices to
+// length zero instead of setting them to nil, so that the memory they
+// allocated is reused. The elements of slices are cleared first, so that
+// they do not keep the values they referenced alive.
+func KeepCapacity() Option {
+    return func(o *options) {
+        o.keepCapacity = true
+    }
+}
+
+// Clear resets the value ptr points to: values of registered types and
+// Resetters are reset, structs and arrays are cleared field by field and
+// element by element, maps and slices are emptied, and everything else,
+// including pointers and interfaces, is set to its zero value. The values
+// pointers refer to are left untouched, since they may be shared.
+func Clear(ptr interface{}, opts ...Option) error {
+    v := reflect.ValueOf(ptr)
+    if v.Kind() != reflect.Ptr || v.IsNil() {
+        return fmt.Errorf("deepzero: Clear needs a non-nil pointer, got %T", ptr)
+    }
+    c := &clearer{}
+    for _, opt := range opts {
+        opt(&c.options)
+    }
+    c.clear(v.Elem())
+    return nil
+}
+
+type clearer struct {
+    options
+}
+
+// clear resets the addressable value v.
+func (c *clearer) clear(v reflect.Value) {
+    if reset, found := registered(v.Type()); found {
+        reset(v)
+        return
+    }
+    if v.Kind() != reflect.Interface && reflect.PointerTo(v.Type()).Implements(resetterType) {
+        v.Addr().Interface().(Resetter).Reset()
+        return
+    }
+
+    switch v.Kind() {
+    case reflect.Map:
+        if c.keepCapacity && !v.IsNil() {
+            v.Clear()
+            return
+        }
+    case reflect.Slice:
+        if c.keepCapacity && !v.IsNil() {
+            full := v.Slice(0, v.Cap())
+            for i := 0; i < full.Len(); i++ {
+                c.clear(full.Index(i))
+            }
+            v.SetLen(0)
+            return
+        }
+    case reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            c.clear(v.Index(i))
+        }
+        return
+    case reflect.Struct:
+        c.clearStruct(v)
+        return
+    }
+    v.Set(reflect.Zero(v.Type()))
+}
+
+// clearStruct clears the fields of the struct v. Unexported fields cannot
+// be cleared one by one; they are reset to zero along with the struct,
+// which then gets back its cleared exported fields.
+func (c *clearer) clearStruct(v reflect.Value) {
+    t := v.Type()
+    unexported := false
+    for i := 0; i < t.NumField(); i++ {
+        if t.Field(i).IsExported() {
+            c.clear(v.Field(i))
+        } else {
+            unexported = true
+        }
+    }
+    if !unexported {
+        return
+    }
+    zero := reflect.New(t).Elem()
+    for i := 0; i < t.NumField(); i++ {
+        if t.Field(i).IsExported() {
+            zero.Field(i).Set(v.Field(i))
+        }
+    }
+    v.Set(zero)
+}
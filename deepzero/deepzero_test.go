@@ -0,0 +1,117 @@
+package deepzero_test
+
+import (
+    "bytes"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/deepzero"
+)
+
+// Test for clearing a value to its zero value
+func TestClear(t *testing.T) {
+    type node struct {
+        Name string
+        Next *node
+    }
+    type request struct {
+        Method  string
+        Headers map[string][]string
+        Body    bytes.Buffer
+        Next    *node
+        Retries [2]*int
+        Started time.Time
+        id      int
+    }
+    retries := 3
+    r := &request{
+        Method:  "GET",
+        Headers: map[string][]string{"Accept": {"*/*"}},
+        Next:    &node{Name: "next"},
+        Retries: [2]*int{&retries},
+        Started: time.Now(),
+        id:      7,
+    }
+    r.Body.WriteString("payload")
+    next := r.Next
+    if err := deepzero.Clear(r); err != nil {
+        t.Fatalf("Clear failed: %v", err)
+    }
+
+    if r.Body.Len() != 0 || r.Body.Cap() == 0 {
+        t.Errorf("Clear did not reset the body in place")
+    }
+    if !reflect.DeepEqual(*r, request{Body: r.Body}) {
+        t.Errorf("Clear left values in %+v", r)
+    }
+    if next.Name != "next" || retries != 3 {
+        t.Errorf("Clear modified a pointed value")
+    }
+
+    if err := deepzero.Clear(*r); err == nil {
+        t.Errorf("Clear accepted a value that is not a pointer")
+    }
+    if err := deepzero.Clear((*request)(nil)); err == nil {
+        t.Errorf("Clear accepted a nil pointer")
+    }
+}
+
+// Test for clearing values of a registered type
+func TestRegister(t *testing.T) {
+    type conn struct {
+        Addr     string
+        released bool
+    }
+    var released []string
+    deepzero.Register(func(c *conn) {
+        released = append(released, c.Addr)
+        *c = conn{released: true}
+    })
+
+    pool := struct {
+        Primary conn
+        Spares  [2]conn
+    }{conn{Addr: "10.0.0.1"}, [2]conn{{Addr: "10.0.0.2"}}}
+    if err := deepzero.Clear(&pool); err != nil {
+        t.Fatalf("Clear failed: %v", err)
+    }
+    if !reflect.DeepEqual(released, []string{"10.0.0.1", "10.0.0.2", ""}) {
+        t.Errorf("got released %q, want every connection in order", released)
+    }
+    if !pool.Primary.released || !pool.Spares[1].released {
+        t.Errorf("Clear did not use the registered function: %+v", pool)
+    }
+}
+
+// Test for clearing a value while keeping its memory
+func TestClearKeepCapacity(t *testing.T) {
+    type part struct {
+        Name string
+        Data *[]byte
+    }
+    type message struct {
+        Headers map[string]string
+        Parts   []part
+        Trailer []string
+    }
+    data := []byte("x")
+    m := &message{Headers: map[string]string{"Accept": "*/*"}, Parts: []part{{Name: "a", Data: &data}}}
+    headers := m.Headers
+    if err := deepzero.Clear(m, deepzero.KeepCapacity()); err != nil {
+        t.Fatalf("Clear failed: %v", err)
+    }
+
+    if len(headers) != 0 || m.Headers == nil {
+        t.Errorf("Clear did not empty the headers in place")
+    }
+    if len(m.Parts) != 0 || cap(m.Parts) != 1 {
+        t.Errorf("got parts of length %d and capacity %d, want 0 and 1", len(m.Parts), cap(m.Parts))
+    }
+    if m.Parts[:1][0] != (part{}) || len(data) != 1 {
+        t.Errorf("Clear did not clear the truncated parts without modifying their data")
+    }
+    if m.Trailer != nil {
+        t.Errorf("Clear allocated a nil slice")
+    }
+}
@@ -0,0 +1,209 @@
+// Package defaults fills in the zero fields of object graphs from their
+// default:"..." struct tags and from registered defaulters, for example to
+// populate a freshly cloned or cleared configuration.
+//
+//	type Server struct {
+//	    Addr    string        `default:":8080"`
+//	    Timeout time.Duration `default:"30s"`
+//	    Limits  *Limits
+//	}
+//
+// Apply walks the graph like cloner.Walk: through pointers, slices, arrays,
+// map values, exported fields and interfaces, visiting shared memory once.
+package defaults
+
+import (
+    "encoding"
+    "fmt"
+    "reflect"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Defaulter is implemented by types that fill in their own defaults. Apply
+// calls SetDefaults after applying the default tags of the value's fields.
+type Defaulter interface {
+    SetDefaults()
+}
+
+var (
+    defaulters      = make(map[reflect.Type]func(reflect.Value))
+    defaultersMutex sync.RWMutex
+)
+
+// Register registers fn as the defaulter of values of type T, called by
+// Apply after the default tags of the value's fields, in place of a
+// SetDefaults method. Defaulters should only fill in zero fields, so that
+// applying defaults twice has no further effect.
+//
+// Registering a second defaulter for the same type replaces the first.
+func Register[T any](fn func(*T)) {
+    t := reflect.TypeOf((*T)(nil)).Elem()
+    defaultersMutex.Lock()
+    defer defaultersMutex.Unlock()
+    defaulters[t] = func(v reflect.Value) {
+        fn(v.Addr().Interface().(*T))
+    }
+}
+
+func registered(t reflect.Type) (func(reflect.Value), bool) {
+    defaultersMutex.RLock()
+    defer defaultersMutex.RUnlock()
+    fn, found := defaulters[t]
+    return fn, found
+}
+
+var (
+    defaulterType       = reflect.TypeOf((*Defaulter)(nil)).Elem()
+    textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+    durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// Apply fills in the defaults of the value ptr points to. Zero fields with
+// a default tag are set from it: strings as-is, booleans and numbers as Go
+// literals, time.Duration as accepted by time.ParseDuration, and types
+// implementing encoding.TextUnmarshaler through it. Pointers to such types
+// are allocated. Fields that are not zero are left alone.
+//
+// A malformed tag is reported with the path of its field, and stops Apply.
+func Apply(ptr interface{}) error {
+    v := reflect.ValueOf(ptr)
+    if v.Kind() != reflect.Ptr || v.IsNil() {
+        return fmt.Errorf("defaults: Apply needs a non-nil pointer, got %T", ptr)
+    }
+    if err := apply(ptr); err != nil {
+        return fmt.Errorf("defaults: %w", err)
+    }
+    return nil
+}
+
+func apply(ptr interface{}) error {
+    return cloner.Walk(ptr, func(node cloner.Node) error {
+        v := node.Value
+        if v.Kind() == reflect.Map && !v.IsNil() {
+            // Map values cannot be modified in place
+            if err := applyEntries(v, string(node.Path)); err != nil {
+                return err
+            }
+            return cloner.SkipChildren
+        }
+        if !v.CanAddr() {
+            return nil
+        }
+        if v.Kind() == reflect.Struct {
+            if err := applyTags(v, string(node.Path)); err != nil {
+                return err
+            }
+        }
+        if fn, found := registered(v.Type()); found {
+            fn(v)
+        } else if v.Kind() != reflect.Interface && reflect.PointerTo(v.Type()).Implements(defaulterType) {
+            v.Addr().Interface().(Defaulter).SetDefaults()
+        }
+        return nil
+    })
+}
+
+// applyEntries applies the defaults of the values of the map m, at path, to
+// copies of them stored back into m.
+func applyEntries(m reflect.Value, path string) error {
+    iter := m.MapRange()
+    for iter.Next() {
+        value := reflect.New(m.Type().Elem())
+        value.Elem().Set(iter.Value())
+        if err := apply(value.Interface()); err != nil {
+            if pe, ok := err.(*pathError); ok {
+                pe.path = fmt.Sprintf("%s[%#v]%s", path, iter.Key().Interface(), pe.path)
+            }
+            return err
+        }
+        m.SetMapIndex(iter.Key(), value.Elem())
+    }
+    return nil
+}
+
+// pathError reports a malformed default tag of the field at path.
+type pathError struct {
+    path string
+    err  error
+}
+
+func (e *pathError) Error() string {
+    return e.path + ": " + e.err.Error()
+}
+
+func (e *pathError) Unwrap() error {
+    return e.err
+}
+
+// applyTags sets the zero fields of the struct v at path that have a
+// default tag.
+func applyTags(v reflect.Value, path string) error {
+    t := v.Type()
+    for i := 0; i < t.NumField(); i++ {
+        field := t.Field(i)
+        tag, ok := field.Tag.Lookup("default")
+        if !ok || !field.IsExported() || !v.Field(i).IsZero() {
+            continue
+        }
+        if err := set(v.Field(i), tag); err != nil {
+            return &pathError{path + "." + field.Name, fmt.Errorf("invalid default %q: %w", tag, err)}
+        }
+    }
+    return nil
+}
+
+// set sets the settable v to the value described by s.
+func set(v reflect.Value, s string) error {
+    if v.Kind() == reflect.Ptr {
+        p := reflect.New(v.Type().Elem())
+        if err := set(p.Elem(), s); err != nil {
+            return err
+        }
+        v.Set(p)
+        return nil
+    }
+    if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+        return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+    }
+    if v.Type() == durationType {
+        d, err := time.ParseDuration(s)
+        v.SetInt(int64(d))
+        return err
+    }
+
+    switch v.Kind() {
+    case reflect.String:
+        v.SetString(s)
+    case reflect.Bool:
+        b, err := strconv.ParseBool(s)
+        if err != nil {
+            return err
+        }
+        v.SetBool(b)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+        if err != nil {
+            return err
+        }
+        v.SetInt(n)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+        if err != nil {
+            return err
+        }
+        v.SetUint(n)
+    case reflect.Float32, reflect.Float64:
+        f, err := strconv.ParseFloat(s, v.Type().Bits())
+        if err != nil {
+            return err
+        }
+        v.SetFloat(f)
+    default:
+        return fmt.Errorf("%v fields cannot have defaults", v.Type())
+    }
+    return nil
+}
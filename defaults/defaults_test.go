@@ -0,0 +1,102 @@
+package defaults_test
+
+import (
+    "net"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/defaults"
+)
+
+type Server struct {
+    Addr     string        `default:":8080"`
+    Timeout  time.Duration `default:"30s"`
+    Retries  *int          `default:"3"`
+    Verbose  bool          `default:"true"`
+    IP       net.IP        `default:"127.0.0.1"`
+    Backends []Backend
+    Routes   map[string]Route
+    Limits   *Limits
+}
+
+type Backend struct {
+    Weight float64 `default:"1.5"`
+}
+
+type Route struct {
+    Method string `default:"GET"`
+}
+
+type Limits struct {
+    MaxConns int
+}
+
+func (l *Limits) SetDefaults() {
+    if l.MaxConns == 0 {
+        l.MaxConns = 100
+    }
+}
+
+type Pool struct {
+    Size int
+}
+
+func init() {
+    defaults.Register(func(p *Pool) {
+        if p.Size == 0 {
+            p.Size = 8
+        }
+    })
+}
+
+// Test for filling in defaults from tags and defaulters
+func TestApply(t *testing.T) {
+    server := Server{
+        Addr:     ":9090",
+        Backends: []Backend{{}, {Weight: 2}},
+        Routes:   map[string]Route{"/": {}},
+        Limits:   &Limits{},
+    }
+    if err := defaults.Apply(&server); err != nil {
+        t.Fatalf("Apply failed: %v", err)
+    }
+
+    if server.Addr != ":9090" || server.Timeout != 30*time.Second || *server.Retries != 3 || !server.Verbose {
+        t.Errorf("Apply did not fill in the fields of %+v", server)
+    }
+    if server.IP.String() != "127.0.0.1" {
+        t.Errorf("got IP %v, want 127.0.0.1", server.IP)
+    }
+    if server.Backends[0].Weight != 1.5 || server.Backends[1].Weight != 2 {
+        t.Errorf("Apply did not fill in the backends %+v", server.Backends)
+    }
+    if server.Routes["/"].Method != "GET" {
+        t.Errorf("Apply did not fill in the routes %+v", server.Routes)
+    }
+    if server.Limits.MaxConns != 100 {
+        t.Errorf("Apply did not call SetDefaults")
+    }
+
+    pools := []Pool{{}, {Size: 2}}
+    if err := defaults.Apply(&pools); err != nil || pools[0].Size != 8 || pools[1].Size != 2 {
+        t.Errorf("Apply returned %v, %+v, want the registered defaulter applied", err, pools)
+    }
+}
+
+// Test for malformed default tags
+func TestApplyErrors(t *testing.T) {
+    type Bad struct {
+        Count int `default:"many"`
+    }
+    type Config struct {
+        Items map[string]Bad
+    }
+    err := defaults.Apply(&Config{Items: map[string]Bad{"a": {}}})
+    if err == nil || !strings.Contains(err.Error(), `.Items["a"].Count: invalid default "many"`) {
+        t.Errorf("got error %v, want an invalid default at .Items[\"a\"].Count", err)
+    }
+    if err := defaults.Apply(Config{}); err == nil {
+        t.Errorf("Apply accepted a value that is not a pointer")
+    }
+}
@@ -0,0 +1,40 @@
+// Package find locates values in object graphs, for example every occurrence
+// of a credential or of a specific pointer before or after a clone.
+package find
+
+import "github.com/jayaprabhakar/go-deeper/cloner"
+
+// Match is a value found in a graph.
+type Match struct {
+    // Path is the path of the value from the root of the graph.
+    Path cloner.Path
+    // Value is the matching value.
+    Value interface{}
+    // Parent is the pointer, slice, array, map or struct holding Value, or
+    // nil for the root.
+    Parent interface{}
+}
+
+// All returns every value in v for which pred returns true, in the order of
+// cloner.Walk. Memory referenced more than once is searched once, but every
+// reference to it is passed to pred, so that all occurrences of a pointer
+// are found.
+func All(v interface{}, pred func(interface{}) bool) []Match {
+    var matches []Match
+    cloner.Walk(v, func(node cloner.Node) error {
+        if !node.Value.IsValid() || !node.Value.CanInterface() {
+            return nil
+        }
+        value := node.Value.Interface()
+        if !pred(value) {
+            return nil
+        }
+        match := Match{Path: node.Path, Value: value}
+        if node.Parent.IsValid() {
+            match.Parent = node.Parent.Interface()
+        }
+        matches = append(matches, match)
+        return nil
+    })
+    return matches
+}
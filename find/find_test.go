@@ -0,0 +1,66 @@
+package find_test
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/find"
+)
+
+type Credentials struct {
+    User     string
+    Password string
+}
+
+type Config struct {
+    Primary  *Credentials
+    Replicas []*Credentials
+    Env      map[string]string
+}
+
+// Test for finding values by predicate
+func TestAll(t *testing.T) {
+    creds := &Credentials{User: "admin", Password: "hunter2"}
+    config := Config{
+        Primary:  creds,
+        Replicas: []*Credentials{creds, {User: "ro", Password: "hunter2"}},
+        Env:      map[string]string{"DB_PASSWORD": "hunter2", "HOME": "/root"},
+    }
+
+    matches := find.All(config, func(v interface{}) bool {
+        return v == "hunter2"
+    })
+    var paths []string
+    for _, m := range matches {
+        paths = append(paths, string(m.Path))
+    }
+    want := `.Primary.Password .Replicas[1].Password .Env["DB_PASSWORD"]`
+    if got := strings.Join(paths, " "); got != want {
+        t.Errorf("got paths %s, want %s", got, want)
+    }
+    if parent, ok := matches[0].Parent.(Credentials); !ok || parent.User != "admin" {
+        t.Errorf("Parent of the first match is incorrect: got %#v", matches[0].Parent)
+    }
+
+    // Every occurrence of a pointer is found
+    matches = find.All(config, func(v interface{}) bool {
+        return v == creds
+    })
+    if len(matches) != 2 || matches[0].Path != ".Primary" || matches[1].Path != ".Replicas[0]" {
+        t.Errorf("got %+v, want matches at .Primary and .Replicas[0]", matches)
+    }
+
+    // The clone holds the same values at other pointers
+    clone := cloner.DeepCopy(config)
+    if matches := find.All(clone, func(v interface{}) bool { return v == creds }); len(matches) != 0 {
+        t.Errorf("Clone references the original credentials at %+v", matches)
+    }
+    if matches := find.All(clone, func(v interface{}) bool { return v == "hunter2" }); len(matches) != 3 {
+        t.Errorf("got %d matches in the clone, want 3", len(matches))
+    }
+
+    if matches := find.All(nil, func(interface{}) bool { return true }); len(matches) != 0 {
+        t.Errorf("got %+v, want no matches", matches)
+    }
+}
@@ -1,3 +1,13 @@
 module github.com/jayaprabhakar/go-deeper
 
 go 1.22.4
+
+require (
+	github.com/shopspring/decimal v1.4.0
+	golang.org/x/tools v0.30.0
+)
+
+require (
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+)
@@ -0,0 +1,104 @@
+// Package graph renders object graphs in the Graphviz DOT language, to show
+// what a deep clone copies and where memory is shared.
+package graph
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Dot renders the references in v as a DOT digraph. Every pointer, slice and
+// map target is a node labeled with its type, and the root is a node as
+// well. Edges go from a node to the targets it references, labeled with the
+// path of the reference relative to the node. References to a target that
+// was already reached through another path are dashed, and references
+// leading back to an ancestor, which form cycles, are red. The graph follows
+// the traversal of cloner.Walk, so it has the nodes a clone allocates.
+func Dot(v interface{}) string {
+    g := &dotGraph{ids: make(map[memref.Ref]int)}
+    g.b.WriteString("digraph {\n")
+    g.b.WriteString("    node [shape=box];\n")
+    cloner.Walk(v, g.visit)
+    g.b.WriteString("}\n")
+    return g.b.String()
+}
+
+// owner is a node whose contents are being walked.
+type owner struct {
+    path cloner.Path
+    id   int
+}
+
+type dotGraph struct {
+    b      strings.Builder
+    ids    map[memref.Ref]int
+    nodes  int
+    owners []owner // ancestors of the visited value that are nodes
+}
+
+func (g *dotGraph) visit(node cloner.Node) error {
+    // Leave the owners whose contents have all been visited
+    for len(g.owners) > 0 && !isWithin(node.Path, g.owners[len(g.owners)-1].path) {
+        g.owners = g.owners[:len(g.owners)-1]
+    }
+
+    v := node.Value
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        if v.IsNil() {
+            return nil
+        }
+    default:
+        if !node.Parent.IsValid() {
+            g.owners = append(g.owners, owner{node.Path, g.addNode(v.Type().String())})
+        }
+        return nil
+    }
+
+    ref := memref.Of(v)
+    id, seen := g.ids[ref]
+    if !seen {
+        id = g.addNode(v.Type().String())
+        g.ids[ref] = id
+    }
+    if len(g.owners) > 0 {
+        from := g.owners[len(g.owners)-1]
+        label := string(node.Path[len(from.path):])
+        if label == "" {
+            label = "*"
+        }
+        attrs := "label=" + strconv.Quote(label)
+        if node.Shared {
+            attrs += ", style=dashed"
+        }
+        if node.Cycle {
+            attrs += ", color=red"
+        }
+        fmt.Fprintf(&g.b, "    n%d -> n%d [%s];\n", from.id, id, attrs)
+    }
+    if !node.Shared {
+        g.owners = append(g.owners, owner{node.Path, id})
+    }
+    return nil
+}
+
+func (g *dotGraph) addNode(label string) int {
+    id := g.nodes
+    g.nodes++
+    fmt.Fprintf(&g.b, "    n%d [label=%s];\n", id, strconv.Quote(label))
+    return id
+}
+
+// isWithin reports whether path is base or a path below it.
+func isWithin(path, base cloner.Path) bool {
+    if !strings.HasPrefix(string(path), string(base)) {
+        return false
+    }
+    rest := path[len(base):]
+    return rest == "" || rest[0] == '.' || rest[0] == '['
+}
@@ -0,0 +1,67 @@
+package graph_test
+
+import (
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/graph"
+)
+
+type Node struct {
+    Name     string
+    Children []*Node
+    Parent   *Node
+    Meta     map[string]*int
+}
+
+// Test for rendering shared pointers and cycles
+func TestDot(t *testing.T) {
+    count := 1
+    root := &Node{Name: "root", Meta: map[string]*int{"count": &count}}
+    child := &Node{Name: "child", Parent: root}
+    root.Children = []*Node{child, child}
+
+    want := `digraph {
+    node [shape=box];
+    n0 [label="*graph_test.Node"];
+    n1 [label="[]*graph_test.Node"];
+    n0 -> n1 [label=".Children"];
+    n2 [label="*graph_test.Node"];
+    n1 -> n2 [label="[0]"];
+    n2 -> n0 [label=".Parent", style=dashed, color=red];
+    n1 -> n2 [label="[1]", style=dashed];
+    n3 [label="map[string]*int"];
+    n0 -> n3 [label=".Meta"];
+    n4 [label="*int"];
+    n3 -> n4 [label="[\"count\"]"];
+}
+`
+    if got := graph.Dot(root); got != want {
+        t.Errorf("got:\n%s\nwant:\n%s", got, want)
+    }
+}
+
+// Test for rendering values that are not references
+func TestDotValues(t *testing.T) {
+    value := 1
+    want := `digraph {
+    node [shape=box];
+    n0 [label="struct { A *int; B **int }"];
+    n1 [label="*int"];
+    n0 -> n1 [label=".A"];
+    n2 [label="**int"];
+    n0 -> n2 [label=".B"];
+    n2 -> n1 [label="*", style=dashed];
+}
+`
+    ptr := &value
+    if got := graph.Dot(struct {
+        A *int
+        B **int
+    }{ptr, &ptr}); got != want {
+        t.Errorf("got:\n%s\nwant:\n%s", got, want)
+    }
+
+    if got, want := graph.Dot(nil), "digraph {\n    node [shape=box];\n}\n"; got != want {
+        t.Errorf("got:\n%s\nwant:\n%s", got, want)
+    }
+}
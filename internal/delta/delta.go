@@ -0,0 +1,271 @@
+// Package delta encodes the changes between two versions of a graph, as
+// found by cloner.Diff, so that they can be saved and applied to a copy of
+// the first version to recover the second.
+//
+// Changes are written to a gob stream as their number and, for each
+// change, its header followed by the keys of the map entries on its path
+// and its value, each streamed with cloner.CloneTo. The types of keys and
+// values are not written: they are found by following the path in the
+// value the changes are applied to.
+package delta
+
+import (
+    "encoding/gob"
+    "errors"
+    "fmt"
+    "io"
+    "reflect"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// step is a step of the path of a change: a struct field if Field is not
+// empty, a map entry whose key is streamed if Key is true, or a slice or
+// array element.
+type step struct {
+    Field string
+    Key   bool
+    Index int
+}
+
+type changeHeader struct {
+    Steps []step
+    // Delete is true for map entries removed from the first version, which
+    // have no value.
+    Delete bool
+}
+
+// Change is a value that differs between two versions of a graph.
+type Change struct {
+    path  cloner.Path
+    steps []step
+    keys  []reflect.Value // keys of the map entries on the path
+    value reflect.Value   // new value, invalid for a removed map entry
+}
+
+// Changes returns the changes from a to b, compared with opts. Differences
+// in unexported fields are left out, since they cannot be streamed.
+func Changes[T any](a, b T, opts []cloner.EqualOption) ([]Change, error) {
+    var changes []Change
+    for _, d := range cloner.Diff(a, b, opts...) {
+        c, ok, err := resolve(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem(), d)
+        if err != nil {
+            return nil, err
+        }
+        if ok {
+            changes = append(changes, c)
+        }
+    }
+    return changes, nil
+}
+
+// resolve returns the change of d from a to b, and false if its path goes
+// through an unexported field.
+func resolve(a, b reflect.Value, d cloner.Difference) (Change, bool, error) {
+    c := Change{path: d.Path}
+    for _, s := range d.Steps {
+        if !b.IsValid() {
+            return c, false, fmt.Errorf("delta: no value at %s", d.Path)
+        }
+        a, b = indirect(a), indirect(b)
+        switch {
+        case s.Kind == cloner.FieldStep && b.Kind() == reflect.Struct:
+            field, ok := b.Type().FieldByName(s.Field)
+            if !ok || len(field.Index) > 1 {
+                return c, false, fmt.Errorf("delta: no field at %s", d.Path)
+            }
+            if !field.IsExported() {
+                return c, false, nil
+            }
+            c.steps = append(c.steps, step{Field: field.Name})
+            if a.Kind() == reflect.Struct {
+                a = a.Field(field.Index[0])
+            } else {
+                a = reflect.Value{}
+            }
+            b = b.Field(field.Index[0])
+        case s.Kind == cloner.KeyStep && b.Kind() == reflect.Map:
+            // Keys are streamed with the key type of the map
+            key := reflect.New(b.Type().Key()).Elem()
+            if s.Key != nil {
+                key.Set(reflect.ValueOf(s.Key))
+            }
+            c.steps = append(c.steps, step{Key: true})
+            c.keys = append(c.keys, key)
+            if a.Kind() == reflect.Map {
+                a = a.MapIndex(key)
+            } else {
+                a = reflect.Value{}
+            }
+            b = b.MapIndex(key)
+        case s.Kind == cloner.IndexStep && (b.Kind() == reflect.Slice || b.Kind() == reflect.Array):
+            if s.Index >= b.Len() {
+                return c, false, fmt.Errorf("delta: no element at %s", d.Path)
+            }
+            c.steps = append(c.steps, step{Index: s.Index})
+            if (a.Kind() == reflect.Slice || a.Kind() == reflect.Array) && s.Index < a.Len() {
+                a = a.Index(s.Index)
+            } else {
+                a = reflect.Value{}
+            }
+            b = b.Index(s.Index)
+        default:
+            return c, false, fmt.Errorf("delta: invalid path %s", d.Path)
+        }
+    }
+    c.value = b
+    return c, true, nil
+}
+
+// indirect returns the value v points to or holds, if any.
+func indirect(v reflect.Value) reflect.Value {
+    for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+        v = v.Elem()
+    }
+    return v
+}
+
+// Write writes changes to enc, streaming their values with cm.
+func Write(enc *gob.Encoder, cm *cloner.CloneManager, changes []Change) error {
+    if err := enc.Encode(len(changes)); err != nil {
+        return err
+    }
+    for _, ch := range changes {
+        if err := enc.Encode(changeHeader{Steps: ch.steps, Delete: !ch.value.IsValid()}); err != nil {
+            return err
+        }
+        for _, key := range ch.keys {
+            if err := write(enc, cm, key); err != nil {
+                return fmt.Errorf("delta: writing key of %s: %w", ch.path, err)
+            }
+        }
+        if ch.value.IsValid() {
+            if err := write(enc, cm, ch.value); err != nil {
+                return fmt.Errorf("delta: writing %s: %w", ch.path, err)
+            }
+        }
+    }
+    return nil
+}
+
+// write streams v to enc through a pointer, so that interface values are
+// written with their type.
+func write(enc *gob.Encoder, cm *cloner.CloneManager, v reflect.Value) error {
+    p := reflect.New(v.Type())
+    p.Elem().Set(v)
+    return cm.CloneTo(enc, p.Interface())
+}
+
+// read reads a value of type t written by write from dec.
+func read(dec *gob.Decoder, cm *cloner.CloneManager, t reflect.Type) (reflect.Value, error) {
+    p := reflect.New(reflect.PointerTo(t))
+    err := cm.CloneFrom(dec, p.Interface())
+    if err == io.EOF {
+        err = io.ErrUnexpectedEOF
+    }
+    if err != nil {
+        return reflect.Value{}, err
+    }
+    if p.Elem().IsNil() {
+        return reflect.Zero(t), nil
+    }
+    return p.Elem().Elem(), nil
+}
+
+// Apply reads the changes written by Write from dec, streaming their values
+// with cm, and applies them to the value dst points to.
+func Apply(dec *gob.Decoder, cm *cloner.CloneManager, dst interface{}) error {
+    var count int
+    if err := dec.Decode(&count); err != nil {
+        return err
+    }
+    root := reflect.ValueOf(dst).Elem()
+    for i := 0; i < count; i++ {
+        var header changeHeader
+        if err := dec.Decode(&header); err != nil {
+            return err
+        }
+        if err := apply(dec, cm, root, header.Steps, header.Delete); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// errNoValue is returned when the path of a change is missing from the
+// value it is applied to.
+var errNoValue = errors.New("delta: change to a missing value")
+
+// apply sets the value at the end of steps from v, which is settable, to
+// the value read from dec, or removes it if it is a map entry and remove is
+// true.
+func apply(dec *gob.Decoder, cm *cloner.CloneManager, v reflect.Value, steps []step, remove bool) error {
+    if len(steps) == 0 {
+        value, err := read(dec, cm, v.Type())
+        if err == nil {
+            v.Set(value)
+        }
+        return err
+    }
+    switch v.Kind() {
+    case reflect.Ptr:
+        if v.IsNil() {
+            return errNoValue
+        }
+        return apply(dec, cm, v.Elem(), steps, remove)
+    case reflect.Interface:
+        if v.IsNil() {
+            return errNoValue
+        }
+        // The values held by interfaces are changed in a copy
+        elem := reflect.New(v.Elem().Type()).Elem()
+        elem.Set(v.Elem())
+        if err := apply(dec, cm, elem, steps, remove); err != nil {
+            return err
+        }
+        v.Set(elem)
+        return nil
+    }
+
+    s := steps[0]
+    switch {
+    case s.Key:
+        if v.Kind() != reflect.Map || v.IsNil() {
+            return errNoValue
+        }
+        key, err := read(dec, cm, v.Type().Key())
+        if err != nil {
+            return err
+        }
+        elem := reflect.New(v.Type().Elem()).Elem()
+        if len(steps) == 1 {
+            if remove {
+                v.SetMapIndex(key, reflect.Value{})
+                return nil
+            }
+        } else if current := v.MapIndex(key); current.IsValid() {
+            elem.Set(current)
+        } else {
+            return errNoValue
+        }
+        if err := apply(dec, cm, elem, steps[1:], remove); err != nil {
+            return err
+        }
+        v.SetMapIndex(key, elem)
+        return nil
+    case s.Field != "":
+        if v.Kind() != reflect.Struct {
+            return errNoValue
+        }
+        field := v.FieldByName(s.Field)
+        if !field.IsValid() || !field.CanSet() {
+            return errNoValue
+        }
+        return apply(dec, cm, field, steps[1:], remove)
+    default:
+        if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || s.Index >= v.Len() {
+            return errNoValue
+        }
+        return apply(dec, cm, v.Index(s.Index), steps[1:], remove)
+    }
+}
@@ -0,0 +1,83 @@
+package delta_test
+
+import (
+    "bytes"
+    "encoding/gob"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/delta"
+)
+
+type Doc struct {
+    Title  string
+    Tags   map[string][]string
+    Parts  []*Doc
+    Pages  map[uint]string
+    Marks  map[Mark]int
+    hidden int
+}
+
+type Mark struct {
+    Line int
+    Note string
+}
+
+// Test for writing the changes between two versions and applying them
+func TestApply(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    a := Doc{
+        Title: "a",
+        Tags:  map[string][]string{"x": {"1"}, "y": nil},
+        Parts: []*Doc{{Title: "p"}},
+        // Keys whose literals are prefixes of one another
+        Pages: map[uint]string{1: "one", 10: "ten"},
+        Marks: map[Mark]int{{1, "a"}: 1, {1, "a]"}: 2},
+    }
+    b := cloner.MustClone(cm, a)
+    b.Title = "b"
+    b.Tags["x"][0] = "2"
+    delete(b.Tags, "y")
+    b.Parts[0].Tags = map[string][]string{"z": {}}
+    b.Pages[10] = "TEN"
+    b.Marks[Mark{1, "a]"}] = 3
+    b.hidden = 1
+
+    changes, err := delta.Changes(a, b, nil)
+    if err != nil || len(changes) != 6 {
+        t.Fatalf("Changes returned %d changes, %v, want 6", len(changes), err)
+    }
+    var buf bytes.Buffer
+    if err := delta.Write(gob.NewEncoder(&buf), cm, changes); err != nil {
+        t.Fatalf("Write failed: %v", err)
+    }
+    if err := delta.Apply(gob.NewDecoder(&buf), cm, &a); err != nil {
+        t.Fatalf("Apply failed: %v", err)
+    }
+    b.hidden = 0
+    if diffs := cloner.Diff(a, b); len(diffs) > 0 {
+        t.Errorf("got differences %v after applying the changes", diffs)
+    }
+}
+
+// Test for the changes of differences naming fields by tag
+func TestApplyFieldNames(t *testing.T) {
+    type Entry struct {
+        Name  string            `json:"name"`
+        Attrs map[string]string `json:"attrs"`
+    }
+    cm := cloner.NewCloneManager()
+    a := Entry{Name: "a", Attrs: map[string]string{"k": "v"}}
+    b := Entry{Name: "b", Attrs: map[string]string{"k": "w"}}
+    changes, err := delta.Changes(a, b, []cloner.EqualOption{cloner.EqualFieldNames("json")})
+    if err != nil || len(changes) != 2 {
+        t.Fatalf("Changes returned %d changes, %v, want 2", len(changes), err)
+    }
+    var buf bytes.Buffer
+    if err := delta.Write(gob.NewEncoder(&buf), cm, changes); err != nil {
+        t.Fatalf("Write failed: %v", err)
+    }
+    if err := delta.Apply(gob.NewDecoder(&buf), cm, &a); err != nil || !cloner.Equal(a, b) {
+        t.Errorf("Apply returned %v and %+v, want %+v", err, a, b)
+    }
+}
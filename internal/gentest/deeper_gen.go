@@ -0,0 +1,216 @@
+// Code generated by deeper-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"reflect"
+
+	"github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// RegisterGenerated registers the generated cloners of this package with cm,
+// so that annotated types are cloned without reflection wherever they occur,
+// including as struct fields, slice elements and map values.
+func RegisterGenerated(cm *cloner.CloneManager) {
+	cm.RegisterCloner(reflect.TypeOf((*Meta)(nil)), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+		return value.(*Meta).Clone(manager)
+	}))
+	cm.RegisterCloner(reflect.TypeOf(Meta{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+		t := value.(Meta)
+		var c Meta
+		if err := t.deeperCloneInto(&c, manager); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}))
+	cm.RegisterCloner(reflect.TypeOf((*Node)(nil)), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+		return value.(*Node).Clone(manager)
+	}))
+	cm.RegisterCloner(reflect.TypeOf(Node{}), cloner.ClonerFunc(func(value interface{}, manager *cloner.CloneManager) (interface{}, error) {
+		t := value.(Node)
+		var c Node
+		if err := t.deeperCloneInto(&c, manager); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}))
+}
+
+// Clone implements cloner.Cloneable for *Meta.
+func (t *Meta) Clone(manager *cloner.CloneManager) (interface{}, error) {
+	if manager == nil {
+		manager = cloner.NewCloneManager()
+	}
+	if t != nil && !manager.Cloning() {
+		// Start a clone, which calls back with a manager tracking the
+		// references met
+		return manager.Clone(t)
+	}
+	return t.deeperClone(manager)
+}
+
+// deeperClone returns the clone of t recorded by the clone in progress,
+// cloning t first if it has not been seen yet.
+func (t *Meta) deeperClone(manager *cloner.CloneManager) (*Meta, error) {
+	if t == nil {
+		return nil, nil
+	}
+	if c, ok := manager.Lookup(t); ok {
+		return c.(*Meta), nil
+	}
+	c := new(Meta)
+	manager.Record(t, c)
+	if err := t.deeperCloneInto(c, manager); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// deeperCloneInto sets every field of c to a deep clone of the same field of t.
+func (t *Meta) deeperCloneInto(c *Meta, manager *cloner.CloneManager) error {
+	if t.Owner != nil {
+		if seen2, ok := manager.Lookup(t.Owner); ok {
+			c.Owner = seen2.(*string)
+		} else {
+			p1 := new(string)
+			manager.Record(t.Owner, p1)
+			*p1 = (*t.Owner)
+			c.Owner = p1
+		}
+	}
+	if t.Labels != nil {
+		c.Labels = make([]string, len(t.Labels), cap(t.Labels))
+		copy(c.Labels, t.Labels)
+	}
+	c.revision = t.revision
+	return nil
+}
+
+// Clone implements cloner.Cloneable for *Node.
+func (t *Node) Clone(manager *cloner.CloneManager) (interface{}, error) {
+	if manager == nil {
+		manager = cloner.NewCloneManager()
+	}
+	if t != nil && !manager.Cloning() {
+		// Start a clone, which calls back with a manager tracking the
+		// references met
+		return manager.Clone(t)
+	}
+	return t.deeperClone(manager)
+}
+
+// deeperClone returns the clone of t recorded by the clone in progress,
+// cloning t first if it has not been seen yet.
+func (t *Node) deeperClone(manager *cloner.CloneManager) (*Node, error) {
+	if t == nil {
+		return nil, nil
+	}
+	if c, ok := manager.Lookup(t); ok {
+		return c.(*Node), nil
+	}
+	c := new(Node)
+	manager.Record(t, c)
+	if err := t.deeperCloneInto(c, manager); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// deeperCloneInto sets every field of c to a deep clone of the same field of t.
+func (t *Node) deeperCloneInto(c *Node, manager *cloner.CloneManager) error {
+	c.Name = t.Name
+	c.Color = t.Color
+	p1, err := t.Parent.deeperClone(manager)
+	if err != nil {
+		return err
+	}
+	c.Parent = p1
+	if t.Children != nil {
+		c.Children = make([]*Node, len(t.Children), cap(t.Children))
+		for i2 := range t.Children {
+			p3, err := t.Children[i2].deeperClone(manager)
+			if err != nil {
+				return err
+			}
+			c.Children[i2] = p3
+		}
+	}
+	p4, err := t.Next.deeperClone(manager)
+	if err != nil {
+		return err
+	}
+	c.Next = p4
+	p5, err := t.Prev.deeperClone(manager)
+	if err != nil {
+		return err
+	}
+	c.Prev = p5
+	if t.Attrs != nil {
+		c.Attrs = make(map[string]*Attr, len(t.Attrs))
+		for k6, v7 := range t.Attrs {
+			var cv8 *Attr
+			if v7 != nil {
+				if seen10, ok := manager.Lookup(v7); ok {
+					cv8 = seen10.(*Attr)
+				} else {
+					p9 := new(Attr)
+					manager.Record(v7, p9)
+					if v11, err := manager.Clone((*v7)); err != nil {
+						return err
+					} else if v11 != nil {
+						*p9 = v11.(Attr)
+					}
+					cv8 = p9
+				}
+			}
+			c.Attrs[k6] = cv8
+		}
+	}
+	if t.Tags != nil {
+		c.Tags = make(map[string][]string, len(t.Tags))
+		for k12, v13 := range t.Tags {
+			var cv14 []string
+			if v13 != nil {
+				cv14 = make([]string, len(v13), cap(v13))
+				copy(cv14, v13)
+			}
+			c.Tags[k12] = cv14
+		}
+	}
+	if err := t.Meta.deeperCloneInto(&c.Meta, manager); err != nil {
+		return err
+	}
+	for i16 := range t.Weights {
+		if t.Weights[i16] != nil {
+			if seen18, ok := manager.Lookup(t.Weights[i16]); ok {
+				c.Weights[i16] = seen18.(*float64)
+			} else {
+				p17 := new(float64)
+				manager.Record(t.Weights[i16], p17)
+				*p17 = (*t.Weights[i16])
+				c.Weights[i16] = p17
+			}
+		}
+	}
+	if t.IDs != nil {
+		c.IDs = make(IDs, len(t.IDs), cap(t.IDs))
+		copy(c.IDs, t.IDs)
+	}
+	c.Created = t.Created
+	if v20, err := manager.Clone(t.Payload); err != nil {
+		return err
+	} else if v20 != nil {
+		c.Payload = v20.(interface{})
+	}
+	if t.Item != nil {
+		if seen22, ok := manager.Lookup(t.Item); ok {
+			c.Item = seen22.(*Item)
+		} else {
+			p21 := new(Item)
+			manager.Record(t.Item, p21)
+			*p21 = (*t.Item)
+			c.Item = p21
+		}
+	}
+	return nil
+}
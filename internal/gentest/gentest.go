@@ -0,0 +1,66 @@
+// Package gentest holds types annotated for deeper-gen. The generated code is
+// checked in so that it is compiled and tested with the rest of the module.
+package gentest
+
+import (
+    "time"
+)
+
+//go:generate go run ../../cmd/deeper-gen
+
+// Color is a value type copied by assignment.
+type Color int
+
+// IDs is a named slice type.
+type IDs []int
+
+// Node is a doubly linked tree node.
+//
+//deeper:generate
+type Node struct {
+    Name     string
+    Color    Color
+    Parent   *Node
+    Children []*Node
+    Next     *Node
+    Prev     *Node
+    Attrs    map[string]*Attr
+    Tags     map[string][]string
+    Meta     Meta
+    Weights  [3]*float64
+    IDs      IDs
+    Created  time.Time
+    Payload  interface{}
+    Item     *Item
+}
+
+// Meta is cloned by generated code when embedded by value.
+//
+//deeper:generate
+type Meta struct {
+    Owner    *string
+    Labels   []string
+    revision int
+}
+
+// Revision returns the unexported revision, which only generated code copies.
+func (m Meta) Revision() int {
+    return m.revision
+}
+
+// SetRevision sets the unexported revision.
+func (m *Meta) SetRevision(revision int) {
+    m.revision = revision
+}
+
+// Attr is not annotated and is cloned through the CloneManager.
+type Attr struct {
+    Key   string
+    Value *int
+}
+
+// Item is a plain value type reached through a pointer.
+type Item struct {
+    ID    int
+    Price float64
+}
@@ -0,0 +1,195 @@
+package gentest_test
+
+import (
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/gentest"
+)
+
+func newTree() *gentest.Node {
+    owner := "ops"
+    weight := 0.5
+    value := 7
+    root := &gentest.Node{
+        Name:    "root",
+        Color:   2,
+        Attrs:   map[string]*gentest.Attr{"a": {Key: "a", Value: &value}},
+        Tags:    map[string][]string{"env": {"prod"}},
+        Meta:    gentest.Meta{Owner: &owner, Labels: []string{"x"}},
+        IDs:     gentest.IDs{1, 2, 3},
+        Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+        Payload: &gentest.Item{ID: 9},
+    }
+    left := &gentest.Node{Name: "left", Parent: root}
+    right := &gentest.Node{Name: "right", Parent: root, Prev: left}
+    left.Next = right
+    root.Children = []*gentest.Node{left, right}
+    root.Weights = [3]*float64{&weight, &weight, nil}
+    root.Item = &gentest.Item{ID: 1, Price: 2.5}
+    return root
+}
+
+// Test for cloning an annotated graph with cycles through the generated code
+func TestGeneratedClone(t *testing.T) {
+    original := newTree()
+
+    cloned, err := original.Clone(nil)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    root := cloned.(*gentest.Node)
+
+    if !reflect.DeepEqual(root, original) {
+        t.Errorf("got = %+v, want = %+v", root, original)
+    }
+    if root == original || root.Children[0] == original.Children[0] {
+        t.Fatalf("Clone did not create new nodes")
+    }
+
+    // Back-pointers and sibling links point into the clone
+    left, right := root.Children[0], root.Children[1]
+    if left.Parent != root || right.Parent != root {
+        t.Errorf("Cloned parent pointers do not point to the cloned root")
+    }
+    if left.Next != right || right.Prev != left {
+        t.Errorf("Cloned sibling links do not point to the cloned siblings")
+    }
+    if root.Weights[0] != root.Weights[1] {
+        t.Errorf("Cloned shared pointers do not point to the same value")
+    }
+
+    // Ensure modifying the original does not affect the clone
+    *original.Meta.Owner = "dev"
+    original.Tags["env"][0] = "test"
+    *original.Attrs["a"].Value = 0
+    original.IDs[0] = 100
+    original.Payload.(*gentest.Item).ID = 0
+    if *root.Meta.Owner != "ops" || root.Tags["env"][0] != "prod" || *root.Attrs["a"].Value != 7 ||
+        root.IDs[0] != 1 || root.Payload.(*gentest.Item).ID != 9 {
+        t.Errorf("Modifying the original affected the cloned node")
+    }
+}
+
+// Test for using generated Clone methods from the CloneManager
+func TestGeneratedCloneThroughManager(t *testing.T) {
+    cm := cloner.NewCloneManager()
+
+    node := newTree()
+    original := struct {
+        First  *gentest.Node
+        Second *gentest.Node
+    }{First: node, Second: node}
+
+    cloned, err := cm.Clone(original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    clonedStruct := cloned.(struct {
+        First  *gentest.Node
+        Second *gentest.Node
+    })
+
+    if clonedStruct.First == node {
+        t.Fatalf("Clone did not create a new node")
+    }
+    if clonedStruct.First != clonedStruct.Second {
+        t.Errorf("Cloned pointers First and Second do not point to the same node")
+    }
+    if !reflect.DeepEqual(clonedStruct.First, node) {
+        t.Errorf("got = %+v, want = %+v", clonedStruct.First, node)
+    }
+}
+
+// Test for cloning annotated values through the registered generated cloners
+func TestRegisterGenerated(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    gentest.RegisterGenerated(cm)
+
+    meta := gentest.Meta{Labels: []string{"a"}}
+    meta.SetRevision(3)
+    original := struct {
+        Metas []gentest.Meta
+    }{Metas: []gentest.Meta{meta}}
+
+    cloned, err := cm.Clone(original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    clonedStruct := cloned.(struct {
+        Metas []gentest.Meta
+    })
+
+    // Only the generated cloner copies unexported fields
+    if got := clonedStruct.Metas[0].Revision(); got != 3 {
+        t.Errorf("Cloned revision is incorrect: got %d, want 3", got)
+    }
+
+    // Ensure modifying the original does not affect the clone
+    original.Metas[0].Labels[0] = "b"
+    if clonedStruct.Metas[0].Labels[0] != "a" {
+        t.Errorf("Modifying the original affected the cloned meta")
+    }
+}
+
+// Test for sharing references between generated and reflection clones
+func TestGeneratedCloneSharing(t *testing.T) {
+    owner := "ops"
+    root := &gentest.Node{Name: "root", Meta: gentest.Meta{Owner: &owner}}
+    leaf := &gentest.Node{Name: "leaf", Parent: root}
+    root.Children = []*gentest.Node{leaf}
+    type graph struct {
+        Root, Leaf *gentest.Node
+        Owner      *string
+    }
+
+    cloned, err := cloner.NewCloneManager().Clone(graph{Root: root, Leaf: leaf, Owner: &owner})
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    c := cloned.(graph)
+    if c.Root == root || c.Root.Children[0] != c.Leaf {
+        t.Errorf("Cloned leaf is not the child of the cloned root")
+    }
+    if c.Leaf.Parent != c.Root {
+        t.Errorf("Cloned parent of the leaf is not the cloned root")
+    }
+    if c.Owner == &owner || c.Root.Meta.Owner != c.Owner {
+        t.Errorf("Cloned owner is not shared by the cloned root")
+    }
+}
+
+// Test for sharing references between values cloned by the registered
+// generated cloners
+func TestRegisterGeneratedSharing(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    gentest.RegisterGenerated(cm)
+
+    owner := "ops"
+    shared := &gentest.Node{Name: "shared"}
+    original := struct {
+        Left, Right gentest.Node
+        Metas       []gentest.Meta
+    }{
+        Left:  gentest.Node{Name: "left", Next: shared},
+        Right: gentest.Node{Name: "right", Prev: shared},
+        Metas: []gentest.Meta{{Owner: &owner}, {Owner: &owner}},
+    }
+
+    cloned, err := cm.Clone(original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    c := cloned.(struct {
+        Left, Right gentest.Node
+        Metas       []gentest.Meta
+    })
+    if c.Left.Next == shared || c.Left.Next != c.Right.Prev {
+        t.Errorf("Cloned siblings do not point to the same cloned node")
+    }
+    if c.Metas[0].Owner == &owner || c.Metas[0].Owner != c.Metas[1].Owner {
+        t.Errorf("Cloned metas do not share the cloned owner")
+    }
+}
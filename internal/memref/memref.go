@@ -0,0 +1,29 @@
+// Package memref identifies the memory referenced by pointers, slices and
+// maps, for the packages keeping track of the references of a graph.
+package memref
+
+import "reflect"
+
+// Ref identifies the memory referenced by a pointer, slice or map.
+// References of different types, or slices of different lengths, are
+// distinct even if they start at the same address, like a struct and its
+// first field or two empty slices.
+type Ref struct {
+    ptr uintptr
+    typ reflect.Type
+    len int
+}
+
+// Of returns the Ref of v, which must be a pointer, slice or map.
+func Of(v reflect.Value) Ref {
+    r := Ref{ptr: v.Pointer(), typ: v.Type()}
+    if v.Kind() == reflect.Slice {
+        r.len = v.Len()
+    }
+    return r
+}
+
+// Type returns the type of the pointer, slice or map of r.
+func (r Ref) Type() reflect.Type {
+    return r.typ
+}
@@ -0,0 +1,33 @@
+package memref_test
+
+import (
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Test for telling apart references starting at the same address
+func TestOf(t *testing.T) {
+    type pair struct{ A, B int }
+    p := &pair{}
+    if memref.Of(reflect.ValueOf(p)) == memref.Of(reflect.ValueOf(&p.A)) {
+        t.Error("a struct and its first field have the same Ref")
+    }
+    if memref.Of(reflect.ValueOf(p)) != memref.Of(reflect.ValueOf(p)) {
+        t.Error("a pointer has different Refs")
+    }
+
+    s := []int{1, 2, 3}
+    if memref.Of(reflect.ValueOf(s)) == memref.Of(reflect.ValueOf(s[:2])) {
+        t.Error("slices of different lengths have the same Ref")
+    }
+    if memref.Of(reflect.ValueOf(s[:2])) != memref.Of(reflect.ValueOf(s[:2:2])) {
+        t.Error("slices of the same array and length have different Refs")
+    }
+
+    m := map[string]int{}
+    if got := memref.Of(reflect.ValueOf(m)).Type(); got != reflect.TypeOf(m) {
+        t.Errorf("got type %v, want %v", got, reflect.TypeOf(m))
+    }
+}
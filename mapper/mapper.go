@@ -0,0 +1,460 @@
+// Package mapper copies values between types of the same shape, such as a
+// DTO and the domain struct it is decoded into. Struct fields of the
+// destination are matched with the fields of the source by name, and the
+// values are converted along the way: pointers are followed or allocated,
+// slices, arrays and maps are copied element by element, and numbers are
+// converted to the type of the destination, as long as it holds them
+// exactly; see WithLossyConversions.
+//
+//	var user User
+//	err := mapper.Map(&user, dto, mapper.WithMatching(mapper.IgnoreCaseAndUnderscores))
+//
+// Values whose type is assignable to the destination are deep cloned with
+// the default manager of cloner, so that the destination shares nothing
+// with the source.
+package mapper
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "reflect"
+    "strings"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+var (
+    // ErrUnmatchedField is reported by strict mappings for a field of the
+    // destination that no field of the source matches.
+    ErrUnmatchedField = errors.New("mapper: unmatched field")
+
+    // ErrAmbiguousField is reported for a field of the destination that
+    // several fields of the source match, none of them by its exact name.
+    ErrAmbiguousField = errors.New("mapper: ambiguous field")
+
+    // ErrIncompatibleTypes is reported for a value of the source that
+    // cannot be converted to the type of the destination.
+    ErrIncompatibleTypes = errors.New("mapper: incompatible types")
+
+    // ErrLossyConversion is reported for a number that the type of the
+    // destination cannot hold exactly, unless WithLossyConversions allows
+    // it.
+    ErrLossyConversion = errors.New("mapper: lossy conversion")
+)
+
+// MapError is an error mapping the value at Path in the destination.
+type MapError struct {
+    Path cloner.Path
+    Err  error
+}
+
+func (e *MapError) Error() string {
+    if e.Path == "" {
+        return e.Err.Error()
+    }
+    return string(e.Path) + ": " + e.Err.Error()
+}
+
+func (e *MapError) Unwrap() error {
+    return e.Err
+}
+
+// Matching is how the fields of the destination are matched with the fields
+// of the source by name.
+type Matching int
+
+const (
+    // ExactNames matches fields with the same name, the default.
+    ExactNames Matching = iota
+    // IgnoreCase matches fields whose names differ in case only, e.g. Url
+    // and URL.
+    IgnoreCase
+    // IgnoreCaseAndUnderscores also ignores underscores, bridging snake_case
+    // and CamelCase, e.g. User_id and UserID.
+    IgnoreCaseAndUnderscores
+)
+
+// key returns the form of name that m compares.
+func (m Matching) key(name string) string {
+    switch m {
+    case IgnoreCase:
+        return strings.ToLower(name)
+    case IgnoreCaseAndUnderscores:
+        return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+    }
+    return name
+}
+
+// Policy is how numeric conversions that lose information are made.
+type Policy int
+
+const (
+    // Error reports the conversion as ErrLossyConversion, the default.
+    Error Policy = iota
+    // Saturate converts to the closest value the destination holds:
+    // numbers out of its range to its bounds, floats to integers truncated
+    // toward zero and NaN to zero.
+    Saturate
+    // Allow converts as Go conversions do, wrapping integers around.
+    Allow
+)
+
+// Option configures Map.
+type Option func(*options)
+
+type options struct {
+    matching Matching
+    strict   bool
+    lossy    Policy
+}
+
+// WithMatching sets how fields are matched by name. A field whose exact name
+// is in the source is always matched with it.
+func WithMatching(m Matching) Option {
+    return func(o *options) {
+        o.matching = m
+    }
+}
+
+// WithStrict makes Map report the exported fields of the destination that
+// no field of the source matches as ErrUnmatchedField, instead of leaving
+// them as they are, to catch the data lost between types that drifted
+// apart. Fields of the source without a match are always ignored.
+func WithStrict() Option {
+    return func(o *options) {
+        o.strict = true
+    }
+}
+
+// WithLossyConversions sets the policy for numeric conversions that lose
+// information: integers out of the range of the destination, including
+// negative integers converted to unsigned ones, floats with a fraction or
+// out of range converted to integers, integers that a float destination
+// cannot hold exactly, and floats out of the range of float32. Rounding a
+// float64 to the precision of a float32 is not considered lossy.
+func WithLossyConversions(p Policy) Option {
+    return func(o *options) {
+        o.lossy = p
+    }
+}
+
+// Map copies src into the value dst points to, converting it to the type of
+// dst. Only exported struct fields are mapped; fields of the destination
+// without a match are left as they are. Pointers to the same memory of src
+// are mapped once, so that cycles and sharing are preserved.
+//
+// A value that cannot be mapped is reported as a *MapError for its path in
+// dst; dst is left unchanged then.
+func Map(dst, src interface{}, opts ...Option) error {
+    d := reflect.ValueOf(dst)
+    if d.Kind() != reflect.Ptr || d.IsNil() {
+        return fmt.Errorf("mapper: Map needs a non-nil pointer, got %T", dst)
+    }
+    m := &mapper{seen: make(map[seenKey]reflect.Value)}
+    for _, opt := range opts {
+        opt(&m.options)
+    }
+    // The value is mapped into a copy, stored only once every field is
+    // mapped
+    result := reflect.New(d.Type().Elem()).Elem()
+    result.Set(d.Elem())
+    if err := m.mapValue(result, reflect.ValueOf(src), ""); err != nil {
+        return err
+    }
+    d.Elem().Set(result)
+    return nil
+}
+
+// seenKey identifies a pointer of the source mapped to a pointer type of
+// the destination.
+type seenKey struct {
+    src memref.Ref
+    dst reflect.Type
+}
+
+type mapper struct {
+    options
+    seen map[seenKey]reflect.Value
+}
+
+// mapValue sets the settable dst, at path, to src converted to its type.
+func (m *mapper) mapValue(dst, src reflect.Value, path cloner.Path) error {
+    if src.Kind() == reflect.Interface {
+        src = src.Elem()
+    }
+    if !src.IsValid() {
+        dst.Set(reflect.Zero(dst.Type()))
+        return nil
+    }
+    if src.Type().AssignableTo(dst.Type()) {
+        cloned, err := cloner.Default().Clone(src.Interface())
+        if err != nil {
+            return &MapError{Path: path, Err: err}
+        }
+        dst.Set(reflect.ValueOf(cloned))
+        return nil
+    }
+    if src.Kind() == reflect.Ptr {
+        if src.IsNil() {
+            dst.Set(reflect.Zero(dst.Type()))
+            return nil
+        }
+        if dst.Kind() != reflect.Ptr {
+            return m.mapValue(dst, src.Elem(), path)
+        }
+        key := seenKey{memref.Of(src), dst.Type()}
+        if p, ok := m.seen[key]; ok {
+            dst.Set(p)
+            return nil
+        }
+        p := reflect.New(dst.Type().Elem())
+        m.seen[key] = p
+        dst.Set(p)
+        return m.mapValue(p.Elem(), src.Elem(), path)
+    }
+    if dst.Kind() == reflect.Ptr {
+        p := reflect.New(dst.Type().Elem())
+        if err := m.mapValue(p.Elem(), src, path); err != nil {
+            return err
+        }
+        dst.Set(p)
+        return nil
+    }
+
+    switch {
+    case dst.Kind() == reflect.Bool && src.Kind() == reflect.Bool:
+        dst.SetBool(src.Bool())
+    case dst.Kind() == reflect.String && src.Kind() == reflect.String:
+        dst.SetString(src.String())
+    case isNumber(dst.Kind()) && isNumber(src.Kind()),
+        isComplex(dst.Kind()) && isComplex(src.Kind()):
+        return m.convertNumber(dst, src, path)
+    case dst.Kind() == reflect.Struct && src.Kind() == reflect.Struct:
+        return m.mapStruct(dst, src, path)
+    case dst.Kind() == reflect.Slice && (src.Kind() == reflect.Slice || src.Kind() == reflect.Array):
+        if src.Kind() == reflect.Slice && src.IsNil() {
+            dst.Set(reflect.Zero(dst.Type()))
+            return nil
+        }
+        elems := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+        if err := m.mapElems(elems, src, path); err != nil {
+            return err
+        }
+        dst.Set(elems)
+    case dst.Kind() == reflect.Array && (src.Kind() == reflect.Slice || src.Kind() == reflect.Array) && src.Len() == dst.Len():
+        return m.mapElems(dst, src, path)
+    case dst.Kind() == reflect.Map && src.Kind() == reflect.Map:
+        return m.mapEntries(dst, src, path)
+    default:
+        return &MapError{Path: path, Err: fmt.Errorf("%w: %v to %v", ErrIncompatibleTypes, src.Type(), dst.Type())}
+    }
+    return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+    return reflect.Int <= k && k <= reflect.Float64
+}
+
+func isInt(k reflect.Kind) bool {
+    return reflect.Int <= k && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+    return reflect.Uint <= k && k <= reflect.Uintptr
+}
+
+func isComplex(k reflect.Kind) bool {
+    return k == reflect.Complex64 || k == reflect.Complex128
+}
+
+// convertNumber sets the number dst, at path, to the number src, applying
+// the policy for lossy conversions.
+func (m *mapper) convertNumber(dst, src reflect.Value, path cloner.Path) error {
+    converted := src.Convert(dst.Type())
+    if m.lossy != Allow && !isComplex(src.Kind()) {
+        if saturated, exact := saturate(src, dst.Type()); !exact {
+            if m.lossy == Error {
+                return &MapError{Path: path, Err: fmt.Errorf("%w: %v does not fit in %v", ErrLossyConversion, src, dst.Type())}
+            }
+            converted = saturated
+        }
+    }
+    dst.Set(converted)
+    return nil
+}
+
+// saturate returns the value of type t, a number type, closest to the
+// number src, and reports whether it equals src.
+func saturate(src reflect.Value, t reflect.Type) (reflect.Value, bool) {
+    v := reflect.New(t).Elem()
+    switch {
+    case isInt(t.Kind()):
+        lo := int64(-1) << (t.Bits() - 1)
+        hi := -(lo + 1)
+        switch {
+        case isInt(src.Kind()):
+            n := src.Int()
+            switch {
+            case n < lo:
+                v.SetInt(lo)
+            case n > hi:
+                v.SetInt(hi)
+            default:
+                v.SetInt(n)
+                return v, true
+            }
+        case isUint(src.Kind()):
+            if u := src.Uint(); u <= uint64(hi) {
+                v.SetInt(int64(u))
+                return v, true
+            }
+            v.SetInt(hi)
+        default:
+            f := src.Float()
+            switch whole := math.Trunc(f); {
+            case math.IsNaN(f):
+            case whole < float64(lo):
+                v.SetInt(lo)
+            case whole >= -float64(lo):
+                v.SetInt(hi)
+            default:
+                v.SetInt(int64(whole))
+                return v, whole == f
+            }
+        }
+        return v, false
+
+    case isUint(t.Kind()):
+        hi := uint64(math.MaxUint64) >> (64 - t.Bits())
+        switch {
+        case isInt(src.Kind()):
+            n := src.Int()
+            switch {
+            case n < 0:
+            case uint64(n) > hi:
+                v.SetUint(hi)
+            default:
+                v.SetUint(uint64(n))
+                return v, true
+            }
+        case isUint(src.Kind()):
+            if u := src.Uint(); u <= hi {
+                v.SetUint(u)
+                return v, true
+            }
+            v.SetUint(hi)
+        default:
+            f := src.Float()
+            switch whole := math.Trunc(f); {
+            case math.IsNaN(f), whole < 0:
+            case whole >= float64(hi)+1:
+                v.SetUint(hi)
+            default:
+                v.SetUint(uint64(whole))
+                return v, whole == f
+            }
+        }
+        return v, false
+    }
+
+    // Floats lose precision, but only integers are checked to survive it
+    switch {
+    case isInt(src.Kind()):
+        n := src.Int()
+        v.SetFloat(float64(n))
+        f := v.Float()
+        return v, f >= math.MinInt64 && f < -math.MinInt64 && int64(f) == n
+    case isUint(src.Kind()):
+        u := src.Uint()
+        v.SetFloat(float64(u))
+        f := v.Float()
+        return v, f < math.MaxUint64 && uint64(f) == u
+    }
+    f := src.Float()
+    if t.Bits() == 32 && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+        v.SetFloat(math.Copysign(math.MaxFloat32, f))
+        return v, false
+    }
+    v.SetFloat(f)
+    return v, true
+}
+
+// mapElems maps the elements of the slice or array src, at path, to the
+// elements of dst, which has as many.
+func (m *mapper) mapElems(dst, src reflect.Value, path cloner.Path) error {
+    for i := 0; i < src.Len(); i++ {
+        if err := m.mapValue(dst.Index(i), src.Index(i), path+cloner.MakePath(cloner.Index(i))); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// mapEntries sets dst, at path, to a map holding the entries of the map
+// src, with their keys and values mapped.
+func (m *mapper) mapEntries(dst, src reflect.Value, path cloner.Path) error {
+    if src.IsNil() {
+        dst.Set(reflect.Zero(dst.Type()))
+        return nil
+    }
+    entries := reflect.MakeMapWithSize(dst.Type(), src.Len())
+    iter := src.MapRange()
+    for iter.Next() {
+        entryPath := path + cloner.MakePath(cloner.Key(iter.Key().Interface()))
+        key := reflect.New(dst.Type().Key()).Elem()
+        if err := m.mapValue(key, iter.Key(), entryPath); err != nil {
+            return err
+        }
+        value := reflect.New(dst.Type().Elem()).Elem()
+        if err := m.mapValue(value, iter.Value(), entryPath); err != nil {
+            return err
+        }
+        entries.SetMapIndex(key, value)
+    }
+    dst.Set(entries)
+    return nil
+}
+
+// mapStruct maps the exported fields of the struct src, at path, to the
+// fields of dst they match.
+func (m *mapper) mapStruct(dst, src reflect.Value, path cloner.Path) error {
+    srcType := src.Type()
+    exact := make(map[string]int, srcType.NumField())
+    matching := make(map[string][]int, srcType.NumField())
+    for i := 0; i < srcType.NumField(); i++ {
+        if field := srcType.Field(i); field.IsExported() {
+            exact[field.Name] = i
+            key := m.matching.key(field.Name)
+            matching[key] = append(matching[key], i)
+        }
+    }
+
+    dstType := dst.Type()
+    for i := 0; i < dstType.NumField(); i++ {
+        field := dstType.Field(i)
+        if !field.IsExported() {
+            continue
+        }
+        fieldPath := path + cloner.MakePath(cloner.Field(field.Name))
+        j, ok := exact[field.Name]
+        if !ok {
+            candidates := matching[m.matching.key(field.Name)]
+            switch {
+            case len(candidates) > 1:
+                return &MapError{Path: fieldPath, Err: fmt.Errorf("%w: matched by %d fields of %v", ErrAmbiguousField, len(candidates), srcType)}
+            case len(candidates) == 1:
+                j, ok = candidates[0], true
+            case m.strict:
+                return &MapError{Path: fieldPath, Err: fmt.Errorf("%w: no field of %v matches", ErrUnmatchedField, srcType)}
+            }
+        }
+        if ok {
+            if err := m.mapValue(dst.Field(i), src.Field(j), fieldPath); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,242 @@
+package mapper_test
+
+import (
+    "errors"
+    "math"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/mapper"
+)
+
+// Test for mapping values between types of the same shape
+func TestMap(t *testing.T) {
+    type addressDTO struct {
+        City string
+        Zip  *string
+    }
+    type userDTO struct {
+        Name      string
+        Age       int64
+        Tags      []string
+        Addresses map[string]addressDTO
+        Joined    time.Time
+        Extra     bool
+    }
+    type address struct {
+        City string
+        Zip  string
+    }
+    type user struct {
+        Name      string
+        Age       int
+        Tags      [2]string
+        Addresses map[string]*address
+        Joined    time.Time
+        Notes     string
+    }
+    zip := "75001"
+    joined := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    dto := &userDTO{
+        Name:      "ada",
+        Age:       36,
+        Tags:      []string{"admin", "ops"},
+        Addresses: map[string]addressDTO{"home": {City: "Paris", Zip: &zip}, "work": {City: "Lyon"}},
+        Joined:    joined,
+        Extra:     true,
+    }
+    u := user{Notes: "kept"}
+    if err := mapper.Map(&u, dto); err != nil {
+        t.Fatalf("Map failed: %v", err)
+    }
+    want := user{
+        Name:      "ada",
+        Age:       36,
+        Tags:      [2]string{"admin", "ops"},
+        Addresses: map[string]*address{"home": {City: "Paris", Zip: "75001"}, "work": {City: "Lyon"}},
+        Joined:    joined,
+        Notes:     "kept",
+    }
+    if !reflect.DeepEqual(u, want) {
+        t.Errorf("got %+v, want %+v", u, want)
+    }
+
+    var copied userDTO
+    if err := mapper.Map(&copied, dto); err != nil {
+        t.Fatalf("Map failed: %v", err)
+    }
+    if !reflect.DeepEqual(copied, *dto) || &copied.Tags[0] == &dto.Tags[0] || copied.Addresses["home"].Zip == &zip {
+        t.Errorf("got %+v, want a deep copy of %+v", copied, *dto)
+    }
+
+    if err := mapper.Map(u, dto); err == nil {
+        t.Errorf("Map accepted a destination that is not a pointer")
+    }
+}
+
+// Test for matching fields regardless of case and underscores
+func TestWithMatching(t *testing.T) {
+    type row struct {
+        User_id      int
+        Display_name string
+        Url          string
+    }
+    type account struct {
+        UserID      int
+        DisplayName string
+        URL         string
+    }
+    r := row{User_id: 7, Display_name: "Ada", Url: "https://example.com"}
+
+    var exact account
+    if err := mapper.Map(&exact, r); err != nil || exact != (account{}) {
+        t.Errorf("got %+v and error %v, want no field matched by exact names", exact, err)
+    }
+    var folded account
+    if err := mapper.Map(&folded, r, mapper.WithMatching(mapper.IgnoreCase)); err != nil || folded != (account{URL: r.Url}) {
+        t.Errorf("got %+v and error %v, want the URL matched regardless of case", folded, err)
+    }
+    var bridged account
+    if err := mapper.Map(&bridged, r, mapper.WithMatching(mapper.IgnoreCaseAndUnderscores)); err != nil ||
+        bridged != (account{UserID: 7, DisplayName: "Ada", URL: r.Url}) {
+        t.Errorf("got %+v and error %v, want every field matched", bridged, err)
+    }
+
+    // An exact name wins over other matches; otherwise they are ambiguous
+    type ids struct {
+        Id, ID, I_d int
+    }
+    var id struct{ ID int }
+    if err := mapper.Map(&id, ids{1, 2, 3}, mapper.WithMatching(mapper.IgnoreCaseAndUnderscores)); err != nil || id.ID != 2 {
+        t.Errorf("got %+v and error %v, want the exact name matched", id, err)
+    }
+    var other struct{ Ident, Id_ int }
+    err := mapper.Map(&other, ids{1, 2, 3}, mapper.WithMatching(mapper.IgnoreCaseAndUnderscores))
+    var mapErr *mapper.MapError
+    if !errors.Is(err, mapper.ErrAmbiguousField) || !errors.As(err, &mapErr) || mapErr.Path != ".Id_" {
+        t.Errorf("got error %v, want the field Id_ reported as ambiguous", err)
+    }
+}
+
+// Test for reporting the fields of the destination without a match
+func TestWithStrict(t *testing.T) {
+    type itemDTO struct {
+        SKU string
+    }
+    type item struct {
+        SKU   string
+        Price int
+    }
+    type order struct {
+        Items []item
+    }
+    dto := struct{ Items []itemDTO }{Items: []itemDTO{{SKU: "a"}}}
+    o := order{Items: []item{{SKU: "old"}}}
+    err := mapper.Map(&o, dto, mapper.WithStrict())
+    var mapErr *mapper.MapError
+    if !errors.Is(err, mapper.ErrUnmatchedField) || !errors.As(err, &mapErr) || mapErr.Path != cloner.Path(".Items[0].Price") {
+        t.Errorf("got error %v, want .Items[0].Price reported as unmatched", err)
+    }
+    if o.Items[0].SKU != "old" {
+        t.Errorf("got order %+v, want it unchanged after an error", o)
+    }
+    if err := mapper.Map(&o, dto); err != nil || o.Items[0] != (item{SKU: "a"}) {
+        t.Errorf("got order %+v and error %v, want the price left zero", o, err)
+    }
+}
+
+// Test for mapping cycles and shared pointers
+func TestMapCycles(t *testing.T) {
+    type nodeDTO struct {
+        Name string
+        Next *nodeDTO
+    }
+    type node struct {
+        Name string
+        Next *node
+    }
+    a := &nodeDTO{Name: "a"}
+    a.Next = &nodeDTO{Name: "b", Next: a}
+    var pair struct{ First, Ring *node }
+    if err := mapper.Map(&pair, struct{ First, Ring *nodeDTO }{a, a}); err != nil {
+        t.Fatalf("Map failed: %v", err)
+    }
+    if pair.First != pair.Ring || pair.First.Next.Name != "b" || pair.First.Next.Next != pair.First {
+        t.Errorf("got %+v, want the ring mapped once", pair)
+    }
+
+    var ch struct{ C int }
+    err := mapper.Map(&ch, struct{ C chan int }{})
+    if !errors.Is(err, mapper.ErrIncompatibleTypes) {
+        t.Errorf("got error %v, want ErrIncompatibleTypes", err)
+    }
+}
+
+// Test for the policies of numeric conversions that lose information
+func TestWithLossyConversions(t *testing.T) {
+    type sample struct {
+        Count  int64
+        Delta  int
+        Ratio  float64
+        Big    uint64
+        Level  float64
+        Offset int64
+    }
+    type compact struct {
+        Count  int32
+        Delta  uint8
+        Ratio  int
+        Big    float64
+        Level  float32
+        Offset int8
+    }
+    exact := sample{Count: 7, Delta: 200, Ratio: 3, Big: 1 << 60, Level: 0.1, Offset: -128}
+    var c compact
+    if err := mapper.Map(&c, exact); err != nil || c != (compact{7, 200, 3, 1 << 60, 0.1, -128}) {
+        t.Errorf("got %+v and error %v, want every number converted", c, err)
+    }
+
+    lossy := []struct {
+        src  sample
+        path cloner.Path
+    }{
+        {sample{Count: 1 << 40}, ".Count"},
+        {sample{Delta: -1}, ".Delta"},
+        {sample{Delta: 256}, ".Delta"},
+        {sample{Ratio: 2.5}, ".Ratio"},
+        {sample{Ratio: math.NaN()}, ".Ratio"},
+        {sample{Big: 1<<60 + 1}, ".Big"},
+        {sample{Level: 1e300}, ".Level"},
+        {sample{Offset: -129}, ".Offset"},
+    }
+    for _, test := range lossy {
+        before := compact{Count: 1}
+        c := before
+        err := mapper.Map(&c, test.src)
+        var mapErr *mapper.MapError
+        if !errors.Is(err, mapper.ErrLossyConversion) || !errors.As(err, &mapErr) || mapErr.Path != test.path || c != before {
+            t.Errorf("got error %v mapping %+v, want a lossy conversion at %s", err, test.src, test.path)
+        }
+    }
+
+    var saturated compact
+    if err := mapper.Map(&saturated, sample{Count: -1 << 40, Delta: -5, Ratio: -2.7, Big: 1<<60 + 1, Level: -1e300, Offset: 1000},
+        mapper.WithLossyConversions(mapper.Saturate)); err != nil {
+        t.Fatalf("Map failed: %v", err)
+    }
+    if saturated != (compact{math.MinInt32, 0, -2, 1 << 60, -math.MaxFloat32, math.MaxInt8}) {
+        t.Errorf("got %+v, want the numbers saturated", saturated)
+    }
+    var nan struct{ Ratio int }
+    if err := mapper.Map(&nan, struct{ Ratio float64 }{math.NaN()}, mapper.WithLossyConversions(mapper.Saturate)); err != nil || nan.Ratio != 0 {
+        t.Errorf("got %+v and error %v, want NaN saturated to zero", nan, err)
+    }
+
+    var wrapped compact
+    if err := mapper.Map(&wrapped, sample{Count: 1<<32 + 5, Delta: 257, Ratio: 2.5, Offset: 129}, mapper.WithLossyConversions(mapper.Allow)); err != nil ||
+        wrapped != (compact{Count: 5, Delta: 1, Ratio: 2, Offset: -127}) {
+        t.Errorf("got %+v and error %v, want the numbers converted as in Go", wrapped, err)
+    }
+}
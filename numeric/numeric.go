@@ -0,0 +1,92 @@
+// Package numeric makes arbitrary-precision numbers, decimals and money
+// types clone, compare and hash by value. Their unexported fields would
+// otherwise be left out of clones, and equal values, such as 1/2 and 2/4 or
+// floats of different precisions, would compare and hash differently.
+//
+// Importing the package registers *big.Int, *big.Rat and *big.Float with the
+// default registry of cloner, its comparers and the hashers of deephash:
+//
+//	import _ "github.com/jayaprabhakar/go-deeper/numeric"
+//
+// Other decimal and money types are registered with Register. Building with
+// the deeper_shopspring tag also registers the decimal.Decimal and
+// decimal.NullDecimal types of github.com/shopspring/decimal.
+package numeric
+
+import (
+    "hash"
+    "math/big"
+    "reflect"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/deephash"
+)
+
+func init() {
+    Register(func(x *big.Int) *big.Int {
+        return new(big.Int).Set(x)
+    }, func(a, b *big.Int) bool {
+        return a.Cmp(b) == 0
+    }, func(x *big.Int) string {
+        return x.String()
+    })
+    Register(func(x *big.Rat) *big.Rat {
+        return new(big.Rat).Set(x)
+    }, func(a, b *big.Rat) bool {
+        return a.Cmp(b) == 0
+    }, func(x *big.Rat) string {
+        return x.RatString()
+    })
+    Register(func(x *big.Float) *big.Float {
+        return new(big.Float).Copy(x)
+    }, func(a, b *big.Float) bool {
+        return a.Cmp(b) == 0
+    }, floatKey)
+}
+
+// Register registers how values of T, a decimal or money type compared by
+// value, are cloned, compared and hashed: clone returns an independent copy
+// of a value, equal reports whether two values are equal and key returns
+// the canonical form of a value, such as "EUR 1/2" for a money type, equal
+// for equal values. Pointer types T are only passed non-nil pointers; nil
+// pointers are cloned, compared and hashed as such.
+//
+// Registering a type again replaces the previous functions.
+func Register[T any](clone func(T) T, equal func(a, b T) bool, key func(T) string) {
+    cloner.Register(func(src T, _ *cloner.CloneManager) (T, error) {
+        if isNil(src) {
+            return src, nil
+        }
+        return clone(src), nil
+    })
+    cloner.RegisterComparer(func(a, b T) bool {
+        if isNil(a) || isNil(b) {
+            return isNil(a) && isNil(b)
+        }
+        return equal(a, b)
+    })
+    deephash.RegisterHasher(func(v T, h hash.Hash64) {
+        if isNil(v) {
+            h.Write([]byte{0})
+            return
+        }
+        h.Write([]byte{1})
+        h.Write([]byte(key(v)))
+    })
+}
+
+// isNil reports whether v is nil or a nil pointer.
+func isNil(v interface{}) bool {
+    rv := reflect.ValueOf(v)
+    return !rv.IsValid() || rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// floatKey returns the exact value of x as a fraction, so that equal floats
+// of different precisions have the same key.
+func floatKey(x *big.Float) string {
+    if x.IsInf() {
+        return x.String()
+    }
+    r, _ := x.Rat(nil)
+    return r.RatString()
+}
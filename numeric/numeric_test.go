@@ -0,0 +1,66 @@
+package numeric_test
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/deephash"
+    "github.com/jayaprabhakar/go-deeper/numeric"
+)
+
+type Invoice struct {
+    Units    *big.Int
+    Rate     *big.Rat
+    Discount *big.Float
+    Total    Money
+    Refund   *big.Rat
+}
+
+// Money is an amount in a currency, kept unexported.
+type Money struct {
+    currency string
+    amount   *big.Rat
+}
+
+func init() {
+    numeric.Register(func(m Money) Money {
+        return Money{currency: m.currency, amount: new(big.Rat).Set(m.amount)}
+    }, func(a, b Money) bool {
+        return a.currency == b.currency && a.amount.Cmp(b.amount) == 0
+    }, func(m Money) string {
+        return m.currency + " " + m.amount.RatString()
+    })
+}
+
+// Test for cloning, comparing and hashing numbers by value
+func TestNumbers(t *testing.T) {
+    original := Invoice{
+        Units:    big.NewInt(3),
+        Rate:     big.NewRat(1, 2),
+        Discount: new(big.Float).SetPrec(200).SetFloat64(0.25),
+        Total:    Money{currency: "EUR", amount: big.NewRat(3, 2)},
+    }
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Units == original.Units || cloned.Units.Int64() != 3 || cloned.Rate.RatString() != "1/2" ||
+        cloned.Discount.String() != "0.25" || cloned.Total.amount == original.Total.amount || cloned.Refund != nil {
+        t.Errorf("got clone %+v, want a deep clone of %+v", cloned, original)
+    }
+    if cloned.Total.currency != "EUR" || cloned.Total.amount.RatString() != "3/2" {
+        t.Errorf("got total %+v, want EUR 3/2", cloned.Total)
+    }
+
+    // Equal values with different representations
+    cloned.Rate = big.NewRat(2, 4)
+    cloned.Discount = big.NewFloat(0.25)
+    if !cloner.Equal(original, cloned) || deephash.Hash(original) != deephash.Hash(cloned) {
+        t.Errorf("got %+v and %+v different, want them equal", original, cloned)
+    }
+    cloned.Total.currency = "USD"
+    if cloner.Equal(original, cloned) || deephash.Hash(original) == deephash.Hash(cloned) {
+        t.Errorf("got %+v and %+v equal, want them different", original, cloned)
+    }
+}
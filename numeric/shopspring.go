@@ -0,0 +1,26 @@
+//go:build deeper_shopspring
+
+package numeric
+
+import "github.com/shopspring/decimal"
+
+func init() {
+    // Decimals are immutable: the clone shares the coefficient of the source
+    Register(func(d decimal.Decimal) decimal.Decimal {
+        return d
+    }, func(a, b decimal.Decimal) bool {
+        return a.Equal(b)
+    }, func(d decimal.Decimal) string {
+        return d.String()
+    })
+    Register(func(d decimal.NullDecimal) decimal.NullDecimal {
+        return d
+    }, func(a, b decimal.NullDecimal) bool {
+        return a.Valid == b.Valid && (!a.Valid || a.Decimal.Equal(b.Decimal))
+    }, func(d decimal.NullDecimal) string {
+        if !d.Valid {
+            return ""
+        }
+        return d.Decimal.String()
+    })
+}
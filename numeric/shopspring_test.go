@@ -0,0 +1,42 @@
+//go:build deeper_shopspring
+
+package numeric_test
+
+import (
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/deephash"
+    "github.com/shopspring/decimal"
+)
+
+// Test for cloning, comparing and hashing decimals by value
+func TestDecimals(t *testing.T) {
+    type order struct {
+        Price    decimal.Decimal
+        Discount decimal.NullDecimal
+        Refund   decimal.NullDecimal
+    }
+    original := order{
+        Price:    decimal.RequireFromString("12.50"),
+        Discount: decimal.NewNullDecimal(decimal.RequireFromString("0.1")),
+    }
+    cloned, err := cloner.Clone(cloner.NewCloneManager(), original)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if cloned.Price.String() != "12.5" || cloned.Discount.Decimal.String() != "0.1" || cloned.Refund.Valid {
+        t.Errorf("got clone %+v, want a clone of %+v", cloned, original)
+    }
+
+    // Equal values with different exponents
+    cloned.Price = decimal.New(125, -1)
+    cloned.Discount = decimal.NewNullDecimal(decimal.RequireFromString("0.100"))
+    if !cloner.Equal(original, cloned) || deephash.Hash(original) != deephash.Hash(cloned) {
+        t.Errorf("got %+v and %+v different, want them equal", original, cloned)
+    }
+    cloned.Refund = decimal.NewNullDecimal(decimal.Zero)
+    if cloner.Equal(original, cloned) || deephash.Hash(original) == deephash.Hash(cloned) {
+        t.Errorf("got %+v and %+v equal, want them different", original, cloned)
+    }
+}
@@ -0,0 +1,135 @@
+// Package observ serves the statistics and configuration of a CloneManager
+// over HTTP, for services that clone state continuously:
+//
+//	http.Handle("/debug/deeper", observ.Handler(cm))
+//
+// The handler serves JSON, or an HTML page to browsers, with the counts of
+// cloner.UpdateStats, those of the window set by cloner.SetStatsWindow, the
+// hot types of the manager collected with cloner.WithTypeStats and its
+// configuration, including its registered cloners and policies.
+package observ
+
+import (
+    "encoding/json"
+    "html/template"
+    "net/http"
+    "sort"
+    "strings"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// maxHotTypes is the number of hot types served.
+const maxHotTypes = 20
+
+// Snapshot is the document served by Handler.
+type Snapshot struct {
+    Stats       map[string]int `json:"stats"`
+    RecentStats map[string]int `json:"recentStats,omitempty"`
+    HotTypes    []HotType      `json:"hotTypes,omitempty"`
+    Config      cloner.Config  `json:"config"`
+}
+
+// HotType is a cloner.TypeStats of a Snapshot.
+type HotType struct {
+    Type  string `json:"type"`
+    Count int    `json:"count"`
+    Time  string `json:"time"`
+}
+
+// Handler returns an http.Handler serving a Snapshot of cm. Clients
+// accepting text/html get an HTML page, others JSON; the format query
+// parameter, json or html, overrides the choice.
+func Handler(cm *cloner.CloneManager) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet && r.Method != http.MethodHead {
+            w.Header().Set("Allow", "GET, HEAD")
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        snapshot := Take(cm)
+
+        format := r.URL.Query().Get("format")
+        if format == "" && strings.Contains(r.Header.Get("Accept"), "text/html") {
+            format = "html"
+        }
+        if format == "html" {
+            w.Header().Set("Content-Type", "text/html; charset=utf-8")
+            page.Execute(w, snapshot)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        enc := json.NewEncoder(w)
+        enc.SetIndent("", "  ")
+        enc.Encode(snapshot)
+    })
+}
+
+// Take returns the current Snapshot of cm.
+func Take(cm *cloner.CloneManager) Snapshot {
+    s := Snapshot{
+        Stats:       cloner.Stats(),
+        RecentStats: cloner.RecentStats(),
+        Config:      cm.Config(),
+    }
+    for _, ts := range cm.HotTypes(maxHotTypes) {
+        s.HotTypes = append(s.HotTypes, HotType{Type: ts.Type.String(), Count: ts.Count, Time: ts.Time.String()})
+    }
+    return s
+}
+
+// count is a row of the stats tables of the HTML page.
+type count struct {
+    Name  string
+    Count int
+}
+
+// sorted returns the counts of m sorted by name.
+func sorted(m map[string]int) []count {
+    counts := make([]count, 0, len(m))
+    for name, n := range m {
+        counts = append(counts, count{name, n})
+    }
+    sort.Slice(counts, func(i, j int) bool {
+        return counts[i].Name < counts[j].Name
+    })
+    return counts
+}
+
+// configJSON formats the configuration for the HTML page.
+func configJSON(cfg cloner.Config) (string, error) {
+    b, err := json.MarshalIndent(cfg, "", "  ")
+    return string(b), err
+}
+
+var page = template.Must(template.New("page").Funcs(template.FuncMap{
+    "sorted": sorted,
+    "json":   configJSON,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><title>deeper</title></head>
+<body>
+<h1>Stats</h1>
+<table>
+<tr><th>Name</th><th>Count</th></tr>
+{{range sorted .Stats}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{if .RecentStats}}<h1>Recent stats</h1>
+<table>
+<tr><th>Name</th><th>Count</th></tr>
+{{range sorted .RecentStats}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}{{if .HotTypes}}<h1>Hot types</h1>
+<table>
+<tr><th>Type</th><th>Count</th><th>Time</th></tr>
+{{range .HotTypes}}<tr><td>{{.Type}}</td><td>{{.Count}}</td><td>{{.Time}}</td></tr>
+{{end}}</table>
+{{end}}<h1>Cloners</h1>
+<ul>
+{{range .Config.Cloners}}<li>{{.}}</li>
+{{end}}</ul>
+<h1>Configuration</h1>
+<pre>{{json .Config}}</pre>
+</body>
+</html>
+`))
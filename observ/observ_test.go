@@ -0,0 +1,78 @@
+package observ_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/observ"
+)
+
+type Order struct {
+    ID    int
+    Lines []string
+}
+
+// Test for serving the stats and configuration of a manager as JSON
+func TestHandlerJSON(t *testing.T) {
+    cm := cloner.NewCloneManager(cloner.WithTypeStats(), cloner.WithMaxDepth(8), cloner.WithExcludePaths("Lines"))
+    if _, err := cm.Clone(&Order{ID: 1}); err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+
+    rec := httptest.NewRecorder()
+    observ.Handler(cm).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/deeper", nil))
+    if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+        t.Fatalf("got status %d and content type %q, want JSON", rec.Code, rec.Header().Get("Content-Type"))
+    }
+    var got struct {
+        Stats    map[string]int
+        HotTypes []observ.HotType
+        Config   map[string]interface{}
+    }
+    if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+        t.Fatalf("invalid JSON %s: %v", rec.Body, err)
+    }
+    if got.Stats["ptr"] == 0 || len(got.HotTypes) == 0 {
+        t.Errorf("got stats %v and hot types %v, want the clone of the order", got.Stats, got.HotTypes)
+    }
+    want := map[string]interface{}{
+        "maxDepth":         8.0,
+        "overBudget":       "error",
+        "funcs":            "error",
+        "chans":            "error",
+        "timers":           "zero",
+        "unexportedFields": "zero",
+        "identity":         "exact",
+        "excludePaths":     []interface{}{"Lines"},
+    }
+    if !reflect.DeepEqual(got.Config, want) {
+        t.Errorf("got config %v, want %v", got.Config, want)
+    }
+}
+
+// Test for serving an HTML page to browsers
+func TestHandlerHTML(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(Order{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        return v, nil
+    }))
+
+    req := httptest.NewRequest(http.MethodGet, "/debug/deeper", nil)
+    req.Header.Set("Accept", "text/html,application/xhtml+xml")
+    rec := httptest.NewRecorder()
+    observ.Handler(cm).ServeHTTP(rec, req)
+    if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(rec.Body.String(), "<li>observ_test.Order</li>") {
+        t.Errorf("got %q:\n%s\nwant an HTML page listing the Order cloner", rec.Header().Get("Content-Type"), rec.Body)
+    }
+
+    rec = httptest.NewRecorder()
+    observ.Handler(cm).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/deeper", nil))
+    if rec.Code != http.StatusMethodNotAllowed {
+        t.Errorf("got status %d for POST, want %d", rec.Code, http.StatusMethodNotAllowed)
+    }
+}
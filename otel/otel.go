@@ -0,0 +1,156 @@
+// Package otel traces clones with OpenTelemetry spans, so that their cost is
+// visible in distributed traces.
+//
+// Every clone made through a Cloner runs in a span named "deeper.Clone",
+// with the attributes:
+//
+//	deeper.root_type    the type of the cloned value
+//	deeper.nodes        the number of values cloned
+//	deeper.duration_ms  the time taken by the clone
+//	deeper.errors       the number of errors, if any, also recorded on the span
+//
+// With WithSubtreeEvents, the values whose clone takes longer than a
+// threshold are added to the span as "deeper.subtree" events with their
+// path, type, number of values and duration, showing which parts of the
+// graph the time goes to.
+//
+// The package does not depend on the OpenTelemetry module: spans are started
+// by a Tracer, which adapts a trace.Tracer in a few lines:
+//
+//	type tracer struct{ trace.Tracer }
+//
+//	func (t tracer) Start(ctx context.Context, name string) (context.Context, otel.Span) {
+//	    ctx, span := t.Tracer.Start(ctx, name)
+//	    return ctx, spanAdapter{span}
+//	}
+//
+// where spanAdapter converts the attributes with attribute.String,
+// attribute.Int64 and attribute.Float64 according to their value.
+package otel
+
+import (
+    "context"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// SpanName is the name of the spans of clones.
+const SpanName = "deeper.Clone"
+
+// Attribute is a key-value pair of a span or an event. Values are strings,
+// int64 or float64 values.
+type Attribute struct {
+    Key   string
+    Value interface{}
+}
+
+// Span is the part of an OpenTelemetry span used to trace clones.
+type Span interface {
+    SetAttributes(attrs ...Attribute)
+    AddEvent(name string, attrs ...Attribute)
+    RecordError(err error)
+    End()
+}
+
+// Tracer starts the spans of clones, typically by adapting a trace.Tracer.
+type Tracer interface {
+    Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Option configures a Cloner.
+type Option func(*Cloner)
+
+// WithSubtreeEvents adds an event to the span of a clone for every value
+// whose clone takes at least threshold, see cloner.WithTrace. Timing every
+// value makes clones slower.
+func WithSubtreeEvents(threshold time.Duration) Option {
+    return func(c *Cloner) {
+        c.threshold = threshold
+    }
+}
+
+// Cloner makes clones with a CloneManager, each in its own span.
+type Cloner struct {
+    manager   *cloner.CloneManager
+    tracer    Tracer
+    threshold time.Duration
+}
+
+// New returns a Cloner tracing the clones of manager with tracer.
+func New(manager *cloner.CloneManager, tracer Tracer, opts ...Option) *Cloner {
+    c := &Cloner{manager: manager, tracer: tracer}
+    for _, opt := range opts {
+        opt(c)
+    }
+    return c
+}
+
+// Clone clones src like CloneManager.Clone in a span started from ctx.
+func (c *Cloner) Clone(ctx context.Context, src interface{}, opts ...cloner.Option) (interface{}, error) {
+    var cloned interface{}
+    err := c.trace(ctx, opts, func(opts []cloner.Option) (err error) {
+        cloned, err = c.manager.Clone(src, opts...)
+        return err
+    })
+    return cloned, err
+}
+
+// Clone is like cloner.Clone in a span started from ctx.
+func Clone[T any](ctx context.Context, c *Cloner, src T, opts ...cloner.Option) (T, error) {
+    var cloned T
+    err := c.trace(ctx, opts, func(opts []cloner.Option) (err error) {
+        cloned, err = cloner.Clone(c.manager, src, opts...)
+        return err
+    })
+    return cloned, err
+}
+
+// trace calls clone with opts and a WithTrace option in a new span.
+func (c *Cloner) trace(ctx context.Context, opts []cloner.Option, clone func([]cloner.Option) error) error {
+    _, span := c.tracer.Start(ctx, SpanName)
+    defer span.End()
+
+    // Subtrees are reported before the values they belong to, so the root
+    // is the last one
+    var root *cloner.Subtree
+    trace := cloner.WithTrace(c.threshold, func(s cloner.Subtree) {
+        if root != nil {
+            span.AddEvent("deeper.subtree", subtreeAttributes(*root)...)
+        }
+        root = &s
+    })
+    start := time.Now()
+    err := clone(append(opts[:len(opts):len(opts)], trace))
+
+    attrs := []Attribute{{Key: "deeper.duration_ms", Value: milliseconds(time.Since(start))}}
+    if root != nil && root.Type != nil {
+        attrs = append(attrs,
+            Attribute{Key: "deeper.root_type", Value: root.Type.String()},
+            Attribute{Key: "deeper.nodes", Value: int64(root.Nodes)})
+    }
+    if err != nil {
+        n := 1
+        if joined, ok := err.(interface{ Unwrap() []error }); ok {
+            n = len(joined.Unwrap())
+        }
+        attrs = append(attrs, Attribute{Key: "deeper.errors", Value: int64(n)})
+        span.RecordError(err)
+    }
+    span.SetAttributes(attrs...)
+    return err
+}
+
+// subtreeAttributes returns the attributes of the event of s.
+func subtreeAttributes(s cloner.Subtree) []Attribute {
+    return []Attribute{
+        {Key: "deeper.path", Value: string(s.Path)},
+        {Key: "deeper.type", Value: s.Type.String()},
+        {Key: "deeper.nodes", Value: int64(s.Nodes)},
+        {Key: "deeper.duration_ms", Value: milliseconds(s.Duration)},
+    }
+}
+
+func milliseconds(d time.Duration) float64 {
+    return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,109 @@
+package otel_test
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/otel"
+)
+
+type Report struct {
+    Title    string
+    Sections []*Section
+}
+
+type Section struct {
+    Body string
+}
+
+// span records what is traced.
+type span struct {
+    name   string
+    attrs  map[string]interface{}
+    events []string
+    errs   []error
+    ended  bool
+}
+
+func (s *span) SetAttributes(attrs ...otel.Attribute) {
+    for _, a := range attrs {
+        s.attrs[a.Key] = a.Value
+    }
+}
+
+func (s *span) AddEvent(name string, attrs ...otel.Attribute) {
+    s.events = append(s.events, name+" "+attrs[0].Value.(string))
+}
+
+func (s *span) RecordError(err error) { s.errs = append(s.errs, err) }
+func (s *span) End()                  { s.ended = true }
+
+type tracer struct {
+    spans []*span
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, otel.Span) {
+    s := &span{name: name, attrs: map[string]interface{}{}}
+    t.spans = append(t.spans, s)
+    return ctx, s
+}
+
+// Test for tracing clones in spans
+func TestClone(t *testing.T) {
+    report := &Report{Title: "q1", Sections: []*Section{{Body: "a"}, {Body: "b"}}}
+    tr := &tracer{}
+    c := otel.New(cloner.NewCloneManager(), tr)
+
+    cloned, err := otel.Clone(context.Background(), c, report)
+    if err != nil || !reflect.DeepEqual(cloned, report) {
+        t.Fatalf("got clone %+v and error %v, want %+v", cloned, err, report)
+    }
+    s := tr.spans[0]
+    if s.name != otel.SpanName || !s.ended || len(s.events) != 0 || len(s.errs) != 0 {
+        t.Errorf("got span %+v, want an ended span without events or errors", s)
+    }
+    if s.attrs["deeper.root_type"] != "*otel_test.Report" || s.attrs["deeper.nodes"] != int64(10) {
+        t.Errorf("got attributes %v, want the root type and 10 nodes", s.attrs)
+    }
+    if _, ok := s.attrs["deeper.duration_ms"].(float64); !ok {
+        t.Errorf("got attributes %v, want the duration", s.attrs)
+    }
+}
+
+// Test for events of slow subtrees and errors
+func TestCloneSubtreesAndErrors(t *testing.T) {
+    cm := cloner.NewCloneManager()
+    cm.RegisterCloner(reflect.TypeOf(&Section{}), cloner.ClonerFunc(func(v interface{}, _ *cloner.CloneManager) (interface{}, error) {
+        time.Sleep(5 * time.Millisecond)
+        if v.(*Section).Body == "" {
+            return nil, errors.New("empty section")
+        }
+        return &Section{Body: v.(*Section).Body}, nil
+    }))
+    tr := &tracer{}
+    c := otel.New(cm, tr, otel.WithSubtreeEvents(time.Millisecond))
+
+    report := &Report{Sections: []*Section{{Body: "a"}, {}, {}}}
+    _, err := c.Clone(context.Background(), report, cloner.CollectErrors())
+    if err == nil {
+        t.Fatalf("Clone did not fail")
+    }
+    s := tr.spans[0]
+    want := []string{
+        "deeper.subtree .Sections[0]",
+        "deeper.subtree .Sections[1]",
+        "deeper.subtree .Sections[2]",
+        "deeper.subtree .Sections",
+        "deeper.subtree ",
+    }
+    if !reflect.DeepEqual(s.events, want) {
+        t.Errorf("got events %q, want %q", s.events, want)
+    }
+    if len(s.errs) != 1 || s.attrs["deeper.errors"] != int64(2) {
+        t.Errorf("got errors %v and attributes %v, want 2 errors", s.errs, s.attrs)
+    }
+}
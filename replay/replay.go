@@ -0,0 +1,167 @@
+// Package replay records the snapshots a service makes of its state to a
+// compact log, so that the sequence of snapshots can be reconstructed when
+// diagnosing state bugs. A Recorder is installed on the CloneManager making
+// the snapshots with cloner.WithRecorder:
+//
+//	recorder := replay.NewRecorder[State](f, nil)
+//	cm := cloner.NewCloneManager(cloner.WithRecorder(recorder.Record))
+//
+// The first snapshot is written whole and every later one as its changes
+// from the previous one, as found by cloner.Diff. Replay reads the log back.
+// As with store.Store, the dynamic types of interface values must be
+// registered with cloner.RegisterType, and unexported fields are not
+// recorded.
+package replay
+
+import (
+    "encoding/gob"
+    "errors"
+    "io"
+    "reflect"
+    "sync"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/deephash"
+    "github.com/jayaprabhakar/go-deeper/internal/delta"
+)
+
+// Entry describes a recorded snapshot.
+type Entry struct {
+    // Seq is the number of the snapshot in the log, from 1.
+    Seq int
+    // Type is the type of the snapshot.
+    Type string
+    // Time is when the snapshot was recorded.
+    Time time.Time
+    // Hash is the deephash.Hash of the value the snapshot was cloned from,
+    // to tell which snapshots were made of the same state.
+    Hash uint64
+    // Full is true if the snapshot is written whole rather than as its
+    // changes from the previous one.
+    Full bool
+    // Changes is the number of values changed since the previous snapshot.
+    Changes int
+}
+
+// Recorder writes the snapshots of type T made by a CloneManager to a log.
+// It is safe for concurrent use.
+type Recorder[T any] struct {
+    manager *cloner.CloneManager
+
+    mutex    sync.Mutex
+    enc      *gob.Encoder
+    seq      int
+    previous T
+    err      error
+}
+
+// NewRecorder returns a Recorder writing to w, copying and streaming the
+// snapshots with manager, or with cloner.Default if manager is nil. manager
+// must not be the manager recording to the Recorder.
+func NewRecorder[T any](w io.Writer, manager *cloner.CloneManager) *Recorder[T] {
+    if manager == nil {
+        manager = cloner.Default()
+    }
+    return &Recorder[T]{manager: manager, enc: gob.NewEncoder(w)}
+}
+
+// Record records clone, the clone of src, if it is a T, and ignores it
+// otherwise. It is meant to be passed to cloner.WithRecorder. Once writing
+// to the log fails, nothing more is recorded and Err returns the error.
+func (r *Recorder[T]) Record(src, clone interface{}) {
+    snapshot, ok := clone.(T)
+    if !ok {
+        return
+    }
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    if r.err != nil {
+        return
+    }
+    r.err = r.record(src, snapshot)
+}
+
+func (r *Recorder[T]) record(src interface{}, snapshot T) error {
+    entry := Entry{
+        Seq:  r.seq + 1,
+        Type: reflect.TypeOf(&snapshot).Elem().String(),
+        Time: time.Now(),
+        Hash: deephash.Hash(src),
+        Full: r.seq == 0,
+    }
+    var changes []delta.Change
+    if !entry.Full {
+        var err error
+        if changes, err = delta.Changes(r.previous, snapshot, nil); err != nil {
+            return err
+        }
+        entry.Changes = len(changes)
+    }
+    if err := r.enc.Encode(entry); err != nil {
+        return err
+    }
+    if entry.Full {
+        if err := r.manager.CloneTo(r.enc, snapshot); err != nil {
+            return err
+        }
+    } else if err := delta.Write(r.enc, r.manager, changes); err != nil {
+        return err
+    }
+
+    // The snapshot is kept in a copy, since it belongs to the caller
+    previous, err := cloner.Clone(r.manager, snapshot)
+    if err != nil {
+        return err
+    }
+    r.seq, r.previous = entry.Seq, previous
+    return nil
+}
+
+// Err returns the error that stopped the recording, if any.
+func (r *Recorder[T]) Err() error {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    return r.err
+}
+
+// Replay reads the log written by a Recorder of T from rd and calls fn with
+// every entry and the snapshot it reconstructs, in order, until fn returns
+// an error. The snapshots are streamed with manager, or with cloner.Default
+// if manager is nil, and each is a copy that fn may keep or change.
+func Replay[T any](rd io.Reader, manager *cloner.CloneManager, fn func(Entry, T) error) error {
+    if manager == nil {
+        manager = cloner.Default()
+    }
+    dec := gob.NewDecoder(rd)
+    var current T
+    for {
+        var entry Entry
+        if err := dec.Decode(&entry); err == io.EOF {
+            return nil
+        } else if err != nil {
+            return err
+        }
+        var err error
+        if entry.Full {
+            var zero T
+            current = zero
+            err = manager.CloneFrom(dec, &current)
+        } else {
+            err = delta.Apply(dec, manager, &current)
+        }
+        if errors.Is(err, io.EOF) {
+            err = io.ErrUnexpectedEOF
+        }
+        if err != nil {
+            return err
+        }
+        snapshot, err := cloner.Clone(manager, current)
+        if err != nil {
+            return err
+        }
+        if err := fn(entry, snapshot); err != nil {
+            return err
+        }
+    }
+}
@@ -0,0 +1,89 @@
+package replay_test
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/deephash"
+    "github.com/jayaprabhakar/go-deeper/replay"
+)
+
+type Session struct {
+    User  string
+    Carts map[string][]int
+    Step  *int
+}
+
+// Test for recording the snapshots of a manager and replaying them
+func TestReplay(t *testing.T) {
+    var log bytes.Buffer
+    recorder := replay.NewRecorder[Session](&log, nil)
+    cm := cloner.NewCloneManager(cloner.WithRecorder(recorder.Record))
+
+    step := 1
+    session := Session{User: "ann", Carts: map[string][]int{"main": {1}}, Step: &step}
+    var snapshots []Session
+    var hashes []uint64
+    for _, mutate := range []func(){
+        func() {},
+        func() { session.Carts["main"] = append(session.Carts["main"], 2) },
+        func() {},
+        func() {
+            delete(session.Carts, "main")
+            session.Carts["gift"] = []int{3}
+            step++
+        },
+    } {
+        mutate()
+        snapshot, err := cloner.Clone(cm, session)
+        if err != nil {
+            t.Fatal(err)
+        }
+        snapshots = append(snapshots, snapshot)
+        hashes = append(hashes, deephash.Hash(session))
+    }
+    // Clones of other types are not recorded
+    cloner.Clone(cm, []int{1})
+    if err := recorder.Err(); err != nil {
+        t.Fatalf("Record failed: %v", err)
+    }
+
+    data := log.Bytes()
+    var entries []replay.Entry
+    err := replay.Replay(bytes.NewReader(data), nil, func(entry replay.Entry, snapshot Session) error {
+        i := len(entries)
+        entries = append(entries, entry)
+        if diffs := cloner.Diff(snapshot, snapshots[i]); len(diffs) > 0 {
+            t.Errorf("snapshot %d differs: %v", entry.Seq, diffs)
+        }
+        if entry.Seq != i+1 || entry.Type != "replay_test.Session" || entry.Hash != hashes[i] || entry.Full != (i == 0) {
+            t.Errorf("got entry %+v", entry)
+        }
+        return nil
+    })
+    if err != nil || len(entries) != 4 {
+        t.Fatalf("Replay returned %v after %d entries, want 4", err, len(entries))
+    }
+    if entries[1].Changes != 1 || entries[2].Changes != 0 || entries[3].Changes != 3 {
+        t.Errorf("got entries %+v, want 1, 0 and 3 changes", entries[1:])
+    }
+    if entries[1].Hash != entries[2].Hash || entries[0].Hash == entries[1].Hash {
+        t.Errorf("got hashes %x, want the second and third alike", hashes)
+    }
+
+    errStop := errors.New("stop")
+    count := 0
+    if err := replay.Replay(bytes.NewReader(data), nil, func(replay.Entry, Session) error {
+        count++
+        return errStop
+    }); err != errStop || count != 1 {
+        t.Errorf("got error %v after %d entries, want the error of fn after 1", err, count)
+    }
+    if err := replay.Replay(bytes.NewReader(data[:len(data)-2]), nil, func(replay.Entry, Session) error {
+        return nil
+    }); err == nil {
+        t.Errorf("Replay accepted a truncated log")
+    }
+}
@@ -0,0 +1,171 @@
+// Package scheduler takes snapshots of a value on an interval or on demand,
+// for services that snapshot their state periodically, and feeds them to
+// subscribers. Requests made while snapshots are being taken are coalesced,
+// so that a burst of requests costs a single clone and snapshots never queue
+// up behind a slow clone.
+package scheduler
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Option configures a Scheduler.
+type Option func(*options)
+
+type options struct {
+    interval      time.Duration
+    maxConcurrent int
+    locker        sync.Locker
+    cloneOpts     []cloner.Option
+}
+
+// Every makes Run take a snapshot every d, in addition to those requested
+// with Trigger.
+func Every(d time.Duration) Option {
+    return func(o *options) {
+        o.interval = d
+    }
+}
+
+// WithMaxConcurrent lets up to n snapshots be taken at the same time; the
+// default is one. Requests made while n snapshots are being taken are
+// coalesced into a single snapshot, taken once one of them is done.
+func WithMaxConcurrent(n int) Option {
+    return func(o *options) {
+        o.maxConcurrent = n
+    }
+}
+
+// WithLocker makes the scheduler hold l while it clones the root, typically
+// the read lock of the mutex guarding it, as returned by RWMutex.RLocker.
+func WithLocker(l sync.Locker) Option {
+    return func(o *options) {
+        o.locker = l
+    }
+}
+
+// WithCloneOptions applies opts to every clone of the root.
+func WithCloneOptions(opts ...cloner.Option) Option {
+    return func(o *options) {
+        o.cloneOpts = append(o.cloneOpts, opts...)
+    }
+}
+
+// Scheduler takes snapshots of the value of type T pointed to by its root
+// and calls its subscribers with each of them. It is safe for concurrent
+// use.
+type Scheduler[T any] struct {
+    cm      *cloner.CloneManager
+    root    *T
+    options options
+
+    mutex       sync.Mutex
+    subscribers []func(T, error)
+    running     int  // number of snapshots being taken
+    pending     bool // a snapshot was requested while running was at the limit
+    idle        sync.Cond
+}
+
+// New returns a Scheduler cloning the value pointed to by root with cm.
+func New[T any](cm *cloner.CloneManager, root *T, opts ...Option) *Scheduler[T] {
+    s := &Scheduler[T]{cm: cm, root: root, options: options{maxConcurrent: 1}}
+    s.idle.L = &s.mutex
+    for _, opt := range opts {
+        opt(&s.options)
+    }
+    if s.options.maxConcurrent < 1 {
+        s.options.maxConcurrent = 1
+    }
+    return s
+}
+
+// Subscribe makes the scheduler call fn with every snapshot taken from now
+// on, or with the error of its clone. Subscribers are called in the order
+// they subscribed, from the goroutine that took the snapshot; they share the
+// snapshot, which must not be changed.
+func (s *Scheduler[T]) Subscribe(fn func(snapshot T, err error)) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    s.subscribers = append(s.subscribers, fn)
+}
+
+// Trigger requests a snapshot and returns without waiting for it. The
+// snapshot is taken right away unless the maximum number of snapshots are
+// being taken, in which case it is taken once one of them is done, together
+// with every other request made in the meantime.
+func (s *Scheduler[T]) Trigger() {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    if s.running >= s.options.maxConcurrent {
+        s.pending = true
+        return
+    }
+    s.running++
+    go s.take()
+}
+
+// take takes snapshots until no request is pending.
+func (s *Scheduler[T]) take() {
+    for {
+        snapshot, err := s.clone()
+        s.mutex.Lock()
+        subscribers := s.subscribers
+        s.mutex.Unlock()
+        for _, fn := range subscribers {
+            fn(snapshot, err)
+        }
+
+        s.mutex.Lock()
+        if !s.pending {
+            s.running--
+            s.idle.Broadcast()
+            s.mutex.Unlock()
+            return
+        }
+        s.pending = false
+        s.mutex.Unlock()
+    }
+}
+
+// clone clones the root, holding the locker, if any.
+func (s *Scheduler[T]) clone() (T, error) {
+    if l := s.options.locker; l != nil {
+        l.Lock()
+        defer l.Unlock()
+    }
+    return cloner.Clone(s.cm, *s.root, s.options.cloneOpts...)
+}
+
+// Run takes a snapshot on every tick of the interval set with Every until
+// ctx is done, then waits for the snapshots being taken and returns the
+// error of ctx. Without an interval, snapshots are only taken on Trigger.
+func (s *Scheduler[T]) Run(ctx context.Context) error {
+    var tick <-chan time.Time
+    if s.options.interval > 0 {
+        ticker := time.NewTicker(s.options.interval)
+        defer ticker.Stop()
+        tick = ticker.C
+    }
+    for {
+        select {
+        case <-tick:
+            s.Trigger()
+        case <-ctx.Done():
+            s.Wait()
+            return ctx.Err()
+        }
+    }
+}
+
+// Wait waits until no snapshot is being taken or pending.
+func (s *Scheduler[T]) Wait() {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    for s.running > 0 {
+        s.idle.Wait()
+    }
+}
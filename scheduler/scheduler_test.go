@@ -0,0 +1,79 @@
+package scheduler_test
+
+import (
+    "context"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/scheduler"
+)
+
+type Inventory struct {
+    Items map[string]int
+}
+
+// Test for coalescing the snapshots requested while one is being taken
+func TestTrigger(t *testing.T) {
+    var mutex sync.RWMutex
+    inventory := Inventory{Items: map[string]int{"apple": 1}}
+    s := scheduler.New(cloner.NewCloneManager(), &inventory, scheduler.WithLocker(mutex.RLocker()))
+
+    started, release := make(chan struct{}), make(chan struct{})
+    var snapshots []Inventory
+    s.Subscribe(func(snapshot Inventory, err error) {
+        if err != nil {
+            t.Errorf("got error %v", err)
+        }
+        if len(snapshots) == 0 {
+            close(started)
+            <-release
+        }
+        snapshots = append(snapshots, snapshot)
+    })
+
+    s.Trigger()
+    <-started
+    for i := 0; i < 10; i++ {
+        s.Trigger()
+    }
+    mutex.Lock()
+    inventory.Items["apple"] = 2
+    mutex.Unlock()
+    close(release)
+    s.Wait()
+
+    if len(snapshots) != 2 || snapshots[0].Items["apple"] != 1 || snapshots[1].Items["apple"] != 2 {
+        t.Errorf("got snapshots %v, want the first and one coalescing the other requests", snapshots)
+    }
+    inventory.Items["apple"] = 3
+    if snapshots[1].Items["apple"] != 2 {
+        t.Errorf("got snapshot %v sharing the root", snapshots[1])
+    }
+}
+
+// Test for taking snapshots on an interval
+func TestRun(t *testing.T) {
+    inventory := Inventory{Items: map[string]int{}}
+    s := scheduler.New(cloner.NewCloneManager(), &inventory, scheduler.Every(time.Millisecond), scheduler.WithMaxConcurrent(2))
+    ctx, cancel := context.WithCancel(context.Background())
+    var mutex sync.Mutex
+    count := 0
+    s.Subscribe(func(Inventory, error) {
+        mutex.Lock()
+        defer mutex.Unlock()
+        if count++; count == 3 {
+            cancel()
+        }
+    })
+
+    if err := s.Run(ctx); err != context.Canceled {
+        t.Errorf("got error %v, want %v", err, context.Canceled)
+    }
+    mutex.Lock()
+    defer mutex.Unlock()
+    if count < 3 {
+        t.Errorf("got %d snapshots, want at least 3", count)
+    }
+}
@@ -0,0 +1,225 @@
+// Package scrub makes privacy-safe clones of production data, for debugging
+// and test fixtures: personal data in the clone is replaced by scrubbers
+// selected by struct tag or by type, while the source is left untouched.
+//
+//    type User struct {
+//        Email string `scrub:"email"`
+//        Phone string `scrub:"phone"`
+//        IP    string `scrub:"ip"`
+//    }
+//
+//    profile := scrub.NewProfile(nil).
+//        Tag("email", scrub.HashEmail(key)).
+//        Tag("phone", scrub.ScramblePhone(key)).
+//        Tag("ip", scrub.TruncateIP(24, 48))
+//    safe, err := scrub.Clone(profile, user)
+package scrub
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "net"
+    "reflect"
+    "strings"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Scrubber returns the scrubbed replacement of a string.
+type Scrubber func(s string) string
+
+// Redact replaces strings with replacement.
+func Redact(replacement string) Scrubber {
+    return func(string) string {
+        return replacement
+    }
+}
+
+// digest returns the HMAC-SHA256 of s keyed with key.
+func digest(key []byte, s string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(s))
+    return mac.Sum(nil)
+}
+
+// HashEmail replaces the local part of email addresses with a keyed hash of
+// the address, keeping the domain: the same address always scrubs to the
+// same value, so that joins between records still work. Strings that are
+// not addresses are hashed entirely.
+func HashEmail(key []byte) Scrubber {
+    return func(s string) string {
+        hash := hex.EncodeToString(digest(key, s))[:16]
+        if at := strings.LastIndex(s, "@"); at >= 0 {
+            return hash + s[at:]
+        }
+        return hash
+    }
+}
+
+// TruncateIP keeps the first ipv4Bits bits of IPv4 addresses and the first
+// ipv6Bits bits of IPv6 addresses, zeroing the rest, e.g. 192.0.2.17
+// becomes 192.0.2.0 with 24 bits. Strings that are not IP addresses are
+// replaced with the empty string.
+func TruncateIP(ipv4Bits, ipv6Bits int) Scrubber {
+    return func(s string) string {
+        ip := net.ParseIP(s)
+        if ip == nil {
+            return ""
+        }
+        if v4 := ip.To4(); v4 != nil {
+            return v4.Mask(net.CIDRMask(ipv4Bits, 32)).String()
+        }
+        return ip.Mask(net.CIDRMask(ipv6Bits, 128)).String()
+    }
+}
+
+// ScramblePhone replaces the digits of phone numbers with digits derived
+// from a keyed hash of the number, preserving its format: the punctuation,
+// spacing and a leading + are kept, e.g. +1 (555) 010-9999 may become
+// +7 (203) 841-5526.
+func ScramblePhone(key []byte) Scrubber {
+    return func(s string) string {
+        sum := digest(key, s)
+        b := []byte(s)
+        n := 0
+        for i, c := range b {
+            if c >= '0' && c <= '9' {
+                b[i] = '0' + sum[n%len(sum)]%10
+                n++
+            }
+        }
+        return string(b)
+    }
+}
+
+// Profile selects the scrubbers applied to a clone.
+type Profile struct {
+    manager *cloner.CloneManager
+    tags    map[string]Scrubber
+    types   map[reflect.Type]Scrubber
+}
+
+// NewProfile returns a Profile without scrubbers, cloning with the
+// configuration of manager, or of cloner.Default if manager is nil.
+func NewProfile(manager *cloner.CloneManager) *Profile {
+    if manager == nil {
+        manager = cloner.Default()
+    }
+    return &Profile{
+        manager: manager,
+        tags:    make(map[string]Scrubber),
+        types:   make(map[reflect.Type]Scrubber),
+    }
+}
+
+// Tag scrubs the fields tagged `scrub:"name"` with s. The tag applies to
+// string fields, to the string pointed to by *string fields, and to the
+// elements of slices and arrays of strings.
+func (p *Profile) Tag(name string, s Scrubber) *Profile {
+    p.tags[name] = s
+    return p
+}
+
+// Type scrubs every value of type t, whose kind must be string, with s, for
+// example a type Email string used throughout a model.
+func (p *Profile) Type(t reflect.Type, s Scrubber) *Profile {
+    p.types[t] = s
+    return p
+}
+
+// Clone returns a clone of src with the scrubbers of p applied. Only
+// exported fields are scrubbed; unexported fields are cloned according to
+// the configuration of the manager of p.
+func Clone[T any](p *Profile, src T) (T, error) {
+    clone, err := cloner.Clone(p.manager, src)
+    if err != nil {
+        return clone, err
+    }
+    s := &scrubber{Profile: p, visited: make(map[memref.Ref]bool)}
+    s.scrub(reflect.ValueOf(&clone).Elem())
+    return clone, nil
+}
+
+type scrubber struct {
+    *Profile
+    visited map[memref.Ref]bool // pointers, slices and maps scrubbed already
+}
+
+// scrub applies the scrubbers to the settable value v.
+func (s *scrubber) scrub(v reflect.Value) {
+    if fn, ok := s.types[v.Type()]; ok && v.Kind() == reflect.String {
+        v.SetString(fn(v.String()))
+        return
+    }
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Map:
+        ref := memref.Of(v)
+        if v.IsNil() || s.visited[ref] {
+            return
+        }
+        s.visited[ref] = true
+    }
+
+    switch v.Kind() {
+    case reflect.Ptr:
+        s.scrub(v.Elem())
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            s.scrub(v.Index(i))
+        }
+    case reflect.Map:
+        iter := v.MapRange()
+        for iter.Next() {
+            value := reflect.New(v.Type().Elem()).Elem()
+            value.Set(iter.Value())
+            s.scrub(value)
+            v.SetMapIndex(iter.Key(), value)
+        }
+    case reflect.Interface:
+        if v.IsNil() {
+            return
+        }
+        value := reflect.New(v.Elem().Type()).Elem()
+        value.Set(v.Elem())
+        s.scrub(value)
+        v.Set(value)
+    case reflect.Struct:
+        t := v.Type()
+        for i := 0; i < t.NumField(); i++ {
+            field := t.Field(i)
+            if !field.IsExported() {
+                continue
+            }
+            if fn, ok := s.tags[field.Tag.Get("scrub")]; ok {
+                s.applyTag(v.Field(i), fn)
+                continue
+            }
+            s.scrub(v.Field(i))
+        }
+    }
+}
+
+// applyTag applies fn to the string field v, the string it points to, or
+// its elements.
+func (s *scrubber) applyTag(v reflect.Value, fn Scrubber) {
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Slice:
+        ref := memref.Of(v)
+        if v.IsNil() || s.visited[ref] {
+            return
+        }
+        s.visited[ref] = true
+    }
+    switch v.Kind() {
+    case reflect.String:
+        v.SetString(fn(v.String()))
+    case reflect.Ptr:
+        s.applyTag(v.Elem(), fn)
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            s.applyTag(v.Index(i), fn)
+        }
+    }
+}
@@ -0,0 +1,157 @@
+package scrub_test
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/scrub"
+)
+
+// Test for scrubbing a clone by tag
+func TestClone(t *testing.T) {
+    type customer struct {
+        Name     string    `scrub:"name"`
+        Aliases  [2]string `scrub:"name"`
+        Phone    string    `scrub:"phone"`
+        LastIP   *string   `scrub:"ip"`
+        Notes    string
+        Referrer *customer
+        nickname string `scrub:"name"`
+    }
+    key := []byte("test key")
+    profile := scrub.NewProfile(cloner.NewCloneManager(cloner.WithUnexportedFields(cloner.Share))).
+        Tag("name", scrub.Redact("x")).
+        Tag("phone", scrub.ScramblePhone(key)).
+        Tag("ip", scrub.TruncateIP(24, 48))
+
+    ip := "192.0.2.17"
+    src := &customer{Name: "Ann", Aliases: [2]string{"annie"}, Phone: "+1 (555) 010-9999", LastIP: &ip, Notes: "vip", nickname: "an"}
+    src.Referrer = src
+    got, err := scrub.Clone(profile, src)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if got.Name != "x" || got.Aliases != [2]string{"x", "x"} {
+        t.Errorf("got names %q, %q, want redacted", got.Name, got.Aliases)
+    }
+    if got.Phone == src.Phone || len(got.Phone) != len(src.Phone) || got.Phone[0] != '+' {
+        t.Errorf("got phone %q, want the format of %q", got.Phone, src.Phone)
+    }
+    if *got.LastIP != "192.0.2.0" {
+        t.Errorf("got IP %q, want 192.0.2.0", *got.LastIP)
+    }
+    if got.Notes != "vip" || got.nickname != "an" {
+        t.Errorf("got notes %q and nickname %q, want them untagged or unexported", got.Notes, got.nickname)
+    }
+    if got.Referrer != got {
+        t.Errorf("Cloned referrer does not point to the clone")
+    }
+
+    // The source is untouched
+    if src.Name != "Ann" || src.Aliases[0] != "annie" || ip != "192.0.2.17" {
+        t.Errorf("Clone modified the source: %+v", src)
+    }
+}
+
+// Test for scrubbing a clone by type
+func TestCloneTypes(t *testing.T) {
+    type email string
+    type account struct {
+        Primary  *email
+        Backup   *email
+        Contacts map[string]email
+        Extra    interface{}
+    }
+    profile := scrub.NewProfile(nil).Type(reflect.TypeOf(email("")), scrub.HashEmail([]byte("test key")))
+
+    // The shared address is scrubbed once
+    primary := email("ann@example.com")
+    src := account{Primary: &primary, Backup: &primary, Contacts: map[string]email{"work": "ann@corp.example"}, Extra: email("bob@example.com")}
+    got, err := scrub.Clone(profile, src)
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if *got.Primary == primary || !strings.HasSuffix(string(*got.Primary), "@example.com") || got.Primary != got.Backup {
+        t.Errorf("got email %q, want a shared hashed address at example.com", *got.Primary)
+    }
+    if want := scrub.HashEmail([]byte("test key"))(string(primary)); string(*got.Primary) != want {
+        t.Errorf("got email %q, want it hashed once to %q", *got.Primary, want)
+    }
+    if work := got.Contacts["work"]; work == src.Contacts["work"] || !strings.HasSuffix(string(work), "@corp.example") {
+        t.Errorf("got contact %q, want a hashed address at corp.example", work)
+    }
+    if extra := got.Extra.(email); extra == src.Extra || !strings.HasSuffix(string(extra), "@example.com") {
+        t.Errorf("got extra %q, want a hashed address at example.com", extra)
+    }
+}
+
+// Test for scrubbing slices shared by several fields once
+func TestCloneSharedSlices(t *testing.T) {
+    type email string
+    type list struct {
+        To  []email
+        Cc  []email
+        Bcc []string `scrub:"address"`
+        Log []string `scrub:"address"`
+    }
+    key := []byte("test key")
+    hash := scrub.HashEmail(key)
+    profile := scrub.NewProfile(nil).Type(reflect.TypeOf(email("")), hash).Tag("address", hash)
+
+    to := []email{"ann@example.com"}
+    bcc := []string{"bob@example.com"}
+    got, err := scrub.Clone(profile, list{To: to, Cc: to, Bcc: bcc, Log: bcc})
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if want := email(hash("ann@example.com")); got.To[0] != want || got.Cc[0] != want {
+        t.Errorf("got addresses %q and %q, want both hashed once to %q", got.To[0], got.Cc[0], want)
+    }
+    if want := hash("bob@example.com"); got.Bcc[0] != want || got.Log[0] != want {
+        t.Errorf("got addresses %q and %q, want both hashed once to %q", got.Bcc[0], got.Log[0], want)
+    }
+
+    // A prefix of a slice is cloned, and scrubbed, apart from the slice
+    all := []email{"ann@example.com", "bob@example.com"}
+    got, err = scrub.Clone(profile, list{To: all[:1], Cc: all})
+    if err != nil {
+        t.Fatalf("Clone failed: %v", err)
+    }
+    if want := email(hash("ann@example.com")); got.To[0] != want || got.Cc[0] != want || got.Cc[1] != email(hash("bob@example.com")) {
+        t.Errorf("got addresses %q and %q, want each hashed once", got.To, got.Cc)
+    }
+}
+
+// Test for the scrubbers
+func TestScrubbers(t *testing.T) {
+    key := []byte("test key")
+    hash := scrub.HashEmail(key)
+    if hash("ann@example.com") != hash("ann@example.com") || hash("ann@example.com") == hash("bob@example.com") {
+        t.Errorf("HashEmail is not a deterministic hash")
+    }
+    if got := hash("not an address"); strings.Contains(got, " ") {
+        t.Errorf("got %q for a string that is not an address", got)
+    }
+
+    phone := scrub.ScramblePhone(key)("+44 20-7946 0958")
+    for i, c := range "+44 20-7946 0958" {
+        isDigit := c >= '0' && c <= '9'
+        if got := rune(phone[i]); isDigit != (got >= '0' && got <= '9') || !isDigit && got != c {
+            t.Errorf("got phone %q, want the format of +44 20-7946 0958", phone)
+            break
+        }
+    }
+
+    truncate := scrub.TruncateIP(16, 32)
+    for ip, want := range map[string]string{
+        "198.51.100.7":   "198.51.0.0",
+        "2001:db8:1::42": "2001:db8::",
+        "localhost":      "",
+    } {
+        if got := truncate(ip); got != want {
+            t.Errorf("TruncateIP(%q) = %q, want %q", ip, got, want)
+        }
+    }
+}
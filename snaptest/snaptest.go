@@ -0,0 +1,246 @@
+// Package snaptest compares snapshots of values with golden files.
+//
+// A snapshot lists every value reachable from the snapshotted value, one per
+// line, with its path:
+//
+//	.ID = 1
+//	.Lines[0].SKU = "a"
+//	.Notes = {}
+//	.Parent = &.
+//
+// Snapshots are canonical: map entries are sorted by key, nil and empty
+// slices and maps are both written as empty, and pointers to values that
+// were already written refer to their first path, so cycles terminate.
+// Values of types implementing encoding.TextMarshaler or fmt.Stringer, such
+// as time.Time, are written as their text.
+//
+// Golden files are stored in testdata/snapshots, relative to the directory of
+// the test. Run the tests with UPDATE_SNAPSHOTS=1 in the environment to
+// create or update them.
+package snaptest
+
+import (
+    "encoding"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/internal/memref"
+)
+
+// Dir is the directory golden files are stored in.
+const Dir = "testdata/snapshots"
+
+// UpdateEnv is the environment variable that makes Match write golden files
+// instead of comparing them.
+const UpdateEnv = "UPDATE_SNAPSHOTS"
+
+// manager clones values before they are snapshotted. Functions and channels
+// are shared since the snapshot only records whether they are nil, and so
+// are unexported fields, which text marshalers like time.Time depend on.
+var manager = cloner.NewCloneManager(
+    cloner.WithFuncs(cloner.Share),
+    cloner.WithChans(cloner.Share),
+    cloner.WithUnexportedFields(cloner.Share),
+)
+
+// Match compares the snapshot of a clone of v with the golden file named
+// after the test, and fails t with a diff of the changed paths if they
+// differ.
+func Match(t testing.TB, v interface{}) {
+    t.Helper()
+    MatchNamed(t, t.Name(), v)
+}
+
+// MatchNamed is like Match with the golden file given by name, for tests
+// taking several snapshots.
+func MatchNamed(t testing.TB, name string, v interface{}) {
+    t.Helper()
+    clone, err := manager.Clone(v)
+    if err != nil {
+        t.Fatalf("snaptest: %v", err)
+        return
+    }
+    got := Snapshot(clone)
+
+    file := filepath.Join(Dir, strings.ReplaceAll(name, "/", "__")+".snap")
+    if os.Getenv(UpdateEnv) != "" {
+        if err := os.MkdirAll(Dir, 0o755); err != nil {
+            t.Fatalf("snaptest: %v", err)
+        }
+        if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+            t.Fatalf("snaptest: %v", err)
+        }
+        return
+    }
+
+    want, err := os.ReadFile(file)
+    if err != nil {
+        t.Fatalf("snaptest: %v (run with %s=1 to create it)", err, UpdateEnv)
+        return
+    }
+    if diff := Diff(string(want), got); diff != "" {
+        t.Errorf("snaptest: snapshot differs from %s (-golden +got):\n%s", file, diff)
+    }
+}
+
+// Snapshot returns the canonical snapshot of v.
+func Snapshot(v interface{}) string {
+    var b strings.Builder
+    firstPath := make(map[memref.Ref]cloner.Path)
+    line := func(path cloner.Path, value string) {
+        if path == "" {
+            path = "."
+        }
+        fmt.Fprintf(&b, "%s = %s\n", path, value)
+    }
+    if v == nil {
+        line("", "nil")
+        return b.String()
+    }
+
+    cloner.Walk(v, func(node cloner.Node) error {
+        v := node.Value
+        if node.Key {
+            // Keys are part of the path of their entry
+            return cloner.SkipChildren
+        }
+        if !v.IsValid() {
+            line(node.Path, "nil")
+            return nil
+        }
+        if text, ok := asText(v); ok {
+            line(node.Path, text)
+            return cloner.SkipChildren
+        }
+
+        switch v.Kind() {
+        case reflect.Ptr, reflect.Slice, reflect.Map:
+            if node.Shared {
+                ref := firstPath[memref.Of(v)]
+                if ref == "" {
+                    ref = "."
+                }
+                line(node.Path, "&"+string(ref))
+                return nil
+            }
+            if !v.IsNil() {
+                if _, ok := firstPath[memref.Of(v)]; !ok {
+                    firstPath[memref.Of(v)] = node.Path
+                }
+            }
+        }
+
+        switch v.Kind() {
+        case reflect.Ptr, reflect.Interface:
+            if v.IsNil() {
+                line(node.Path, "nil")
+            }
+        case reflect.Slice, reflect.Array:
+            if v.Len() == 0 {
+                line(node.Path, "[]")
+            }
+        case reflect.Map:
+            if v.Len() == 0 {
+                line(node.Path, "{}")
+            }
+        case reflect.Struct:
+            if !hasExportedFields(v.Type()) {
+                line(node.Path, v.Type().String()+"{}")
+            }
+        case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+            if v.IsNil() {
+                line(node.Path, "nil")
+            } else {
+                line(node.Path, v.Kind().String())
+            }
+        case reflect.String:
+            line(node.Path, fmt.Sprintf("%q", v.String()))
+        default:
+            line(node.Path, fmt.Sprint(v))
+        }
+        return nil
+    })
+    return b.String()
+}
+
+// asText returns the text of values implementing encoding.TextMarshaler or
+// fmt.Stringer, other than basic types.
+func asText(v reflect.Value) (string, bool) {
+    if !v.CanInterface() {
+        return "", false
+    }
+    switch v.Kind() {
+    case reflect.Struct, reflect.Array:
+    case reflect.Ptr:
+        if v.IsNil() {
+            return "", false
+        }
+    default:
+        return "", false
+    }
+    switch value := v.Interface().(type) {
+    case encoding.TextMarshaler:
+        text, err := value.MarshalText()
+        if err == nil {
+            return fmt.Sprintf("%q", text), true
+        }
+    case fmt.Stringer:
+        return fmt.Sprintf("%q", value.String()), true
+    }
+    return "", false
+}
+
+func hasExportedFields(t reflect.Type) bool {
+    for i := 0; i < t.NumField(); i++ {
+        if t.Field(i).IsExported() {
+            return true
+        }
+    }
+    return false
+}
+
+// Diff compares two snapshots path by path and returns the removed, added
+// and changed values, one per line, or "" if they are equal.
+func Diff(want, got string) string {
+    wantPaths, wantValues := parse(want)
+    gotPaths, gotValues := parse(got)
+
+    var b strings.Builder
+    for _, path := range wantPaths {
+        if _, ok := gotValues[path]; !ok {
+            fmt.Fprintf(&b, "- %s = %s\n", path, wantValues[path])
+        }
+    }
+    for _, path := range gotPaths {
+        wantValue, ok := wantValues[path]
+        switch {
+        case !ok:
+            fmt.Fprintf(&b, "+ %s = %s\n", path, gotValues[path])
+        case wantValue != gotValues[path]:
+            fmt.Fprintf(&b, "- %s = %s\n+ %s = %s\n", path, wantValue, path, gotValues[path])
+        }
+    }
+    return b.String()
+}
+
+// parse returns the paths of a snapshot in order and their values.
+func parse(snapshot string) ([]string, map[string]string) {
+    var paths []string
+    values := make(map[string]string)
+    for _, line := range strings.Split(strings.TrimSuffix(snapshot, "\n"), "\n") {
+        if line == "" {
+            continue
+        }
+        path, value, _ := strings.Cut(line, " = ")
+        if _, ok := values[path]; !ok {
+            paths = append(paths, path)
+        }
+        values[path] = value
+    }
+    return paths, values
+}
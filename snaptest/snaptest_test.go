@@ -0,0 +1,140 @@
+package snaptest_test
+
+import (
+    "fmt"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/jayaprabhakar/go-deeper/snaptest"
+)
+
+// Test for the canonical snapshot of a value
+func TestSnapshot(t *testing.T) {
+    type node struct {
+        Name string
+        Next *node
+    }
+    loop := &node{Name: "a"}
+    loop.Next = loop
+    shared := &node{Name: "b"}
+
+    tests := []struct {
+        name  string
+        value interface{}
+        want  string
+    }{
+        {"nil", nil, ". = nil\n"},
+        {"cycle", loop, ".Name = \"a\"\n.Next = &.\n"},
+        {
+            name:  "shared",
+            value: []*node{shared, shared},
+            want:  "[0].Name = \"b\"\n[0].Next = nil\n[1] = &[0]\n",
+        },
+        {
+            name:  "sorted keys",
+            value: map[string]int{"z": 1, "a": 2},
+            want:  "[\"a\"] = 2\n[\"z\"] = 1\n",
+        },
+        {
+            name: "text",
+            value: struct {
+                At      time.Time
+                Site    *url.URL
+                Timeout time.Duration
+            }{time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC), &url.URL{Scheme: "https", Host: "example.com"}, time.Second},
+            want: ".At = \"2024-05-06T07:08:09Z\"\n.Site = \"https://example.com\"\n.Timeout = 1s\n",
+        },
+        {
+            name: "opaque",
+            value: struct {
+                Extra  interface{}
+                OnSave func()
+                Done   chan bool
+                Lock   struct{ held bool }
+            }{OnSave: func() {}},
+            want: ".Extra = nil\n.OnSave = func\n.Done = nil\n.Lock = struct { held bool }{}\n",
+        },
+    }
+    for _, tt := range tests {
+        if got := snaptest.Snapshot(tt.value); got != tt.want {
+            t.Errorf("%s: got:\n%s\nwant:\n%s", tt.name, got, tt.want)
+        }
+    }
+
+    // Nil and empty containers have the same snapshot
+    type list struct {
+        Items []int
+        Index map[string]int
+    }
+    if a, b := snaptest.Snapshot(list{}), snaptest.Snapshot(list{Items: []int{}, Index: map[string]int{}}); a != b {
+        t.Errorf("Snapshots of nil and empty containers differ:\n%s\n%s", a, b)
+    }
+}
+
+// line and order are the values snapshotted in testdata/snapshots.
+type line struct {
+    SKU      string
+    Quantity int
+}
+
+type order struct {
+    ID    int
+    Lines []line
+    Notes map[string]string
+}
+
+// Test for comparing snapshots with a golden file
+func TestMatch(t *testing.T) {
+    o := order{ID: 7, Lines: []line{{"b", 2}, {"a", 1}}, Notes: map[string]string{"z": "last", "a": "first"}}
+    snaptest.Match(t, o)
+    snaptest.MatchNamed(t, "lines", o.Lines)
+}
+
+// fakeT records the failures of a test instead of failing it.
+type fakeT struct {
+    testing.TB
+    failures []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Name() string {
+    return "TestMatch"
+}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+    f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+    f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+// Test for reporting the paths that differ from the golden file
+func TestMatchDiff(t *testing.T) {
+    // TestMatch.snap holds a second line and no "m" note
+    o := order{ID: 8, Lines: []line{{"b", 2}}, Notes: map[string]string{"z": "last", "m": "middle", "a": "first"}}
+
+    f := &fakeT{TB: t}
+    snaptest.Match(f, o)
+    if len(f.failures) != 1 {
+        t.Fatalf("got failures %q, want 1", f.failures)
+    }
+    for _, want := range []string{
+        "- .ID = 7\n+ .ID = 8\n",
+        "- .Lines[1].SKU = \"a\"\n",
+        "+ .Notes[\"m\"] = \"middle\"\n",
+    } {
+        if !strings.Contains(f.failures[0], want) {
+            t.Errorf("Diff does not contain %q:\n%s", want, f.failures[0])
+        }
+    }
+
+    f = &fakeT{TB: t}
+    snaptest.MatchNamed(f, "missing", o)
+    if len(f.failures) != 1 || !strings.Contains(f.failures[0], snaptest.UpdateEnv) {
+        t.Errorf("got failures %q, want a hint to create the golden file", f.failures)
+    }
+}
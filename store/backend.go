@@ -0,0 +1,172 @@
+package store
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// ErrNotFound is returned by a Backend reading a blob that does not exist,
+// and by Store for keys without snapshots.
+var ErrNotFound = errors.New("store: not found")
+
+// Backend stores named blobs, in the manner of an object store such as S3.
+// Names are slash-separated paths. Implementations must be safe for
+// concurrent use.
+type Backend interface {
+    // Put stores the content of r as the blob name, replacing any previous
+    // blob of that name.
+    Put(ctx context.Context, name string, r io.Reader) error
+    // Get opens the blob name, or returns ErrNotFound.
+    Get(ctx context.Context, name string) (io.ReadCloser, error)
+    // List returns the names of the blobs starting with prefix, sorted.
+    List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Dir is a Backend storing blobs as files under a directory. Blobs are
+// written to a temporary file first, so that a failed Put never leaves a
+// partial blob behind.
+type Dir string
+
+// path returns the path of the file of the blob name, which must not lead
+// out of the directory.
+func (d Dir) path(name string) (string, error) {
+    local := filepath.FromSlash(name)
+    if !filepath.IsLocal(local) {
+        return "", fmt.Errorf("store: invalid blob name %q", name)
+    }
+    return filepath.Join(string(d), local), nil
+}
+
+func (d Dir) Put(ctx context.Context, name string, r io.Reader) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    path, err := d.path(name)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return err
+    }
+    f, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+    if err != nil {
+        return err
+    }
+    _, err = io.Copy(f, r)
+    if closeErr := f.Close(); err == nil {
+        err = closeErr
+    }
+    if err == nil {
+        err = os.Rename(f.Name(), path)
+    }
+    if err != nil {
+        os.Remove(f.Name())
+    }
+    return err
+}
+
+func (d Dir) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    path, err := d.path(name)
+    if err != nil {
+        return nil, err
+    }
+    f, err := os.Open(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil, ErrNotFound
+    }
+    return f, err
+}
+
+func (d Dir) List(ctx context.Context, prefix string) ([]string, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    var names []string
+    err := filepath.WalkDir(string(d), func(path string, entry fs.DirEntry, err error) error {
+        if err != nil {
+            if errors.Is(err, fs.ErrNotExist) {
+                return nil
+            }
+            return err
+        }
+        if entry.IsDir() || strings.HasPrefix(entry.Name(), ".put-") {
+            return nil
+        }
+        rel, err := filepath.Rel(string(d), path)
+        if err != nil {
+            return err
+        }
+        if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+            names = append(names, name)
+        }
+        return nil
+    })
+    sort.Strings(names)
+    return names, err
+}
+
+// Memory is a Backend keeping blobs in memory, for tests and caches.
+type Memory struct {
+    mu    sync.RWMutex
+    blobs map[string][]byte
+}
+
+// NewMemory returns an empty Memory backend.
+func NewMemory() *Memory {
+    return &Memory{blobs: make(map[string][]byte)}
+}
+
+func (m *Memory) Put(ctx context.Context, name string, r io.Reader) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return err
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.blobs[name] = data
+    return nil
+}
+
+func (m *Memory) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    data, ok := m.blobs[name]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *Memory) List(ctx context.Context, prefix string) ([]string, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    var names []string
+    for name := range m.blobs {
+        if strings.HasPrefix(name, prefix) {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names, nil
+}
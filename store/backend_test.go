@@ -0,0 +1,76 @@
+package store_test
+
+import (
+    "context"
+    "errors"
+    "io"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/store"
+)
+
+// Test for storing and listing blobs in a directory
+func TestDir(t *testing.T) {
+    ctx := context.Background()
+    dir := store.Dir(t.TempDir())
+
+    for _, name := range []string{"a/1", "a/2", "b/1"} {
+        if err := dir.Put(ctx, name, strings.NewReader(name)); err != nil {
+            t.Fatalf("Put failed: %v", err)
+        }
+    }
+    names, err := dir.List(ctx, "a/")
+    if err != nil || !reflect.DeepEqual(names, []string{"a/1", "a/2"}) {
+        t.Errorf("got names %v, %v, want [a/1 a/2]", names, err)
+    }
+
+    r, err := dir.Get(ctx, "b/1")
+    if err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    data, _ := io.ReadAll(r)
+    r.Close()
+    if string(data) != "b/1" {
+        t.Errorf("got %q, want %q", data, "b/1")
+    }
+    if _, err := dir.Get(ctx, "c"); !errors.Is(err, store.ErrNotFound) {
+        t.Errorf("got error %v, want ErrNotFound", err)
+    }
+
+    // Failed writes leave nothing behind
+    failure := errors.New("broken")
+    if err := dir.Put(ctx, "a/3", io.MultiReader(strings.NewReader("partial"), errReader{failure})); err != failure {
+        t.Errorf("got error %v, want %v", err, failure)
+    }
+    if names, _ := dir.List(ctx, ""); len(names) != 3 {
+        t.Errorf("got names %v after a failed Put, want 3 names", names)
+    }
+    if names, err := store.Dir(t.TempDir()+"/missing").List(ctx, ""); err != nil || len(names) != 0 {
+        t.Errorf("got names %v, %v for a missing directory, want none", names, err)
+    }
+
+    // Blobs cannot be stored or read out of the directory
+    outer := t.TempDir()
+    nested := store.Dir(outer + "/store")
+    for _, name := range []string{"../escaped", "a/../../escaped", "/escaped", ""} {
+        if err := nested.Put(ctx, name, strings.NewReader("x")); err == nil {
+            t.Errorf("Put accepted the name %q", name)
+        }
+        if _, err := nested.Get(ctx, name); err == nil {
+            t.Errorf("Get accepted the name %q", name)
+        }
+    }
+    if names, _ := store.Dir(outer).List(ctx, ""); len(names) != 0 {
+        t.Errorf("got names %v out of the store directory, want none", names)
+    }
+}
+
+type errReader struct {
+    err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+    return 0, r.err
+}
@@ -0,0 +1,164 @@
+// Package store checkpoints object graphs to a Backend, such as a directory
+// or an object store, under versioned keys. Snapshots are streamed with
+// cloner.CloneTo, so that a graph is saved without being copied in memory
+// first, and read back with cloner.CloneFrom, which restores its shared
+// references and cycles.
+package store
+
+import (
+    "context"
+    "encoding/gob"
+    "fmt"
+    "io"
+    "path"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Store saves and loads snapshots of values. Every Save of a key adds a new
+// version; Load returns the latest one.
+type Store struct {
+    backend Backend
+    manager *cloner.CloneManager
+    mu      sync.Mutex // serializes Save, so that versions are not reused
+}
+
+// New returns a Store saving snapshots to backend with the configuration of
+// manager, or of cloner.Default if manager is nil. Versions are numbered by
+// the Store: stores saving the same keys to a shared backend concurrently
+// must be coordinated by the caller.
+func New(backend Backend, manager *cloner.CloneManager) *Store {
+    if manager == nil {
+        manager = cloner.Default()
+    }
+    return &Store{backend: backend, manager: manager}
+}
+
+// name returns the name of the blob holding version of key.
+func name(key string, version int) string {
+    return fmt.Sprintf("%s/%08d", key, version)
+}
+
+// checkKey reports keys that are not clean relative slash-separated paths,
+// such as keys ending with a slash or leading out of the store with "..".
+func checkKey(key string) error {
+    if key == "" || path.Clean(key) != key || path.IsAbs(key) ||
+        key == "." || key == ".." || strings.HasPrefix(key, "../") {
+        return fmt.Errorf("store: invalid key %q", key)
+    }
+    return nil
+}
+
+// Save stores a snapshot of v as the next version of key and returns that
+// version, numbered from 1. The dynamic types of interface values in v must
+// be registered with cloner.RegisterType.
+func (s *Store) Save(ctx context.Context, key string, v interface{}) (int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    versions, err := s.Versions(ctx, key)
+    if err != nil {
+        return 0, err
+    }
+    version := 1
+    if len(versions) > 0 {
+        version = versions[len(versions)-1] + 1
+    }
+
+    // The snapshot is streamed to the backend while it is encoded
+    r, w := io.Pipe()
+    done := make(chan error, 1)
+    go func() {
+        err := s.manager.CloneTo(gob.NewEncoder(ctxWriter{ctx, w}), v)
+        w.CloseWithError(err)
+        done <- err
+    }()
+    err = s.backend.Put(ctx, name(key, version), r)
+    r.CloseWithError(err)
+    if cloneErr := <-done; err == nil {
+        err = cloneErr
+    }
+    if err != nil {
+        return 0, err
+    }
+    return version, nil
+}
+
+// Load reads the latest version of key into the value dst points to. It
+// returns ErrNotFound if key has no snapshot.
+func (s *Store) Load(ctx context.Context, key string, dst interface{}) error {
+    versions, err := s.Versions(ctx, key)
+    if err != nil {
+        return err
+    }
+    if len(versions) == 0 {
+        return ErrNotFound
+    }
+    return s.LoadVersion(ctx, key, versions[len(versions)-1], dst)
+}
+
+// LoadVersion reads version of key into the value dst points to.
+func (s *Store) LoadVersion(ctx context.Context, key string, version int, dst interface{}) error {
+    if err := checkKey(key); err != nil {
+        return err
+    }
+    r, err := s.backend.Get(ctx, name(key, version))
+    if err != nil {
+        return err
+    }
+    defer r.Close()
+    err = s.manager.CloneFrom(gob.NewDecoder(ctxReader{ctx, r}), dst)
+    if err == io.EOF {
+        err = io.ErrUnexpectedEOF
+    }
+    return err
+}
+
+// Versions returns the versions of key in the backend, in increasing order.
+func (s *Store) Versions(ctx context.Context, key string) ([]int, error) {
+    if err := checkKey(key); err != nil {
+        return nil, err
+    }
+    names, err := s.backend.List(ctx, key+"/")
+    if err != nil {
+        return nil, err
+    }
+    var versions []int
+    for _, name := range names {
+        suffix := strings.TrimPrefix(name, key+"/")
+        if version, err := strconv.Atoi(suffix); err == nil && version > 0 && len(suffix) >= 8 {
+            versions = append(versions, version)
+        }
+    }
+    sort.Ints(versions)
+    return versions, nil
+}
+
+// ctxWriter is a Writer failing once its context is done.
+type ctxWriter struct {
+    ctx context.Context
+    w   io.Writer
+}
+
+func (w ctxWriter) Write(p []byte) (int, error) {
+    if err := w.ctx.Err(); err != nil {
+        return 0, err
+    }
+    return w.w.Write(p)
+}
+
+// ctxReader is a Reader failing once its context is done.
+type ctxReader struct {
+    ctx context.Context
+    r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+    if err := r.ctx.Err(); err != nil {
+        return 0, err
+    }
+    return r.r.Read(p)
+}
@@ -0,0 +1,105 @@
+package store_test
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/store"
+)
+
+// Test for saving and loading versioned snapshots
+func TestSaveLoad(t *testing.T) {
+    ctx := context.Background()
+    for name, backend := range map[string]store.Backend{
+        "dir":    store.Dir(t.TempDir()),
+        "memory": store.NewMemory(),
+    } {
+        t.Run(name, func(t *testing.T) {
+            type task struct {
+                Name    string
+                Depends []*task
+            }
+            build := &task{Name: "build"}
+            original := map[string]*task{"build": build, "test": {Name: "test", Depends: []*task{build}}}
+
+            s := store.New(backend, nil)
+            if version, err := s.Save(ctx, "jobs/nightly", original); err != nil || version != 1 {
+                t.Fatalf("Save returned %d, %v, want version 1", version, err)
+            }
+            original["deploy"] = &task{Name: "deploy"}
+            if version, err := s.Save(ctx, "jobs/nightly", original); err != nil || version != 2 {
+                t.Fatalf("Save returned %d, %v, want version 2", version, err)
+            }
+
+            var latest map[string]*task
+            if err := s.Load(ctx, "jobs/nightly", &latest); err != nil {
+                t.Fatalf("Load failed: %v", err)
+            }
+            if !reflect.DeepEqual(latest, original) {
+                t.Errorf("got = %+v, want = %+v", latest, original)
+            }
+            if latest["test"].Depends[0] != latest["build"] {
+                t.Errorf("Loaded dependency does not point to the loaded task")
+            }
+
+            // Saved versions do not change with the value they were saved from
+            build.Name = "compile"
+            var first map[string]*task
+            if err := s.LoadVersion(ctx, "jobs/nightly", 1, &first); err != nil {
+                t.Fatalf("LoadVersion failed: %v", err)
+            }
+            if len(first) != 2 || first["build"].Name != "build" {
+                t.Errorf("got %+v in version 1, want the build and test tasks", first)
+            }
+            versions, err := s.Versions(ctx, "jobs/nightly")
+            if err != nil || !reflect.DeepEqual(versions, []int{1, 2}) {
+                t.Errorf("got versions %v, %v, want [1 2]", versions, err)
+            }
+
+            if err := s.Load(ctx, "jobs", &latest); !errors.Is(err, store.ErrNotFound) {
+                t.Errorf("got error %v, want ErrNotFound", err)
+            }
+        })
+    }
+}
+
+// Test for snapshots that cannot be saved
+func TestSaveErrors(t *testing.T) {
+    ctx := context.Background()
+    s := store.New(store.NewMemory(), nil)
+
+    type job struct {
+        Name   string
+        Failed func()
+    }
+    state := job{Name: "nightly", Failed: func() {}}
+    if _, err := s.Save(ctx, "state", state); !errors.Is(err, cloner.ErrUncloneableKind) {
+        t.Errorf("got error %v, want ErrUncloneableKind", err)
+    }
+    if versions, _ := s.Versions(ctx, "state"); len(versions) != 0 {
+        t.Errorf("got versions %v after a failed Save, want none", versions)
+    }
+
+    // The options of the manager apply
+    s = store.New(store.NewMemory(), cloner.NewCloneManager(cloner.WithFuncs(cloner.Zero)))
+    if _, err := s.Save(ctx, "state", state); err != nil {
+        t.Errorf("Save failed: %v", err)
+    }
+
+    cancelled, cancel := context.WithCancel(ctx)
+    cancel()
+    if _, err := s.Save(cancelled, "state", job{Name: "nightly"}); !errors.Is(err, context.Canceled) {
+        t.Errorf("got error %v, want context.Canceled", err)
+    }
+    for _, key := range []string{"", "state/", "..", "../state", "a/../../state", "/state", "./state", "a//b"} {
+        if _, err := s.Save(ctx, key, state); err == nil {
+            t.Errorf("Save accepted the key %q", key)
+        }
+        if err := s.Load(ctx, key, &state); err == nil || errors.Is(err, store.ErrNotFound) {
+            t.Errorf("got error %v loading the key %q, want an invalid key", err, key)
+        }
+    }
+}
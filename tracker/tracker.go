@@ -0,0 +1,63 @@
+// Package tracker keeps snapshots of in-memory state and reports what
+// changed between them, for change-data-capture on values that are updated
+// in place.
+package tracker
+
+import (
+    "sync"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Tracker holds the last snapshot of a value of type T. It is safe for
+// concurrent use.
+type Tracker[T any] struct {
+    cm   *cloner.CloneManager
+    opts []cloner.EqualOption
+
+    mutex    sync.Mutex
+    snapshot T
+    taken    bool
+}
+
+// New returns a Tracker taking snapshots with cm and comparing them with
+// opts, e.g. cloner.IgnorePaths for fields that change on every update.
+func New[T any](cm *cloner.CloneManager, opts ...cloner.EqualOption) *Tracker[T] {
+    return &Tracker[T]{cm: cm, opts: opts}
+}
+
+// CloneAndDiff clones src as the new snapshot and returns it with the
+// differences from the previous snapshot to it, as reported by cloner.Diff.
+// The first snapshot is compared with the zero value of T. The snapshot is
+// kept by the tracker to compute the next differences and must not be
+// changed. If src cannot be cloned, the previous snapshot is kept and the
+// error is returned.
+func (t *Tracker[T]) CloneAndDiff(src T) (T, []cloner.Difference, error) {
+    snapshot, err := cloner.Clone(t.cm, src)
+    if err != nil {
+        var zero T
+        return zero, nil, err
+    }
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+    diffs := cloner.Diff(t.snapshot, snapshot, t.opts...)
+    t.snapshot, t.taken = snapshot, true
+    return snapshot, diffs, nil
+}
+
+// Snapshot returns the last snapshot, which must not be changed, and false
+// if none was taken.
+func (t *Tracker[T]) Snapshot() (T, bool) {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+    return t.snapshot, t.taken
+}
+
+// Reset drops the last snapshot, so that the next one is compared with the
+// zero value of T.
+func (t *Tracker[T]) Reset() {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+    var zero T
+    t.snapshot, t.taken = zero, false
+}
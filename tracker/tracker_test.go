@@ -0,0 +1,54 @@
+package tracker_test
+
+import (
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/tracker"
+)
+
+type Inventory struct {
+    Version int
+    Stock   map[string]int
+    Owners  []string
+}
+
+// Test for diffing the snapshots of a value updated in place
+func TestCloneAndDiff(t *testing.T) {
+    state := &Inventory{Version: 1, Stock: map[string]int{"apple": 3}}
+    tr := tracker.New[*Inventory](cloner.NewCloneManager(), cloner.IgnorePaths("Version"))
+    if _, ok := tr.Snapshot(); ok {
+        t.Error("got a snapshot before the first one")
+    }
+
+    first, diffs, err := tr.CloneAndDiff(state)
+    if err != nil || first == state || len(diffs) != 1 || diffs[0].Path != "" {
+        t.Fatalf("got snapshot %v, diffs %v and error %v, want the root added", first, diffs, err)
+    }
+
+    state.Version++
+    state.Stock["apple"] = 2
+    state.Stock["pear"] = 5
+    second, diffs, err := tr.CloneAndDiff(state)
+    if err != nil {
+        t.Fatalf("CloneAndDiff failed: %v", err)
+    }
+    var paths []string
+    for _, d := range diffs {
+        paths = append(paths, string(d.Path))
+    }
+    if len(paths) != 2 || paths[0] != `.Stock["apple"]` || paths[1] != `.Stock["pear"]` {
+        t.Errorf("got diffs %v, want the stock changes", diffs)
+    }
+    if first.Stock["apple"] != 3 || second.Stock["apple"] != 2 {
+        t.Errorf("got snapshots %v and %v, want them isolated from the state", first, second)
+    }
+
+    if _, diffs, _ := tr.CloneAndDiff(state); len(diffs) != 0 {
+        t.Errorf("got diffs %v, want none", diffs)
+    }
+    tr.Reset()
+    if snapshot, ok := tr.Snapshot(); ok || snapshot != nil {
+        t.Errorf("got snapshot %v after Reset", snapshot)
+    }
+}
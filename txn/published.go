@@ -0,0 +1,51 @@
+package txn
+
+import (
+    "sync"
+    "sync/atomic"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Published holds a value of type T that readers load without locks while
+// writers replace it with updated clones, so that every reader sees a
+// complete version of the value, never one being changed. The zero
+// Published is not usable; create one with NewPublished.
+type Published[T any] struct {
+    cm      *cloner.CloneManager
+    mutex   sync.Mutex // serializes updates
+    current atomic.Pointer[T]
+}
+
+// NewPublished returns a Published holding v and cloning it with cm.
+func NewPublished[T any](cm *cloner.CloneManager, v T) *Published[T] {
+    p := &Published[T]{cm: cm}
+    p.current.Store(&v)
+    return p
+}
+
+// Load returns the current version of the value. It is shared by every
+// reader of the version and must not be changed.
+func (p *Published[T]) Load() *T {
+    return p.current.Load()
+}
+
+// Update clones the current version of the value and calls mutate with the
+// clone, which is published as the new version if mutate returns nil, as
+// with DoWith. Updates are serialized; readers keep loading the previous
+// version until the new one is published.
+//
+// As with DoWith, an update of a value whose unexported fields hold data
+// fails with cloner.ErrUnexportedField rather than publishing a version
+// without them, unless the manager of p sets a policy for such fields with
+// cloner.WithUnexportedFields.
+func (p *Published[T]) Update(mutate func(*T) error) error {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    next := *p.current.Load()
+    if err := DoWith(p.cm, &next, mutate); err != nil {
+        return err
+    }
+    p.current.Store(&next)
+    return nil
+}
@@ -0,0 +1,80 @@
+package txn_test
+
+import (
+    "errors"
+    "sync"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/txn"
+)
+
+type Routes struct {
+    Version  int
+    Backends map[string][]string
+}
+
+// Test for publishing updated versions to concurrent readers
+func TestPublished(t *testing.T) {
+    p := txn.NewPublished(cloner.NewCloneManager(), Routes{Backends: map[string][]string{"api": {"a"}}})
+    first := p.Load()
+
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(2)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 50; j++ {
+                routes := p.Load()
+                if len(routes.Backends["api"]) != routes.Version+1 {
+                    t.Errorf("got %d backends in version %d", len(routes.Backends["api"]), routes.Version)
+                    return
+                }
+            }
+        }()
+        go func() {
+            defer wg.Done()
+            p.Update(func(r *Routes) error {
+                r.Backends["api"] = append(r.Backends["api"], "b")
+                r.Version++
+                return nil
+            })
+        }()
+    }
+    wg.Wait()
+
+    if routes := p.Load(); routes.Version != 4 || first.Version != 0 || len(first.Backends["api"]) != 1 {
+        t.Errorf("got versions %+v and %+v, want 4 updates isolated from the first", routes, first)
+    }
+    errFailed := errors.New("failed")
+    if err := p.Update(func(r *Routes) error {
+        r.Version = -1
+        return errFailed
+    }); err != errFailed || p.Load().Version != 4 {
+        t.Errorf("got error %v and version %d, want the update discarded", err, p.Load().Version)
+    }
+}
+
+// Test for updates of values with unexported fields
+func TestPublishedUnexportedFields(t *testing.T) {
+    type limits struct {
+        Rate  int
+        burst int
+    }
+    p := txn.NewPublished(cloner.NewCloneManager(), limits{Rate: 10, burst: 20})
+    err := p.Update(func(l *limits) error {
+        l.Rate = 5
+        return nil
+    })
+    if !errors.Is(err, cloner.ErrUnexportedField) || *p.Load() != (limits{Rate: 10, burst: 20}) {
+        t.Errorf("got error %v and version %+v, want the update rejected", err, *p.Load())
+    }
+
+    p = txn.NewPublished(cloner.NewCloneManager(cloner.WithUnexportedFields(cloner.Share)), limits{Rate: 10, burst: 20})
+    if err := p.Update(func(l *limits) error {
+        l.Rate = 5
+        return nil
+    }); err != nil || *p.Load() != (limits{Rate: 5, burst: 20}) {
+        t.Errorf("got error %v and version %+v, want the burst kept", err, *p.Load())
+    }
+}
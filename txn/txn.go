@@ -0,0 +1,55 @@
+// Package txn updates in-memory state all or nothing: changes are made to a
+// clone of the state, which replaces it only if every change succeeds.
+// Published applies such updates to state read concurrently without locks.
+package txn
+
+import "github.com/jayaprabhakar/go-deeper/cloner"
+
+// Do is DoWith for the default manager of cloner.
+func Do[T any](v *T, mutate func(*T) error, opts ...cloner.Option) error {
+    return DoWith(cloner.Default(), v, mutate, opts...)
+}
+
+// DoWith clones *v with cm and calls mutate with a pointer to the clone. If
+// mutate returns nil, the clone is stored in *v; otherwise, or if mutate
+// panics, *v is left as it was and the clone is discarded, so that none of
+// the changes of mutate are seen. The error of mutate, or of the clone, is
+// returned. Options apply to the clone as in cloner.Clone.
+//
+// The clone replaces *v as a whole, so unexported fields it would leave at
+// their zero value, as managers do by default, would be lost by every
+// transaction: unless cm or opts set a policy with
+// cloner.WithUnexportedFields, DoWith reports unexported fields holding
+// data as cloner.ErrUnexportedField and leaves *v as it was. Types cloning
+// themselves, such as Cloneable types, keep their unexported fields.
+//
+// Only the value stored in *v is replaced: code holding pointers into the
+// previous state keeps seeing it unchanged. Callers updating v concurrently
+// must synchronize.
+//
+//	err := txn.Do(&accounts, func(a *Accounts) error {
+//	    if err := a.Withdraw("alice", 10); err != nil {
+//	        return err
+//	    }
+//	    return a.Deposit("bob", 10)
+//	})
+func DoWith[T any](cm *cloner.CloneManager, v *T, mutate func(*T) error, opts ...cloner.Option) error {
+    clone, err := cloner.Clone(cm, *v, append(keepUnexported(cm), opts...)...)
+    if err != nil {
+        return err
+    }
+    if err := mutate(&clone); err != nil {
+        return err
+    }
+    *v = clone
+    return nil
+}
+
+// keepUnexported returns the options making clones with cm report the
+// unexported fields holding data that they would reset to zero.
+func keepUnexported(cm *cloner.CloneManager) []cloner.Option {
+    if cm.Config().UnexportedFields != cloner.Zero {
+        return nil
+    }
+    return []cloner.Option{cloner.WithUnexportedFields(cloner.Error)}
+}
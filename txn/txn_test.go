@@ -0,0 +1,99 @@
+package txn_test
+
+import (
+    "errors"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/txn"
+)
+
+type Accounts struct {
+    Balances map[string]int
+}
+
+var errInsufficient = errors.New("insufficient funds")
+
+func (a *Accounts) Transfer(from, to string, amount int) error {
+    a.Balances[to] += amount
+    if a.Balances[from] < amount {
+        return errInsufficient
+    }
+    a.Balances[from] -= amount
+    return nil
+}
+
+// Test for updates applied all or nothing
+func TestDo(t *testing.T) {
+    accounts := Accounts{Balances: map[string]int{"alice": 10}}
+    before := accounts.Balances
+
+    if err := txn.Do(&accounts, func(a *Accounts) error {
+        return a.Transfer("alice", "bob", 4)
+    }); err != nil {
+        t.Fatalf("Do failed: %v", err)
+    }
+    if accounts.Balances["alice"] != 6 || accounts.Balances["bob"] != 4 || before["alice"] != 10 {
+        t.Errorf("got balances %v, want the transfer applied to a clone of %v", accounts.Balances, before)
+    }
+
+    // The deposit made before the failure is rolled back
+    err := txn.Do(&accounts, func(a *Accounts) error {
+        return a.Transfer("alice", "bob", 40)
+    })
+    if !errors.Is(err, errInsufficient) || accounts.Balances["bob"] != 4 {
+        t.Errorf("got error %v and balances %v, want the transfer rolled back", err, accounts.Balances)
+    }
+
+    func() {
+        defer func() {
+            recover()
+        }()
+        txn.Do(&accounts, func(a *Accounts) error {
+            a.Balances["bob"] = 0
+            panic("crash")
+        })
+    }()
+    if accounts.Balances["bob"] != 4 {
+        t.Errorf("got balances %v, want the changes of a panicking update discarded", accounts.Balances)
+    }
+}
+
+// Test for transactions on values with unexported fields
+func TestDoUnexportedFields(t *testing.T) {
+    type session struct {
+        User  string
+        token string
+    }
+    s := session{User: "alice", token: "secret"}
+    err := txn.Do(&s, func(s *session) error {
+        s.User = "bob"
+        return nil
+    })
+    if !errors.Is(err, cloner.ErrUnexportedField) || s != (session{User: "alice", token: "secret"}) {
+        t.Errorf("got error %v and session %+v, want the unexported token reported and kept", err, s)
+    }
+
+    cm := cloner.NewCloneManager(cloner.WithUnexportedFields(cloner.Share))
+    if err := txn.DoWith(cm, &s, func(s *session) error {
+        s.User = "bob"
+        return nil
+    }); err != nil || s != (session{User: "bob", token: "secret"}) {
+        t.Errorf("got error %v and session %+v, want the token shared", err, s)
+    }
+
+    anonymous := session{User: "guest"}
+    if err := txn.Do(&anonymous, func(s *session) error {
+        s.User = "carol"
+        return nil
+    }); err != nil || anonymous.User != "carol" {
+        t.Errorf("got error %v and session %+v, want zero unexported fields accepted", err, anonymous)
+    }
+
+    // Zero is applied only when asked for
+    if err := txn.Do(&s, func(s *session) error {
+        return nil
+    }, cloner.WithUnexportedFields(cloner.Zero)); err != nil || s.token != "" {
+        t.Errorf("got error %v and session %+v, want the token dropped", err, s)
+    }
+}
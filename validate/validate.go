@@ -0,0 +1,107 @@
+// Package validate checks the integrity of object graphs, such as snapshots
+// rebuilt from storage, by calling the Validate method of every value that
+// has one and collecting the failures with their paths.
+package validate
+
+import (
+    "reflect"
+    "strings"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// Validator is implemented by values that check their own integrity.
+type Validator interface {
+    Validate() error
+}
+
+// Failure is an error returned by the Validate method of the value at Path.
+type Failure struct {
+    Path cloner.Path
+    Err  error
+}
+
+func (f *Failure) Error() string {
+    if f.Path == "" {
+        return f.Err.Error()
+    }
+    return string(f.Path) + ": " + f.Err.Error()
+}
+
+func (f *Failure) Unwrap() error {
+    return f.Err
+}
+
+// Failures is the error returned by All when some values are invalid.
+type Failures []*Failure
+
+func (fs Failures) Error() string {
+    lines := make([]string, len(fs))
+    for i, f := range fs {
+        lines[i] = f.Error()
+    }
+    return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the failures, so that errors.Is and errors.As look into
+// them.
+func (fs Failures) Unwrap() []error {
+    errs := make([]error, len(fs))
+    for i, f := range fs {
+        errs[i] = f
+    }
+    return errs
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// All calls Validate on every value in v that implements Validator, in the
+// order of cloner.Walk, and returns the failures as Failures, or nil if
+// every value is valid. Memory referenced more than once is validated once.
+// Methods with a pointer receiver are called for values reached through a
+// pointer or stored in addressable memory, such as slice elements.
+func All(v interface{}) error {
+    var failures Failures
+    cloner.Walk(v, func(node cloner.Node) error {
+        if node.Shared {
+            return nil
+        }
+        if validator, ok := validatorOf(node); ok {
+            if err := validator.Validate(); err != nil {
+                failures = append(failures, &Failure{Path: node.Path, Err: err})
+            }
+        }
+        return nil
+    })
+    if len(failures) == 0 {
+        return nil
+    }
+    return failures
+}
+
+// validatorOf returns the Validator of the value of node, if any. A pointer
+// whose element type implements Validator is skipped in favor of its
+// element, so that every value is validated once.
+func validatorOf(node cloner.Node) (Validator, bool) {
+    v := node.Value
+    if !v.IsValid() || !v.CanInterface() {
+        return nil, false
+    }
+    t := v.Type()
+    switch {
+    case t.Kind() == reflect.Ptr:
+        if v.IsNil() || t.Elem().Implements(validatorType) || !t.Implements(validatorType) {
+            return nil, false
+        }
+    case t.Implements(validatorType):
+    case v.CanAddr() && reflect.PointerTo(t).Implements(validatorType):
+        if node.Parent.Kind() == reflect.Ptr {
+            // Validated through its pointer
+            return nil, false
+        }
+        v = v.Addr()
+    default:
+        return nil, false
+    }
+    return v.Interface().(Validator), true
+}
@@ -0,0 +1,75 @@
+package validate_test
+
+import (
+    "errors"
+    "fmt"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/validate"
+)
+
+type Graph struct {
+    Nodes []Node
+    Edges []*Edge
+    Root  *Node
+}
+
+type Node struct {
+    Name string
+}
+
+func (n *Node) Validate() error {
+    if n.Name == "" {
+        return errors.New("unnamed node")
+    }
+    return nil
+}
+
+type Edge struct {
+    From, To int
+}
+
+var errSelfLoop = errors.New("self loop")
+
+func (e Edge) Validate() error {
+    if e.From == e.To {
+        return fmt.Errorf("%w at %d", errSelfLoop, e.From)
+    }
+    return nil
+}
+
+// Test for validating every value of a graph
+func TestAll(t *testing.T) {
+    loop := &Edge{From: 1, To: 1}
+    g := &Graph{
+        Nodes: []Node{{Name: "a"}, {}},
+        Edges: []*Edge{{From: 0, To: 1}, loop, loop},
+        Root:  &Node{},
+    }
+
+    err := validate.All(g)
+    var failures validate.Failures
+    if !errors.As(err, &failures) {
+        t.Fatalf("got error %v, want Failures", err)
+    }
+    var got []string
+    for _, f := range failures {
+        got = append(got, f.Error())
+    }
+    want := []string{
+        ".Nodes[1]: unnamed node",
+        ".Edges[1]: self loop at 1",
+        ".Root: unnamed node",
+    }
+    if fmt.Sprint(got) != fmt.Sprint(want) {
+        t.Errorf("got failures %q, want %q", got, want)
+    }
+    if !errors.Is(err, errSelfLoop) {
+        t.Errorf("errors.Is did not find the failure of an edge in %v", err)
+    }
+
+    g.Nodes[1].Name, g.Root.Name, loop.To = "b", "r", 2
+    if err := validate.All(g); err != nil {
+        t.Errorf("got error %v for a valid graph", err)
+    }
+}
@@ -0,0 +1,281 @@
+// Package view gives read-only access to object graphs without copying
+// them, for code that must not change a value it is handed but does not need
+// a clone of it. A View reads the values of a graph through the paths and
+// traversal of cloner: pointers and interfaces are followed, and only
+// exported struct fields are visible. Booleans, numbers and strings are
+// returned as values; pointers, slices and maps are only reachable through
+// other views, so that the graph cannot be changed through a View.
+package view
+
+import (
+    "fmt"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// View is a read-only accessor of a value. The zero View is invalid, like
+// the views of values that do not exist.
+type View struct {
+    v reflect.Value
+}
+
+// Of returns a view of v.
+func Of(v interface{}) View {
+    return newView(reflect.ValueOf(v))
+}
+
+// newView returns the view of v, following non-nil pointers and interfaces.
+func newView(v reflect.Value) View {
+    for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+        v = v.Elem()
+    }
+    return View{v: v}
+}
+
+// IsValid reports whether the view is of a value.
+func (v View) IsValid() bool {
+    return v.v.IsValid()
+}
+
+// Kind returns the kind of the value, Invalid for an invalid view.
+func (v View) Kind() reflect.Kind {
+    return v.v.Kind()
+}
+
+// Type returns the type of the value, nil for an invalid view.
+func (v View) Type() reflect.Type {
+    if !v.v.IsValid() {
+        return nil
+    }
+    return v.v.Type()
+}
+
+// IsNil reports whether the value is a nil pointer, interface, slice or map.
+func (v View) IsNil() bool {
+    switch v.v.Kind() {
+    case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+        return v.v.IsNil()
+    }
+    return false
+}
+
+// Len returns the length of a slice, array, map or string, and 0 for other
+// values.
+func (v View) Len() int {
+    switch v.v.Kind() {
+    case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+        return v.v.Len()
+    }
+    return 0
+}
+
+// Field returns the view of the exported struct field name, or an invalid
+// view if there is none.
+func (v View) Field(name string) View {
+    if v.v.Kind() != reflect.Struct {
+        return View{}
+    }
+    field, ok := v.v.Type().FieldByName(name)
+    if !ok || !field.IsExported() || len(field.Index) > 1 {
+        return View{}
+    }
+    return newView(v.v.Field(field.Index[0]))
+}
+
+// Index returns the view of element i of a slice or array, or an invalid
+// view if there is none.
+func (v View) Index(i int) View {
+    switch v.v.Kind() {
+    case reflect.Slice, reflect.Array:
+        if i >= 0 && i < v.v.Len() {
+            return newView(v.v.Index(i))
+        }
+    }
+    return View{}
+}
+
+// Key returns the view of the value of a map for key, or an invalid view if
+// there is none.
+func (v View) Key(key interface{}) View {
+    if v.v.Kind() != reflect.Map {
+        return View{}
+    }
+    k := reflect.ValueOf(key)
+    if !k.IsValid() || !k.Type().AssignableTo(v.v.Type().Key()) {
+        return View{}
+    }
+    return newView(v.v.MapIndex(k))
+}
+
+// Get returns the view of the value at path, written as in the paths of
+// cloner, e.g. .Items[2].Name or .Labels["env"]. An empty path is the value
+// itself. Map keys are matched by their Go literal.
+func (v View) Get(path cloner.Path) (View, error) {
+    rest := string(path)
+    for rest != "" {
+        var next View
+        switch {
+        case rest[0] == '.':
+            end := strings.IndexAny(rest[1:], ".[") + 1
+            if end == 0 {
+                end = len(rest)
+            }
+            next = v.Field(rest[1:end])
+            rest = rest[end:]
+        case rest[0] == '[':
+            end := strings.Index(rest, "]")
+            if end < 2 {
+                return View{}, fmt.Errorf("view: invalid path %q", path)
+            }
+            next = v.elem(rest[1:end])
+            rest = rest[end+1:]
+        default:
+            return View{}, fmt.Errorf("view: invalid path %q", path)
+        }
+        if !next.IsValid() {
+            return View{}, fmt.Errorf("view: no value at %s of %q", strings.TrimSuffix(string(path), rest), path)
+        }
+        v = next
+    }
+    return v, nil
+}
+
+// elem returns the view of the element of a slice, array or map written as
+// text in a path.
+func (v View) elem(text string) View {
+    if v.v.Kind() != reflect.Map {
+        i, err := strconv.Atoi(text)
+        if err != nil {
+            return View{}
+        }
+        return v.Index(i)
+    }
+    for _, key := range v.v.MapKeys() {
+        if key.CanInterface() && fmt.Sprintf("%#v", key.Interface()) == text {
+            return newView(v.v.MapIndex(key))
+        }
+    }
+    return View{}
+}
+
+// Range calls fn with the views of the index, or key, and the value of
+// every element of a slice, array or map, in order of index or of the Go
+// literals of the keys, until fn returns false.
+func (v View) Range(fn func(key, value View) bool) {
+    switch v.v.Kind() {
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.v.Len(); i++ {
+            if !fn(Of(i), newView(v.v.Index(i))) {
+                return
+            }
+        }
+    case reflect.Map:
+        keys := v.v.MapKeys()
+        literals := make([]string, len(keys))
+        for i, key := range keys {
+            literals[i] = fmt.Sprintf("%#v", key)
+        }
+        sort.Sort(byLiteral{keys, literals})
+        for _, key := range keys {
+            if !fn(newView(key), newView(v.v.MapIndex(key))) {
+                return
+            }
+        }
+    }
+}
+
+// byLiteral sorts map keys by their Go literals.
+type byLiteral struct {
+    keys     []reflect.Value
+    literals []string
+}
+
+func (b byLiteral) Len() int           { return len(b.keys) }
+func (b byLiteral) Less(i, j int) bool { return b.literals[i] < b.literals[j] }
+func (b byLiteral) Swap(i, j int) {
+    b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+    b.literals[i], b.literals[j] = b.literals[j], b.literals[i]
+}
+
+// Walk calls fn with the path and the view of every value of the graph
+// below the value, the value itself included, in the order of cloner.Walk.
+// Memory referenced more than once is walked once. Walk returns the error
+// returned by fn, if any, other than cloner.SkipChildren.
+func (v View) Walk(fn func(path cloner.Path, v View) error) error {
+    if !v.v.IsValid() || !v.v.CanInterface() {
+        return nil
+    }
+    return cloner.Walk(v.v.Interface(), func(node cloner.Node) error {
+        if node.Key || node.Value.Kind() == reflect.Ptr && !node.Value.IsNil() {
+            // Pointed values are walked with the path of their pointer
+            return nil
+        }
+        return fn(node.Path, newView(node.Value))
+    })
+}
+
+// Interface returns the value if it is a boolean, a number or a string,
+// which cannot be used to change the graph, or false otherwise.
+func (v View) Interface() (interface{}, bool) {
+    switch v.v.Kind() {
+    case reflect.Bool, reflect.String,
+        reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+        reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+        if v.v.CanInterface() {
+            return v.v.Interface(), true
+        }
+    }
+    return nil, false
+}
+
+// Bool returns a boolean value, or false for other values.
+func (v View) Bool() bool {
+    return v.v.Kind() == reflect.Bool && v.v.Bool()
+}
+
+// Int returns a signed integer value, or 0 for other values.
+func (v View) Int() int64 {
+    switch v.v.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return v.v.Int()
+    }
+    return 0
+}
+
+// Uint returns an unsigned integer value, or 0 for other values.
+func (v View) Uint() uint64 {
+    switch v.v.Kind() {
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return v.v.Uint()
+    }
+    return 0
+}
+
+// Float returns a floating-point value, or 0 for other values.
+func (v View) Float() float64 {
+    switch v.v.Kind() {
+    case reflect.Float32, reflect.Float64:
+        return v.v.Float()
+    }
+    return 0
+}
+
+// String returns a string value, or a description like "<[]string Value>"
+// for other values, as reflect.Value.String does.
+func (v View) String() string {
+    return v.v.String()
+}
+
+// Clone returns a clone of the value made by cm, for callers that need a
+// copy they may change after all.
+func (v View) Clone(cm *cloner.CloneManager) (interface{}, error) {
+    if !v.v.IsValid() || !v.v.CanInterface() {
+        return nil, nil
+    }
+    return cm.Clone(v.v.Interface())
+}
@@ -0,0 +1,91 @@
+package view_test
+
+import (
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/view"
+)
+
+type Order struct {
+    ID     int
+    Paid   bool
+    Lines  []*Line
+    Labels map[string]string
+    Total  interface{}
+    note   string
+}
+
+type Line struct {
+    SKU   string
+    Price float64
+}
+
+// Test for reading a graph through a view
+func TestView(t *testing.T) {
+    order := &Order{
+        ID:     7,
+        Paid:   true,
+        Lines:  []*Line{{SKU: "a", Price: 1.5}, {SKU: "b", Price: 2}},
+        Labels: map[string]string{"env": "prod", "app": "shop"},
+        Total:  3.5,
+        note:   "hidden",
+    }
+    v := view.Of(order)
+
+    if v.Field("ID").Int() != 7 || !v.Field("Paid").Bool() || v.Field("Lines").Len() != 2 || v.Field("Total").Float() != 3.5 {
+        t.Errorf("got fields %v, %v, %v and %v", v.Field("ID"), v.Field("Paid"), v.Field("Lines").Len(), v.Field("Total"))
+    }
+    if v.Field("note").IsValid() || v.Field("Lines").Index(2).IsValid() || v.Field("Labels").Key("dev").IsValid() {
+        t.Error("got views of values that are not visible")
+    }
+    if _, ok := v.Field("Lines").Interface(); ok {
+        t.Error("got the lines, want them only reachable through views")
+    }
+
+    for path, want := range map[cloner.Path]interface{}{
+        ".Lines[1].SKU":   "b",
+        ".Lines[0].Price": 1.5,
+        `.Labels["env"]`:  "prod",
+    } {
+        got, err := v.Get(path)
+        if err != nil {
+            t.Errorf("Get(%q) failed: %v", path, err)
+            continue
+        }
+        if value, _ := got.Interface(); value != want {
+            t.Errorf("got %v at %q, want %v", value, path, want)
+        }
+    }
+    if root, err := v.Get(""); err != nil || root.Field("ID").Int() != 7 {
+        t.Errorf("got root %v and error %v, want the order", root, err)
+    }
+    if _, err := v.Get(".Lines[5].SKU"); err == nil {
+        t.Error("Get of a missing line succeeded")
+    }
+
+    var keys []string
+    v.Field("Labels").Range(func(key, value view.View) bool {
+        keys = append(keys, key.String()+"="+value.String())
+        return true
+    })
+    if len(keys) != 2 || keys[0] != "app=shop" || keys[1] != "env=prod" {
+        t.Errorf("got labels %v, want them in order", keys)
+    }
+
+    var skus []string
+    v.Walk(func(path cloner.Path, v view.View) error {
+        if sku, ok := v.Field("SKU").Interface(); ok {
+            skus = append(skus, string(path)+"="+sku.(string))
+        }
+        return nil
+    })
+    if len(skus) != 2 || skus[0] != ".Lines[0]=a" || skus[1] != ".Lines[1]=b" {
+        t.Errorf("got SKUs %v, want those of the lines", skus)
+    }
+
+    cloned, err := v.Clone(cloner.NewCloneManager())
+    if err != nil || cloned.(Order).Lines[0] == order.Lines[0] {
+        t.Errorf("got clone %v and error %v, want a deep clone", cloned, err)
+    }
+}
@@ -0,0 +1,92 @@
+// Package wire hands deep copies of object graphs over to another process
+// through a connection, such as a pipe to a worker or a TCP connection. The
+// receiving side gets a graph equal to the one sent, with the same shared
+// references and cycles.
+//
+// Every value is sent as a frame: its length as a 4-byte big-endian integer
+// followed by the value streamed by cloner.CloneTo and encoded with gob. The
+// dynamic types of interface values must be registered with
+// cloner.RegisterType on both sides.
+package wire
+
+import (
+    "bytes"
+    "encoding/binary"
+    "encoding/gob"
+    "errors"
+    "fmt"
+    "io"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+)
+
+// ErrFrameTooLarge reports a frame longer than the MaxFrameSize of the
+// connection.
+var ErrFrameTooLarge = errors.New("wire: frame too large")
+
+// DefaultMaxFrameSize is the default MaxFrameSize of a Conn.
+const DefaultMaxFrameSize = 64 << 20
+
+// Conn sends and receives values over a connection. A Conn may send and
+// receive concurrently, but concurrent Sends, or Receives, must be
+// serialized by the caller.
+type Conn struct {
+    // MaxFrameSize limits the length of the frames sent and received, so
+    // that a broken peer cannot exhaust the memory of the receiver.
+    MaxFrameSize int
+
+    rw      io.ReadWriter
+    manager *cloner.CloneManager
+}
+
+// NewConn returns a Conn sending values over rw with the configuration of
+// manager, or of cloner.Default if manager is nil.
+func NewConn(rw io.ReadWriter, manager *cloner.CloneManager) *Conn {
+    if manager == nil {
+        manager = cloner.Default()
+    }
+    return &Conn{MaxFrameSize: DefaultMaxFrameSize, rw: rw, manager: manager}
+}
+
+// Send writes a deep copy of v to the connection as one frame. Values that
+// cannot be cloned are reported before anything is written.
+func (c *Conn) Send(v interface{}) error {
+    // The frame is written at once, after the header is filled in
+    var frame bytes.Buffer
+    frame.Write(make([]byte, 4))
+    if err := c.manager.CloneTo(gob.NewEncoder(&frame), v); err != nil {
+        return err
+    }
+    size := frame.Len() - 4
+    if size > c.MaxFrameSize {
+        return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+    }
+    binary.BigEndian.PutUint32(frame.Bytes(), uint32(size))
+    _, err := c.rw.Write(frame.Bytes())
+    return err
+}
+
+// Receive reads the next frame of the connection into the value dst points
+// to. It returns io.EOF if the connection is closed between frames. A frame
+// that does not hold a value of the type of dst is skipped entirely, so that
+// the next Receive reads the next frame. After ErrFrameTooLarge, the
+// connection is out of sync and must be closed.
+func (c *Conn) Receive(dst interface{}) error {
+    var header [4]byte
+    if _, err := io.ReadFull(c.rw, header[:]); err != nil {
+        return err
+    }
+    size := binary.BigEndian.Uint32(header[:])
+    if int64(size) > int64(c.MaxFrameSize) {
+        return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+    }
+    frame := io.LimitReader(c.rw, int64(size))
+    err := c.manager.CloneFrom(gob.NewDecoder(frame), dst)
+    if _, discardErr := io.Copy(io.Discard, frame); err == nil {
+        err = discardErr
+    }
+    if err == io.EOF {
+        err = io.ErrUnexpectedEOF
+    }
+    return err
+}
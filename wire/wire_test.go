@@ -0,0 +1,98 @@
+package wire_test
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "net"
+    "reflect"
+    "testing"
+
+    "github.com/jayaprabhakar/go-deeper/cloner"
+    "github.com/jayaprabhakar/go-deeper/wire"
+)
+
+// Test for handing a graph over to another goroutine through a pipe
+func TestSendReceive(t *testing.T) {
+    client, server := net.Pipe()
+    defer client.Close()
+    defer server.Close()
+
+    type chunk struct {
+        Data []byte
+    }
+    type work struct {
+        ID       int
+        Inputs   []*chunk
+        Priority map[string]int
+    }
+    shared := &chunk{Data: []byte("abc")}
+    original := work{ID: 7, Inputs: []*chunk{shared, shared}, Priority: map[string]int{"cpu": 2}}
+
+    errs := make(chan error, 1)
+    go func() {
+        conn := wire.NewConn(client, nil)
+        err := conn.Send(original)
+        if err == nil {
+            err = conn.Send("done")
+        }
+        errs <- err
+    }()
+
+    conn := wire.NewConn(server, nil)
+    var received work
+    if err := conn.Receive(&received); err != nil {
+        t.Fatalf("Receive failed: %v", err)
+    }
+    var done string
+    if err := conn.Receive(&done); err != nil || done != "done" {
+        t.Fatalf("Receive returned %q, %v, want done", done, err)
+    }
+    if err := <-errs; err != nil {
+        t.Fatalf("Send failed: %v", err)
+    }
+
+    if !reflect.DeepEqual(received, original) {
+        t.Errorf("got = %+v, want = %+v", received, original)
+    }
+    if received.Inputs[0] != received.Inputs[1] {
+        t.Errorf("Received inputs do not point to the same chunk")
+    }
+}
+
+// Test for frames that cannot be sent or received
+func TestFrameErrors(t *testing.T) {
+    var buf bytes.Buffer
+    conn := wire.NewConn(&buf, nil)
+
+    if err := conn.Send(func() {}); !errors.Is(err, cloner.ErrUncloneableKind) || buf.Len() != 0 {
+        t.Errorf("got error %v with %d bytes written, want ErrUncloneableKind and none", err, buf.Len())
+    }
+
+    // Frames of another type are skipped
+    if err := conn.Send([]string{"skipped"}); err != nil {
+        t.Fatalf("Send failed: %v", err)
+    }
+    if err := conn.Send(3); err != nil {
+        t.Fatalf("Send failed: %v", err)
+    }
+    var n int
+    if err := conn.Receive(&n); !errors.Is(err, cloner.ErrTypeMismatch) {
+        t.Errorf("got error %v, want ErrTypeMismatch", err)
+    }
+    if err := conn.Receive(&n); err != nil || n != 3 {
+        t.Errorf("Receive returned %d, %v, want 3", n, err)
+    }
+    if err := conn.Receive(&n); err != io.EOF {
+        t.Errorf("got error %v, want io.EOF", err)
+    }
+
+    conn.MaxFrameSize = 16
+    if err := conn.Send(bytes.Repeat([]byte("a"), 16)); !errors.Is(err, wire.ErrFrameTooLarge) {
+        t.Errorf("got error %v, want ErrFrameTooLarge", err)
+    }
+    buf.Write([]byte{0, 0, 1, 0})
+    if err := conn.Receive(&n); !errors.Is(err, wire.ErrFrameTooLarge) {
+        t.Errorf("got error %v, want ErrFrameTooLarge", err)
+    }
+}